@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/inkcheck/ink/internal/config"
+	"github.com/inkcheck/ink/internal/model"
+)
+
+// runExport runs "ink export", which writes a book to a file without
+// starting the interface: ink export -epub|-html [-o path] [folder].
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ink export -epub|-html [-o path] [folder]")
+		fs.PrintDefaults()
+	}
+	epub := fs.Bool("epub", false, "write the book as an EPUB, in reading order with a table of contents")
+	html := fs.Bool("html", false, "write the book as a static website, with an index and a page per document")
+	out := fs.String("o", "", "the `path` to write; by default the book's folder name with .epub, or _site, in the folder")
+	follow := fs.Bool("L", false, "follow symbolic links to directories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("export takes one folder")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	if *epub == *html {
+		return errors.New("export needs one format: -epub or -html")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	opts := []model.Option{model.WithFollowSymlinks(*follow)}
+	if *epub {
+		if *out == "" {
+			*out = filepath.Join(abs, filepath.Base(abs)+".epub")
+		}
+		err = model.ExportEPUB(dir, *out, opts...)
+	} else {
+		if *out == "" {
+			*out = model.SitePath(abs)
+		}
+		var theme string
+		if theme, err = exportTheme(); err != nil {
+			return err
+		}
+		if theme != "" {
+			opts = append(opts, model.WithTheme(theme))
+		}
+		err = model.ExportSite(dir, *out, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Exported", *out)
+	return nil
+}
+
+// exportTheme returns the theme the viewer would start in, from $INK_THEME
+// or the config file, for the website to match; "" for the default.
+func exportTheme() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	if path, err := config.Path(); err == nil {
+		if err := addThemes(filepath.Join(filepath.Dir(path), "themes")); err != nil {
+			return "", err
+		}
+	}
+	if v := os.Getenv(themeEnv); v != "" {
+		name, err := themeName(v)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", themeEnv, err)
+		}
+		return name, nil
+	}
+	if v := cfg.Get("display", "theme"); v != "" {
+		name, err := themeName(v)
+		if err != nil {
+			return "", fmt.Errorf("config: [display] theme: %w", err)
+		}
+		return name, nil
+	}
+	return "", nil
+}
@@ -1,17 +1,57 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 
+	"github.com/inkcheck/ink/internal/config"
+	"github.com/inkcheck/ink/internal/metrics"
 	"github.com/inkcheck/ink/internal/model"
+	"github.com/inkcheck/ink/internal/render"
 )
 
-func parseFlags() int {
-	width := flag.Int("w", 80, "max content width")
+// widthEnv names the environment variable that sets the default of -w.
+const widthEnv = "INK_WIDTH"
+
+// themeEnv names the environment variable that picks the theme, over the
+// config file's.
+const themeEnv = "INK_THEME"
+
+// last is set by -last, which reopens the views ink was showing when it
+// last quit in place of any arguments.
+var last = flag.Bool("last", false, "reopen the views ink was showing when it last quit")
+
+func parseFlags() (int, []model.Option, error) {
+	defaultWidth := 80
+	if v := os.Getenv(widthEnv); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, nil, fmt.Errorf("%s: %q is not a positive whole number", widthEnv, v)
+		}
+		defaultWidth = n
+	}
+	width := flag.Int("w", defaultWidth, "max content width; $"+widthEnv+" sets the default")
+	follow := flag.Bool("L", false, "follow symbolic links to directories")
+	autosave := flag.Int("autosave", 0, "autosave in the editor after `seconds` idle (0 disables)")
+	autosaveBlur := flag.Bool("autosave-blur", false, "autosave in the editor when the terminal loses focus")
+	vim := flag.Bool("vim", false, "use vim-style modal keys in the editor")
+	wrap := flag.Int("wrap", 0, "wrap editor text at `columns` (0 follows -w)")
+	longSentence := flag.Int("long-sentence", 25, "mark sentences of more than `words` words in the editor")
+	hardGrade := flag.Float64("hard-grade", 14, "mark sentences above this Flesch-Kincaid `grade` in the editor")
+	ascii := flag.Bool("ascii", false, "draw with plain ASCII instead of box drawing, bullets and checkboxes")
+	recent := flag.Bool("recent", false, "start on the documents recently opened in any book")
+	preview := flag.String("preview", "", "serve a browser page at `address`, such as localhost:8090, that follows the open document")
 	flag.Parse()
 	if *width < 1 {
 		*width = 1
@@ -19,14 +59,237 @@ func parseFlags() int {
 	if *width > 200 {
 		*width = 200
 	}
-	return *width
+	opts := []model.Option{
+		model.WithFollowSymlinks(*follow),
+		model.WithAutosave(time.Duration(max(*autosave, 0))*time.Second, *autosaveBlur),
+		model.WithVimKeys(*vim),
+		model.WithWrapWidth(max(*wrap, 0)),
+		model.WithSentenceLimits(*longSentence, *hardGrade),
+		model.WithASCII(*ascii),
+		model.WithRecent(*recent),
+		// Colors themselves are dropped by the terminal renderer.
+		model.WithNoColor(os.Getenv("NO_COLOR") != ""),
+	}
+	if *preview != "" {
+		p, err := startPreview(*preview)
+		if err != nil {
+			return 0, nil, fmt.Errorf("-preview: %w", err)
+		}
+		opts = append(opts, model.WithPreview(p))
+	}
+	return *width, opts, nil
 }
 
 
-func resolveModel(args []string, width int) (tea.Model, error) {
+// configOptions turns the config file's settings into model options.
+func configOptions(cfg *config.Config) ([]model.Option, error) {
+	opts := []model.Option{
+		model.WithSaveHooks(cfg.All("editor", "on-save")),
+		model.WithDateFormats(cfg.Get("editor", "date-format"), cfg.Get("editor", "time-format"), cfg.Get("editor", "timestamp-format")),
+		model.WithLintCommands(cfg.All("editor", "lint")),
+	}
+	pandocArgs := make(map[string][]string)
+	for _, format := range append([]string{""}, model.ExportFormats()...) {
+		key := strings.TrimPrefix(format+"-args", "-")
+		for _, line := range cfg.All("export", key) {
+			pandocArgs[format] = append(pandocArgs[format], strings.Fields(line)...)
+		}
+	}
+	opts = append(opts, model.WithPandocArgs(pandocArgs))
+	wrapOnSave, err := configCount(cfg, "editor", "wrap-on-save")
+	if err != nil {
+		return nil, err
+	}
+	zenWidth, err := configCount(cfg, "editor", "zen-width")
+	if err != nil {
+		return nil, err
+	}
+	zenPadding, err := configCount(cfg, "editor", "zen-padding")
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, model.WithWrapOnSave(wrapOnSave), model.WithZenLayout(zenWidth, zenPadding))
+	target, err := metrics.ParseTarget(cfg.Get("metrics", "target"))
+	if err != nil {
+		return nil, fmt.Errorf("config: [metrics] %w", err)
+	}
+	var tolerance float64
+	if v := cfg.Get("metrics", "tolerance"); v != "" {
+		tolerance, err = strconv.ParseFloat(v, 64)
+		if err != nil || tolerance <= 0 || tolerance > 1 {
+			return nil, fmt.Errorf("config: [metrics] tolerance: %q is not a number from 0 to 1", v)
+		}
+	}
+	if len(target) > 0 {
+		opts = append(opts, model.WithMetricsTarget(target, tolerance))
+	}
+	var thresholds []metrics.Threshold
+	for _, v := range cfg.All("metrics", "warn") {
+		t, err := metrics.ParseThreshold(v)
+		if err != nil {
+			return nil, fmt.Errorf("config: [metrics] warn: %w", err)
+		}
+		thresholds = append(thresholds, t)
+	}
+	if len(thresholds) > 0 {
+		opts = append(opts, model.WithThresholds(thresholds))
+	}
+	if v := cfg.Get("metrics", "readability"); v != "" {
+		var names []string
+		for _, name := range strings.Split(v, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !slices.Contains(model.ReadabilityNames(), name) {
+				return nil, fmt.Errorf("config: [metrics] readability: %q is not one of %s", name, strings.Join(model.ReadabilityNames(), ", "))
+			}
+			names = append(names, name)
+		}
+		opts = append(opts, model.WithReadability(names))
+	}
+	if v := cfg.Get("editor", "metrics-strip"); v != "" {
+		var axes []string
+		for _, name := range strings.Split(v, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !slices.Contains(metrics.AxisNames, name) {
+				return nil, fmt.Errorf("config: [editor] metrics-strip: %q is not one of %s", name, strings.Join(metrics.AxisNames, ", "))
+			}
+			axes = append(axes, name)
+		}
+		if len(axes) > 3 {
+			return nil, fmt.Errorf("config: [editor] metrics-strip: at most 3 axes fit")
+		}
+		opts = append(opts, model.WithMetricsStrip(axes))
+	}
+	if v := cfg.Get("display", "theme"); v != "" {
+		name, err := themeName(v)
+		if err != nil {
+			return nil, fmt.Errorf("config: [display] theme: %w", err)
+		}
+		opts = append(opts, model.WithTheme(name))
+	}
+	formats, err := configFormats(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(formats) > 0 {
+		opts = append(opts, model.WithFormats(formats))
+	}
+	if entries := cfg.Section("external-editor"); len(entries) > 0 {
+		args := make(map[string]string, len(entries))
+		for _, e := range entries {
+			if !strings.Contains(e.Value, "{line}") {
+				return nil, fmt.Errorf("config: [external-editor] line %d: %s has no {line}", e.Line, e.Key)
+			}
+			args[e.Key] = e.Value
+		}
+		opts = append(opts, model.WithEditorArgs(args))
+	}
+	if v := cfg.Get("rewrite", "command"); v != "" {
+		opts = append(opts, model.WithRewrite(v, cfg.Get("rewrite", "prompt")))
+	}
+	public := false
+	if v := cfg.Get("share", "public"); v != "" {
+		if public, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("config: [share] public: %q is not true or false", v)
+		}
+	}
+	opts = append(opts, model.WithShare(cfg.Get("share", "command"), cfg.Get("share", "token"), public))
+	var keys map[string][]string
+	if entries := cfg.Section("keys"); len(entries) > 0 {
+		keys = make(map[string][]string)
+		for _, e := range entries {
+			var bound []string
+			for _, k := range strings.Split(e.Value, ",") {
+				if k = strings.TrimSpace(k); k != "" {
+					bound = append(bound, k)
+				}
+			}
+			keys[e.Key] = bound
+		}
+		if err := model.CheckKeys(keys); err != nil {
+			return nil, fmt.Errorf("config: [keys] %w", err)
+		}
+		opts = append(opts, model.WithKeys(keys))
+	}
+	if entries := cfg.Section("plugins"); len(entries) > 0 {
+		plugins := make(map[string]string, len(entries))
+		for _, e := range entries {
+			if e.Value == "" {
+				return nil, fmt.Errorf("config: [plugins] line %d: %s has no command", e.Line, e.Key)
+			}
+			plugins[e.Key] = e.Value
+		}
+		if err := model.CheckPlugins(plugins, keys); err != nil {
+			return nil, fmt.Errorf("config: [plugins] %w", err)
+		}
+		opts = append(opts, model.WithPlugins(plugins))
+	}
+	return opts, nil
+}
+
+// addThemes adds the glamour style JSON files in dir as themes, named
+// after the files.
+func addThemes(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		t, err := render.OpenGlamourTheme(path)
+		if err != nil {
+			return err
+		}
+		render.AddTheme(t)
+	}
+	return nil
+}
+
+// themeName resolves a theme setting: the name of a theme, or the path of
+// a glamour style JSON file, which is added as a theme.
+func themeName(v string) (string, error) {
+	if strings.EqualFold(filepath.Ext(v), ".json") {
+		t, err := render.OpenGlamourTheme(v)
+		if err != nil {
+			return "", err
+		}
+		render.AddTheme(t)
+		return t.Name, nil
+	}
+	if _, ok := render.ThemeNamed(v); !ok {
+		return "", fmt.Errorf("%q is not one of %s", v, strings.Join(render.ThemeNames(), ", "))
+	}
+	return v, nil
+}
+
+// configFormats reads the [formats] section: commands converting files with
+// other extensions to markdown, by lowercase extension with its dot.
+func configFormats(cfg *config.Config) (map[string]string, error) {
+	formats := make(map[string]string)
+	for _, e := range cfg.Section("formats") {
+		if e.Value == "" {
+			return nil, fmt.Errorf("config: [formats] line %d: %s has no command", e.Line, e.Key)
+		}
+		formats["."+strings.ToLower(strings.TrimPrefix(e.Key, "."))] = e.Value
+	}
+	return formats, nil
+}
+
+// configCount reads a setting that must be a whole number, 0 when unset.
+func configCount(cfg *config.Config, section, key string) (int, error) {
+	v := cfg.Get(section, key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("config: [%s] %s: %q is not a whole number", section, key, v)
+	}
+	return n, nil
+}
+
+func resolveModel(args []string, width int, formats map[string]string, opts []model.Option) (tea.Model, error) {
 	switch {
 	case len(args) == 0:
-		return model.New(".", width), nil
+		return model.New(".", width, opts...), nil
 
 	case len(args) == 1:
 		arg := args[0]
@@ -35,12 +298,12 @@ func resolveModel(args []string, width int) (tea.Model, error) {
 			return nil, err
 		}
 		if info.IsDir() {
-			return model.New(arg, width), nil
+			return model.New(arg, width, opts...), nil
 		}
-		if !model.IsMarkdownFile(arg) {
+		if !model.IsDocumentFile(arg, formats) {
 			return nil, fmt.Errorf("%s is not a markdown file", arg)
 		}
-		return model.NewFromFile(arg, width), nil
+		return model.NewFromFile(arg, width, opts...), nil
 
 	default:
 		var files []string
@@ -51,27 +314,99 @@ func resolveModel(args []string, width int) (tea.Model, error) {
 			}
 			if info.IsDir() {
 				files = append(files, arg)
-			} else if model.IsMarkdownFile(arg) {
+			} else if model.IsDocumentFile(arg, formats) {
 				files = append(files, arg)
 			}
 		}
 		if len(files) == 0 {
 			return nil, fmt.Errorf("no markdown files found in arguments")
 		}
-		return model.NewFromFiles(files, width), nil
+		return model.NewFromFiles(files, width, opts...), nil
 	}
 }
 
+// commands are the subcommands run in place of the interface, by name.
+var commands = map[string]func([]string) error{
+	"export":     runExport,
+	"serve":      runServe,
+	"ssh-server": runSSHServer,
+}
+
 func main() {
-	width := parseFlags()
-	m, err := resolveModel(flag.Args(), width)
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				if !errors.Is(err, flag.ErrHelp) {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	width, opts, err := parseFlags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if path, err := config.Path(); err == nil {
+		opts = append(opts, model.WithConfigPath(path))
+		if err := addThemes(filepath.Join(filepath.Dir(path), "themes")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	cfgOpts, err := configOptions(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts = append(opts, cfgOpts...)
+	if v := os.Getenv(themeEnv); v != "" {
+		name, err := themeName(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", themeEnv, err)
+			os.Exit(1)
+		}
+		opts = append(opts, model.WithTheme(name))
+	}
+	var m tea.Model
+	if *last {
+		if flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "Error: -last takes no files or folders")
+			os.Exit(1)
+		}
+		m, err = model.NewLast(width, opts...)
+	} else {
+		// configOptions has reported any error in the formats.
+		formats, _ := configFormats(cfg)
+		m, err = resolveModel(flag.Args(), width, formats, opts)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	p := tea.NewProgram(m)
-	if _, err := p.Run(); err != nil {
+	// Closing the terminal sends SIGHUP; quit normally so unsaved editor
+	// changes are kept as a draft.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		<-hup
+		p.Quit()
+	}()
+	final, err := p.Run()
+	if fm, ok := final.(model.Model); ok {
+		fm.SaveState()
+		fm.Close()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/model"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// runServe runs "ink serve", which serves a book as a website that reloads
+// when its files change: ink serve [-addr host:port] [folder].
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ink serve [-addr host:port] [folder]")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "localhost:8080", "the `address` to listen on; :8080 lets other devices, like a phone, connect")
+	follow := fs.Bool("L", false, "follow symbolic links to directories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("serve takes one folder")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	name, err := exportTheme()
+	if err != nil {
+		return err
+	}
+	theme := render.Themes[0]
+	if name != "" {
+		theme, _ = render.ThemeNamed(name)
+	}
+	root, source, err := model.ServeSource(dir, model.WithFollowSymlinks(*follow))
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	srv := export.NewServer(root, source, theme)
+	go srv.Watch(context.Background(), 2*time.Second)
+	fmt.Printf("Serving %s at http://%s (ctrl+c to stop)\n", root, net.JoinHostPort(host(*addr), port(ln.Addr())))
+	return http.Serve(ln, srv)
+}
+
+// startPreview serves the browser preview at addr in the background, for
+// -preview.
+func startPreview(addr string) (*export.Preview, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p := export.NewPreview()
+	go http.Serve(ln, p)
+	return p, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/wish/v2"
+	"charm.land/wish/v2/activeterm"
+	"charm.land/wish/v2/bubbletea"
+	"charm.land/wish/v2/logging"
+	"github.com/charmbracelet/ssh"
+
+	"github.com/inkcheck/ink/internal/model"
+	"github.com/inkcheck/ink/internal/render"
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// hostKeyName is the file, in ink's state directory, holding the host key
+// "ink ssh-server" generates when it is given none.
+const hostKeyName = "ssh_host_ed25519"
+
+// runSSHServer runs "ink ssh-server", which serves the interface to SSH
+// clients so a book on a server can be read with nothing installed:
+// ink ssh-server [-addr host:port] [-key file] [-authorized-keys file] [-write] [folder].
+func runSSHServer(args []string) error {
+	fs := flag.NewFlagSet("ssh-server", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ink ssh-server [-addr host:port] [-key file] [-authorized-keys file] [-write] [folder]")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "localhost:23234", "the `address` to listen on; :23234 lets other machines connect")
+	keyPath := fs.String("key", "", "the host key `file`; by default one is generated in ink's state directory")
+	authKeys := fs.String("authorized-keys", "", "let in only the public keys listed in `file`, as in ~/.ssh/authorized_keys; by default anyone can connect")
+	width := fs.Int("w", 80, "max content width")
+	follow := fs.Bool("L", false, "follow symbolic links to directories")
+	write := fs.Bool("write", false, "let clients edit, create and delete files; needs -authorized-keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("ssh-server takes one folder")
+	}
+	if *write && *authKeys == "" {
+		return errors.New("ssh-server -write needs -authorized-keys, or anyone who can connect could change the book")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	if *keyPath == "" {
+		stateDir, err := state.Dir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(stateDir, 0o700); err != nil {
+			return err
+		}
+		*keyPath = filepath.Join(stateDir, hostKeyName)
+	}
+	name, err := exportTheme()
+	if err != nil {
+		return err
+	}
+	// The theme is shared by every session, so it is set once up front and
+	// sessions may not change it.
+	if theme, ok := render.ThemeNamed(name); ok {
+		render.SetTheme(theme)
+	}
+	opts := []model.Option{
+		model.WithFollowSymlinks(*follow),
+		model.WithReadOnly(!*write),
+	}
+	handler := func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
+		session := fmt.Sprintf("ssh %s@%s", s.User(), s.RemoteAddr())
+		m := model.New(dir, min(max(*width, 1), 200), append(opts, model.WithSession(session))...)
+		last := &lastModel{m: m}
+		s.Context().SetValue(lastModelKey{}, last)
+		return sessionModel{Model: m, last: last}, []tea.ProgramOption{tea.WithoutSignalHandler()}
+	}
+	serverOpts := []ssh.Option{
+		ssh.EmulatePty(),
+		wish.WithAddress(*addr),
+		wish.WithHostKeyPath(*keyPath),
+		// The last middleware runs first: log the connection, turn away
+		// clients without a terminal, run ink, then put the session away.
+		wish.WithMiddleware(
+			closeSession,
+			bubbletea.Middleware(handler),
+			activeterm.Middleware(),
+			logging.Middleware(),
+		),
+	}
+	if *authKeys != "" {
+		if _, err := os.Stat(*authKeys); err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, wish.WithAuthorizedKeys(*authKeys))
+	}
+	srv, err := wish.NewServer(serverOpts...)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	mode := "read-only"
+	if *write {
+		mode = "writable"
+	}
+	who := "anyone"
+	if *authKeys != "" {
+		who = "the keys in " + *authKeys
+	}
+	fmt.Printf("Serving %s, %s, to %s, at ssh -p %s %s (ctrl+c to stop)\n", dir, mode, who, port(ln.Addr()), host(*addr))
+	return srv.Serve(ln)
+}
+
+// lastModelKey keys a session's lastModel in its context.
+type lastModelKey struct{}
+
+// lastModel holds the model a session's program last returned, so it can be
+// put away once the program ends.
+type lastModel struct {
+	m model.Model
+}
+
+// sessionModel runs a session's model, keeping track of its latest state.
+type sessionModel struct {
+	model.Model
+	last *lastModel
+}
+
+func (s sessionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := s.Model.Update(msg)
+	if m, ok := next.(model.Model); ok {
+		s.Model = m
+		s.last.m = m
+	}
+	return s, cmd
+}
+
+// closeSession saves what a session read and releases what it held once its
+// program has ended. A client that disconnects mid-edit leaves its changes
+// as a draft.
+func closeSession(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		if last, ok := s.Context().Value(lastModelKey{}).(*lastModel); ok {
+			last.m.SaveState()
+			last.m.Close()
+		}
+		next(s)
+	}
+}
+
+// host returns the host part of addr for the connection hint, localhost
+// when it listens on every interface.
+func host(addr string) string {
+	h, _, err := net.SplitHostPort(addr)
+	if err != nil || h == "" {
+		return "localhost"
+	}
+	return h
+}
+
+// port returns the port a listener got.
+func port(a net.Addr) string {
+	_, p, _ := net.SplitHostPort(a.String())
+	return p
+}
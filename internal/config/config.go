@@ -0,0 +1,225 @@
+// Package config reads ink's configuration file: "key = value" lines grouped
+// under [section] headers, with # comments. A key may be given more than
+// once where a setting takes several values.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Entry is one "key = value" line of a section.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int // line number in the file, for error messages
+}
+
+// Config is a parsed configuration file. A nil *Config is valid and behaves
+// as an empty configuration.
+type Config struct {
+	sections map[string][]Entry
+}
+
+// Env names the environment variable that points ink at another
+// configuration file.
+const Env = "INK_CONFIG"
+
+// Path returns the configuration file's location: $INK_CONFIG, or else
+// $XDG_CONFIG_HOME/ink/config, or ~/.config/ink/config when XDG_CONFIG_HOME
+// is unset.
+func Path() (string, error) {
+	if path := os.Getenv(Env); path != "" {
+		return path, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "ink", "config"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ink", "config"), nil
+}
+
+// Load reads the configuration file from the location Path returns. A
+// missing file yields an empty configuration, unless $INK_CONFIG named it.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, err
+	}
+	if os.Getenv(Env) != "" {
+		if _, err := os.Stat(path); err != nil {
+			return &Config{}, fmt.Errorf("%s: %w", Env, err)
+		}
+	}
+	return Open(path)
+}
+
+// Open reads the configuration file at path. A missing file yields an empty
+// configuration.
+func Open(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return &Config{}, err
+	}
+	defer f.Close()
+	c, err := Parse(f)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// pressSections are the sections whose keys are key presses, such as "P" or
+// "alt+x", which keep their case: "P" and "p" are different keys.
+var pressSections = []string{"plugins"}
+
+// keyName returns how key is stored in section: lowercased, as keys are
+// case-insensitive, except for key presses.
+func keyName(section, key string) string {
+	if slices.Contains(pressSections, section) {
+		return key
+	}
+	return strings.ToLower(key)
+}
+
+// Parse reads a configuration from r. Keys before the first section header
+// belong to the section "". Values may be double-quoted to keep surrounding
+// spaces or a #. Section names and keys are case-insensitive, except keys
+// that are key presses; values keep their case, so the keys [keys] binds
+// do too.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{sections: make(map[string][]Entry)}
+	section := ""
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return c, fmt.Errorf("line %d: unterminated section header", n)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return c, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key = keyName(section, strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return c, fmt.Errorf("line %d: bad quoted value", n)
+			}
+			value = v
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		c.sections[section] = append(c.sections[section], Entry{Key: key, Value: value, Line: n})
+	}
+	return c, sc.Err()
+}
+
+// Section returns the entries of a section in file order.
+func (c *Config) Section(name string) []Entry {
+	if c == nil {
+		return nil
+	}
+	return c.sections[strings.ToLower(name)]
+}
+
+// Get returns the value of key in section, the last one if it is given more
+// than once, or "" when absent.
+func (c *Config) Get(section, key string) string {
+	values := c.All(section, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// All returns every value of key in section, in file order.
+func (c *Config) All(section, key string) []string {
+	var values []string
+	for _, e := range c.Section(section) {
+		if e.Key == keyName(strings.ToLower(section), key) {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// Set writes key = value into section of the configuration file at path. It
+// replaces the key's last value in the section, or else adds the key at the
+// end of the section, adding the section to the file if it is missing. The
+// rest of the file, comments included, is kept as it was.
+func Set(path, section, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var lines []string
+	if text := strings.TrimRight(string(data), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	section = strings.ToLower(section)
+	key = keyName(section, key)
+	if value != strings.TrimSpace(value) || strings.Contains(value, " #") || strings.HasPrefix(value, `"`) {
+		value = strconv.Quote(value)
+	}
+	entry := key + " = " + value
+
+	// found is the line of the key's last value; end the line after the
+	// section's last entry, or -1 when the file lacks the section.
+	found, end, current := -1, -1, ""
+	if section == "" {
+		end = 0
+	}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if current == section {
+				end = i + 1
+			}
+		case current != section || line == "" || strings.HasPrefix(line, "#"):
+		default:
+			end = i + 1
+			if k, _, ok := strings.Cut(line, "="); ok && keyName(section, strings.TrimSpace(k)) == key {
+				found = i
+			}
+		}
+	}
+	switch {
+	case found >= 0:
+		lines[found] = entry
+	case end >= 0:
+		lines = append(lines[:end], append([]string{entry}, lines[end:]...)...)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]", entry)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# ink settings
+top = level
+
+[Editor]
+on-save = prettier --write {file}
+on-save = git add {file}  # stage it
+title = "  padded # kept  "
+
+[plugins]
+P = pandoc -o out.pdf {file}
+p = proselint {file}
+
+[keys]
+back = Q
+`
+	c, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := c.Get("", "top"); got != "level" {
+		t.Errorf("top = %q", got)
+	}
+	hooks := c.All("editor", "on-save")
+	if len(hooks) != 2 || hooks[0] != "prettier --write {file}" || hooks[1] != "git add {file}" {
+		t.Errorf("on-save = %q", hooks)
+	}
+	if got := c.Get("editor", "title"); got != "  padded # kept  " {
+		t.Errorf("quoted value = %q", got)
+	}
+	if entries := c.Section("EDITOR"); len(entries) != 3 || entries[1].Line != 6 {
+		t.Errorf("Section = %+v", entries)
+	}
+	if c.Get("plugins", "P") != "pandoc -o out.pdf {file}" || c.Get("plugins", "p") != "proselint {file}" {
+		t.Errorf("plugins = %+v, want P and p kept apart", c.Section("plugins"))
+	}
+	if got := c.Get("keys", "back"); got != "Q" {
+		t.Errorf("keys back = %q, want Q", got)
+	}
+	if c.Get("editor", "missing") != "" || c.Get("nope", "x") != "" {
+		t.Error("missing keys should be empty")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{"[editor", "just words", `k = "open`} {
+		if _, err := Parse(strings.NewReader(src)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("Parse(%q) error = %v, want one naming line 1", src, err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "config"))
+	if err != nil || c.Get("editor", "x") != "" {
+		t.Errorf("missing file: %v, %+v", err, c)
+	}
+	var nilConfig *Config
+	if nilConfig.Get("a", "b") != "" || nilConfig.Section("a") != nil {
+		t.Error("nil config should be empty")
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, _ := Path()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("[editor]\nwrap = 72\n"), 0644)
+	c, err = Load()
+	if err != nil || c.Get("editor", "wrap") != "72" {
+		t.Errorf("Load = %v, %v", c, err)
+	}
+
+	other := filepath.Join(t.TempDir(), "ink.conf")
+	os.WriteFile(other, []byte("[editor]\nwrap = 60\n"), 0644)
+	t.Setenv(Env, other)
+	if c, err = Load(); err != nil || c.Get("editor", "wrap") != "60" {
+		t.Errorf("Load with %s = %v, %v", Env, c, err)
+	}
+	t.Setenv(Env, other+".missing")
+	if _, err = Load(); err == nil {
+		t.Errorf("a missing file named by %s should be an error", Env)
+	}
+}
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ink", "config")
+	if err := Set(path, "display", "theme", "light"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("# mine\n[editor]\nwrap = 72 # narrow\n\n[display]\ntheme = ink\n\n[metrics]\nwarn = grade > 12\n"), 0644)
+	steps := []struct{ section, key, value string }{
+		{"display", "theme", "light"},
+		{"editor", "zen-width", "66"},
+		{"keys", "save", "ctrl+x"},
+		{"editor", "lint", " vale # quiet"},
+	}
+	for _, s := range steps {
+		if err := Set(path, s.section, s.key, s.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	want := "# mine\n[editor]\nwrap = 72 # narrow\nzen-width = 66\nlint = \" vale # quiet\"\n\n[display]\ntheme = light\n\n" +
+		"[metrics]\nwarn = grade > 12\n\n[keys]\nsave = ctrl+x\n"
+	if string(data) != want {
+		t.Errorf("config after Set:\n%s\nwant:\n%s", data, want)
+	}
+	c, err := Open(path)
+	if err != nil || c.Get("editor", "lint") != " vale # quiet" || c.Get("display", "theme") != "light" {
+		t.Errorf("reading back: %v, %v", c, err)
+	}
+}
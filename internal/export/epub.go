@@ -0,0 +1,293 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Meta describes a book for its EPUB package.
+type Meta struct {
+	Title    string
+	Author   string // may be empty
+	Language string // BCP 47 tag; "en" when empty
+	ID       string // names the book, like its folder, for a stable identifier; the title when empty
+	Modified time.Time
+}
+
+// imageTypes are the media types of the images an EPUB embeds, by
+// extension.
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// epubChapter is a document as a file of the EPUB.
+type epubChapter struct {
+	ID       string
+	Href     string
+	Title    string
+	Body     string
+	Sections []heading // second-level headings, listed under the chapter
+}
+
+// epubImage is an image file embedded in the EPUB.
+type epubImage struct {
+	ID        string
+	Href      string
+	MediaType string
+	src       string
+}
+
+// EPUB writes docs, in order, to w as an EPUB 3 book with a chapter per
+// document and a table of contents of the chapters and their second-level
+// headings. Local images are embedded, and links between the documents
+// lead to their chapters.
+func EPUB(w io.Writer, meta Meta, docs []Document) error {
+	if meta.Language == "" {
+		meta.Language = "en"
+	}
+	if meta.ID == "" {
+		meta.ID = meta.Title
+	}
+	meta.ID = uuidFor(meta.ID)
+	if meta.Modified.IsZero() {
+		meta.Modified = time.Now()
+	}
+	chapterOf := make(map[string]string, len(docs))
+	for i, doc := range docs {
+		chapterOf[doc.Path] = fmt.Sprintf("chapter-%03d.xhtml", i+1)
+	}
+	var images []epubImage
+	imageOf := make(map[string]string)
+	rewrite := func(p, fragment string, image bool) (string, bool) {
+		if image {
+			if href, ok := imageOf[p]; ok {
+				return href, true
+			}
+			mediaType, ok := imageTypes[strings.ToLower(filepath.Ext(p))]
+			if !ok {
+				return "", false
+			}
+			if _, err := os.Stat(p); err != nil {
+				return "", false
+			}
+			id := fmt.Sprintf("image-%03d", len(images)+1)
+			href := "images/" + id + strings.ToLower(filepath.Ext(p))
+			images = append(images, epubImage{ID: id, Href: href, MediaType: mediaType, src: p})
+			imageOf[p] = href
+			return href, true
+		}
+		href, ok := chapterOf[p]
+		if ok && fragment != "" {
+			href += "#" + fragment
+		}
+		return href, ok
+	}
+	chapters := make([]epubChapter, len(docs))
+	for i, doc := range docs {
+		body, headings, err := toXHTML(doc, rewrite, false)
+		if err != nil {
+			return fmt.Errorf("%s: %w", doc.Path, err)
+		}
+		c := epubChapter{
+			ID:    strings.TrimSuffix(chapterOf[doc.Path], ".xhtml"),
+			Href:  chapterOf[doc.Path],
+			Title: doc.Title,
+			Body:  string(body),
+		}
+		for _, h := range headings {
+			if h.Level == 2 {
+				c.Sections = append(c.Sections, h)
+			}
+		}
+		chapters[i] = c
+	}
+
+	z := zip.NewWriter(w)
+	create := func(name string, method uint16) (io.Writer, error) {
+		return z.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: meta.Modified})
+	}
+	// The mimetype file comes first and uncompressed, so readers can tell
+	// an EPUB from its first bytes.
+	mt, err := create("mimetype", zip.Store)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mt, "application/epub+zip"); err != nil {
+		return err
+	}
+	data := struct {
+		Meta     Meta
+		Modified string
+		Chapters []epubChapter
+		Images   []epubImage
+	}{meta, meta.Modified.UTC().Format(time.RFC3339), chapters, images}
+	files := []struct {
+		name string
+		tmpl *template.Template
+		data any
+	}{
+		{"META-INF/container.xml", containerTmpl, nil},
+		{"OEBPS/content.opf", packageTmpl, data},
+		{"OEBPS/nav.xhtml", navTmpl, data},
+		{"OEBPS/style.css", styleTmpl, nil},
+	}
+	for _, f := range files {
+		fw, err := create(f.name, zip.Deflate)
+		if err != nil {
+			return err
+		}
+		if err := f.tmpl.Execute(fw, f.data); err != nil {
+			return err
+		}
+	}
+	for _, c := range chapters {
+		fw, err := create(path.Join("OEBPS", c.Href), zip.Deflate)
+		if err != nil {
+			return err
+		}
+		if err := chapterTmpl.Execute(fw, struct {
+			Meta    Meta
+			Chapter epubChapter
+		}{meta, c}); err != nil {
+			return err
+		}
+	}
+	for _, img := range images {
+		fw, err := create(path.Join("OEBPS", img.Href), zip.Deflate)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(fw, img.src); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// copyFile copies the file at src to w.
+func copyFile(w io.Writer, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// uuidFor derives a stable name-based (version 5 style) UUID URN from s, so
+// exporting the same book again keeps its identifier.
+func uuidFor(s string) string {
+	h := sha1.Sum([]byte(s))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// xmlEscaper escapes text for XML content and attribute values.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+var funcs = template.FuncMap{"xml": xmlEscaper.Replace}
+
+var containerTmpl = template.Must(template.New("container").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`))
+
+var packageTmpl = template.Must(template.New("package").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{xml .Meta.Language}}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{xml .Meta.ID}}</dc:identifier>
+    <dc:title>{{xml .Meta.Title}}</dc:title>
+{{- if .Meta.Author}}
+    <dc:creator>{{xml .Meta.Author}}</dc:creator>
+{{- end}}
+    <dc:language>{{xml .Meta.Language}}</dc:language>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+{{- range .Chapters}}
+    <item id="{{.ID}}" href="{{.Href}}" media-type="application/xhtml+xml"/>
+{{- end}}
+{{- range .Images}}
+    <item id="{{.ID}}" href="{{.Href}}" media-type="{{.MediaType}}"/>
+{{- end}}
+  </manifest>
+  <spine>
+{{- range .Chapters}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+`))
+
+var navTmpl = template.Must(template.New("nav").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="{{xml .Meta.Language}}" xml:lang="{{xml .Meta.Language}}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{xml .Meta.Title}}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>Contents</h1>
+    <ol>
+{{- range .Chapters}}
+      <li><a href="{{.Href}}">{{xml .Title}}</a>
+{{- if .Sections}}
+        <ol>
+{{- $href := .Href}}
+{{- range .Sections}}
+          <li><a href="{{$href}}#{{xml .ID}}">{{xml .Text}}</a></li>
+{{- end}}
+        </ol>
+{{- end}}
+      </li>
+{{- end}}
+    </ol>
+  </nav>
+</body>
+</html>
+`))
+
+var chapterTmpl = template.Must(template.New("chapter").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="{{xml .Meta.Language}}" xml:lang="{{xml .Meta.Language}}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{xml .Chapter.Title}}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+{{.Chapter.Body}}</body>
+</html>
+`))
+
+var styleTmpl = template.Must(template.New("style").Parse(`body { font-family: serif; line-height: 1.5; }
+h1, h2, h3, h4, h5, h6 { font-family: sans-serif; line-height: 1.2; }
+pre, code { font-family: monospace; font-size: 0.9em; }
+pre { white-space: pre-wrap; }
+blockquote { margin-left: 1em; padding-left: 1em; border-left: 3px solid #ccc; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; }
+img { max-width: 100%; }
+`))
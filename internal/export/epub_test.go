@@ -0,0 +1,163 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadTitle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"matter.md":  "---\ntitle: From Matter\n---\n# Heading",
+		"heading.md": "Intro.\n\n## The `First` Heading\n",
+		"none.md":    "Just text.",
+	})
+	tests := map[string]string{
+		"matter.md":  "From Matter",
+		"heading.md": "The First Heading",
+		"none.md":    "none",
+	}
+	for name, want := range tests {
+		doc, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Title != want {
+			t.Errorf("%s: Title = %q, want %q", name, doc.Title, want)
+		}
+	}
+	doc, _ := Load(filepath.Join(dir, "matter.md"))
+	if string(doc.Source) != "# Heading" {
+		t.Errorf("Source = %q, want the front matter left out", doc.Source)
+	}
+}
+
+func TestEPUB(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"one.md":  "# One & Only\n\nSee [two](two.md#part-b) and [the web](https://example.com). ![A pic](pic.png) ![Gone](gone.png)\n\n## Part A\n",
+		"two.md":  "# Two\n\n## Part B\n\nText.\n",
+		"pic.png": "PNG",
+	})
+	var docs []Document
+	for _, name := range []string{"one.md", "two.md"} {
+		doc, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	var buf bytes.Buffer
+	meta := Meta{Title: "A <Book>", Author: "Ann", ID: dir, Modified: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if err := EPUB(&buf, meta, docs); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := z.File[0]; f.Name != "mimetype" || f.Method != zip.Store {
+		t.Errorf("first entry = %s, method %d; want mimetype, stored", f.Name, f.Method)
+	}
+	files := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".opf") || strings.HasSuffix(f.Name, ".xhtml") {
+			if err := wellFormed(data); err != nil {
+				t.Errorf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+	}
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("mimetype = %q", files["mimetype"])
+	}
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:title>A &lt;Book&gt;</dc:title>",
+		"<dc:creator>Ann</dc:creator>",
+		"<dc:language>en</dc:language>",
+		`<meta property="dcterms:modified">2026-01-02T03:04:05Z</meta>`,
+		`<item id="image-001" href="images/image-001.png" media-type="image/png"/>`,
+		`<itemref idref="chapter-001"/>`,
+		`<itemref idref="chapter-002"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf lacks %s:\n%s", want, opf)
+		}
+	}
+	nav := files["OEBPS/nav.xhtml"]
+	for _, want := range []string{
+		`<a href="chapter-001.xhtml">One &amp; Only</a>`,
+		`<a href="chapter-001.xhtml#part-a">Part A</a>`,
+		`<a href="chapter-002.xhtml#part-b">Part B</a>`,
+	} {
+		if !strings.Contains(nav, want) {
+			t.Errorf("nav.xhtml lacks %s:\n%s", want, nav)
+		}
+	}
+	one := files["OEBPS/chapter-001.xhtml"]
+	for _, want := range []string{
+		`<a href="chapter-002.xhtml#part-b">two</a>`,
+		`<a href="https://example.com">the web</a>`,
+		`<img src="images/image-001.png" alt="A pic" />`,
+		`<img src="gone.png" alt="Gone" />`,
+		`<h2 id="part-a">Part A</h2>`,
+	} {
+		if !strings.Contains(one, want) {
+			t.Errorf("chapter-001.xhtml lacks %s:\n%s", want, one)
+		}
+	}
+	if files["OEBPS/images/image-001.png"] != "PNG" {
+		t.Error("the local image should be embedded")
+	}
+
+	var again bytes.Buffer
+	if err := EPUB(&again, meta, docs); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("exporting the same book twice should give the same file")
+	}
+}
+
+// wellFormed reports whether data parses as XML.
+func wellFormed(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+	for {
+		_, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
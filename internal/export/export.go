@@ -0,0 +1,211 @@
+// Package export turns a book's markdown documents into files for reading
+// elsewhere, such as an EPUB.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// xhtml converts markdown to XHTML, which EPUB requires. Raw HTML in the
+// markdown is left out, as it may not be well-formed XML.
+var xhtml = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithRendererOptions(html.WithXHTML()),
+)
+
+// Document is one markdown document of a book.
+type Document struct {
+	Path   string // absolute path, to resolve relative links and images
+	Title  string // front matter title, else the first heading, else the file name
+	Matter frontmatter.Matter
+	Source []byte // markdown without the front matter
+}
+
+// Load reads the document at path.
+func Load(path string) (Document, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return newDocument(abs, raw), nil
+}
+
+// newDocument makes a Document of raw, the content of the file at path.
+func newDocument(path string, raw []byte) Document {
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	doc := Document{Path: path, Source: raw}
+	if m, ok := frontmatter.Parse(raw); ok {
+		doc.Matter = m
+		doc.Source = bytes.TrimLeft(raw[frontmatter.Len(raw):], "\n")
+	}
+	doc.Title = doc.Matter.Get("title")
+	if doc.Title == "" {
+		doc.Title = firstHeading(doc.Source)
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return doc
+}
+
+// Fragment returns the body of raw, the content of the document at path,
+// as HTML without a page around it, to paste into mail or chat. Links and
+// images keep their destinations.
+func Fragment(path string, raw []byte) (string, error) {
+	body, _, err := toXHTML(newDocument(path, raw), func(string, string, bool) (string, bool) { return "", false }, false)
+	return string(body), err
+}
+
+// firstHeading returns the text of the first heading of markdown source.
+func firstHeading(source []byte) string {
+	root := xhtml.Parser().Parse(text.NewReader(source))
+	for n := root.FirstChild(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok {
+			return plainText(h, source)
+		}
+	}
+	return ""
+}
+
+// plainText returns the text inside node, without markup.
+func plainText(node ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					b.Write(t.Segment.Value(source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// heading is a heading of a converted document, for a table of contents.
+type heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// toXHTML converts doc to XHTML. Headings get GitHub-style ids, so links to
+// "#section" keep working, and are returned for a table of contents. The
+// destination of each relative link and image is passed through rewrite,
+// with the path resolved against the document's folder; rewrite returns
+// the new destination, or false to keep the old one. With lines set, each
+// top-level block gets the source line it starts on, from 0, as data-line.
+func toXHTML(doc Document, rewrite rewriteFunc, lines bool) ([]byte, []heading, error) {
+	root := xhtml.Parser().Parse(text.NewReader(doc.Source))
+	if lines {
+		markLines(root, doc.Source)
+	}
+	var headings []heading
+	ids := make(map[string]int)
+	dir := filepath.Dir(doc.Path)
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			h := heading{Level: n.Level, Text: plainText(n, doc.Source)}
+			h.ID = render.Slug(h.Text)
+			if c := ids[h.ID]; c > 0 {
+				ids[h.ID]++
+				h.ID = fmt.Sprintf("%s-%d", h.ID, c)
+			} else {
+				ids[h.ID] = 1
+			}
+			n.SetAttributeString("id", []byte(h.ID))
+			headings = append(headings, h)
+		case *ast.Link:
+			n.Destination = rewriteLocal(n.Destination, dir, false, rewrite)
+		case *ast.Image:
+			n.Destination = rewriteLocal(n.Destination, dir, true, rewrite)
+		}
+		return ast.WalkContinue, nil
+	})
+	var buf bytes.Buffer
+	if err := xhtml.Renderer().Render(&buf, doc.Source, root); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), headings, nil
+}
+
+// markLines sets data-line on each top-level block of root to the line of
+// source it starts on.
+func markLines(root ast.Node, source []byte) {
+	for n := root.FirstChild(); n != nil; n = n.NextSibling() {
+		if start, ok := blockStart(n); ok {
+			n.SetAttributeString("data-line", []byte(strconv.Itoa(bytes.Count(source[:start], []byte("\n")))))
+		}
+	}
+}
+
+// blockStart returns where in the source the text of block n, or of the
+// first block inside it, starts.
+func blockStart(n ast.Node) (int, bool) {
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if start, ok := blockStart(c); ok {
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// rewriteFunc gives the new destination of a link or image to path, or
+// false to keep it.
+type rewriteFunc func(path, fragment string, image bool) (string, bool)
+
+// rewriteLocal passes a relative destination through rewrite, resolved
+// against dir. Web addresses and links within the document are kept.
+func rewriteLocal(dest []byte, dir string, image bool, rewrite rewriteFunc) []byte {
+	u, err := url.Parse(string(dest))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return dest
+	}
+	path := u.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+	}
+	if to, ok := rewrite(path, u.Fragment, image); ok {
+		return []byte(to)
+	}
+	return dest
+}
@@ -0,0 +1,177 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// Preview serves a page that shows the document open in ink, rendered as
+// HTML, and follows it: edits, the line scrolled to and the theme reach the
+// page as server-sent events as soon as ink reports them with Show.
+type Preview struct {
+	mu      sync.Mutex
+	shown   previewState
+	changed chan struct{} // closed, and replaced, when Show changes something
+}
+
+// previewState is what the preview shows.
+type previewState struct {
+	path    string
+	content string
+	line    int // source line at the top of ink's view, from 0
+	theme   render.Theme
+}
+
+// previewEvent is sent to the page: the document, when it changed, and the
+// line to scroll to.
+type previewEvent struct {
+	Title string `json:"title,omitempty"`
+	HTML  string `json:"html,omitempty"`
+	Style string `json:"style,omitempty"`
+	Line  int    `json:"line"`
+}
+
+// NewPreview returns a Preview with nothing to show yet.
+func NewPreview() *Preview {
+	return &Preview{changed: make(chan struct{})}
+}
+
+// Show has the preview show content, the text of the document at path,
+// scrolled to line, from 0, in theme's colors.
+func (p *Preview) Show(path, content string, line int, theme render.Theme) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := previewState{path: path, content: content, line: line, theme: theme}
+	if s.path == p.shown.path && s.content == p.shown.content && s.line == p.shown.line && s.theme.Name == p.shown.theme.Name {
+		return
+	}
+	p.shown = s
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// state returns what to show and a channel closed when that changes.
+func (p *Preview) state() (previewState, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.shown, p.changed
+}
+
+func (p *Preview) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	s, _ := p.state()
+	switch name {
+	case "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = previewTmpl.Execute(w, nil)
+		return
+	case "_events":
+		p.serveEvents(w, r)
+		return
+	case "style.css":
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		_ = siteStyleTmpl.Execute(w, siteColors(s.theme))
+		return
+	}
+	// Images are served from the document's folder, as its relative links
+	// name them.
+	if _, ok := imageTypes[strings.ToLower(path.Ext(name))]; ok && s.path != "" {
+		http.ServeFile(w, r, filepath.Join(filepath.Dir(s.path), filepath.FromSlash(name)))
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// serveEvents streams the document to the page, then each change to it,
+// until the page goes away.
+func (p *Preview) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": following ink\n\n")
+	flusher.Flush()
+	var sent previewState
+	first := true
+	for {
+		s, changed := p.state()
+		if s.path != "" {
+			ev, err := previewUpdate(sent, s, first)
+			if err != nil {
+				ev = previewEvent{Title: "Error", HTML: "<p>" + template.HTMLEscapeString(err.Error()) + "</p>"}
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			sent, first = s, false
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// previewUpdate makes the event that takes the page from showing sent to
+// showing s: the line alone, unless the document or theme changed.
+func previewUpdate(sent, s previewState, first bool) (previewEvent, error) {
+	doc := newDocument(s.path, []byte(s.content))
+	// The line counts from the top of the file; the rendering starts after
+	// the front matter.
+	skipped := strings.Count(s.content, "\n") - bytes.Count(doc.Source, []byte("\n"))
+	ev := previewEvent{Line: max(s.line-skipped, 0)}
+	if !first && s.path == sent.path && s.content == sent.content && s.theme.Name == sent.theme.Name {
+		return ev, nil
+	}
+	body, _, err := toXHTML(doc, func(string, string, bool) (string, bool) { return "", false }, true)
+	if err != nil {
+		return ev, err
+	}
+	ev.Title, ev.HTML = doc.Title, string(body)
+	ev.Style = "style.css?theme=" + s.theme.Name
+	return ev, nil
+}
+
+var previewTmpl = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>ink preview</title>
+  <link id="style" rel="stylesheet" href="style.css">
+</head>
+<body>
+  <main id="doc"><p>Waiting for ink to open a document…</p></main>
+  <script>
+const doc = document.getElementById("doc");
+new EventSource("/_events").onmessage = (e) => {
+  const ev = JSON.parse(e.data);
+  if (ev.html) {
+    doc.innerHTML = ev.html;
+    document.title = ev.title;
+    document.getElementById("style").href = ev.style;
+  }
+  let top = null;
+  for (const el of doc.querySelectorAll("[data-line]")) {
+    if (Number(el.dataset.line) > ev.line) break;
+    top = el;
+  }
+  if (top) top.scrollIntoView(); else window.scrollTo(0, 0);
+};
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+func TestPreview(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"pic.png": "PNG"})
+	path := filepath.Join(dir, "doc.md")
+	const content = "---\ntitle: Doc\n---\n# Doc\n\nFirst.\n\n![A pic](pic.png)\n\n- one\n- two\n"
+	p := NewPreview()
+	p.Show(path, content, 7, render.Themes[0])
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	for path, want := range map[string]string{"/": `new EventSource("/_events")`, "/style.css": "max-width: 46em", "/pic.png": "PNG"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET %s should have %s:\n%s", path, want, body)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	next := func() previewEvent {
+		t.Helper()
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading events: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var ev previewEvent
+				if err := json.Unmarshal([]byte(data), &ev); err != nil {
+					t.Fatal(err)
+				}
+				return ev
+			}
+		}
+	}
+
+	// The document comes first, its blocks marked with their lines; the line
+	// given counts the front matter, the rendering doesn't.
+	ev := next()
+	for _, want := range []string{`<h1 data-line="0" id="doc">Doc</h1>`, `<p data-line="4"><img src="pic.png"`, `<ul data-line="6">`} {
+		if !strings.Contains(ev.HTML, want) {
+			t.Errorf("first event should have %s:\n%s", want, ev.HTML)
+		}
+	}
+	if ev.Title != "Doc" || ev.Line != 4 {
+		t.Errorf("first event: title %q, line %d", ev.Title, ev.Line)
+	}
+
+	// Scrolling sends only the line; an edit sends the document again.
+	p.Show(path, content, 9, render.Themes[0])
+	if ev := next(); ev.HTML != "" || ev.Line != 6 {
+		t.Errorf("after scrolling: %+v", ev)
+	}
+	p.Show(path, content+"\nMore.\n", 9, render.Themes[0])
+	if ev := next(); !strings.Contains(ev.HTML, "More.") {
+		t.Errorf("after an edit: %+v", ev)
+	}
+}
@@ -0,0 +1,178 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inkcheck/ink/internal/ignore"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// Source reads a book for the server: its metadata and its documents in
+// reading order. It is called for each page, so edits show on the next load.
+type Source func() (Meta, []Document, error)
+
+// Server serves a book as a website like Site's, rendering each page when it
+// is asked for, and has the pages open in browsers reload when the book
+// changes.
+type Server struct {
+	root   string
+	source Source
+	theme  render.Theme
+
+	mu      sync.Mutex
+	changed chan struct{} // closed, and replaced, when the book changes
+}
+
+// NewServer returns a Server for the book in the folder root, read from
+// source and styled in theme's colors.
+func NewServer(root string, source Source, theme render.Theme) *Server {
+	return &Server{root: root, source: source, theme: theme, changed: make(chan struct{})}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	switch name {
+	case "_reload":
+		s.serveReload(w, r)
+		return
+	case "style.css":
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		_ = siteStyleTmpl.Execute(w, siteColors(s.theme))
+		return
+	}
+	// Images the pages show are served from the book's folder; nothing else
+	// there is.
+	if _, ok := imageTypes[strings.ToLower(path.Ext(name))]; ok {
+		http.ServeFile(w, r, filepath.Join(s.root, filepath.FromSlash(name)))
+		return
+	}
+	meta, docs, err := s.source()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	site := newSite(s.root, meta, docs)
+	site.live = true
+	var buf bytes.Buffer
+	switch {
+	case name == "" || name == "index.html":
+		err = site.index(&buf)
+	default:
+		i := -1
+		for j, p := range site.pages {
+			if p.Href == name {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		_, err = site.page(&buf, i)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// serveReload holds a server-sent event stream open until the book changes,
+// then sends the page the word to reload.
+func (s *Server) serveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	changed := s.changes()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": waiting for changes\n\n")
+	flusher.Flush()
+	select {
+	case <-changed:
+		fmt.Fprint(w, "data: reload\n\n")
+		flusher.Flush()
+	case <-r.Context().Done():
+	}
+}
+
+// changes returns a channel closed at the next change to the book.
+func (s *Server) changes() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// notify tells the pages waiting on changes that the book changed.
+func (s *Server) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Watch looks for changes to the files in the book's folder every interval
+// until ctx is done, having open pages reload after each.
+func (s *Server) Watch(ctx context.Context, interval time.Duration) {
+	last := folderStamp(s.root)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if now := folderStamp(s.root); now != last {
+			last = now
+			s.notify()
+		}
+	}
+}
+
+// folderStamp sums up the paths, sizes and modification times of the files
+// below root, so that any change to them changes the stamp. Hidden folders,
+// and files and folders the book ignores, are left out, so node_modules or
+// .git aren't walked each time.
+func folderStamp(root string) uint64 {
+	h := fnv.New64a()
+	ignored := &ignore.Matcher{}
+	ignored.Add(root, ignore.Defaults...)
+	ignored.LoadParents(root)
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return nil
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") || ignored.Match(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			ignored.Load(p)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64()
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+func TestServer(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"one.md":    "# One\n\nSee [two](two.md). ![A pic](pic.png)\n",
+		"two.md":    "# Two\n",
+		"pic.png":   "PNG",
+		"notes.txt": "private",
+	})
+	source := func() (Meta, []Document, error) {
+		var docs []Document
+		for _, name := range []string{"one.md", "two.md"} {
+			doc, err := Load(filepath.Join(dir, name))
+			if err != nil {
+				return Meta{}, nil, err
+			}
+			docs = append(docs, doc)
+		}
+		return Meta{Title: "Book"}, docs, nil
+	}
+	srv := NewServer(dir, source, render.Themes[0])
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/", `<a href="one.html">One</a>`},
+		{"/one.html", `<a href="two.html">two</a>`},
+		{"/one.html", `<img src="pic.png" alt="A pic" />`},
+		{"/one.html", `new EventSource("/_reload")`},
+		{"/style.css", "max-width: 46em"},
+		{"/pic.png", "PNG"},
+	}
+	for _, tt := range tests {
+		if code, body := get(tt.path); code != http.StatusOK || !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s = %d, want 200 with %s:\n%s", tt.path, code, tt.want, body)
+		}
+	}
+	for _, path := range []string{"/three.html", "/notes.txt", "/one.md"} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, code)
+		}
+	}
+
+	// Edits show on the next load, and open pages hear to reload.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Watch(ctx, 10*time.Millisecond)
+	resp, err := http.Get(ts.URL + "/_reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	if line, err := events.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("first event line = %q, %v; want a comment", line, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "two.md"), []byte("# Two, Revised\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading events: %v", err)
+		}
+		if line == "data: reload\n" {
+			break
+		}
+	}
+	if _, body := get("/two.html"); !strings.Contains(body, "Two, Revised") {
+		t.Errorf("the page should show the edit:\n%s", body)
+	}
+}
+
+func TestFolderStampSkipsIgnored(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"one.md":                "# One\n",
+		".gitignore":            "build/\n",
+		"build/out.html":        "old",
+		"node_modules/pkg/a.js": "old",
+		".git/objects/ab":       "old",
+		"chapters/.inkignore":   "draft.md\n",
+		"chapters/draft.md":     "old",
+		"chapters/two.md":       "# Two\n",
+	})
+	stamp := folderStamp(dir)
+	for _, name := range []string{"build/out.html", "node_modules/pkg/a.js", ".git/objects/ab", "chapters/draft.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("changed"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := folderStamp(dir); got != stamp {
+			t.Errorf("changing the ignored %s changed the stamp", name)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "chapters", "two.md"), []byte("# Two, Revised\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if folderStamp(dir) == stamp {
+		t.Error("changing a document should change the stamp")
+	}
+}
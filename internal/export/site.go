@@ -0,0 +1,360 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// sitePage is a document as a page of the site.
+type sitePage struct {
+	Href  string // slash-separated path below the site root
+	Title string
+	doc   Document
+}
+
+// siteEntry is a folder or page of the site's index.
+type siteEntry struct {
+	Name     string // folder name, for folders
+	Href     string // page path, for pages
+	Title    string
+	Children []*siteEntry
+}
+
+// site is a book laid out as a website, ready to render its pages.
+type site struct {
+	root   string
+	meta   Meta
+	pages  []sitePage
+	pageOf map[string]string // page paths, by document path
+	live   bool              // pages reload when the server says the book changed
+}
+
+// newSite lays docs out as pages at their paths below root, with .html for
+// .md; documents outside root go at the top.
+func newSite(root string, meta Meta, docs []Document) *site {
+	if meta.Language == "" {
+		meta.Language = "en"
+	}
+	s := &site{root: root, meta: meta, pages: make([]sitePage, len(docs)), pageOf: make(map[string]string, len(docs))}
+	for i, doc := range docs {
+		rel, err := filepath.Rel(root, doc.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(doc.Path)
+		}
+		href := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)) + ".html")
+		s.pages[i] = sitePage{Href: href, Title: doc.Title, doc: doc}
+		s.pageOf[doc.Path] = href
+	}
+	return s
+}
+
+// Site writes docs as a static website into dir: a page per document at its
+// path below root, with .html for .md, each linking to the index and to the
+// documents before and after it, and an index.html listing the documents by
+// folder in the order given. Links between the documents lead to their
+// pages, local images below root are copied beside them, and the pages are
+// styled in theme's colors. Files already in dir are overwritten, not
+// removed.
+func Site(dir, root string, meta Meta, docs []Document, theme render.Theme) error {
+	s := newSite(root, meta, docs)
+	images := make(map[string]string)
+	for i, p := range s.pages {
+		err := writeFile(filepath.Join(dir, filepath.FromSlash(p.Href)), func(w io.Writer) error {
+			shown, err := s.page(w, i)
+			maps.Copy(images, shown)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeFile(filepath.Join(dir, "index.html"), s.index); err != nil {
+		return err
+	}
+	err := writeFile(filepath.Join(dir, "style.css"), func(w io.Writer) error {
+		return siteStyleTmpl.Execute(w, siteColors(theme))
+	})
+	if err != nil {
+		return err
+	}
+	for rel, src := range images {
+		err := writeFile(filepath.Join(dir, filepath.FromSlash(rel)), func(w io.Writer) error {
+			return copyFile(w, src)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// page renders the i-th page to w. It returns the local images the page
+// shows, by their slash-separated path below the book's root, to be served
+// or copied beside it.
+func (s *site) page(w io.Writer, i int) (map[string]string, error) {
+	p := s.pages[i]
+	from := filepath.Dir(filepath.FromSlash(p.Href))
+	relTo := func(href string) string {
+		rel, err := filepath.Rel(from, filepath.FromSlash(href))
+		if err != nil {
+			return href
+		}
+		return filepath.ToSlash(rel)
+	}
+	images := make(map[string]string)
+	rewrite := func(path, fragment string, image bool) (string, bool) {
+		if image {
+			rel, err := filepath.Rel(s.root, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return "", false
+			}
+			if _, err := os.Stat(path); err != nil {
+				return "", false
+			}
+			images[filepath.ToSlash(rel)] = path
+			return relTo(filepath.ToSlash(rel)), true
+		}
+		href, ok := s.pageOf[path]
+		if !ok {
+			return "", false
+		}
+		href = relTo(href)
+		if fragment != "" {
+			href += "#" + fragment
+		}
+		return href, true
+	}
+	body, _, err := toXHTML(p.doc, rewrite, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.doc.Path, err)
+	}
+	data := struct {
+		Meta     Meta
+		Page     sitePage
+		Body     template.HTML
+		Index    string
+		Style    string
+		Prev     *sitePage
+		Next     *sitePage
+		PrevHref string
+		NextHref string
+		Live     bool
+	}{
+		Meta:  s.meta,
+		Page:  p,
+		Body:  template.HTML(body),
+		Index: relTo("index.html"),
+		Style: relTo("style.css"),
+		Live:  s.live,
+	}
+	if i > 0 {
+		data.Prev, data.PrevHref = &s.pages[i-1], relTo(s.pages[i-1].Href)
+	}
+	if i < len(s.pages)-1 {
+		data.Next, data.NextHref = &s.pages[i+1], relTo(s.pages[i+1].Href)
+	}
+	return images, pageTmpl.Execute(w, data)
+}
+
+// index renders the index page to w.
+func (s *site) index(w io.Writer) error {
+	return indexTmpl.Execute(w, struct {
+		Meta    Meta
+		Entries []*siteEntry
+		Live    bool
+	}{s.meta, siteIndex(s.pages), s.live})
+}
+
+// siteIndex arranges pages into a tree of folders, each folder placed where
+// its first page comes in the order of pages.
+func siteIndex(pages []sitePage) []*siteEntry {
+	root := &siteEntry{}
+	for _, p := range pages {
+		parent := root
+		parts := strings.Split(p.Href, "/")
+		for _, name := range parts[:len(parts)-1] {
+			var folder *siteEntry
+			for _, c := range parent.Children {
+				if c.Href == "" && c.Name == name {
+					folder = c
+					break
+				}
+			}
+			if folder == nil {
+				folder = &siteEntry{Name: name}
+				parent.Children = append(parent.Children, folder)
+			}
+			parent = folder
+		}
+		parent.Children = append(parent.Children, &siteEntry{Href: p.Href, Title: p.Title})
+	}
+	return root.Children
+}
+
+// writeFile creates the file at path, and its folder, with what write
+// writes.
+func writeFile(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// siteColorSet is the palette of the site's stylesheet.
+type siteColorSet struct {
+	Background, Text, Muted, Border, Link       string
+	Heading1, Heading2, Heading3, Heading4      string
+	Code, CodeBackground, InlineCode, TableHead string
+}
+
+// siteColors turns theme into CSS colors. Themes leave the background to the
+// terminal, so the page gets a dark one when the theme's text is light, and
+// a white one otherwise.
+func siteColors(theme render.Theme) siteColorSet {
+	text := render.HexColor(theme.Text)
+	background := "#ffffff"
+	if text != "" && luminance(text) > 0.5 {
+		background = "#1c1c1c"
+	}
+	if text == "" {
+		text = "#222222"
+	}
+	or := func(c, fallback string) string {
+		if c = render.HexColor(c); c == "" {
+			return fallback
+		}
+		return c
+	}
+	// The first heading's color is drawn on its background in the terminal;
+	// on a page, the background color reads better as the heading's.
+	h1 := or(theme.Heading1Background, or(theme.Heading1, text))
+	return siteColorSet{
+		Background:     background,
+		Text:           text,
+		Muted:          or(theme.Muted, text),
+		Border:         or(theme.Border, text),
+		Link:           or(theme.Link, text),
+		Heading1:       h1,
+		Heading2:       or(theme.Heading2, h1),
+		Heading3:       or(theme.Heading3, h1),
+		Heading4:       or(theme.Heading4, h1),
+		Code:           or(theme.Code, text),
+		CodeBackground: or(theme.CodeBackground, background),
+		InlineCode:     or(theme.InlineCode, text),
+		TableHead:      or(theme.TableHeader, text),
+	}
+}
+
+// luminance returns the relative luminance, from 0 to 1, of a #rrggbb color.
+func luminance(hex string) float64 {
+	var c color.RGBA
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return 0
+	}
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
+
+var pageTmpl = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="{{.Meta.Language}}">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Page.Title}} · {{.Meta.Title}}</title>
+  <link rel="stylesheet" href="{{.Style}}">
+</head>
+<body>
+  <header><a href="{{.Index}}">{{.Meta.Title}}</a></header>
+  <main>
+{{.Body}}  </main>
+  <nav class="pager">
+    {{- if .Prev}}<a class="prev" href="{{.PrevHref}}">← {{.Prev.Title}}</a>{{end}}
+    {{- if .Next}}<a class="next" href="{{.NextHref}}">{{.Next.Title}} →</a>{{end}}
+  </nav>
+{{- if .Live}}
+  <script>new EventSource("/_reload").onmessage = () => location.reload();</script>
+{{- end}}
+</body>
+</html>
+`))
+
+var indexTmpl = template.Must(template.New("index").Parse(`{{define "tree"}}<ul>
+{{- range .}}
+<li>{{if .Href}}<a href="{{.Href}}">{{.Title}}</a>{{else}}<span class="folder">{{.Name}}</span>{{template "tree" .Children}}{{end}}</li>
+{{- end}}
+</ul>{{end}}<!DOCTYPE html>
+<html lang="{{.Meta.Language}}">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Meta.Title}}</title>
+  <link rel="stylesheet" href="style.css">
+</head>
+<body>
+  <main>
+    <h1>{{.Meta.Title}}</h1>
+    {{- if .Meta.Author}}
+    <p class="author">{{.Meta.Author}}</p>
+    {{- end}}
+    <nav class="contents">
+{{template "tree" .Entries}}
+    </nav>
+  </main>
+{{- if .Live}}
+  <script>new EventSource("/_reload").onmessage = () => location.reload();</script>
+{{- end}}
+</body>
+</html>
+`))
+
+var siteStyleTmpl = texttemplate.Must(texttemplate.New("style").Parse(`body {
+  margin: 0 auto;
+  max-width: 46em;
+  padding: 1em 1.5em 3em;
+  background: {{.Background}};
+  color: {{.Text}};
+  font-family: Georgia, serif;
+  line-height: 1.6;
+}
+header { margin-bottom: 2em; font-family: sans-serif; }
+header a { color: {{.Muted}}; text-decoration: none; }
+h1, h2, h3, h4, h5, h6 { font-family: sans-serif; line-height: 1.25; }
+h1 { color: {{.Heading1}}; }
+h2 { color: {{.Heading2}}; }
+h3 { color: {{.Heading3}}; }
+h4, h5, h6 { color: {{.Heading4}}; }
+a { color: {{.Link}}; }
+code, pre { font-family: ui-monospace, monospace; font-size: 0.9em; }
+code { color: {{.InlineCode}}; background: {{.CodeBackground}}; padding: 0.1em 0.3em; border-radius: 3px; }
+pre { color: {{.Code}}; background: {{.CodeBackground}}; padding: 1em; overflow-x: auto; border-radius: 4px; }
+pre code { color: inherit; background: none; padding: 0; }
+blockquote { margin-left: 0; padding-left: 1em; border-left: 3px solid {{.Border}}; }
+hr { border: none; border-top: 1px solid {{.Border}}; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid {{.Border}}; padding: 0.3em 0.7em; }
+th { color: {{.TableHead}}; }
+del, .footnotes { color: {{.Muted}}; }
+img { max-width: 100%; }
+.author { color: {{.Muted}}; }
+.contents ul { list-style: none; padding-left: 1.2em; }
+.contents > ul { padding-left: 0; }
+.folder { color: {{.Muted}}; font-family: sans-serif; }
+.pager { display: flex; justify-content: space-between; margin-top: 3em; padding-top: 1em; border-top: 1px solid {{.Border}}; font-family: sans-serif; }
+.pager .next { margin-left: auto; }
+`))
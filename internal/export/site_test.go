@@ -0,0 +1,90 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+func TestSite(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"intro.md": "# Intro\n\nOn to [the deep end](notes/deep.md#way-down).\n",
+		"pic.png":  "PNG",
+	})
+	if err := os.Mkdir(filepath.Join(root, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	deep := "# Deep\n\n## Way Down\n\nBack [home](../intro.md). ![A pic](../pic.png)\n"
+	if err := os.WriteFile(filepath.Join(root, "notes", "deep.md"), []byte(deep), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var docs []Document
+	for _, name := range []string{"intro.md", "notes/deep.md"} {
+		doc, err := Load(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	out := filepath.Join(t.TempDir(), "site")
+	theme, _ := render.ThemeNamed("light")
+	if err := Site(out, root, Meta{Title: "Notes & Such", Author: "Ann"}, docs, theme); err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	checks := map[string][]string{
+		"index.html": {
+			"<title>Notes &amp; Such</title>",
+			`<li><a href="intro.html">Intro</a></li>`,
+			`<span class="folder">notes</span><ul>`,
+			`<li><a href="notes/deep.html">Deep</a></li>`,
+		},
+		"intro.html": {
+			`<a href="notes/deep.html#way-down">the deep end</a>`,
+			`<link rel="stylesheet" href="style.css">`,
+			`<a class="next" href="notes/deep.html">Deep →</a>`,
+		},
+		"notes/deep.html": {
+			`<a href="../intro.html">home</a>`,
+			`<img src="../pic.png" alt="A pic" />`,
+			`<h2 id="way-down">Way Down</h2>`,
+			`<link rel="stylesheet" href="../style.css">`,
+			`<header><a href="../index.html">Notes &amp; Such</a></header>`,
+			`<a class="prev" href="../intro.html">← Intro</a>`,
+		},
+		"style.css": {
+			"background: #ffffff;",
+			"a { color: " + render.HexColor(theme.Link) + "; }",
+		},
+	}
+	for name, wants := range checks {
+		got := read(name)
+		for _, want := range wants {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s lacks %s:\n%s", name, want, got)
+			}
+		}
+	}
+	if read("pic.png") != "PNG" {
+		t.Error("the image should be copied to the site")
+	}
+}
+
+func TestSiteColorsDarkTheme(t *testing.T) {
+	theme, _ := render.ThemeNamed("ink")
+	if c := siteColors(theme); c.Background != "#1c1c1c" || c.Text != render.HexColor(theme.Text) {
+		t.Errorf("a theme with light text should get a dark page, got %+v", c)
+	}
+	if c := siteColors(render.Theme{}); c.Background != "#ffffff" || c.Link != c.Text {
+		t.Errorf("a theme without colors should fall back to dark text on white, got %+v", c)
+	}
+}
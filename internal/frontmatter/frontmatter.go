@@ -0,0 +1,179 @@
+// Package frontmatter reads the simple YAML front matter block (--- delimited)
+// that ink writes at the top of new documents.
+package frontmatter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// headLimit caps how much of a file ReadFile scans looking for front matter.
+const headLimit = 64 * 1024
+
+// Field is a single key/value pair from a front matter block. Block lists
+// ("key:" followed by "- item" lines) are collected in Items.
+type Field struct {
+	Key   string
+	Value string
+	Items []string
+}
+
+// Matter is the parsed front matter of a document, in source order.
+type Matter struct {
+	Fields []Field
+}
+
+// Get returns the value for key (case-insensitive), or "" when absent.
+func (m Matter) Get(key string) string {
+	for _, f := range m.Fields {
+		if strings.EqualFold(f.Key, key) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// List returns the values of a list field. Both block lists and inline
+// forms ("[a, b]" or "a, b") are supported.
+func (m Matter) List(key string) []string {
+	for _, f := range m.Fields {
+		if !strings.EqualFold(f.Key, key) {
+			continue
+		}
+		if len(f.Items) > 0 {
+			return f.Items
+		}
+		value := strings.TrimSuffix(strings.TrimPrefix(f.Value, "["), "]")
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+// Parse extracts front matter from the start of source. It reports false when
+// source does not begin with a complete --- delimited block.
+func Parse(source []byte) (Matter, bool) {
+	source = bytes.ReplaceAll(source, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(source, []byte("---\n")) {
+		return Matter{}, false
+	}
+	var m Matter
+	sc := bufio.NewScanner(bytes.NewReader(source[4:]))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimRight(line, " \t") == "---" {
+			return m, true
+		}
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && len(m.Fields) > 0 {
+			last := &m.Fields[len(m.Fields)-1]
+			if last.Value == "" {
+				last.Items = append(last.Items, unquote(strings.TrimSpace(item)))
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m.Fields = append(m.Fields, Field{
+			Key:   strings.TrimSpace(key),
+			Value: unquote(strings.TrimSpace(value)),
+		})
+	}
+	return Matter{}, false
+}
+
+// Len returns the length in bytes of the front matter block at the start of
+// source, including its closing --- line, or 0 when there is none.
+func Len(source []byte) int {
+	if !bytes.HasPrefix(source, []byte("---\n")) {
+		return 0
+	}
+	for i := 4; i < len(source); {
+		end := bytes.IndexByte(source[i:], '\n')
+		next := i + end + 1
+		if end < 0 {
+			end, next = len(source)-i, len(source)
+		}
+		if strings.TrimRight(string(source[i:i+end]), " \t") == "---" {
+			return next
+		}
+		i = next
+	}
+	return 0
+}
+
+// Format renders m as a --- delimited block. Values are quoted where YAML
+// needs it, except inline lists ("[a, b]"), which are written as they are.
+func Format(m Matter) []byte {
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	for _, f := range m.Fields {
+		if len(f.Items) > 0 {
+			b.WriteString(f.Key + ":\n")
+			for _, item := range f.Items {
+				b.WriteString("  - " + Quote(item) + "\n")
+			}
+			continue
+		}
+		value := f.Value
+		if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+			value = Quote(value)
+		}
+		b.WriteString(f.Key + ": " + value + "\n")
+	}
+	b.WriteString("---\n")
+	return b.Bytes()
+}
+
+// Quote returns s as a YAML scalar, double-quoted when it would otherwise
+// be read differently: empty, padded, holding ": " or " #", or starting
+// with a YAML indicator.
+func Quote(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.Contains(s, ": ") ||
+		strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ReadFile parses the front matter of the file at path, reading at most the
+// first headLimit bytes.
+func ReadFile(path string) (Matter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Matter{}, err
+	}
+	defer f.Close()
+	buf := make([]byte, headLimit)
+	n, _ := io.ReadFull(f, buf)
+	m, _ := Parse(buf[:n])
+	return m, nil
+}
+
+// unquote strips matching single or double quotes from a scalar value.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
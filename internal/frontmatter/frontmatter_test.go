@@ -0,0 +1,97 @@
+package frontmatter
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	src := "---\ntitle: \"My \\\"Doc\\\"\"\nauthor: 'O''Brien'\ndate: 2024-05-01\n---\n\n# Body"
+	m, ok := Parse([]byte(src))
+	if !ok {
+		t.Fatal("Parse: expected front matter")
+	}
+	tests := map[string]string{
+		"title":  `My "Doc"`,
+		"author": "O'Brien",
+		"date":   "2024-05-01",
+		"Title":  `My "Doc"`,
+		"none":   "",
+	}
+	for key, want := range tests {
+		if got := m.Get(key); got != want {
+			t.Errorf("Get(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	m, ok := Parse([]byte("---\r\ntitle: Hello\r\n---\r\n"))
+	if !ok || m.Get("title") != "Hello" {
+		t.Errorf("Parse CRLF: got %+v, %v", m, ok)
+	}
+}
+
+func TestParseMissing(t *testing.T) {
+	for _, src := range []string{"# No front matter", "---\ntitle: unterminated\n"} {
+		if _, ok := Parse([]byte(src)); ok {
+			t.Errorf("Parse(%q): expected no front matter", src)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	src := "---\ntags: [go, \"tui\", markdown]\ncategories: notes, drafts\nkeywords:\n  - alpha\n  - 'beta'\ntitle: x\n---\n"
+	m, ok := Parse([]byte(src))
+	if !ok {
+		t.Fatal("Parse: expected front matter")
+	}
+	tests := map[string][]string{
+		"tags":       {"go", "tui", "markdown"},
+		"categories": {"notes", "drafts"},
+		"keywords":   {"alpha", "beta"},
+		"missing":    nil,
+	}
+	for key, want := range tests {
+		got := m.List(key)
+		if len(got) != len(want) {
+			t.Errorf("List(%q) = %q, want %q", key, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("List(%q) = %q, want %q", key, got, want)
+			}
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	tests := map[string]int{
+		"---\ntitle: A\n---\n# Body": 17,
+		"---\ntitle: A\n---":         16,
+		"---\ntitle: A\n":            0,
+		"# No front matter":          0,
+	}
+	for src, want := range tests {
+		if got := Len([]byte(src)); got != want {
+			t.Errorf("Len(%q) = %d, want %d", src, got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m := Matter{Fields: []Field{
+		{Key: "title", Value: "Notes: day one"},
+		{Key: "date", Value: "2024-05-01"},
+		{Key: "tags", Value: "[go, tui]"},
+		{Key: "authors", Items: []string{"Ann", "#1 fan"}},
+		{Key: "status", Value: "draft"},
+	}}
+	want := "---\ntitle: \"Notes: day one\"\ndate: 2024-05-01\ntags: [go, tui]\nauthors:\n  - Ann\n  - \"#1 fan\"\nstatus: draft\n---\n"
+	got := Format(m)
+	if string(got) != want {
+		t.Errorf("Format:\n%s\nwant:\n%s", got, want)
+	}
+	parsed, ok := Parse(got)
+	if !ok || parsed.Get("title") != "Notes: day one" || len(parsed.List("authors")) != 2 || parsed.List("authors")[1] != "#1 fan" {
+		t.Errorf("Format output doesn't parse back: %+v", parsed)
+	}
+}
@@ -0,0 +1,404 @@
+// Package htmlmd turns the HTML that browsers put on the clipboard into
+// markdown. It reads the tags a copied page fragment is made of (paragraphs,
+// headings, emphasis, links, images, lists, quotes and code) and keeps the
+// text of everything else.
+package htmlmd
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tagRe  = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	attrRe = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+// skipped tags hold no text worth keeping.
+var skipped = map[string]bool{"head": true, "script": true, "style": true, "template": true, "title": true}
+
+// blocks are the tags that start a new paragraph.
+var blocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"header": true, "main": true, "nav": true, "p": true, "section": true,
+	"table": true,
+}
+
+// Convert returns the markdown for src, a fragment or page of HTML.
+func Convert(src string) string {
+	c := converter{}
+	c.run(src)
+	return c.String()
+}
+
+// Text returns the text of src, without markup, as it reads on the page.
+func Text(src string) string {
+	c := converter{plain: true}
+	c.run(src)
+	return c.String()
+}
+
+// converter writes markdown, or plain text, as it reads the tags of a
+// document.
+type converter struct {
+	plain   bool
+	out     []byte
+	breaks  int      // newlines owed before the next text
+	quote   int      // open blockquotes
+	pre     int      // open pre blocks, whose text is kept as it is
+	code    int      // open code spans, whose text isn't escaped
+	skip    int      // open tags whose text is dropped
+	lists   []list   // open lists
+	items   []string // indent of each open list item's lines
+	marker  string   // list item marker that starts the next line
+	links   []string // destinations of open links
+	started bool     // whether anything was written
+	hard    bool     // whether the owed newline is a line break, from br
+}
+
+// list is an open ul or ol.
+type list struct {
+	ordered bool
+	next    int
+}
+
+func (c *converter) run(src string) {
+	for src != "" {
+		i := strings.IndexByte(src, '<')
+		if i < 0 {
+			c.text(src)
+			return
+		}
+		c.text(src[:i])
+		src = src[i:]
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			end := strings.Index(src, "-->")
+			if end < 0 {
+				return
+			}
+			src = src[end+3:]
+		case strings.HasPrefix(src, "<!"), strings.HasPrefix(src, "<?"):
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				return
+			}
+			src = src[end+1:]
+		default:
+			m := tagRe.FindStringSubmatch(src)
+			if m == nil {
+				c.text("<")
+				src = src[1:]
+				continue
+			}
+			src = src[len(m[0]):]
+			name := strings.ToLower(m[2])
+			if m[1] == "/" {
+				c.close(name)
+			} else {
+				c.open(name, attributes(m[3]))
+			}
+		}
+	}
+}
+
+// attributes parses the attributes of a start tag.
+func attributes(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+func (c *converter) open(name string, attrs map[string]string) {
+	if skipped[name] {
+		c.skip++
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+	switch {
+	case blocks[name], name == "tr":
+		c.block(2)
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		c.block(2)
+		c.mark(strings.Repeat("#", int(name[1]-'0')) + " ")
+	}
+	switch name {
+	case "br":
+		if c.pre > 0 {
+			c.breaks++
+			return
+		}
+		c.hard = true
+		c.block(1)
+	case "hr":
+		c.block(2)
+		c.mark("---")
+		c.block(2)
+	case "b", "strong":
+		c.inline("**", false)
+	case "em", "i":
+		c.inline("*", false)
+	case "del", "s", "strike":
+		c.inline("~~", false)
+	case "code", "kbd", "samp", "tt":
+		c.inline("`", false)
+		c.code++
+	case "a":
+		href := attrs["href"]
+		if strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			href = ""
+		}
+		c.links = append(c.links, href)
+		if href != "" {
+			c.mark("[")
+		}
+	case "img":
+		if src := attrs["src"]; src != "" && !c.plain {
+			c.mark("![" + escape(attrs["alt"]) + "](" + destination(src) + ")")
+		}
+	case "td", "th":
+		c.text(" ")
+	case "pre":
+		c.block(2)
+		c.mark("```")
+		c.block(1)
+		c.pre++
+	case "blockquote":
+		c.block(2)
+		c.quote++
+	case "ul", "ol":
+		c.block(2)
+		if len(c.items) > 0 && c.started {
+			// Lists inside list items stay tight.
+			c.breaks = 1
+		}
+		start, err := strconv.Atoi(attrs["start"])
+		if err != nil {
+			start = 1
+		}
+		c.lists = append(c.lists, list{ordered: name == "ol", next: start})
+	case "li":
+		c.block(1)
+		marker := "- "
+		if n := len(c.lists); n > 0 && c.lists[n-1].ordered {
+			marker = strconv.Itoa(c.lists[n-1].next) + ". "
+			c.lists[n-1].next++
+		}
+		c.items = append(c.items, strings.Repeat(" ", len(marker)))
+		c.marker = marker
+	}
+}
+
+func (c *converter) close(name string) {
+	if skipped[name] {
+		c.skip = max(c.skip-1, 0)
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+	switch {
+	case blocks[name], name == "tr":
+		c.block(2)
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		c.block(2)
+	}
+	switch name {
+	case "b", "strong":
+		c.inline("**", true)
+	case "em", "i":
+		c.inline("*", true)
+	case "del", "s", "strike":
+		c.inline("~~", true)
+	case "code", "kbd", "samp", "tt":
+		c.inline("`", true)
+		c.code = max(c.code-1, 0)
+	case "a":
+		if n := len(c.links); n > 0 {
+			if href := c.links[n-1]; href != "" {
+				c.mark("](" + destination(href) + ")")
+			}
+			c.links = c.links[:n-1]
+		}
+	case "pre":
+		if c.pre > 0 {
+			c.pre--
+			c.block(1)
+			c.mark("```")
+			c.block(2)
+		}
+	case "blockquote":
+		c.block(2)
+		c.quote = max(c.quote-1, 0)
+	case "ul", "ol":
+		if n := len(c.lists); n > 0 {
+			c.lists = c.lists[:n-1]
+		}
+		c.block(2)
+	case "li":
+		if n := len(c.items); n > 0 {
+			c.items = c.items[:n-1]
+		}
+		c.marker = ""
+		c.block(1)
+	}
+}
+
+// inline writes an emphasis or code delimiter, which pre blocks don't have.
+// A closing delimiter goes before the space that ends the text it closes,
+// as markdown wants it.
+func (c *converter) inline(delim string, closing bool) {
+	if c.pre > 0 || c.plain {
+		return
+	}
+	if !closing || !c.lineStarted() || c.out[len(c.out)-1] != ' ' {
+		c.write(delim)
+		return
+	}
+	c.out = append(c.out[:len(c.out)-1], delim+" "...)
+}
+
+// mark writes markup, which plain text leaves out.
+func (c *converter) mark(s string) {
+	if !c.plain {
+		c.write(s)
+	}
+}
+
+// text writes the text between tags: as it is inside pre, or else with its
+// runs of spaces and newlines folded to one space.
+func (c *converter) text(s string) {
+	if c.skip > 0 || s == "" {
+		return
+	}
+	s = html.UnescapeString(s)
+	if c.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 && c.started {
+				c.breaks++
+			}
+			c.write(line)
+		}
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if c.lineStarted() {
+			c.space()
+		}
+		return
+	}
+	if strings.IndexFunc(s[:1], isSpace) == 0 {
+		c.space()
+	}
+	for i, f := range fields {
+		if i > 0 {
+			c.space()
+		}
+		if c.plain || c.code > 0 {
+			c.write(f)
+		} else {
+			c.write(escape(f))
+		}
+	}
+	if strings.LastIndexFunc(s, isSpace) == len(s)-1 {
+		c.space()
+	}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// space writes a space between words, unless the line has none yet.
+func (c *converter) space() {
+	if c.lineStarted() && c.out[len(c.out)-1] != ' ' {
+		c.out = append(c.out, ' ')
+	}
+}
+
+// lineStarted reports whether the current line has text on it.
+func (c *converter) lineStarted() bool {
+	return c.started && c.breaks == 0
+}
+
+// block asks for n newlines, one to end the line or two to end the
+// paragraph, before whatever is written next.
+func (c *converter) block(n int) {
+	if c.started {
+		c.breaks = max(c.breaks, n)
+	}
+}
+
+// write adds s, after the newlines and the line prefix it is owed.
+func (c *converter) write(s string) {
+	if s == "" {
+		return
+	}
+	if !c.started {
+		c.started = true
+		c.out = append(c.out, c.prefix(true)...)
+	}
+	if c.breaks > 0 {
+		c.out = []byte(strings.TrimRight(string(c.out), " "))
+		if c.hard && c.breaks == 1 && !c.plain {
+			c.out = append(c.out, '\\')
+		}
+		for range c.breaks - 1 {
+			c.out = append(c.out, '\n')
+			c.out = append(c.out, strings.TrimRight(c.prefix(false), " ")...)
+		}
+		c.out = append(c.out, '\n')
+		c.out = append(c.out, c.prefix(true)...)
+		c.breaks = 0
+	}
+	c.hard = false
+	c.out = append(c.out, s...)
+}
+
+// prefix returns what starts a line: the quote markers and the list item's
+// indent, or its marker when first says this is the item's first line.
+func (c *converter) prefix(first bool) string {
+	if c.plain {
+		return ""
+	}
+	p := strings.Repeat("> ", c.quote)
+	for i, indent := range c.items {
+		if first && i == len(c.items)-1 && c.marker != "" {
+			p += c.marker
+			continue
+		}
+		p += indent
+	}
+	if first {
+		c.marker = ""
+	}
+	return p
+}
+
+func (c *converter) String() string {
+	return strings.TrimSpace(string(c.out))
+}
+
+// escape keeps text from reading as markdown syntax.
+func escape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+// destination writes a link destination, in angle brackets when it has
+// spaces or parentheses.
+func destination(s string) string {
+	if strings.ContainsAny(s, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(s) + ">"
+	}
+	return s
+}
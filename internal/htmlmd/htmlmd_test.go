@@ -0,0 +1,34 @@
+package htmlmd
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"inline", `<b>Bold</b> and <em>soft </em>text`, "**Bold** and *soft* text"},
+		{"browser fragment", "<meta charset='utf-8'><h2 id=\"x\">Title</h2><p>One &amp; two,\n  <a href=\"https://example.com/a b\">link</a>.</p><p>Next<br>line</p>",
+			"## Title\n\nOne & two, [link](<https://example.com/a b>).\n\nNext\\\nline"},
+		{"lists", "<ul><li>one</li><li>two<ol start=\"3\"><li>three</li></ol></li></ul><p>after</p>",
+			"- one\n- two\n  3. three\n\nafter"},
+		{"quote", "<blockquote><p>first</p><p>second</p></blockquote>", "> first\n>\n> second"},
+		{"code", "<p>Run <code>a*b</code></p><pre><code>x := 1\n\ny := 2\n</code></pre>",
+			"Run `a*b`\n\n```\nx := 1\n\ny := 2\n```"},
+		{"escaped", "<p>2 * 3 [not a link]</p>", `2 \* 3 \[not a link\]`},
+		{"image", `<img src="cat.png" alt="A cat">`, "![A cat](cat.png)"},
+		{"dropped", "<style>p { color: red }</style><script>alert(1)</script><!-- note --><span>kept</span>", "kept"},
+		{"rule", "<p>a</p><hr><p>b</p>", "a\n\n---\n\nb"},
+	}
+	for _, tt := range tests {
+		if got := Convert(tt.html); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	got := Text(`<h1>Title</h1><p>Some <b>bold</b> <a href="x">link</a></p><ul><li>item</li></ul><img src="x.png">`)
+	if want := "Title\n\nSome bold link\n\nitem"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+// Package ignore matches paths against gitignore-style patterns, as found in
+// .gitignore and .inkignore files.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Files are the names of the ignore files read from each directory, in
+// order of precedence (later files override earlier ones).
+var Files = []string{".gitignore", ".inkignore"}
+
+// Defaults are the patterns ignored in every book, below the ignore files'
+// own, so an ignore file can re-include them with a "!" pattern.
+var Defaults = []string{"node_modules/", "vendor/", "__pycache__/"}
+
+// rule is a single pattern, relative to the directory it was read from.
+type rule struct {
+	base     string // directory the pattern is relative to
+	pattern  string // slash-separated glob, without leading "/" or trailing "/"
+	negate   bool   // "!pattern" re-includes a previously ignored path
+	dirOnly  bool   // "pattern/" only matches directories
+	anchored bool   // the pattern contains a "/" and matches from base
+}
+
+// Matcher decides whether paths are ignored. The last matching pattern
+// wins, as in git. The zero value ignores nothing.
+type Matcher struct {
+	rules []rule
+}
+
+// Add adds patterns in gitignore syntax, relative to the directory base.
+func (m *Matcher) Add(base string, patterns ...string) {
+	for _, line := range patterns {
+		if r, ok := parseRule(base, line); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// Load adds the patterns of the ignore files in dir, if any.
+func (m *Matcher) Load(dir string) {
+	for _, name := range Files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			m.Add(dir, sc.Text())
+		}
+	}
+}
+
+// LoadParents adds the patterns of the ignore files in dir and each of its
+// parent directories up to the root of the enclosing git repository (or
+// the filesystem root outside a repository). Patterns closer to dir take
+// precedence.
+func (m *Matcher) LoadParents(dir string) {
+	var dirs []string
+	for d := filepath.Clean(dir); ; {
+		dirs = append(dirs, d)
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		m.Load(dirs[i])
+	}
+}
+
+// Match reports whether the file or directory at path is ignored.
+func (m *Matcher) Match(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, r := range m.rules {
+		// Only rules that would flip the current verdict matter.
+		if r.negate != ignored || r.dirOnly && !isDir {
+			continue
+		}
+		if r.match(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parseRule parses one line of an ignore file.
+func parseRule(base, line string) (rule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// match reports whether path, which must be below the rule's base, matches
+// the pattern.
+func (r rule) match(p string) bool {
+	rel, err := filepath.Rel(r.base, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if !r.anchored {
+		return matchSegments([]string{r.pattern}, []string{path.Base(rel)})
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against glob segments, where "**"
+// matches any number of segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,85 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	root := filepath.FromSlash("/book")
+	var m Matcher
+	m.Add(root,
+		"# comment",
+		"",
+		"build/",
+		"*.draft.md",
+		"!keep.draft.md",
+		"/TODO.md",
+		"docs/**/generated.md",
+		`\#hash.md`,
+	)
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"sub/build", true, true},
+		{"build", false, false}, // dir-only pattern
+		{"a.draft.md", false, true},
+		{"sub/b.draft.md", false, true},
+		{"keep.draft.md", false, false},
+		{"TODO.md", false, true},
+		{"sub/TODO.md", false, false}, // anchored to the base
+		{"docs/generated.md", false, true},
+		{"docs/a/b/generated.md", false, true},
+		{"other/generated.md", false, false},
+		{"#hash.md", false, true},
+		{"notes.md", false, false},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := m.Match(p, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+	if m.Match(filepath.FromSlash("/elsewhere/a.draft.md"), false) {
+		t.Error("patterns should only apply below their base")
+	}
+	var nilMatcher *Matcher
+	if nilMatcher.Match(filepath.Join(root, "build"), true) {
+		t.Error("nil Matcher should ignore nothing")
+	}
+}
+
+func TestLoadParents(t *testing.T) {
+	repo := t.TempDir()
+	book := filepath.Join(repo, "book")
+	for path, content := range map[string]string{
+		".git/HEAD":        "ref: refs/heads/main\n",
+		".gitignore":       "out/\n*.gen.md\n",
+		"book/.inkignore":  "private.md\n!keep.gen.md\n",
+		"book/private.md":  "",
+		"book/keep.gen.md": "",
+	} {
+		full := filepath.Join(repo, filepath.FromSlash(path))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	var m Matcher
+	m.LoadParents(book)
+	tests := map[string]bool{
+		"out":         true,
+		"a.gen.md":    true,
+		"keep.gen.md": false, // re-included by the closer .inkignore
+		"private.md":  true,
+		"chapter.md":  false,
+	}
+	for name, want := range tests {
+		isDir := name == "out"
+		if got := m.Match(filepath.Join(book, name), isDir); got != want {
+			t.Errorf("Match(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
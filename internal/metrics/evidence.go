@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+
+	"github.com/inkcheck/readability"
+)
+
+// maxEvidence is how many sentences an axis's evidence holds at most.
+const maxEvidence = 5
+
+// hardGrade is the Flesch-Kincaid grade above which a sentence counts
+// against readability.
+const hardGrade = 12
+
+// evidence returns the sentences that pull the axis called name down, worst
+// first. words holds the words of each sentence.
+func evidence(name string, sentences []Sentence, words [][]string) []Sentence {
+	score := make([]float64, len(sentences))
+	switch name {
+	case Rhythm:
+		// A run of sentences of about the same length drones; each
+		// sentence of the longest run counts by how long the run is.
+		start, best, bestStart := 0, 1, -1
+		for i := 1; i <= len(words); i++ {
+			if i < len(words) && sameLength(len(words[i-1]), len(words[i])) {
+				continue
+			}
+			if n := i - start; n > best {
+				best, bestStart = n, start
+			}
+			start = i
+		}
+		if best < 3 {
+			return nil
+		}
+		for i := bestStart; i < bestStart+best; i++ {
+			score[i] = float64(best)
+		}
+	case Economy:
+		for i, w := range words {
+			for _, word := range w {
+				if fillers[word] {
+					score[i]++
+				}
+			}
+		}
+	case Richness:
+		// Words repeated within a sentence, beyond the short ones that
+		// every sentence repeats.
+		for i, w := range words {
+			seen := map[string]bool{}
+			for _, word := range w {
+				if len(word) > 3 && seen[word] {
+					score[i]++
+				}
+				seen[word] = true
+			}
+		}
+	case Readability:
+		for i, s := range sentences {
+			if len(words[i]) < 5 {
+				continue
+			}
+			if g := readability.NewAnalysis(s.Text).FleschKincaidGrade(); g > hardGrade {
+				score[i] = g
+			}
+		}
+	case Variety:
+		// Sentences that open like an earlier one, the most repeated
+		// opening first.
+		openers := map[string]int{}
+		for _, w := range words {
+			if len(w) > 0 {
+				openers[w[0]]++
+			}
+		}
+		seen := map[string]bool{}
+		for i, w := range words {
+			if len(w) == 0 {
+				continue
+			}
+			if seen[w[0]] {
+				score[i] = float64(openers[w[0]])
+			}
+			seen[w[0]] = true
+		}
+	}
+	var order []int
+	for i, s := range score {
+		if s > 0 {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case score[a] > score[b]:
+			return -1
+		case score[a] < score[b]:
+			return 1
+		}
+		return 0
+	})
+	var out []Sentence
+	for _, i := range order[:min(len(order), maxEvidence)] {
+		out = append(out, sentences[i])
+	}
+	return out
+}
+
+// sameLength reports whether two sentence lengths, in words, are close
+// enough to sound alike: within two words or a fifth of each other.
+func sameLength(a, b int) bool {
+	d := math.Abs(float64(a - b))
+	return d <= 2 || d <= 0.2*float64(max(a, b))
+}
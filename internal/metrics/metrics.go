@@ -0,0 +1,459 @@
+// Package metrics measures the style of a markdown document's prose: how
+// its sentence lengths vary, how much filler it carries, how rich its
+// vocabulary is. Each measurement is an axis scored from 0 to 1, so a
+// document's axes together form its writing signature.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/inkcheck/readability"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// Axis is one dimension of a document's writing signature.
+type Axis struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`  // 0 to 1, higher is better
+	Detail string  `json:"detail"` // the measurement behind the score
+	// Evidence holds the sentences that pull the score down, worst first.
+	// An Analyzer that can't point at sentences leaves it empty.
+	Evidence []Sentence `json:"evidence,omitempty"`
+}
+
+// SentenceStats sums up the lengths of a document's sentences, in words.
+type SentenceStats struct {
+	Count    int     `json:"count"`
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"stddev"`
+	Shortest int     `json:"shortest"`
+	Longest  int     `json:"longest"`
+}
+
+// Result is the analysis of one document.
+type Result struct {
+	Words     int
+	Grade     float64 // Flesch-Kincaid grade; 0 when the text is too short
+	Sentences SentenceStats
+	Axes      []Axis
+}
+
+// Axis returns the axis called name, reporting whether the result has it.
+func (r Result) Axis(name string) (Axis, bool) {
+	for _, a := range r.Axes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Axis{}, false
+}
+
+// Analyzer measures a document. Native is the one built into ink; another
+// implementation, such as a service or a fake in tests, can stand in for it.
+type Analyzer interface {
+	Analyze(markdown string) (Result, error)
+}
+
+// Native is the Analyzer built into ink. It never fails.
+type Native struct{}
+
+// Analyze measures markdown with the package's Analyze.
+func (Native) Analyze(markdown string) (Result, error) {
+	return Analyze(markdown), nil
+}
+
+// Target is a desired writing signature: a score from 0 to 1 per axis name.
+type Target map[string]float64
+
+// ParseTarget reads a target written as axis and score pairs separated by
+// commas, such as "economy 0.8, rhythm 0.5". A colon or equals sign may
+// stand between the two. Axis names aren't checked, since an Analyzer other
+// than Native may measure axes of its own.
+func ParseTarget(s string) (Target, error) {
+	t := Target{}
+	for _, pair := range strings.Split(s, ",") {
+		fields := strings.Fields(strings.NewReplacer(":", " ", "=", " ").Replace(pair))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("target %q: want an axis and a score", strings.TrimSpace(pair))
+		}
+		score, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("target %q: the score must be from 0 to 1", strings.TrimSpace(pair))
+		}
+		t[strings.ToLower(fields[0])] = score
+	}
+	return t, nil
+}
+
+// Threshold is a limit on a measurement: an axis score, or the grade.
+type Threshold struct {
+	Name  string  // axis name, or "grade" for the Flesch-Kincaid grade
+	Above bool    // true when exceeding the limit is higher, false when lower
+	Limit float64 // the limit itself
+}
+
+// ParseThreshold reads a threshold written as a name, < or >, and a
+// limit, such as "grade > 12" or "economy < 0.4".
+func ParseThreshold(s string) (Threshold, error) {
+	i := strings.IndexAny(s, "<>")
+	if i < 0 {
+		return Threshold{}, fmt.Errorf("threshold %q: want a name, < or >, and a limit", s)
+	}
+	t := Threshold{Name: strings.ToLower(strings.TrimSpace(s[:i])), Above: s[i] == '>'}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(s[i+1:]), 64)
+	if err != nil || t.Name == "" {
+		return Threshold{}, fmt.Errorf("threshold %q: want a name, < or >, and a limit", s)
+	}
+	t.Limit = limit
+	return t, nil
+}
+
+// Check returns the value r measured for the threshold and whether it goes
+// beyond the limit. A measurement r lacks, such as the grade of text too
+// short to grade, never does.
+func (t Threshold) Check(r Result) (value float64, exceeded bool) {
+	if t.Name == "grade" {
+		if r.Grade == 0 {
+			return 0, false
+		}
+		value = r.Grade
+	} else {
+		a, ok := r.Axis(t.Name)
+		if !ok {
+			return 0, false
+		}
+		value = a.Score
+	}
+	if t.Above {
+		return value, value > t.Limit
+	}
+	return value, value < t.Limit
+}
+
+// String writes the threshold the way ParseThreshold reads it.
+func (t Threshold) String() string {
+	op := "<"
+	if t.Above {
+		op = ">"
+	}
+	return fmt.Sprintf("%s %s %g", t.Name, op, t.Limit)
+}
+
+// Axis names, in the order Analyze reports them.
+const (
+	Rhythm      = "rhythm"
+	Economy     = "economy"
+	Richness    = "richness"
+	Readability = "readability"
+	Variety     = "variety"
+)
+
+// AxisNames lists the axes Analyze reports, in order.
+var AxisNames = []string{Rhythm, Economy, Richness, Readability, Variety}
+
+// richnessWindow is the number of words over which vocabulary richness is
+// measured, so long documents aren't marked down for repeating common
+// words more often.
+const richnessWindow = 50
+
+// fillers are words that rarely add meaning to a sentence.
+var fillers = map[string]bool{
+	"very": true, "really": true, "just": true, "quite": true, "rather": true,
+	"actually": true, "basically": true, "simply": true, "literally": true,
+	"totally": true, "somewhat": true, "certainly": true, "definitely": true,
+	"probably": true, "perhaps": true, "maybe": true, "pretty": true,
+	"fairly": true, "truly": true, "extremely": true, "completely": true,
+	"absolutely": true, "honestly": true, "seriously": true, "essentially": true,
+}
+
+// Analyze measures the prose of a markdown document: headings, code, tables
+// and front matter are left out.
+func Analyze(markdown string) Result {
+	sentences := Sentences(markdown)
+	var words []string
+	lengths := make([]int, 0, len(sentences))
+	perSentence := make([][]string, 0, len(sentences))
+	for _, s := range sentences {
+		w := Words(s.Text)
+		words = append(words, w...)
+		lengths = append(lengths, len(w))
+		perSentence = append(perSentence, w)
+	}
+
+	r := Result{Words: len(words), Sentences: sentenceStats(lengths)}
+	var prose strings.Builder
+	for _, s := range sentences {
+		prose.WriteString(s.Text)
+		prose.WriteString(" ")
+	}
+	if a := readability.NewAnalysis(prose.String()); a.Stats().Words >= 10 {
+		r.Grade = a.FleschKincaidGrade()
+	}
+	if len(words) == 0 {
+		return r
+	}
+	r.Axes = []Axis{
+		rhythm(r.Sentences),
+		economy(words),
+		richness(words),
+		readable(r.Grade),
+		variety(sentences),
+	}
+	for i := range r.Axes {
+		r.Axes[i].Evidence = evidence(r.Axes[i].Name, sentences, perSentence)
+	}
+	return r
+}
+
+// sentenceStats sums up sentence lengths.
+func sentenceStats(lengths []int) SentenceStats {
+	s := SentenceStats{Count: len(lengths)}
+	if len(lengths) == 0 {
+		return s
+	}
+	s.Shortest = lengths[0]
+	total := 0
+	for _, n := range lengths {
+		total += n
+		s.Shortest = min(s.Shortest, n)
+		s.Longest = max(s.Longest, n)
+	}
+	s.Mean = float64(total) / float64(len(lengths))
+	var sq float64
+	for _, n := range lengths {
+		sq += (float64(n) - s.Mean) * (float64(n) - s.Mean)
+	}
+	s.StdDev = math.Sqrt(sq / float64(len(lengths)))
+	return s
+}
+
+// rhythm scores how much sentence lengths vary. Prose that mixes short and
+// long sentences has a standard deviation of around half the mean or more.
+func rhythm(s SentenceStats) Axis {
+	cv := 0.0
+	if s.Mean > 0 {
+		cv = s.StdDev / s.Mean
+	}
+	return Axis{
+		Name:   Rhythm,
+		Score:  clamp(cv / 0.6),
+		Detail: fmt.Sprintf("lengths vary by ±%.0f words", s.StdDev),
+	}
+}
+
+// economy scores how few filler words the prose carries; one in twenty
+// words scores zero.
+func economy(words []string) Axis {
+	n := 0
+	for _, w := range words {
+		if fillers[w] {
+			n++
+		}
+	}
+	share := float64(n) / float64(len(words))
+	return Axis{
+		Name:   Economy,
+		Score:  clamp(1 - share*20),
+		Detail: fmt.Sprintf("%d filler words (%.1f%%)", n, share*100),
+	}
+}
+
+// richness scores the vocabulary by its moving-average type-token ratio:
+// the share of distinct words in each window of richnessWindow words.
+func richness(words []string) Axis {
+	window := min(richnessWindow, len(words))
+	var total float64
+	windows := len(words) - window + 1
+	counts := map[string]int{}
+	for _, w := range words[:window] {
+		counts[w]++
+	}
+	total += float64(len(counts)) / float64(window)
+	for i := window; i < len(words); i++ {
+		out := words[i-window]
+		if counts[out]--; counts[out] == 0 {
+			delete(counts, out)
+		}
+		counts[words[i]]++
+		total += float64(len(counts)) / float64(window)
+	}
+	ttr := total / float64(windows)
+	return Axis{
+		Name:   Richness,
+		Score:  clamp(ttr),
+		Detail: fmt.Sprintf("%.0f%% distinct words", ttr*100),
+	}
+}
+
+// readable scores the Flesch-Kincaid grade: grade 6 or below scores 1,
+// grade 18 or above 0.
+func readable(grade float64) Axis {
+	if grade == 0 {
+		return Axis{Name: Readability, Score: 1, Detail: "too short to grade"}
+	}
+	return Axis{
+		Name:   Readability,
+		Score:  clamp(1 - (grade-6)/12),
+		Detail: fmt.Sprintf("grade %.1f", grade),
+	}
+}
+
+// variety scores how differently sentences begin: the share of distinct
+// first words.
+func variety(sentences []Sentence) Axis {
+	openers := map[string]bool{}
+	n := 0
+	for _, s := range sentences {
+		if w := Words(s.Text); len(w) > 0 {
+			openers[w[0]] = true
+			n++
+		}
+	}
+	score := 0.0
+	if n > 0 {
+		score = float64(len(openers)) / float64(n)
+	}
+	return Axis{
+		Name:   Variety,
+		Score:  clamp(score),
+		Detail: fmt.Sprintf("%d different openings in %d sentences", len(openers), n),
+	}
+}
+
+func clamp(x float64) float64 {
+	return min(max(x, 0), 1)
+}
+
+// Words returns the words of text, lowercased and without surrounding
+// punctuation.
+func Words(text string) []string {
+	var words []string
+	for _, f := range strings.Fields(text) {
+		w := strings.TrimFunc(strings.ToLower(f), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// Sentence is a sentence of a document's prose and the source line, front
+// matter included, that it starts on.
+type Sentence struct {
+	Text string `json:"text"`
+	Line int    `json:"line"` // 0-based
+}
+
+var (
+	linkPattern  = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	listPattern  = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+	rulePattern  = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+	emphasisChar = strings.NewReplacer("**", "", "__", "", "*", "", "`", "")
+)
+
+// Sentences splits the prose of a markdown document into sentences.
+// Headings, code, tables, HTML and front matter aren't prose; list items and
+// block quotes are read without their markers.
+func Sentences(markdown string) []Sentence {
+	n := frontmatter.Len([]byte(markdown))
+	first := strings.Count(markdown[:n], "\n")
+	lines := strings.Split(markdown[n:], "\n")
+
+	var sentences []Sentence
+	var para []string
+	start := 0
+	flush := func() {
+		sentences = append(sentences, split(para, start)...)
+		para = nil
+	}
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			flush()
+			fence = trimmed[:3]
+			continue
+		}
+		for strings.HasPrefix(trimmed, ">") {
+			trimmed = strings.TrimSpace(trimmed[1:])
+		}
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "|"),
+			strings.HasPrefix(trimmed, "<"), rulePattern.MatchString(trimmed),
+			strings.HasPrefix(line, "    ") && len(para) == 0, strings.HasPrefix(line, "\t") && len(para) == 0:
+			flush()
+			continue
+		}
+		if m := listPattern.FindString(trimmed); m != "" {
+			flush()
+			trimmed = trimmed[len(m):]
+		}
+		if len(para) == 0 {
+			start = first + i
+		}
+		trimmed = linkPattern.ReplaceAllStringFunc(trimmed, func(s string) string {
+			if strings.HasPrefix(s, "!") {
+				return ""
+			}
+			return linkPattern.FindStringSubmatch(s)[1]
+		})
+		para = append(para, emphasisChar.Replace(trimmed))
+	}
+	flush()
+	return sentences
+}
+
+// split divides a paragraph, whose first line is source line start, into
+// sentences at terminal punctuation followed by a space or the end.
+func split(para []string, start int) []Sentence {
+	var sentences []Sentence
+	text := []rune(strings.Join(para, "\n"))
+	from := 0
+	add := func(to int) {
+		s := strings.Join(strings.Fields(string(text[from:to])), " ")
+		if s != "" {
+			line := start + strings.Count(string(text[:from]), "\n")
+			// Leading line breaks belong to the next line.
+			for i := from; i < to && unicode.IsSpace(text[i]); i++ {
+				if text[i] == '\n' {
+					line++
+				}
+			}
+			sentences = append(sentences, Sentence{Text: s, Line: line})
+		}
+		from = to
+	}
+	for i := 0; i < len(text); i++ {
+		if !strings.ContainsRune(".!?", text[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && strings.ContainsRune(".!?\"')]", text[end]) {
+			end++
+		}
+		if end < len(text) && !unicode.IsSpace(text[end]) {
+			continue
+		}
+		add(end)
+		i = end
+	}
+	add(len(text))
+	return sentences
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSentences(t *testing.T) {
+	doc := `---
+title: Test
+---
+# A heading. Not prose.
+
+First sentence here. Second one
+runs across lines! Third?
+
+- A [linked](http://example.com) item. ![image](a.png)
+> Quoted **bold** text.
+
+` + "```" + `
+code. Not prose.
+` + "```" + `
+| table. | cell. |
+`
+	want := []Sentence{
+		{"First sentence here.", 5},
+		{"Second one runs across lines!", 5},
+		{"Third?", 6},
+		{"A linked item.", 8},
+		{"Quoted bold text.", 9},
+	}
+	got := Sentences(doc)
+	if len(got) != len(want) {
+		t.Fatalf("Sentences = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sentence %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	got, err := ParseTarget("economy 0.8, Rhythm: 0.5,variety=1,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[Economy] != 0.8 || got[Rhythm] != 0.5 || got[Variety] != 1 {
+		t.Errorf("ParseTarget = %v", got)
+	}
+	for _, bad := range []string{"economy", "economy high", "economy 1.5", "economy 0.8 rhythm 0.5"} {
+		if _, err := ParseTarget(bad); err == nil {
+			t.Errorf("ParseTarget(%q) should fail", bad)
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	r := Result{Grade: 13.5, Axes: []Axis{{Name: Economy, Score: 0.3}}}
+	tests := []struct {
+		in       string
+		value    float64
+		exceeded bool
+	}{
+		{"grade > 12", 13.5, true},
+		{"Grade>14", 13.5, false},
+		{"economy < 0.4", 0.3, true},
+		{"economy < 0.2", 0.3, false},
+		{"rhythm < 0.5", 0, false},
+	}
+	for _, tt := range tests {
+		th, err := ParseThreshold(tt.in)
+		if err != nil {
+			t.Errorf("ParseThreshold(%q): %v", tt.in, err)
+			continue
+		}
+		if value, exceeded := th.Check(r); value != tt.value || exceeded != tt.exceeded {
+			t.Errorf("%q: Check = %v, %v; want %v, %v", tt.in, value, exceeded, tt.value, tt.exceeded)
+		}
+	}
+	if th, _ := ParseThreshold("economy < 0.4"); th.String() != "economy < 0.4" {
+		t.Errorf("String = %q", th.String())
+	}
+	if _, err := ParseThreshold("grade = 12"); err == nil {
+		t.Error("a threshold without < or > should fail")
+	}
+	if _, err := ParseThreshold("grade > high"); err == nil {
+		t.Error("a threshold without a number should fail")
+	}
+	if _, exceeded := (Threshold{Name: "grade", Above: true, Limit: 1}).Check(Result{}); exceeded {
+		t.Error("an ungraded text shouldn't exceed a grade threshold")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	if r := Analyze("# Only a heading\n"); r.Words != 0 || len(r.Axes) != 0 {
+		t.Errorf("no prose: %+v", r)
+	}
+
+	flat := strings.Repeat("The cat sat on the mat. ", 20)
+	varied := "Stop. The old cat, tired after a long night of hunting mice in the barn, sat on the mat by the fire. " +
+		"It slept. Outside, rain fell on the fields and the quiet roads that wound between the hills. " +
+		"Nobody came. Morning found the cat awake and hungry, staring at an empty bowl beside the door."
+	f, v := Analyze(flat), Analyze(varied)
+
+	if f.Sentences.Count != 20 || f.Sentences.Mean != 6 || f.Sentences.StdDev != 0 {
+		t.Errorf("flat sentence stats = %+v", f.Sentences)
+	}
+	if v.Sentences.Shortest != 1 || v.Sentences.Longest != 21 {
+		t.Errorf("varied sentence stats = %+v", v.Sentences)
+	}
+	for _, name := range []string{Rhythm, Richness, Variety} {
+		fa, _ := f.Axis(name)
+		va, _ := v.Axis(name)
+		if fa.Score >= va.Score {
+			t.Errorf("%s: flat text scores %.2f, varied %.2f", name, fa.Score, va.Score)
+		}
+	}
+
+	filler := Analyze("This is really very good and it is just quite nice, actually.")
+	if a, _ := filler.Axis(Economy); a.Score != 0 || !strings.HasPrefix(a.Detail, "5 filler words") {
+		t.Errorf("economy of filler = %+v", a)
+	}
+	for _, a := range v.Axes {
+		if a.Score < 0 || a.Score > 1 || math.IsNaN(a.Score) {
+			t.Errorf("%s score %v out of range", a.Name, a.Score)
+		}
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	doc := "It was really very late.\n\n" +
+		"The tide, having withdrawn beyond the furthest sandbars during the unusually protracted afternoon, " +
+		"left innumerable glistening pools reflecting extraordinarily luminous atmospheric phenomena.\n\n" +
+		"It rained. It was dark. Then we went home."
+	r := Analyze(doc)
+	tests := []struct {
+		axis  string
+		first string
+		line  int
+	}{
+		{Economy, "It was really very late.", 0},
+		{Readability, "The tide, having withdrawn", 2},
+		{Variety, "It rained.", 4},
+	}
+	for _, tt := range tests {
+		a, _ := r.Axis(tt.axis)
+		if len(a.Evidence) == 0 {
+			t.Errorf("%s: no evidence", tt.axis)
+			continue
+		}
+		if got := a.Evidence[0]; !strings.HasPrefix(got.Text, tt.first) || got.Line != tt.line {
+			t.Errorf("%s: worst sentence = %+v, want %q on line %d", tt.axis, got, tt.first, tt.line)
+		}
+	}
+	if a, _ := Analyze("Stop. The old cat slept by the fire all night long.").Axis(Rhythm); len(a.Evidence) != 0 {
+		t.Errorf("varied rhythm has evidence %+v", a.Evidence)
+	}
+}
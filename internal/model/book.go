@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"charm.land/bubbles/v2/list"
+	"charm.land/bubbles/v2/spinner"
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
 
@@ -17,6 +18,17 @@ import (
 // clearBookStatusMsg clears the Book status bar feedback text.
 type clearBookStatusMsg struct{}
 
+// dirScannedMsg delivers the listing of a directory scanned in the
+// background by changeDir or refresh.
+type dirScannedMsg struct {
+	seq      int // matches Book.scanSeq unless a newer scan superseded it
+	dir      string
+	items    []list.Item
+	refresh  bool   // true for a rescan of the listing already shown
+	selected string // path a refresh keeps highlighted
+	err      error
+}
+
 // Book is the file browser view.
 type Book struct {
 	list        list.Model
@@ -25,49 +37,120 @@ type Book struct {
 	dir         string
 	rootDir     string
 	naming      bool
+	renamePath  string // file being renamed while naming; "" when creating
+	copyPath    string // file being duplicated while naming; "" when creating
+	yankPath    string // item whose absolute path "y" copied last
+	comparePath string // file marked by "C" to compare with the next one
 	input       textinput.Model
 	statusText  string
 	help        HelpPane
-	preFiltered bool // true when built from explicit file args (no directory navigation)
+	preFiltered bool                // true when built from explicit file args (no directory navigation)
+	files       []string            // the explicit file args, listed in place of rootDir when preFiltered
+	sortModes   map[string]sortMode // sort order remembered per directory
+	flat        bool                // true lists every document below dir by relative path
+	picker      list.Model          // folder or tag picker shown in place of the list
+	picking     pickerKind          // what the picker is choosing, if open
+	movePath    string              // file being moved with the folder picker
+	tag         string              // when set, only documents with this tag are listed
+	modified    dateFilter          // when set, only documents below dir modified in the period are listed
+	order       []string            // manual reading order of the book, if defined
+	orderFile   string              // file that defined order (e.g. SUMMARY.md)
+
+	preview        bool   // true shows the preview pane beside the list
+	previewKey     string // path, width and theme of the cached preview
+	previewContent string // rendered preview of the highlighted item
+
+	git *gitInfo // git status of the book root; nil outside a repository
+
+	dirCounts map[string]int // documents below each counted folder, by path
+
+	loading bool          // true while a directory scan is in flight
+	scanSeq int           // sequence number of the latest directory scan
+	spinner spinner.Model // shown in place of the list while loading
+
+	showStats    bool                // true shows the statistics screen instead of the list
+	statsLoading bool                // true while the statistics are being computed
+	statsCache   map[string]docStats // per-document statistics, by path; nil until computed
+	writing      writingTotals       // editing sessions logged on the book's documents
+	reading      readingTotals       // time logged reading the book's documents
+
+	journal *journal // calendar of dated notes, when open
+
+	metrics *bookMetrics // the book's writing signature, when open
+
+	trash []trashed // deleted documents, most recent last, for undo
+
+	exporting string // format pandoc is exporting to, "" when idle
 }
 
 // newBookList creates a configured list.Model for the book view.
 func newBookList(items []list.Item, ctx *ViewContext) list.Model {
-	delegate := list.NewDefaultDelegate()
+	delegate := bookDelegate{list.NewDefaultDelegate()}
 	l := list.New(items, delegate, ctx.contentWidth(), ctx.height-bookChromeHeight)
 	l.SetShowTitle(false)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 	l.SetShowHelp(false)
-	l.KeyMap.PrevPage.SetKeys("pgup", "b", "u", "ctrl+b")
-	l.KeyMap.NextPage.SetKeys("pgdown", "f", "d", "ctrl+f")
+	// "u" is left free for undo.
+	l.KeyMap.PrevPage.SetKeys(ctx.keys.keys(bookKeys, actionPageUp)...)
+	l.KeyMap.NextPage.SetKeys(ctx.keys.keys(bookKeys, actionPageDown)...)
 	return l
 }
 
-// NewBook creates a new Book file browser for the given directory.
+// NewBook creates a new Book file browser for the given directory. The
+// directory is scanned in the background once Init runs.
 func NewBook(ctx *ViewContext, dir string) Book {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		absDir = dir
 	}
-	items, err := scanDir(absDir)
-	if err != nil {
-		items = nil
-	}
-
-	return Book{
-		list:     newBookList(items, ctx),
-		ctx:      ctx,
-		bookName: dirToBookName(absDir),
-		dir:      absDir,
-		rootDir:  absDir,
-		help:     NewHelpPane(bookHelpEntries),
+	b := Book{
+		list:      newBookList(nil, ctx),
+		ctx:       ctx,
+		bookName:  dirToBookName(absDir),
+		dir:       absDir,
+		rootDir:   absDir,
+		help:      NewHelpPane(ctx.keys.help(bookKeys, bookHelpEntries)),
+		sortModes: make(map[string]sortMode),
+		dirCounts: make(map[string]int),
+		spinner:   newBookSpinner(),
+		loading:   true,
+		scanSeq:   1,
 	}
+	b.order, b.orderFile = loadOrder(absDir)
+	return b
 }
 
 // NewBookFromFiles creates a Book view from explicit file/directory paths
 // instead of scanning a directory. Used when ink is called with multiple args.
 func NewBookFromFiles(ctx *ViewContext, files []string) Book {
+	items := fileArgItems(files)
+
+	// Derive common parent directory
+	parentDir := commonParentDir(files)
+
+	b := Book{
+		list:        newBookList(nil, ctx),
+		ctx:         ctx,
+		bookName:    dirToBookName(parentDir),
+		dir:         parentDir,
+		rootDir:     parentDir,
+		help:        NewHelpPane(ctx.keys.help(bookKeys, bookHelpEntries)),
+		preFiltered: true,
+		files:       files,
+		sortModes:   make(map[string]sortMode),
+		dirCounts:   make(map[string]int),
+		spinner:     newBookSpinner(),
+	}
+	// Keep the argument order; only the Pinned section is prepended.
+	b.list.SetItems(b.withSections(items))
+	b.skipHeaders(1)
+	return b
+}
+
+// fileArgItems lists the files and folders named on the command line that
+// still exist, in the order given.
+func fileArgItems(files []string) []list.Item {
 	var items []list.Item
 	for _, f := range files {
 		absPath, err := filepath.Abs(f)
@@ -79,129 +162,445 @@ func NewBookFromFiles(ctx *ViewContext, files []string) Book {
 			continue
 		}
 		if info.IsDir() {
-			mc := countMarkdownFiles(absPath)
-			if mc > 0 {
-				items = append(items, dirItem{
-					name:    filepath.Base(absPath),
-					path:    absPath,
-					mdCount: mc,
-				})
-			}
-		} else {
-			items = append(items, fileItem{
+			items = append(items, dirItem{
 				name:    filepath.Base(absPath),
 				path:    absPath,
-				modTime: info.ModTime(),
+				mdCount: countPending,
 			})
+		} else {
+			items = append(items, newFileItem(filepath.Base(absPath), absPath, info))
 		}
 	}
+	return items
+}
 
-	// Derive common parent directory
-	parentDir := commonParentDir(files)
+// newBookSpinner creates the spinner shown while a directory is scanned.
+func newBookSpinner() spinner.Model {
+	return spinner.New(
+		spinner.WithSpinner(spinner.Dot),
+		spinner.WithStyle(statusBarHintStyle),
+	)
+}
 
-	return Book{
-		list:        newBookList(items, ctx),
-		ctx:         ctx,
-		bookName:    dirToBookName(parentDir),
-		dir:         parentDir,
-		rootDir:     parentDir,
-		help:        NewHelpPane(bookHelpEntries),
-		preFiltered: true,
+// changeDir lists dir, scanning it in the background. The current listing
+// is replaced once the scan completes; on error it is kept and the error is
+// shown in the status bar.
+func (b *Book) changeDir(dir string) tea.Cmd {
+	b.scanSeq++
+	b.loading = true
+	return tea.Batch(b.spinner.Tick, b.scanCmd(dir))
+}
+
+// scanCmd scans dir as the listing currently configured (folder, flat or
+// tag view) and reports the result as a dirScannedMsg.
+func (b Book) scanCmd(dir string) tea.Cmd {
+	seq := b.scanSeq
+	return func() tea.Msg {
+		items, err := b.scan(dir)
+		return dirScannedMsg{seq: seq, dir: dir, items: items, err: err}
 	}
 }
 
-func (b *Book) changeDir(dir string) {
-	b.dir = dir
-	b.bookName = dirToBookName(dir)
+// dirScanned shows the listing delivered by a completed scan, ignoring
+// scans superseded by a later changeDir or refresh.
+func (b *Book) dirScanned(msg dirScannedMsg) tea.Cmd {
+	if msg.seq != b.scanSeq {
+		return nil
+	}
+	b.loading = false
+	if msg.err != nil {
+		return b.flashStatus("Error: " + msg.err.Error())
+	}
+	b.dir = msg.dir
+	b.bookName = dirToBookName(msg.dir)
 	b.ctx.bookName = b.bookName
-	items, err := scanDir(dir)
-	if err != nil {
-		b.statusText = "Error: " + err.Error()
-		return
+	cmd := b.setItems(msg.items)
+	if msg.refresh {
+		b.selectPath(msg.selected)
+		return cmd
 	}
-	b.list.SetItems(items)
 	b.list.ResetSelected()
+	b.skipHeaders(1)
+	return cmd
 }
 
-// createFile validates the name, writes a new markdown file with frontmatter,
-// and refreshes the directory listing.
-func (b *Book) createFile(raw string) tea.Cmd {
-	name := strings.TrimSpace(raw)
-	if name == "" {
-		b.naming = false
-		return nil
+// refresh rescans the current directory in the background, keeping the
+// highlighted item selected when it is still listed.
+func (b *Book) refresh() tea.Cmd {
+	var selected string
+	if item := b.list.SelectedItem(); item != nil {
+		selected = itemPath(item)
+	}
+	return b.refreshSelecting(selected)
+}
+
+// refreshSelecting rescans the current directory in the background and
+// highlights path once the new listing arrives. The current listing stays
+// on screen meanwhile.
+func (b *Book) refreshSelecting(path string) tea.Cmd {
+	b.scanSeq++
+	scan := b.scanCmd(b.dir)
+	return tea.Batch(func() tea.Msg {
+		msg := scan().(dirScannedMsg)
+		msg.refresh, msg.selected = true, path
+		return msg
+	}, b.loadGitStatus())
+}
+
+// scan lists dir as a folder listing, recursively when the flat view is on,
+// or the whole book's documents carrying the active tag. A date filter lists
+// the documents below dir modified within its period. A Book built from
+// explicit files lists those again rather than their common folder.
+func (b Book) scan(dir string) ([]list.Item, error) {
+	switch {
+	case b.tag != "":
+		return b.ctx.scanner.scanTag(b.rootDir, b.tag)
+	case b.preFiltered && dir == b.rootDir:
+		items := fileArgItems(b.files)
+		if b.modified != dateAny {
+			items = filterByDate(items, b.modified, time.Now())
+		}
+		return items, nil
+	case b.modified != dateAny:
+		items, err := b.ctx.scanner.scanTree(dir)
+		return filterByDate(items, b.modified, time.Now()), err
+	case b.flat:
+		return b.ctx.scanner.scanTree(dir)
+	}
+	return b.ctx.scanner.scanDir(dir)
+}
+
+// currentSort returns the sort order remembered for the current directory.
+func (b Book) currentSort() sortMode {
+	return b.sortModes[b.dir]
+}
+
+// setItems sorts items by the current directory's sort order and shows them
+// below the Pinned section. A manual reading order replaces the name order.
+// Folders whose documents have not been counted yet are counted in the
+// background.
+func (b *Book) setItems(items []list.Item) tea.Cmd {
+	items = b.withCounts(items)
+	// Explicit files keep the order they were given in unless sorted.
+	if !b.preFiltered || b.dir != b.rootDir || b.currentSort() != sortByName {
+		sortItems(items, b.currentSort())
+	}
+	if b.manualOrder() {
+		applyOrder(items, b.order)
+	}
+	if b.currentSort() == sortByStatus {
+		items = b.groupByStatus(items)
+	}
+	return tea.Batch(b.list.SetItems(b.decorate(b.withSections(items))), b.countDirs(items))
+}
+
+// manualOrder reports whether the book's reading order file is in effect.
+func (b Book) manualOrder() bool {
+	return len(b.order) > 0 && b.currentSort() == sortByName
+}
+
+// cycleSort advances the current directory to the next sort order, keeping
+// the highlighted item selected.
+func (b *Book) cycleSort() tea.Cmd {
+	b.sortModes[b.dir] = b.currentSort().next()
+	return b.refresh()
+}
+
+// selectPath moves the cursor to the visible item with the given path.
+func (b *Book) selectPath(path string) {
+	for i, item := range b.list.VisibleItems() {
+		if itemPath(item) == path {
+			b.list.Select(i)
+			return
+		}
+	}
+}
+
+// skipHeaders moves the cursor off a section header, stepping in direction
+// step (+1 or -1) and reversing at the end of the list.
+func (b *Book) skipHeaders(step int) {
+	items := b.list.VisibleItems()
+	for range 2 {
+		for i := b.list.Index(); i >= 0 && i < len(items); i += step {
+			if _, ok := items[i].(headerItem); !ok {
+				b.list.Select(i)
+				return
+			}
+		}
+		step = -step
 	}
-	if !strings.HasSuffix(strings.ToLower(name), ".md") {
+}
+
+// validateName normalizes raw into a markdown file name and resolves it
+// inside dir, rejecting names that would escape dir.
+func validateName(dir, raw string) (string, bool) {
+	name := strings.TrimSpace(raw)
+	if !IsMarkdownFile(name) {
 		name += ".md"
 	}
-	filePath := filepath.Join(b.dir, name)
-	absPath, err := filepath.Abs(filePath)
+	absPath, err := filepath.Abs(filepath.Join(dir, name))
 	if err != nil {
-		b.naming = false
-		b.statusText = "Invalid filename"
-		return clearStatusAfter(2*time.Second, clearBookStatusMsg{})
+		return "", false
 	}
-	rel, err := filepath.Rel(b.dir, absPath)
+	rel, err := filepath.Rel(dir, absPath)
 	if err != nil || strings.HasPrefix(rel, "..") || strings.Contains(rel, string(os.PathSeparator)) {
-		b.naming = false
-		b.statusText = "Invalid filename"
-		return clearStatusAfter(2*time.Second, clearBookStatusMsg{})
+		return "", false
 	}
+	return absPath, true
+}
+
+// flashStatus shows text in the status bar and clears it after a delay.
+func (b *Book) flashStatus(text string) tea.Cmd {
+	b.statusText = text
+	return clearStatusAfter(2*time.Second, clearBookStatusMsg{})
+}
+
+// startNaming opens the status bar text input, prefilled with value.
+func (b *Book) startNaming(placeholder, value string) tea.Cmd {
+	ti := textinput.New()
+	ti.Placeholder = placeholder
+	ti.CharLimit = 255
+	ti.SetValue(value)
+	focusCmd := ti.Focus()
+	b.input = ti
+	b.naming = true
+	return focusCmd
+}
+
+// createFile validates the name, writes a new markdown file with frontmatter,
+// and refreshes the directory listing.
+func (b *Book) createFile(raw string) tea.Cmd {
+	b.naming = false
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	absPath, ok := validateName(b.dir, raw)
+	if !ok {
+		return b.flashStatus("Invalid filename")
+	}
+	name := filepath.Base(absPath)
 	title := strings.TrimSuffix(name, filepath.Ext(name))
-	frontmatter := fmt.Sprintf("---\ntitle: %q\nauthor: %s\ndate: %s\n---\n",
+	if err := os.WriteFile(absPath, newDocument(title), 0644); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	b.forgetCounts(absPath)
+	return b.refreshSelecting(absPath)
+}
+
+// newDocument returns the front matter ink writes into new documents.
+func newDocument(title string) []byte {
+	return fmt.Appendf(nil, "---\ntitle: %q\nauthor: %s\ndate: %s\n---\n",
 		title, currentUser(), time.Now().Format(time.RFC3339))
-	if err := os.WriteFile(absPath, []byte(frontmatter), 0644); err != nil {
-		b.naming = false
-		b.statusText = "Error: " + err.Error()
-		return clearStatusAfter(2*time.Second, clearBookStatusMsg{})
+}
+
+// renameFile renames the file chosen with "R" to raw within its directory,
+// refusing to overwrite an existing file, and keeps it selected.
+func (b *Book) renameFile(raw string) tea.Cmd {
+	from := b.renamePath
+	b.naming = false
+	b.renamePath = ""
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	to, ok := validateName(filepath.Dir(from), raw)
+	if !ok {
+		return b.flashStatus("Invalid filename")
+	}
+	if to == from {
+		return nil
+	}
+	// A case-only rename resolves to the same file on case-insensitive
+	// filesystems, so it is not a collision.
+	if !strings.EqualFold(to, from) {
+		if _, err := os.Stat(to); err == nil {
+			return b.flashStatus("File exists")
+		}
+	}
+	if err := os.Rename(from, to); err != nil {
+		return b.flashStatus("Error: " + err.Error())
 	}
+	b.renamed(from, to)
+	return b.refreshSelecting(to)
+}
+
+// duplicateFile copies the file chosen with "D" to raw within its directory,
+// refusing to overwrite an existing file, and selects the copy.
+func (b *Book) duplicateFile(raw string) tea.Cmd {
+	from := b.copyPath
 	b.naming = false
-	b.changeDir(b.dir)
-	return nil
+	b.copyPath = ""
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	to, ok := validateName(filepath.Dir(from), raw)
+	if !ok {
+		return b.flashStatus("Invalid filename")
+	}
+	if _, err := os.Stat(to); err == nil {
+		return b.flashStatus("File exists")
+	}
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	if err := os.WriteFile(to, data, 0644); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	b.forgetCounts(to)
+	return b.refreshSelecting(to)
+}
+
+// copyItemPath copies the absolute path of the highlighted item to the
+// clipboard, or its path relative to the book root when pressed again.
+func (b *Book) copyItemPath() tea.Cmd {
+	path := itemPath(b.list.SelectedItem())
+	if path == "" {
+		return nil
+	}
+	text, status := path, "Copied path"
+	if path == b.yankPath {
+		if rel, err := filepath.Rel(b.rootDir, path); err == nil {
+			text, status = filepath.ToSlash(rel), "Copied relative path"
+		}
+		b.yankPath = ""
+	} else {
+		b.yankPath = path
+	}
+	if err := writeClipboard(text); err != nil {
+		return b.flashStatus("Copy failed")
+	}
+	return b.flashStatus(status)
+}
+
+// copyName suggests a name for a duplicate of path: "name copy.md", then
+// "name copy 2.md" and so on until the name is free.
+func copyName(path string) string {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	name := stem + " copy" + ext
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return name
+		}
+		name = fmt.Sprintf("%s copy %d%s", stem, i, ext)
+	}
 }
 
 // resizeList recalculates the list dimensions based on the current view state.
 func (b *Book) resizeList() {
+	width := b.ctx.contentWidth()
+	if b.preview {
+		width, _ = previewWidths(b.ctx)
+	}
 	filtering := b.list.FilterState() == list.Filtering
-	b.list.SetSize(b.ctx.contentWidth(), bookListHeight(b.ctx, b.help.HeightIfVisible(), filtering))
+	b.list.SetSize(width, bookListHeight(b.ctx, b.help.HeightIfVisible(), filtering))
+	if b.picking != pickerNone {
+		filtering := b.picker.FilterState() == list.Filtering
+		b.picker.SetSize(width, bookListHeight(b.ctx, b.help.HeightIfVisible(), filtering))
+	}
 }
 
 func (b Book) Init() tea.Cmd {
-	return nil
+	if b.loading {
+		return tea.Batch(b.loadGitStatus(), b.spinner.Tick, b.scanCmd(b.dir))
+	}
+	return tea.Batch(b.loadGitStatus(), b.countDirs(b.list.Items()))
 }
 
 func (b Book) Update(msg tea.Msg) (Book, tea.Cmd) {
+	b, cmd := b.update(msg)
+	b.updatePreview()
+	return b, cmd
+}
+
+func (b Book) update(msg tea.Msg) (Book, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		b.resizeList()
 	case clearBookStatusMsg:
 		b.statusText = ""
 		return b, nil
+	case gitStatusMsg:
+		if msg.root != b.rootDir {
+			return b, nil
+		}
+		b.git = msg.info
+		return b, b.list.SetItems(b.decorate(b.list.Items()))
+	case dirCountMsg:
+		return b, b.applyDirCount(msg)
+	case dirScannedMsg:
+		return b, b.dirScanned(msg)
+	case bookStatsMsg:
+		if msg.root != b.rootDir {
+			return b, nil
+		}
+		b.statsCache = msg.docs
+		b.statsLoading = false
+		return b, nil
+	case bookMetricsMsg:
+		b.metricsLoaded(msg)
+		return b, nil
+	case exportDoneMsg:
+		return b, b.exportDone(msg)
+	case spinner.TickMsg:
+		if !b.loading && !b.statsLoading && b.exporting == "" && (b.metrics == nil || !b.metrics.loading) {
+			return b, nil
+		}
+		var cmd tea.Cmd
+		b.spinner, cmd = b.spinner.Update(msg)
+		return b, cmd
 	case tea.KeyMsg:
 		// Handle naming mode input
 		if b.naming {
 			switch msg.String() {
 			case "enter":
+				if b.renamePath != "" {
+					return b, b.renameFile(b.input.Value())
+				}
+				if b.copyPath != "" {
+					return b, b.duplicateFile(b.input.Value())
+				}
 				return b, b.createFile(b.input.Value())
 			case "esc":
 				b.naming = false
+				b.renamePath = ""
+				b.copyPath = ""
 				return b, nil
 			}
 			var cmd tea.Cmd
 			b.input, cmd = b.input.Update(msg)
 			return b, cmd
 		}
+		if b.picking != pickerNone {
+			return b.updatePicker(msg)
+		}
+		if b.journal != nil {
+			return b.updateJournal(msg)
+		}
+		if b.metrics != nil {
+			return b.updateMetrics(msg)
+		}
+		if b.showStats {
+			switch msg.String() {
+			case "S", "esc", "q", "ctrl+w":
+				return b, b.toggleStats()
+			}
+			return b, nil
+		}
 		// Don't intercept keys when filtering is active
 		if b.list.FilterState() == list.Filtering {
 			break
 		}
-		switch msg.String() {
+		if why := b.ctx.refusal(bookKeys, msg.String()); why != "" {
+			return b, b.flashStatus(why)
+		}
+		switch b.ctx.keys.resolve(bookKeys, msg.String()) {
 		case "enter", "right", "l":
 			selected := b.list.SelectedItem()
 			switch item := selected.(type) {
 			case dirItem:
-				b.changeDir(item.path)
-				return b, nil
+				return b, b.changeDir(item.path)
 			case fileItem:
 				return b, func() tea.Msg {
 					return OpenChapterMsg{FilePath: item.path}
@@ -209,33 +608,96 @@ func (b Book) Update(msg tea.Msg) (Book, tea.Cmd) {
 			}
 		case "backspace", "left", "h":
 			if !b.preFiltered && b.dir != b.rootDir {
-				b.changeDir(filepath.Dir(b.dir))
-				return b, nil
+				return b, b.changeDir(filepath.Dir(b.dir))
 			}
 		case "n":
 			if b.preFiltered {
-				b.statusText = "Not allowed"
-				return b, clearStatusAfter(2*time.Second, clearBookStatusMsg{})
+				return b, b.flashStatus("Not allowed")
 			}
-			ti := textinput.New()
-			ti.Placeholder = "filename.md"
-			ti.CharLimit = 255
-			focusCmd := ti.Focus()
-			b.input = ti
-			b.naming = true
-			return b, focusCmd
+			return b, b.startNaming("filename.md", "")
+		case "R":
+			item, ok := b.list.SelectedItem().(fileItem)
+			if !ok {
+				return b, nil
+			}
+			b.renamePath = item.path
+			return b, b.startNaming("filename.md", filepath.Base(item.path))
+		case "y":
+			return b, b.copyItemPath()
+		case "C":
+			return b, b.compareFile()
+		case "x", "delete":
+			return b, b.deleteFile()
+		case "u":
+			return b, b.undoDelete()
+		case "D":
+			item, ok := b.list.SelectedItem().(fileItem)
+			if !ok {
+				return b, nil
+			}
+			b.copyPath = item.path
+			return b, b.startNaming("filename.md", copyName(item.path))
 		case "m":
+			if b.preFiltered {
+				return b, b.flashStatus("Not allowed")
+			}
+			return b, b.startMove()
+		case "M":
 			toggleMouse(b.ctx)
 			return b, nil
-		case "r", "ctrl+r":
-			b.changeDir(b.dir)
+		case "p":
+			return b, b.togglePin()
+		case "t":
+			return b, b.startTags()
+		case "O":
+			return b, b.startRecent()
+		case "E":
+			return b, b.startExport()
+		case "tab":
+			b.preview = !b.preview
+			b.previewKey = ""
+			b.resizeList()
+			return b, nil
+		case "s":
+			return b, b.cycleSort()
+		case "S":
+			return b, b.toggleStats()
+		case "A":
+			return b, b.toggleMetrics()
+		case "c":
+			if b.preFiltered {
+				return b, b.flashStatus("Not allowed")
+			}
+			b.openJournal(time.Now())
 			return b, nil
+		case "T":
+			b.modified = b.modified.next()
+			return b, b.changeDir(b.dir)
+		case "F":
+			if b.preFiltered {
+				return b, b.flashStatus("Not allowed")
+			}
+			b.flat = !b.flat
+			return b, b.changeDir(b.dir)
+		case "r", "ctrl+r":
+			if !b.preFiltered {
+				b.order, b.orderFile = loadOrder(b.rootDir)
+			}
+			clear(b.dirCounts)
+			return b, tea.Batch(b.changeDir(b.dir), b.loadGitStatus())
 		case "esc", "q", "ctrl+w":
 			if b.help.Visible() {
 				b.help.Hide()
 				b.resizeList()
 				return b, nil
 			}
+			if b.tag != "" {
+				return b, b.filterByTag("")
+			}
+			if b.modified != dateAny {
+				b.modified = dateAny
+				return b, b.changeDir(b.dir)
+			}
 			return b, tea.Quit
 		case "?":
 			b.help.Toggle()
@@ -244,9 +706,21 @@ func (b Book) Update(msg tea.Msg) (Book, tea.Cmd) {
 		}
 	}
 
+	if b.picking != pickerNone {
+		return b.updatePicker(msg)
+	}
+
 	var cmd tea.Cmd
 	prevFilterState := b.list.FilterState()
+	prevIndex := b.list.Index()
 	b.list, cmd = b.list.Update(msg)
+	if _, ok := b.list.SelectedItem().(headerItem); ok {
+		step := 1
+		if b.list.Index() < prevIndex {
+			step = -1
+		}
+		b.skipHeaders(step)
+	}
 	// Resize the list when filter state changes so the filter input line
 	// doesn't steal a row from the visible items.
 	if b.list.FilterState() != prevFilterState {
@@ -256,9 +730,9 @@ func (b Book) Update(msg tea.Msg) (Book, tea.Cmd) {
 }
 
 var bookHelpEntries = [][]helpEntry{
-	{{"k/↑", "up"}, {"j/↓", "down"}, {"enter", "open"}},
-	{{"backspace", "back"}, {"n", "new file"}, {"/", "filter"}},
-	{{"r", "reload"}, {"m", "toggle mouse"}, {"?", "toggle help"}},
+	{{"k/↑", "up"}, {"j/↓", "down"}, {"enter", "open"}, {"backspace", "back"}, {"/", "filter"}, {"C", "compare"}, {"r", "reload"}, {"?", "toggle help"}},
+	{{"n", "new file"}, {"R", "rename"}, {"D", "duplicate"}, {"y", "copy path"}, {"m", "move"}, {"x", "delete"}, {"u", "undo delete"}, {"p", "pin/unpin"}, {"E", "export"}},
+	{{"t", "tags"}, {"s", "cycle sort"}, {"F", "flat view"}, {"T", "date filter"}, {"tab", "preview"}, {"S", "statistics"}, {"A", "metrics"}, {"c", "calendar"}, {"O", "all recent"}, {"M", "toggle mouse"}},
 }
 
 func bookListHeight(ctx *ViewContext, helpExtraHeight int, filtering bool) int {
@@ -273,41 +747,100 @@ func bookListHeight(ctx *ViewContext, helpExtraHeight int, filtering bool) int {
 
 func (b Book) statusBarView() string {
 	if b.naming {
-		label := statusBarPromptStyle.Render("New file:")
+		prompt := "New file:"
+		if b.renamePath != "" {
+			prompt = "Rename:"
+		} else if b.copyPath != "" {
+			prompt = "Duplicate as:"
+		}
+		label := statusBarPromptStyle.Render(prompt)
 		input := statusBarInputStyle.Render(b.input.View())
 		return statusBarFill(label+input, "", b.ctx.width)
 	}
+	if b.picking == pickerMove {
+		label := statusBarPromptStyle.Render("Move to:")
+		hint := statusBarHintStyle.Render("enter move | esc cancel")
+		return statusBarFill(label, hint, b.ctx.width)
+	}
 
 	left := statusBarBookName(b.bookName)
+	if b.git != nil && b.git.branch != "" {
+		left += statusBarNameStyle.Render("⎇ " + b.git.branch)
+	}
 	var parts []string
+	if b.exporting != "" {
+		parts = append(parts, b.spinner.View()+" Exporting "+b.exporting+"…")
+	}
 	if b.statusText != "" {
 		parts = append(parts, b.statusText)
 	}
+	if b.flat {
+		parts = append(parts, "flat")
+	}
+	if b.tag != "" {
+		parts = append(parts, "#"+b.tag)
+	}
+	if b.modified != dateAny {
+		parts = append(parts, b.modified.String())
+	}
+	if b.manualOrder() {
+		parts = append(parts, "sort: "+b.orderFile)
+	} else {
+		parts = append(parts, "sort: "+b.currentSort().String())
+	}
 	n := b.docCount()
 	parts = append(parts, fmt.Sprintf("%d %s", n, pluralize(n, "document", "documents")))
 	return renderStatusBar(b.ctx, left, parts, "? help")
 }
 
+// docCount counts the distinct documents listed, so a file shown both in a
+// section and in the listing counts once.
 func (b Book) docCount() int {
-	count := 0
+	seen := make(map[string]bool)
 	for _, item := range b.list.Items() {
-		if _, ok := item.(fileItem); ok {
-			count++
+		if f, ok := item.(fileItem); ok {
+			seen[f.path] = true
 		}
 	}
-	return count
+	return len(seen)
 }
 
 func (b Book) View() string {
 	title := render.H1Style.Render(b.bookName)
+	l := b.list
+	if b.picking != pickerNone {
+		title = render.H1Style.Render(b.pickerTitle())
+		l = b.picker
+	}
 	// Reserve a blank line for the filter input so the list doesn't jump
 	// when "/" is pressed. When filtering is active, the list component
 	// renders its own filter input line, so we drop the placeholder.
-	filtering := b.list.FilterState() == list.Filtering
+	filtering := l.FilterState() == list.Filtering
 	filterLine := "\n"
 	if filtering {
 		filterLine = ""
 	}
-	content := centerContent(title+"\n"+filterLine+"\n"+b.list.View(), b.ctx.width, b.ctx.maxWidth)
+	body := l.View()
+	switch {
+	case b.picking != pickerNone:
+	case b.journal != nil:
+		title = render.H1Style.Render(b.journal.title())
+		body = b.journal.view()
+	case b.metrics != nil:
+		title = render.H1Style.Render("Metrics")
+		body = b.metricsView()
+	case b.showStats:
+		title = render.H1Style.Render("Statistics")
+		body = b.statsView()
+	case b.loading:
+		body = "  " + b.spinner.View() + " Loading…"
+	}
+	content := title + "\n" + filterLine + "\n" + body
+	if b.preview {
+		listW, previewW := previewWidths(b.ctx)
+		content = splitPanes(content, b.previewContent, listW, previewW)
+	} else {
+		content = centerContent(content, b.ctx.width, b.ctx.maxWidth)
+	}
 	return layoutView(logo, content, b.statusBarView(), b.help.View(b.ctx.width))
 }
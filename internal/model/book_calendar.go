@@ -0,0 +1,146 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// journal is the calendar of dated notes (YYYY-MM-DD.md) shown by "c".
+type journal struct {
+	dir     string            // folder new notes are created in
+	cursor  time.Time         // highlighted day
+	entries map[string]string // note path by date (YYYY-MM-DD)
+}
+
+// journalEntries finds the dated notes below dir, keyed by date.
+func (s scanner) journalEntries(dir string) map[string]string {
+	entries := make(map[string]string)
+	files, _ := s.scanTree(dir)
+	for _, item := range files {
+		path := itemPath(item)
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if _, err := time.Parse(time.DateOnly, name); err == nil {
+			if _, dup := entries[name]; !dup {
+				entries[name] = path
+			}
+		}
+	}
+	return entries
+}
+
+// openJournal shows the calendar for the current folder, on today's date.
+func (b *Book) openJournal(today time.Time) {
+	b.journal = &journal{
+		dir:     b.dir,
+		cursor:  time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local),
+		entries: b.ctx.scanner.journalEntries(b.dir),
+	}
+}
+
+// updateJournal handles keys while the calendar is open.
+func (b Book) updateJournal(msg tea.KeyMsg) (Book, tea.Cmd) {
+	j := b.journal
+	switch msg.String() {
+	case "left", "h":
+		j.cursor = j.cursor.AddDate(0, 0, -1)
+	case "right", "l":
+		j.cursor = j.cursor.AddDate(0, 0, 1)
+	case "up", "k":
+		j.cursor = j.cursor.AddDate(0, 0, -7)
+	case "down", "j":
+		j.cursor = j.cursor.AddDate(0, 0, 7)
+	case "pgup", "b", "[":
+		j.cursor = addMonths(j.cursor, -1)
+	case "pgdown", "f", "]":
+		j.cursor = addMonths(j.cursor, 1)
+	case "T":
+		now := time.Now()
+		j.cursor = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	case "enter":
+		return b, b.openJournalEntry()
+	case "esc", "q", "c":
+		b.journal = nil
+	}
+	return b, nil
+}
+
+// addMonths moves t by n months, clamping the day to the target month's
+// length (so Jan 31 + 1 month is Feb 28/29, not early March).
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// openJournalEntry opens the note for the highlighted day, creating it in
+// the journal folder first when there is none.
+func (b *Book) openJournalEntry() tea.Cmd {
+	date := b.journal.cursor.Format(time.DateOnly)
+	path, ok := b.journal.entries[date]
+	if !ok {
+		if b.ctx.readOnly {
+			return b.flashStatus("Read-only: no note that day")
+		}
+		path = filepath.Join(b.journal.dir, date+".md")
+		if err := os.WriteFile(path, newDocument(date), 0644); err != nil {
+			return b.flashStatus("Error: " + err.Error())
+		}
+		b.forgetCounts(path)
+	}
+	b.journal = nil
+	return func() tea.Msg { return OpenChapterMsg{FilePath: path} }
+}
+
+var (
+	// journalEntryStyle marks days that have a note.
+	journalEntryStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	// journalCursorStyle highlights the selected day.
+	journalCursorStyle = lipgloss.NewStyle().Reverse(true)
+	// journalMutedStyle dims the weekday header and the footer.
+	journalMutedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// title is the heading shown above the calendar.
+func (j *journal) title() string {
+	return j.cursor.Format("January 2006")
+}
+
+// view renders the month of the highlighted day as a Monday-first grid.
+func (j *journal) view() string {
+	first := time.Date(j.cursor.Year(), j.cursor.Month(), 1, 0, 0, 0, 0, j.cursor.Location())
+	lead := (int(first.Weekday()) + 6) % 7 // days before the 1st, Monday first
+	var sb strings.Builder
+	sb.WriteString("  " + journalMutedStyle.Render("Mo  Tu  We  Th  Fr  Sa  Su") + "\n")
+	sb.WriteString("  " + strings.Repeat("    ", lead))
+	count := 0
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		cell := fmt.Sprintf("%2d", d.Day())
+		_, ok := j.entries[d.Format(time.DateOnly)]
+		switch {
+		case d.Equal(j.cursor):
+			cell = journalCursorStyle.Render(cell)
+		case ok:
+			cell = journalEntryStyle.Render(cell)
+		}
+		if ok {
+			count++
+		}
+		sb.WriteString(cell)
+		if (lead+d.Day())%7 == 0 {
+			sb.WriteString("\n  ")
+		} else {
+			sb.WriteString("  ")
+		}
+	}
+	footer := fmt.Sprintf("%d %s this month", count, pluralize(count, "entry", "entries"))
+	if _, ok := j.entries[j.cursor.Format(time.DateOnly)]; !ok {
+		footer += " · enter creates " + j.cursor.Format(time.DateOnly) + ".md"
+	}
+	return strings.TrimRight(sb.String(), " \n") + "\n\n  " + journalMutedStyle.Render(footer)
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+)
+
+// countPending marks a dirItem whose document count is still being computed.
+const countPending = -1
+
+// countWorkers is how many folders are counted at once.
+const countWorkers = 4
+
+// dirCountMsg carries the number of documents found below a folder.
+type dirCountMsg struct {
+	path  string
+	count int
+	next  []string // folders the same worker counts next
+}
+
+// countDirs counts the documents below every folder in items whose count is
+// still pending in the background, so that slow or large trees never block
+// the UI. The folders are shared among at most countWorkers workers, each
+// counting its folders one after another.
+func (b Book) countDirs(items []list.Item) tea.Cmd {
+	var queues [countWorkers][]string
+	n := 0
+	for _, item := range items {
+		d, ok := item.(dirItem)
+		if !ok || d.mdCount != countPending {
+			continue
+		}
+		queues[n%countWorkers] = append(queues[n%countWorkers], d.path)
+		n++
+	}
+	cmds := make([]tea.Cmd, 0, countWorkers)
+	for _, queue := range queues {
+		cmds = append(cmds, b.countNext(queue))
+	}
+	return tea.Batch(cmds...)
+}
+
+// countNext counts the first of paths and hands the rest on with the result.
+func (b Book) countNext(paths []string) tea.Cmd {
+	if len(paths) == 0 {
+		return nil
+	}
+	s := b.ctx.scanner
+	return func() tea.Msg {
+		return dirCountMsg{path: paths[0], count: s.countMarkdownFiles(paths[0]), next: paths[1:]}
+	}
+}
+
+// withCounts fills in the folder counts that are already known and drops
+// folders known to hold no documents.
+func (b Book) withCounts(items []list.Item) []list.Item {
+	kept := items[:0]
+	for _, item := range items {
+		if d, ok := item.(dirItem); ok && d.mdCount == countPending {
+			if n, ok := b.dirCounts[d.path]; ok {
+				if n == 0 {
+					continue
+				}
+				d.mdCount = n
+				item = d
+			}
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// applyDirCount records a folder count and shows it in the list, removing
+// the folder when it turned out to hold no documents, then counts the
+// worker's next folder. The picker keeps empty folders since they are valid
+// destinations.
+func (b *Book) applyDirCount(msg dirCountMsg) tea.Cmd {
+	b.dirCounts[msg.path] = msg.count
+	if b.picking == pickerMove {
+		setDirCount(&b.picker, msg, false)
+	}
+	selected := ""
+	if item := b.list.SelectedItem(); item != nil {
+		selected = itemPath(item)
+	}
+	cmd := setDirCount(&b.list, msg, true)
+	if msg.count == 0 {
+		b.selectPath(selected)
+		b.skipHeaders(1)
+	}
+	return tea.Batch(cmd, b.countNext(msg.next))
+}
+
+// setDirCount updates the count of the folder at msg.path in l, or removes
+// the folder when it is empty and dropEmpty is set.
+func setDirCount(l *list.Model, msg dirCountMsg, dropEmpty bool) tea.Cmd {
+	items := slices.Clone(l.Items())
+	for i, item := range items {
+		d, ok := item.(dirItem)
+		if !ok || d.path != msg.path {
+			continue
+		}
+		if msg.count == 0 && dropEmpty {
+			items = slices.Delete(items, i, i+1)
+		} else {
+			d.mdCount = msg.count
+			items[i] = d
+		}
+		return l.SetItems(items)
+	}
+	return nil
+}
+
+// forgetCounts drops the cached counts of every folder containing path, so
+// they are recounted the next time they are listed.
+func (b *Book) forgetCounts(path string) {
+	for dir := range b.dirCounts {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			delete(b.dirCounts, dir)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"charm.land/bubbles/v2/list"
+)
+
+// dateFilter narrows the Book to documents modified within a period.
+type dateFilter int
+
+const (
+	dateAny dateFilter = iota
+	dateToday
+	dateThisWeek
+	dateThisMonth
+	dateFilterCount
+)
+
+func (d dateFilter) String() string {
+	switch d {
+	case dateToday:
+		return "today"
+	case dateThisWeek:
+		return "this week"
+	case dateThisMonth:
+		return "this month"
+	default:
+		return "any time"
+	}
+}
+
+// next returns the filter that follows d, wrapping around to dateAny.
+func (d dateFilter) next() dateFilter {
+	return (d + 1) % dateFilterCount
+}
+
+// since returns the start of the period containing now: midnight today,
+// Monday of this week, or the first of this month.
+func (d dateFilter) since(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch d {
+	case dateToday:
+		return midnight
+	case dateThisWeek:
+		return midnight.AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+	case dateThisMonth:
+		return midnight.AddDate(0, 0, 1-now.Day())
+	}
+	return time.Time{}
+}
+
+// filterByDate keeps the documents modified since the start of d's period.
+func filterByDate(items []list.Item, d dateFilter, now time.Time) []list.Item {
+	since := d.since(now)
+	return slices.DeleteFunc(items, func(item list.Item) bool {
+		f, ok := item.(fileItem)
+		return !ok || f.modTime.Before(since)
+	})
+}
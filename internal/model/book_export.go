@@ -0,0 +1,320 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/frontmatter"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// exportFormat is a format pandoc exports documents to.
+type exportFormat struct {
+	name  string // pandoc's name and the file extension, like "pdf"
+	label string // name shown in the menu, like "PDF"
+}
+
+// exportFormats are the formats of the export menu, in menu order.
+var exportFormats = []exportFormat{
+	{"pdf", "PDF"},
+	{"docx", "DOCX"},
+	{"odt", "ODT"},
+}
+
+// ExportFormats returns the names of the formats WithPandocArgs accepts
+// arguments for.
+func ExportFormats() []string {
+	names := make([]string, len(exportFormats))
+	for i, f := range exportFormats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Formats ink writes itself, for books only.
+var (
+	epubFormat = exportFormat{"epub", "EPUB"}
+	siteFormat = exportFormat{"site", "website"}
+)
+
+// exportItem is an entry of the export menu: a format, for the selected
+// document or the whole book.
+type exportItem struct {
+	format exportFormat
+	path   string // document exported, or "" for the whole book
+	out    string // file written
+}
+
+func (e exportItem) Title() string {
+	if e.path == "" {
+		return "Book as " + e.format.label
+	}
+	return "Document as " + e.format.label
+}
+func (e exportItem) Description() string {
+	switch {
+	case e.path == "" && e.format == epubFormat:
+		return filepath.Base(e.out) + ", with a table of contents"
+	case e.path == "" && e.format == siteFormat:
+		return filepath.Base(e.out) + "/index.html, with a page per document"
+	}
+	if e.path == "" {
+		return filepath.Base(e.out) + ", every document in reading order"
+	}
+	return filepath.Base(e.out) + " beside " + filepath.Base(e.path)
+}
+func (e exportItem) FilterValue() string { return e.Title() }
+
+// exportDoneMsg reports how a pandoc export went.
+type exportDoneMsg struct {
+	out    string
+	output string // last line pandoc printed
+	err    error
+}
+
+// startExport opens the export menu: when pandoc is installed, each of its
+// formats for the selected document and then for the whole book, and the
+// whole book as an EPUB and as a website.
+func (b *Book) startExport() tea.Cmd {
+	if b.exporting != "" {
+		return b.flashStatus("Export already running")
+	}
+	var items []list.Item
+	if _, err := exec.LookPath("pandoc"); err == nil {
+		if item, ok := b.list.SelectedItem().(fileItem); ok {
+			stem := strings.TrimSuffix(item.path, filepath.Ext(item.path))
+			for _, f := range exportFormats {
+				items = append(items, exportItem{format: f, path: item.path, out: stem + "." + f.name})
+			}
+		}
+		for _, f := range exportFormats {
+			items = append(items, exportItem{format: f, out: bookExportPath(b.rootDir, f.name)})
+		}
+	}
+	items = append(items,
+		exportItem{format: epubFormat, out: bookExportPath(b.rootDir, epubFormat.name)},
+		exportItem{format: siteFormat, out: SitePath(b.rootDir)},
+	)
+	b.openPicker(pickerExport, items)
+	return nil
+}
+
+// bookExportPath returns the file a book is exported to in format: in its
+// folder, named after it.
+func bookExportPath(root, format string) string {
+	return filepath.Join(root, filepath.Base(root)+"."+format)
+}
+
+// SitePath returns the folder a book's website is written to by default:
+// _site in the book's folder.
+func SitePath(root string) string {
+	return filepath.Join(root, "_site")
+}
+
+// export runs pandoc, or writes the EPUB or website, in the background for
+// the chosen menu entry, showing a spinner in the status bar until it is
+// done.
+func (b *Book) export(item exportItem) tea.Cmd {
+	args := append(slices.Clone(b.ctx.pandocArgs[""]), b.ctx.pandocArgs[item.format.name]...)
+	b.exporting = item.format.label
+	if item.path != "" {
+		return tea.Batch(b.spinner.Tick, pandocExport(filepath.Dir(item.path), []string{item.path}, item.out, args))
+	}
+	root, orderFile, docs := b.rootDir, b.orderFile, b.exportDocuments()
+	run := func() tea.Msg {
+		files := docs()
+		if len(files) == 0 {
+			return exportDoneMsg{out: item.out, err: errors.New("no documents")}
+		}
+		switch item.format {
+		case epubFormat:
+			return exportDoneMsg{out: item.out, err: writeEPUB(root, orderFile, files, item.out)}
+		case siteFormat:
+			return exportDoneMsg{out: item.out, err: writeSite(root, orderFile, files, item.out)}
+		}
+		return pandocExport(root, files, item.out, args)()
+	}
+	return tea.Batch(b.spinner.Tick, run)
+}
+
+// exportDocuments returns a function, to run in the background, listing the
+// documents a book export includes: those listed, for a Book built from
+// arguments, or else the book's documents in reading order.
+func (b Book) exportDocuments() func() []string {
+	if b.preFiltered {
+		var paths []string
+		for _, f := range b.listedFiles() {
+			if IsMarkdownFile(f.path) {
+				paths = append(paths, f.path)
+			}
+		}
+		return func() []string { return paths }
+	}
+	s, root := b.ctx.scanner, b.rootDir
+	return func() []string { return s.exportOrder(root) }
+}
+
+// exportOrder returns the markdown documents of the book rooted at root in
+// reading order, leaving out the file defining the order: it is a table of
+// contents rather than a chapter.
+func (s scanner) exportOrder(root string) []string {
+	paths := s.readingOrder(root)
+	_, orderFile := loadOrder(root)
+	skip := filepath.Join(root, orderFile)
+	return slices.DeleteFunc(paths, func(p string) bool {
+		return orderFile != "" && p == skip || !IsMarkdownFile(p)
+	})
+}
+
+// loadDocuments reads the documents at paths for an export.
+func loadDocuments(paths []string) ([]export.Document, error) {
+	docs := make([]export.Document, 0, len(paths))
+	for _, p := range paths {
+		doc, err := export.Load(p)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// writeEPUB writes the documents at paths, in order, to out as an EPUB.
+func writeEPUB(root, orderFile string, paths []string, out string) error {
+	docs, err := loadDocuments(paths)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := export.EPUB(f, bookMeta(root, orderFile, docs), docs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeSite writes the documents at paths, in order, as a website into the
+// folder out, styled in the current theme.
+func writeSite(root, orderFile string, paths []string, out string) error {
+	docs, err := loadDocuments(paths)
+	if err != nil {
+		return err
+	}
+	return export.Site(out, root, bookMeta(root, orderFile, docs), docs, render.CurrentTheme())
+}
+
+// bookMeta reads a book's metadata from front matter: the title, author and
+// lang of the file defining the reading order, with the author and lang of
+// the first document filling in. The title defaults to the folder name.
+func bookMeta(root, orderFile string, docs []export.Document) export.Meta {
+	meta := export.Meta{Title: filepath.Base(root), ID: root}
+	var m frontmatter.Matter
+	if orderFile != "" {
+		m, _ = frontmatter.ReadFile(filepath.Join(root, orderFile))
+		if t := m.Get("title"); t != "" {
+			meta.Title = t
+		}
+	}
+	meta.Author, meta.Language = m.Get("author"), m.Get("lang")
+	if len(docs) > 0 {
+		if meta.Author == "" {
+			meta.Author = docs[0].Matter.Get("author")
+		}
+		if meta.Language == "" {
+			meta.Language = docs[0].Matter.Get("lang")
+		}
+	}
+	return meta
+}
+
+// ExportEPUB writes the book rooted at dir to out as an EPUB: its documents
+// in reading order, with metadata from front matter.
+func ExportEPUB(dir, out string, opts ...Option) error {
+	return exportBook(dir, out, writeEPUB, opts)
+}
+
+// ExportSite writes the book rooted at dir as a static website into the
+// folder out: an index of its folders and documents in reading order, and a
+// page per document, styled in the theme set by WithTheme.
+func ExportSite(dir, out string, opts ...Option) error {
+	return exportBook(dir, out, writeSite, opts)
+}
+
+// ServeSource returns what export.NewServer needs to serve the book rooted
+// at dir: the book's folder, and a Source reading its documents in reading
+// order, with metadata from front matter, each time it is called.
+func ServeSource(dir string, opts ...Option) (string, export.Source, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	ctx := newViewContext(0, true, opts...)
+	return root, func() (export.Meta, []export.Document, error) {
+		_, orderFile := loadOrder(root)
+		docs, err := loadDocuments(ctx.scanner.exportOrder(root))
+		if err != nil {
+			return export.Meta{}, nil, err
+		}
+		return bookMeta(root, orderFile, docs), docs, nil
+	}, nil
+}
+
+// exportBook lists the documents of the book rooted at dir in reading order
+// and has write export them to out.
+func exportBook(dir, out string, write func(root, orderFile string, paths []string, out string) error, opts []Option) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	ctx := newViewContext(0, true, opts...)
+	paths := ctx.scanner.exportOrder(root)
+	if len(paths) == 0 {
+		return errors.New("no documents in " + dir)
+	}
+	_, orderFile := loadOrder(root)
+	return write(root, orderFile, paths, out)
+}
+
+// pandocExport has pandoc convert files, joined in order, into out, run from
+// dir so relative image paths resolve. args come before the files and may
+// set anything pandoc takes, like a template or a PDF engine.
+func pandocExport(dir string, files []string, out string, args []string) tea.Cmd {
+	return func() tea.Msg {
+		cmdArgs := append(slices.Clone(args), "-o", out)
+		cmdArgs = append(cmdArgs, files...)
+		cmd := exec.Command("pandoc", cmdArgs...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		return exportDoneMsg{out: out, output: lastLine(string(output)), err: err}
+	}
+}
+
+// exportDone reports the outcome of an export in the status bar.
+func (b *Book) exportDone(msg exportDoneMsg) tea.Cmd {
+	b.exporting = ""
+	name := msg.out
+	if rel, ok := bookRel(b.rootDir, msg.out); ok {
+		name = rel
+	}
+	switch {
+	case msg.err != nil && msg.output != "":
+		b.statusText = "Export failed: " + msg.output
+	case msg.err != nil:
+		b.statusText = "Export failed: " + msg.err.Error()
+	default:
+		b.statusText = "Exported " + name
+	}
+	return clearStatusAfter(5*time.Second, clearBookStatusMsg{})
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// gitInfo is the git status of the repository containing a book.
+type gitInfo struct {
+	prefix string            // book root relative to the repo root ("" or "dir/")
+	branch string            // current branch, or "(detached)"
+	marks  map[string]string // status marker by repo-relative slash path
+}
+
+// gitStatusMsg delivers the result of loadGitStatus for root.
+type gitStatusMsg struct {
+	root string
+	info *gitInfo // nil when root is not inside a git work tree
+}
+
+// loadGitStatus queries git in the background for the book root's status.
+func (b Book) loadGitStatus() tea.Cmd {
+	root := b.rootDir
+	return func() tea.Msg {
+		info, err := readGitStatus(root)
+		if err != nil {
+			return gitStatusMsg{root: root}
+		}
+		return gitStatusMsg{root: root, info: info}
+	}
+}
+
+// readGitStatus runs git status for the work tree containing dir.
+func readGitStatus(dir string) (*gitInfo, error) {
+	prefix, err := exec.Command("git", "-C", dir, "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return nil, err
+	}
+	info := parseGitStatus(out)
+	info.prefix = strings.TrimSpace(string(prefix))
+	return info, nil
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch -z` output.
+func parseGitStatus(out []byte) *gitInfo {
+	info := &gitInfo{marks: make(map[string]string)}
+	records := bytes.Split(out, []byte{0})
+	for i := 0; i < len(records); i++ {
+		rec := string(records[i])
+		switch {
+		case strings.HasPrefix(rec, "# branch.head "):
+			info.branch = strings.TrimPrefix(rec, "# branch.head ")
+		case strings.HasPrefix(rec, "? "):
+			info.marks[rec[2:]] = "?"
+		case strings.HasPrefix(rec, "1 "), strings.HasPrefix(rec, "2 "), strings.HasPrefix(rec, "u "):
+			// Ordinary (1) entries have 8 fields before the path, renames (2)
+			// add a score field and are followed by the original path, and
+			// unmerged (u) entries have 10.
+			n := 8
+			switch rec[0] {
+			case '2':
+				n = 9
+				i++ // skip the original path record
+			case 'u':
+				n = 10
+			}
+			fields := strings.SplitN(rec, " ", n+1)
+			if len(fields) == n+1 {
+				info.marks[fields[n]] = gitMark(fields[1])
+			}
+		}
+	}
+	return info
+}
+
+// gitMark turns a porcelain XY status into a short marker: S for staged
+// changes, M for unstaged changes, or SM for both.
+func gitMark(xy string) string {
+	if len(xy) != 2 {
+		return ""
+	}
+	var mark string
+	if xy[0] != '.' {
+		mark += "S"
+	}
+	if xy[1] != '.' {
+		mark += "M"
+	}
+	return mark
+}
+
+// gitMarkFor returns the git marker for the file at path, or "".
+func (b Book) gitMarkFor(path string) string {
+	if b.git == nil {
+		return ""
+	}
+	rel, err := filepath.Rel(b.rootDir, path)
+	if err != nil {
+		return ""
+	}
+	return b.git.marks[b.git.prefix+filepath.ToSlash(rel)]
+}
@@ -2,18 +2,53 @@ package model
 
 import (
 	"fmt"
+	"io"
+	"strings"
 	"time"
+
+	"charm.land/bubbles/v2/list"
+	"charm.land/lipgloss/v2"
 )
 
 // fileItem represents a markdown file in the list.
 type fileItem struct {
-	name    string
-	path    string
-	modTime time.Time
+	name     string
+	path     string
+	modTime  time.Time
+	size     int64
+	opened   time.Time // set for Recently opened entries
+	gitMark  string    // git status marker (S, M, SM or ?), if any
+	progress float64   // fraction read (0-1)
+}
+
+func (f fileItem) Title() string {
+	if f.gitMark != "" {
+		return f.name + " [" + f.gitMark + "]"
+	}
+	return f.name
+}
+func (f fileItem) Description() string {
+	desc := relativeTime(f.modTime, time.Now())
+	if !f.opened.IsZero() {
+		desc = "opened " + relativeTime(f.opened, time.Now())
+	}
+	if badge := progressBadge(f.progress); badge != "" {
+		desc = badge + " · " + desc
+	}
+	return desc
 }
 
-func (f fileItem) Title() string       { return f.name }
-func (f fileItem) Description() string { return relativeTime(f.modTime, time.Now()) }
+// progressBadge shows reading progress as a percentage, or a check mark once
+// a document has been read to the end.
+func progressBadge(p float64) string {
+	switch {
+	case p >= 1:
+		return "✓"
+	case p > 0:
+		return fmt.Sprintf("%d%%", int(p*100))
+	}
+	return ""
+}
 func (f fileItem) FilterValue() string { return f.name }
 
 // dirItem represents a navigable folder in the list.
@@ -25,6 +60,70 @@ type dirItem struct {
 
 func (d dirItem) Title() string { return d.name + "/" }
 func (d dirItem) Description() string {
+	if d.mdCount == countPending {
+		return "counting…"
+	}
 	return fmt.Sprintf("%d %s", d.mdCount, pluralize(d.mdCount, "document", "documents"))
 }
 func (d dirItem) FilterValue() string { return d.name }
+
+// headerItem is a non-selectable section heading in the list (e.g. "Pinned").
+type headerItem struct {
+	title string
+}
+
+func (h headerItem) FilterValue() string { return "" }
+
+// sectionHeaderStyle styles headerItem titles, aligned with item titles.
+var sectionHeaderStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("205")).
+	Padding(0, 0, 0, 2)
+
+// sectionRuleStyle styles the rule drawn under headerItem titles.
+var sectionRuleStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("240")).
+	Padding(0, 0, 0, 2)
+
+// bookDelegate renders headerItems as section headings and everything else
+// with the default delegate.
+type bookDelegate struct {
+	list.DefaultDelegate
+}
+
+func (d bookDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	h, ok := item.(headerItem)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+	if m.FilterState() == list.Filtering {
+		return
+	}
+	rule := strings.Repeat(asciiOr("─", "-"), lipgloss.Width(h.title))
+	fmt.Fprintf(w, "%s\n%s", sectionHeaderStyle.Render(h.title), sectionRuleStyle.Render(rule))
+}
+
+// decorate sets the git marker and reading progress on every file item.
+func (b Book) decorate(items []list.Item) []list.Item {
+	for i, item := range items {
+		if f, ok := item.(fileItem); ok {
+			f.gitMark = b.gitMarkFor(f.path)
+			f.progress = b.ctx.state.Progress(f.path)
+			items[i] = f
+		}
+	}
+	return items
+}
+
+// itemPath returns the filesystem path of a Book list item, or "" for
+// unknown item types.
+func itemPath(item list.Item) string {
+	switch it := item.(type) {
+	case fileItem:
+		return it.path
+	case dirItem:
+		return it.path
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// docLink is a link from one document of the book to another.
+type docLink struct {
+	from, to string // paths of the linking and linked documents
+	line     int    // source line of the link in from
+	text     string // that line, trimmed, for context
+}
+
+var (
+	// wikiLinkRe matches [[target]], [[target#heading]] and [[target|text]].
+	wikiLinkRe = regexp.MustCompile(`\[\[([^\[\]|#]+)(?:#[^\[\]|]*)?(?:\|[^\[\]]*)?\]\]`)
+	// inlineLinkRe matches [text](dest) and ![alt](dest); images are told
+	// apart by the leading !.
+	inlineLinkRe = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+	// linkDefRe matches a link reference definition, [id]: dest.
+	linkDefRe = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*<?([^\s>]+)`)
+)
+
+// linkIndex finds the links between the documents under root: wiki-links
+// naming a document by its path below root or its file name, without the
+// extension, and relative markdown links. Links in code blocks, to images
+// or to documents outside the book are left out.
+func (s scanner) linkIndex(root string) []docLink {
+	files, _ := s.scanTree(root)
+	byName := make(map[string]string, 2*len(files))
+	for _, item := range files {
+		path := itemPath(item)
+		rel := strings.ToLower(strings.TrimSuffix(item.(fileItem).name, filepath.Ext(path)))
+		base := filepath.Base(rel)
+		if _, ok := byName[base]; !ok {
+			byName[base] = path
+		}
+		byName[rel] = path
+	}
+	known := make(map[string]bool, len(files))
+	for _, path := range byName {
+		known[path] = true
+	}
+	var links []docLink
+	for _, item := range files {
+		path := itemPath(item)
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		text := string(raw)
+		start := strings.Count(text[:frontmatter.Len(raw)], "\n")
+		fenced := false
+		for i, line := range strings.Split(text, "\n")[start:] {
+			if isFence([]rune(line)) {
+				fenced = !fenced
+				continue
+			}
+			if fenced {
+				continue
+			}
+			for _, to := range lineLinks(line, path, byName) {
+				if known[to] && to != path {
+					links = append(links, docLink{from: path, to: to, line: start + i, text: strings.TrimSpace(line)})
+				}
+			}
+		}
+	}
+	return links
+}
+
+// lineLinks returns the documents line, in the document at path, links to,
+// in the order of the links. byName maps lowercase document names, with and
+// without their folders, to paths, for wiki-links.
+func lineLinks(line, path string, byName map[string]string) []string {
+	type found struct {
+		at int
+		to string
+	}
+	var links []found
+	for _, m := range wikiLinkRe.FindAllStringSubmatchIndex(line, -1) {
+		name := strings.ToLower(strings.TrimSpace(line[m[2]:m[3]]))
+		if IsMarkdownFile(name) {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		if to, ok := byName[strings.TrimPrefix(filepath.ToSlash(name), "/")]; ok {
+			links = append(links, found{m[0], to})
+		}
+	}
+	for _, m := range inlineLinkRe.FindAllStringSubmatchIndex(line, -1) {
+		if m[3] > m[2] {
+			continue // an image
+		}
+		if to, ok := markdownTarget(line[m[4]:m[5]], path); ok {
+			links = append(links, found{m[0], to})
+		}
+	}
+	if m := linkDefRe.FindStringSubmatch(line); m != nil {
+		if to, ok := markdownTarget(m[1], path); ok {
+			links = append(links, found{0, to})
+		}
+	}
+	slices.SortStableFunc(links, func(a, b found) int { return a.at - b.at })
+	targets := make([]string, len(links))
+	for i, l := range links {
+		targets[i] = l.to
+	}
+	return targets
+}
+
+// markdownTarget resolves a link destination in the document at path to the
+// markdown file it names.
+func markdownTarget(dest, path string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || !IsMarkdownFile(u.Path) {
+		return "", false
+	}
+	to := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(to) {
+		to = filepath.Join(filepath.Dir(path), to)
+	}
+	return filepath.Clean(to), true
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/metrics"
+)
+
+// docMetrics is the analysis of one document of the book.
+type docMetrics struct {
+	path   string
+	name   string // path relative to the book root
+	result metrics.Result
+}
+
+// axisSummary sums up one axis over the book's documents.
+type axisSummary struct {
+	name     string
+	mean     float64
+	low      float64
+	high     float64
+	docs     int
+	outlier  docMetrics // the document scoring furthest from the mean
+	outScore float64    // the outlier's score
+}
+
+// bookMetrics is the book's writing signature: every axis averaged over its
+// documents, with the document that stands out the most.
+type bookMetrics struct {
+	loading bool
+	axes    []axisSummary
+	docs    int   // documents analyzed
+	failed  int   // documents the analysis failed on
+	err     error // the first failure
+	cursor  int   // the selected axis
+}
+
+// bookMetricsMsg delivers the analyses of a background walk over the book.
+type bookMetricsMsg struct {
+	root   string
+	docs   []docMetrics
+	failed int
+	err    error
+}
+
+// bookFiles returns the documents of the book, listing them from the
+// explicit files when the book was built from them.
+func (b Book) bookFiles() func() []fileItem {
+	root := b.rootDir
+	s := b.ctx.scanner
+	var files []fileItem
+	if b.preFiltered {
+		files = b.listedFiles()
+	}
+	return func() []fileItem {
+		if files != nil {
+			return files
+		}
+		items, _ := s.scanTree(root)
+		for _, item := range items {
+			if f, ok := item.(fileItem); ok {
+				files = append(files, f)
+			}
+		}
+		return files
+	}
+}
+
+// loadMetrics analyzes every document of the book in the background.
+func (b Book) loadMetrics() tea.Cmd {
+	root := b.rootDir
+	list := b.bookFiles()
+	ctx := b.ctx
+	return func() tea.Msg {
+		msg := bookMetricsMsg{root: root}
+		for _, f := range list() {
+			data, err := os.ReadFile(f.path)
+			if err == nil {
+				var r metrics.Result
+				if r, err = ctx.analyze(documentText(data)); err == nil {
+					msg.docs = append(msg.docs, docMetrics{path: f.path, name: f.name, result: r})
+					continue
+				}
+			}
+			msg.failed++
+			if msg.err == nil {
+				msg.err = err
+			}
+		}
+		return msg
+	}
+}
+
+// summarizeMetrics averages each axis over the documents that have it, in
+// the order the analysis reports the axes.
+func summarizeMetrics(docs []docMetrics) []axisSummary {
+	var axes []axisSummary
+	index := map[string]int{}
+	for _, d := range docs {
+		for _, a := range d.result.Axes {
+			i, ok := index[a.Name]
+			if !ok {
+				i = len(axes)
+				index[a.Name] = i
+				axes = append(axes, axisSummary{name: a.Name, low: a.Score, high: a.Score})
+			}
+			s := &axes[i]
+			s.docs++
+			s.mean += a.Score
+			s.low = min(s.low, a.Score)
+			s.high = max(s.high, a.Score)
+		}
+	}
+	for i := range axes {
+		s := &axes[i]
+		s.mean /= float64(s.docs)
+		spread := -1.0
+		for _, d := range docs {
+			a, ok := d.result.Axis(s.name)
+			if !ok {
+				continue
+			}
+			if dist := math.Abs(a.Score - s.mean); dist > spread || dist == spread && d.name < s.outlier.name {
+				spread, s.outlier, s.outScore = dist, d, a.Score
+			}
+		}
+	}
+	return axes
+}
+
+// toggleMetrics opens the book's metrics screen and starts the analysis, or
+// closes it.
+func (b *Book) toggleMetrics() tea.Cmd {
+	if b.metrics != nil {
+		b.metrics = nil
+		return nil
+	}
+	b.metrics = &bookMetrics{loading: true}
+	return tea.Batch(b.spinner.Tick, b.loadMetrics())
+}
+
+// updateMetrics handles keys on the metrics screen. enter opens the
+// selected axis's outlier in the Metrics view.
+func (b Book) updateMetrics(msg tea.KeyMsg) (Book, tea.Cmd) {
+	bm := b.metrics
+	switch msg.String() {
+	case "A", "esc", "q", "ctrl+w":
+		return b, b.toggleMetrics()
+	case "up", "k":
+		bm.cursor = max(bm.cursor-1, 0)
+	case "down", "j":
+		bm.cursor = min(bm.cursor+1, max(len(bm.axes)-1, 0))
+	case "enter", "right", "l":
+		if bm.cursor >= len(bm.axes) {
+			return b, nil
+		}
+		path := bm.axes[bm.cursor].outlier.path
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return b, b.flashStatus("Error: " + err.Error())
+		}
+		return b, func() tea.Msg {
+			return OpenMetricsMsg{FilePath: path, Content: documentText(data)}
+		}
+	}
+	return b, nil
+}
+
+// metricsView renders the metrics screen.
+func (b Book) metricsView() string {
+	bm := b.metrics
+	if bm.loading {
+		return "  " + b.spinner.View() + " Analyzing…"
+	}
+	if len(bm.axes) == 0 {
+		if bm.err != nil {
+			return "  Analysis failed: " + bm.err.Error()
+		}
+		return "  No prose to measure"
+	}
+	width := b.ctx.contentWidth()
+	rows := []string{matterHintStyle.Render(fmt.Sprintf("  Average of %d documents; enter opens the one furthest from it", bm.docs)), ""}
+	for i, s := range bm.axes {
+		cursor := "  "
+		if i == bm.cursor {
+			cursor = "▸ "
+		}
+		row := fmt.Sprintf("%s%s%s %.2f  %.2f–%.2f  ", cursor, statsLabelStyle.Render(s.name), axisBar(s.mean, axisGoal{}), s.mean, s.low, s.high)
+		row += matterHintStyle.Render(fmt.Sprintf("%s (%.2f)", s.outlier.name, s.outScore))
+		rows = append(rows, ansi.Truncate(row, width, "…"))
+	}
+	if bm.failed > 0 {
+		rows = append(rows, "", fmt.Sprintf("  %d documents could not be analyzed: %v", bm.failed, bm.err))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// metricsLoaded takes in the analyses of the book's documents.
+func (b *Book) metricsLoaded(msg bookMetricsMsg) {
+	if b.metrics == nil || msg.root != b.rootDir {
+		return
+	}
+	bm := b.metrics
+	bm.loading = false
+	bm.axes = summarizeMetrics(msg.docs)
+	bm.docs, bm.failed, bm.err = len(msg.docs), msg.failed, msg.err
+	bm.cursor = min(bm.cursor, max(len(bm.axes)-1, 0))
+}
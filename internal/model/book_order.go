@@ -0,0 +1,155 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+)
+
+// orderFiles define a book's manual reading order, in order of precedence.
+// .ink-order lists one relative path per line; SUMMARY.md and index.md are
+// read for links to markdown files.
+var orderFiles = []string{".ink-order", "SUMMARY.md", "index.md"}
+
+// orderLinkRe matches the destination of an inline markdown link.
+var orderLinkRe = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)`)
+
+// listItemRe matches the start of a markdown list item.
+var listItemRe = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+
+// loadOrder reads the manual reading order defined in dir. It returns the
+// absolute paths listed and the name of the file that defined them, or nil
+// when dir has no usable order file.
+func loadOrder(dir string) ([]string, string) {
+	for _, name := range orderFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var refs []string
+		switch name {
+		case ".ink-order":
+			refs = parseOrderList(data)
+		case "index.md":
+			refs = parseOrderLinks(data, true)
+		default:
+			refs = parseOrderLinks(data, false)
+		}
+		if paths := resolveOrder(dir, refs); len(paths) > 0 {
+			return paths, name
+		}
+	}
+	return nil, ""
+}
+
+// parseOrderList reads one path per line, skipping blanks and # comments.
+func parseOrderList(data []byte) []string {
+	var refs []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		refs = append(refs, line)
+	}
+	return refs
+}
+
+// parseOrderLinks collects link destinations in document order. When
+// listsOnly is set, only links inside list items count.
+func parseOrderLinks(data []byte, listsOnly bool) []string {
+	var refs []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if listsOnly && !listItemRe.MatchString(line) {
+			continue
+		}
+		for _, m := range orderLinkRe.FindAllStringSubmatch(line, -1) {
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// resolveOrder turns relative markdown references into unique absolute
+// paths, dropping anchors, URLs and non-markdown targets.
+func resolveOrder(dir string, refs []string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, ref := range refs {
+		ref, _, _ = strings.Cut(ref, "#")
+		if ref == "" || strings.Contains(ref, "://") || !IsMarkdownFile(ref) {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(ref); err == nil {
+			ref = unescaped
+		}
+		path := filepath.Join(dir, filepath.FromSlash(ref))
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// applyOrder stably reorders items to follow order. A folder ranks with the
+// first ordered document inside it; unlisted items keep their relative
+// order after the listed ones.
+func applyOrder(items []list.Item, order []string) {
+	rank := func(item list.Item) int {
+		path := itemPath(item)
+		for i, p := range order {
+			if p == path {
+				return i
+			}
+			if _, ok := item.(dirItem); ok && strings.HasPrefix(p, path+string(os.PathSeparator)) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	type ranked struct {
+		item list.Item
+		rank int
+	}
+	rs := make([]ranked, len(items))
+	for i, item := range items {
+		rs[i] = ranked{item, rank(item)}
+	}
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].rank < rs[j].rank })
+	for i, r := range rs {
+		items[i] = r.item
+	}
+}
+
+// readingOrder returns every document in the book rooted at root in reading
+// order: the manual order first, then the remaining documents by path.
+func (s scanner) readingOrder(root string) []string {
+	order, _ := loadOrder(root)
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range order {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	files, _ := s.scanTree(root)
+	sortItems(files, sortByName)
+	for _, item := range files {
+		if p := itemPath(item); !seen[p] {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
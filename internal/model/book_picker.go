@@ -0,0 +1,146 @@
+package model
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+)
+
+// pickerKind identifies what the Book's picker list is choosing.
+type pickerKind int
+
+const (
+	pickerNone   pickerKind = iota
+	pickerMove              // destination folder for "m"
+	pickerTag               // front matter tag for "t"
+	pickerRecent            // recently opened document of any book for "O"
+	pickerExport            // format and scope of an export for "E"
+)
+
+// openPicker replaces the file list with a picker of items until one is
+// chosen or the picker is cancelled.
+func (b *Book) openPicker(kind pickerKind, items []list.Item) {
+	b.picker = newBookList(items, b.ctx)
+	b.picking = kind
+	b.resizeList()
+}
+
+// closePicker returns to the file list.
+func (b *Book) closePicker() {
+	b.picking = pickerNone
+	b.movePath = ""
+}
+
+// pickerTitle is the heading shown above the picker.
+func (b Book) pickerTitle() string {
+	switch b.picking {
+	case pickerTag:
+		return "Tags"
+	case pickerRecent:
+		return "Recently opened"
+	case pickerExport:
+		return "Export"
+	}
+	return "Move " + filepath.Base(b.movePath)
+}
+
+// updatePicker routes messages to the picker while it is open.
+func (b Book) updatePicker(msg tea.Msg) (Book, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && b.picker.FilterState() != list.Filtering {
+		switch msg.String() {
+		case "enter":
+			var cmd tea.Cmd
+			switch item := b.picker.SelectedItem().(type) {
+			case dirItem:
+				cmd = b.moveFile(item.path)
+			case tagItem:
+				cmd = b.filterByTag(item.tag)
+			case recentItem:
+				cmd = func() tea.Msg { return OpenRecentMsg{FilePath: item.path, BookDir: item.bookDir} }
+			case exportItem:
+				cmd = b.export(item)
+			}
+			b.closePicker()
+			return b, cmd
+		case "esc", "q":
+			b.closePicker()
+			return b, nil
+		}
+	}
+	var cmd tea.Cmd
+	prevFilterState := b.picker.FilterState()
+	b.picker, cmd = b.picker.Update(msg)
+	if b.picker.FilterState() != prevFilterState {
+		b.resizeList()
+	}
+	return b, cmd
+}
+
+// scanDirTree lists root and every folder below it as dirItems named by
+// their path relative to root, skipping exclude.
+func (s scanner) scanDirTree(root, exclude string) []list.Item {
+	var dirs []list.Item
+	add := func(path string) {
+		if path == exclude {
+			return
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return
+		}
+		dirs = append(dirs, dirItem{
+			name:    filepath.ToSlash(rel),
+			path:    path,
+			mdCount: countPending,
+		})
+	}
+	add(root)
+	_ = s.walk(root, func(path string, d fs.DirEntry) error {
+		if d.IsDir() {
+			add(path)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// startMove opens the directory picker for the selected file.
+func (b *Book) startMove() tea.Cmd {
+	item, ok := b.list.SelectedItem().(fileItem)
+	if !ok {
+		return nil
+	}
+	dirs := b.ctx.scanner.scanDirTree(b.rootDir, filepath.Dir(item.path))
+	if len(dirs) == 0 {
+		return b.flashStatus("No other folders")
+	}
+	b.movePath = item.path
+	b.openPicker(pickerMove, dirs)
+	return b.countDirs(b.picker.Items())
+}
+
+// moveFile moves the file being moved into dir, refusing to overwrite an
+// existing file of the same name.
+func (b *Book) moveFile(dir string) tea.Cmd {
+	from := b.movePath
+	b.movePath = ""
+	to := filepath.Join(dir, filepath.Base(from))
+	if _, err := os.Stat(to); err == nil {
+		return b.flashStatus("File exists")
+	}
+	if err := os.Rename(from, to); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	b.forgetCounts(from)
+	b.forgetCounts(to)
+	b.renamed(from, to)
+	cmd := b.refreshSelecting(to)
+	rel, err := filepath.Rel(b.rootDir, dir)
+	if err != nil {
+		rel = dir
+	}
+	return tea.Batch(cmd, b.flashStatus("Moved to "+filepath.ToSlash(rel)))
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// previewReadLimit caps how much of a file is read for the preview pane.
+const previewReadLimit = 16 * 1024
+
+// previewMinListWidth is the narrowest the list pane gets in preview mode.
+const previewMinListWidth = 30
+
+// previewWidths splits the terminal width between the list and preview panes.
+func previewWidths(ctx *ViewContext) (listW, previewW int) {
+	listW = max(ctx.width*2/5, previewMinListWidth)
+	previewW = max(ctx.width-listW-splitDividerWidth, 1)
+	return listW, previewW
+}
+
+// updatePreview re-renders the preview pane when the highlighted item or the
+// pane width changed since the last render.
+func (b *Book) updatePreview() {
+	if !b.preview {
+		return
+	}
+	_, width := previewWidths(b.ctx)
+	path := ""
+	if item := b.list.SelectedItem(); item != nil {
+		path = itemPath(item)
+	}
+	key := fmt.Sprintf("%s:%d:%s", path, width, render.CurrentTheme().Name)
+	if key == b.previewKey {
+		return
+	}
+	b.previewKey = key
+	switch item := b.list.SelectedItem().(type) {
+	case fileItem:
+		b.previewContent = previewFile(item.path, width)
+	case dirItem:
+		b.previewContent = previewDir(b.ctx.scanner, item.path)
+	default:
+		b.previewContent = ""
+	}
+}
+
+// previewFile renders the beginning of a markdown file at width.
+func previewFile(path string, width int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, previewReadLimit))
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return render.Render([]byte(documentText(head)), width)
+}
+
+// previewDir lists the documents and folders directly inside dir.
+func previewDir(s scanner, dir string) string {
+	items, err := s.scanDir(dir)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	var lines []string
+	for _, item := range items {
+		switch it := item.(type) {
+		case dirItem:
+			lines = append(lines, it.Title())
+		case fileItem:
+			lines = append(lines, it.Title())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
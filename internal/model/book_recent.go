@@ -0,0 +1,70 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+)
+
+// recentItem is a recently opened document of any book, as listed by the
+// recents picker.
+type recentItem struct {
+	name    string // path relative to its book
+	path    string
+	bookDir string // root of the book it was opened in
+	opened  time.Time
+}
+
+func (r recentItem) Title() string { return r.name }
+func (r recentItem) Description() string {
+	return dirToBookName(r.bookDir) + " · opened " + relativeTime(r.opened, time.Now())
+}
+func (r recentItem) FilterValue() string { return r.name + " " + dirToBookName(r.bookDir) }
+
+// recentBook is the book root recorded with documents opened now: the book's
+// root directory, or "" when ink was started on a single document.
+func (c *ViewContext) recentBook() string {
+	if !c.isBook {
+		return ""
+	}
+	return c.bookDir
+}
+
+// allRecentItems lists the recently opened documents of every book that
+// still exist, newest first. A document opened on its own counts its folder
+// as its book.
+func (b Book) allRecentItems() []list.Item {
+	if b.ctx.state == nil {
+		return nil
+	}
+	var items []list.Item
+	for _, v := range b.ctx.state.Recent {
+		info, err := os.Stat(v.Path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		bookDir := v.Book
+		if bookDir == "" {
+			bookDir = filepath.Dir(v.Path)
+		}
+		rel, err := filepath.Rel(bookDir, v.Path)
+		if err != nil {
+			rel = filepath.Base(v.Path)
+		}
+		items = append(items, recentItem{name: filepath.ToSlash(rel), path: v.Path, bookDir: bookDir, opened: v.Opened})
+	}
+	return items
+}
+
+// startRecent opens the picker of recently opened documents.
+func (b *Book) startRecent() tea.Cmd {
+	items := b.allRecentItems()
+	if len(items) == 0 {
+		return b.flashStatus("Nothing opened yet")
+	}
+	b.openPicker(pickerRecent, items)
+	return nil
+}
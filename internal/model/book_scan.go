@@ -1,12 +1,14 @@
 package model
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"charm.land/bubbles/v2/list"
+
+	"github.com/inkcheck/ink/internal/ignore"
 )
 
 // IsMarkdownFile reports whether name has a markdown extension (case-insensitive).
@@ -56,70 +58,187 @@ func dirToBookName(dir string) string {
 	return name
 }
 
-func scanDir(dir string) ([]list.Item, error) {
+// scanner lists and walks the directories of a book.
+type scanner struct {
+	followSymlinks bool              // descend into symbolic links to directories
+	formats        map[string]string // commands converting other markup to markdown, by lowercase extension like ".rst"
+}
+
+// resolve returns the entry e in dir as seen through a symbolic link: a
+// link is replaced by its target, keeping the link's name. It reports false
+// for broken links and, unless following symlinks, links to directories.
+func (s scanner) resolve(dir string, e fs.DirEntry) (fs.DirEntry, bool) {
+	if e.Type()&fs.ModeSymlink == 0 {
+		return e, true
+	}
+	info, err := os.Stat(filepath.Join(dir, e.Name()))
+	if err != nil || info.IsDir() && !s.followSymlinks {
+		return nil, false
+	}
+	return fs.FileInfoToDirEntry(info), true
+}
+
+// ignores returns the ignore rules in effect for dir: the defaults, then the
+// .gitignore and .inkignore files of dir and its parents up to the root of
+// the git repository.
+func (s scanner) ignores(dir string) *ignore.Matcher {
+	m := &ignore.Matcher{}
+	m.Add(dir, ignore.Defaults...)
+	m.LoadParents(dir)
+	return m
+}
+
+// walk calls fn for every entry below root, depth first, skipping hidden and
+// ignored entries. Returning filepath.SkipDir from fn for a directory skips
+// its contents. Linked directories are followed when followSymlinks is set;
+// a directory already visited under another path is skipped so that link
+// cycles terminate. Only an error reading root itself is returned.
+func (s scanner) walk(root string, fn func(path string, d fs.DirEntry) error) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		real = root
+	}
+	visited := map[string]bool{real: true}
+	return s.walkEntries(root, real, entries, s.ignores(root), visited, fn)
+}
+
+func (s scanner) walkEntries(dir, realDir string, entries []fs.DirEntry, ignored *ignore.Matcher, visited map[string]bool, fn func(string, fs.DirEntry) error) error {
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		link := e.Type()&fs.ModeSymlink != 0
+		e, ok := s.resolve(dir, e)
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if ignored.Match(path, e.IsDir()) {
+			continue
+		}
+		if !e.IsDir() {
+			if err := fn(path, e); err != nil {
+				return err
+			}
+			continue
+		}
+		real := filepath.Join(realDir, name)
+		if link {
+			if real, ok = evalSymlinks(path); !ok {
+				continue
+			}
+		}
+		if visited[real] {
+			continue
+		}
+		visited[real] = true
+		if err := fn(path, e); err != nil {
+			if err == filepath.SkipDir {
+				continue
+			}
+			return err
+		}
+		sub, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		ignored.Load(path)
+		if err := s.walkEntries(path, real, sub, ignored, visited, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalSymlinks resolves path to its real location.
+func evalSymlinks(path string) (string, bool) {
+	real, err := filepath.EvalSymlinks(path)
+	return real, err == nil
+}
+
+// scanDir lists the folders and documents directly inside dir. Folder
+// document counts are left pending for countDirs.
+func (s scanner) scanDir(dir string) ([]list.Item, error) {
 	var dirs []list.Item
 	var files []list.Item
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
+	ignored := s.ignores(dir)
 	for _, e := range entries {
 		name := e.Name()
 		if strings.HasPrefix(name, ".") {
 			continue
 		}
+		e, ok := s.resolve(dir, e)
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if ignored.Match(path, e.IsDir()) {
+			continue
+		}
 		if e.IsDir() {
-			subPath := filepath.Join(dir, name)
-			mc := countMarkdownFiles(subPath)
-			if mc > 0 {
-				dirs = append(dirs, dirItem{
-					name:    name,
-					path:    subPath,
-					mdCount: mc,
-				})
-			}
-		} else if IsMarkdownFile(name) {
-			info, err := e.Info()
-			var modTime time.Time
-			if err == nil {
-				modTime = info.ModTime()
-			}
-			files = append(files, fileItem{
+			dirs = append(dirs, dirItem{
 				name:    name,
-				path:    filepath.Join(dir, name),
-				modTime: modTime,
+				path:    path,
+				mdCount: countPending,
 			})
+		} else if s.isDocument(name) {
+			info, _ := e.Info()
+			files = append(files, newFileItem(name, path, info))
 		}
 	}
 	// Directories first, then files
 	return append(dirs, files...), nil
 }
 
-// skipDirs contains directory names to exclude when scanning for markdown files.
-var skipDirs = map[string]bool{
-	"node_modules": true,
-	"vendor":       true,
-	"__pycache__":  true,
+// scanTree walks dir recursively and returns every markdown file beneath it
+// as a fileItem named by its slash-separated path relative to dir.
+func (s scanner) scanTree(dir string) ([]list.Item, error) {
+	var files []list.Item
+	err := s.walk(dir, func(path string, d fs.DirEntry) error {
+		if d.IsDir() || !s.isDocument(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = d.Name()
+		}
+		info, _ := d.Info()
+		files = append(files, newFileItem(filepath.ToSlash(rel), path, info))
+		return nil
+	})
+	return files, err
 }
 
-func countMarkdownFiles(dir string) int {
+// newFileItem builds a fileItem, tolerating a nil info when stat failed.
+func newFileItem(name, path string, info os.FileInfo) fileItem {
+	item := fileItem{name: name, path: path}
+	if info != nil {
+		item.modTime = info.ModTime()
+		item.size = info.Size()
+	}
+	return item
+}
+
+// countMarkdownFiles counts the documents in dir and up to three levels of
+// folders below it.
+func (s scanner) countMarkdownFiles(dir string) int {
 	count := 0
 	dirDepth := strings.Count(dir, string(os.PathSeparator))
-	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			name := d.Name()
-			if strings.HasPrefix(name, ".") || skipDirs[name] {
-				return filepath.SkipDir
-			}
-		}
+	_ = s.walk(dir, func(path string, d fs.DirEntry) error {
 		depth := strings.Count(path, string(os.PathSeparator)) - dirDepth
 		if d.IsDir() && depth > 3 {
 			return filepath.SkipDir
 		}
-		if !d.IsDir() && IsMarkdownFile(d.Name()) {
+		if !d.IsDir() && s.isDocument(d.Name()) {
 			count++
 		}
 		return nil
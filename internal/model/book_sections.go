@@ -0,0 +1,120 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// pinnedItems returns the pinned documents inside the book, in pin order,
+// named by their path relative to the book root.
+func (b Book) pinnedItems() []list.Item {
+	if b.ctx.state == nil {
+		return nil
+	}
+	var items []list.Item
+	for _, path := range b.ctx.state.Pins {
+		rel, err := filepath.Rel(b.rootDir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		items = append(items, newFileItem(filepath.ToSlash(rel), path, info))
+	}
+	return items
+}
+
+// recentLimit is the number of entries in the Recently opened section.
+const recentLimit = 3
+
+// recentItems returns the most recently opened documents inside the book that
+// are not already pinned, newest first.
+func (b Book) recentItems() []list.Item {
+	if b.ctx.state == nil {
+		return nil
+	}
+	var items []list.Item
+	for _, v := range b.ctx.state.Recent {
+		if len(items) == recentLimit {
+			break
+		}
+		if b.ctx.state.Pinned(v.Path) {
+			continue
+		}
+		rel, err := filepath.Rel(b.rootDir, v.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		info, err := os.Stat(v.Path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		item := newFileItem(filepath.ToSlash(rel), v.Path, info)
+		item.opened = v.Opened
+		items = append(items, item)
+	}
+	return items
+}
+
+// withSections prepends the Pinned and Recently opened sections to a
+// directory listing. Pinned files are shown once, in their section, rather
+// than again below it; recent files stay in the listing as well.
+func (b Book) withSections(items []list.Item) []list.Item {
+	pins := b.pinnedItems()
+	recent := b.recentItems()
+	if len(pins) == 0 && len(recent) == 0 {
+		return items
+	}
+	var out []list.Item
+	if len(pins) > 0 {
+		out = append(out, headerItem{title: "Pinned"})
+		out = append(out, pins...)
+	}
+	if len(recent) > 0 {
+		out = append(out, headerItem{title: "Recently opened"})
+		out = append(out, recent...)
+	}
+	out = append(out, headerItem{title: "Documents"})
+	for _, item := range items {
+		if f, ok := item.(fileItem); ok && b.ctx.state.Pinned(f.path) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// renamed carries the pin and bookmarks of a document renamed or moved from
+// from over to its new path to.
+func (b *Book) renamed(from, to string) {
+	b.ctx.state.Rename(from, to)
+	// The file has moved either way; a failed write only loses its pin.
+	_ = b.ctx.state.Save()
+}
+
+// togglePin pins or unpins the highlighted file and persists the change.
+func (b *Book) togglePin() tea.Cmd {
+	item, ok := b.list.SelectedItem().(fileItem)
+	if !ok || b.ctx.state == nil {
+		return nil
+	}
+	status := "Unpinned"
+	if b.ctx.state.TogglePin(item.path) {
+		status = "Pinned"
+		if raw, err := os.ReadFile(item.path); err == nil {
+			b.ctx.state.Seen(item.path, state.ContentHash(documentText(raw)))
+		}
+	}
+	if err := b.ctx.state.Save(); err != nil {
+		status = "Error: " + err.Error()
+	}
+	return tea.Batch(b.refresh(), b.flashStatus(status))
+}
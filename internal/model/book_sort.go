@@ -0,0 +1,147 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/list"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// sortMode is the ordering applied to files in the Book list.
+type sortMode int
+
+const (
+	sortByName sortMode = iota
+	sortByModified
+	sortByCreated
+	sortBySize
+	sortByTitle
+	sortByStatus
+	sortModeCount
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortByModified:
+		return "modified"
+	case sortByCreated:
+		return "created"
+	case sortBySize:
+		return "size"
+	case sortByTitle:
+		return "title"
+	case sortByStatus:
+		return "status"
+	default:
+		return "name"
+	}
+}
+
+// next returns the sort mode that follows s, wrapping around.
+func (s sortMode) next() sortMode {
+	return (s + 1) % sortModeCount
+}
+
+// sortItems orders items in place: directories first (by name), then files
+// according to mode. Newest and largest files come first for the time and
+// size orders; name and title sort alphabetically; status follows the
+// publishing pipeline (see statusRank).
+func sortItems(items []list.Item, mode sortMode) {
+	// Front matter is only read for the modes that need it.
+	var titles map[string]string
+	var created map[string]time.Time
+	var statuses map[string]string
+	switch mode {
+	case sortByTitle:
+		titles = make(map[string]string)
+		for _, it := range items {
+			if f, ok := it.(fileItem); ok {
+				titles[f.path] = fileTitle(f)
+			}
+		}
+	case sortByCreated:
+		created = make(map[string]time.Time)
+		for _, it := range items {
+			if f, ok := it.(fileItem); ok {
+				created[f.path] = fileCreated(f)
+			}
+		}
+	case sortByStatus:
+		statuses = make(map[string]string)
+		for _, it := range items {
+			if f, ok := it.(fileItem); ok {
+				statuses[f.path] = fileStatus(f.path)
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		ad, aIsDir := a.(dirItem)
+		bd, bIsDir := b.(dirItem)
+		if aIsDir != bIsDir {
+			return aIsDir
+		}
+		if aIsDir {
+			return strings.ToLower(ad.name) < strings.ToLower(bd.name)
+		}
+		af, _ := a.(fileItem)
+		bf, _ := b.(fileItem)
+		switch mode {
+		case sortByModified:
+			if !af.modTime.Equal(bf.modTime) {
+				return af.modTime.After(bf.modTime)
+			}
+		case sortByCreated:
+			if ca, cb := created[af.path], created[bf.path]; !ca.Equal(cb) {
+				return ca.After(cb)
+			}
+		case sortBySize:
+			if af.size != bf.size {
+				return af.size > bf.size
+			}
+		case sortByTitle:
+			if ta, tb := titles[af.path], titles[bf.path]; ta != tb {
+				return ta < tb
+			}
+		case sortByStatus:
+			if sa, sb := statuses[af.path], statuses[bf.path]; sa != sb {
+				if ra, rb := statusRank(sa), statusRank(sb); ra != rb {
+					return ra < rb
+				}
+				return sa < sb
+			}
+		}
+		return strings.ToLower(af.name) < strings.ToLower(bf.name)
+	})
+}
+
+// fileTitle returns the lowercased front matter title of f, falling back to
+// its file name.
+func fileTitle(f fileItem) string {
+	m, err := frontmatter.ReadFile(f.path)
+	if err == nil {
+		if title := m.Get("title"); title != "" {
+			return strings.ToLower(title)
+		}
+	}
+	return strings.ToLower(f.name)
+}
+
+// fileCreated returns the creation date recorded in the front matter "date"
+// field (as written by ink for new files), falling back to the mod time.
+func fileCreated(f fileItem) time.Time {
+	m, err := frontmatter.ReadFile(f.path)
+	if err == nil {
+		date := m.Get("date")
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", time.DateOnly} {
+			if t, err := time.Parse(layout, date); err == nil {
+				return t
+			}
+		}
+	}
+	return f.modTime
+}
@@ -0,0 +1,266 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// docStats holds the statistics of one document. They are cached by path
+// and recomputed only when the file's size or mod time changes.
+type docStats struct {
+	name    string // path relative to the book root
+	modTime time.Time
+	size    int64
+	words   int
+	grade   float64
+	graded  bool // false when the document is too short to grade
+}
+
+// bookStats summarizes every document in the book.
+type bookStats struct {
+	docs     int
+	words    int
+	grade    float64 // average grade over the graded documents
+	graded   int
+	largest  docStats
+	smallest docStats
+	recent   docStats
+}
+
+// writingTotals sums up the editing sessions logged on a book's documents.
+type writingTotals struct {
+	sessions int
+	added    int
+	typing   time.Duration
+}
+
+// readingTotals sums up the time logged reading a book's documents.
+type readingTotals struct {
+	active   time.Duration
+	mostRead string        // document read longest, relative to the book root
+	mostTime time.Duration // time spent reading it
+}
+
+// bookRel returns the path of path relative to root, reporting false when
+// path isn't under root.
+func bookRel(root, path string) (string, bool) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// bookWriting totals the logged editing sessions on documents under root.
+func bookWriting(st *state.State, root string) writingTotals {
+	var w writingTotals
+	sessions, _ := st.Sessions()
+	for _, s := range sessions {
+		if _, ok := bookRel(root, s.Path); !ok {
+			continue
+		}
+		w.sessions++
+		w.added += s.Added
+		w.typing += s.Typing
+	}
+	return w
+}
+
+// bookReading totals the time logged reading documents under root.
+func bookReading(st *state.State, root string) readingTotals {
+	var r readingTotals
+	reads, _ := st.Reads()
+	byDoc := make(map[string]time.Duration)
+	for _, read := range reads {
+		rel, ok := bookRel(root, read.Path)
+		if !ok {
+			continue
+		}
+		r.active += read.Active
+		byDoc[rel] += read.Active
+	}
+	for rel, d := range byDoc {
+		if d > r.mostTime || d == r.mostTime && rel < r.mostRead {
+			r.mostRead, r.mostTime = rel, d
+		}
+	}
+	return r
+}
+
+// bookStatsMsg delivers the per-document statistics of a background walk.
+type bookStatsMsg struct {
+	root string
+	docs map[string]docStats
+}
+
+// loadStats walks the book in the background and computes the statistics of
+// every document, reusing the cached ones that did not change.
+func (b Book) loadStats() tea.Cmd {
+	root := b.rootDir
+	list := b.bookFiles()
+	cache := maps.Clone(b.statsCache)
+	return func() tea.Msg {
+		files := list()
+		docs := make(map[string]docStats, len(files))
+		for _, f := range files {
+			if s, ok := cache[f.path]; ok && s.size == f.size && s.modTime.Equal(f.modTime) {
+				docs[f.path] = s
+				continue
+			}
+			data, err := os.ReadFile(f.path)
+			if err != nil {
+				continue
+			}
+			text := documentText(data)
+			s := docStats{name: f.name, modTime: f.modTime, size: f.size, words: countWords(text)}
+			s.grade, s.graded = fleschKincaidScore(text)
+			docs[f.path] = s
+		}
+		return bookStatsMsg{root: root, docs: docs}
+	}
+}
+
+// listedFiles returns the distinct documents in the list.
+func (b Book) listedFiles() []fileItem {
+	seen := make(map[string]bool)
+	files := []fileItem{}
+	for _, item := range b.list.Items() {
+		if f, ok := item.(fileItem); ok && !seen[f.path] {
+			seen[f.path] = true
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// summarizeStats totals the per-document statistics.
+func summarizeStats(docs map[string]docStats) bookStats {
+	var s bookStats
+	var gradeSum float64
+	for _, d := range docs {
+		s.docs++
+		s.words += d.words
+		if d.graded {
+			s.graded++
+			gradeSum += d.grade
+		}
+		if s.docs == 1 || d.size > s.largest.size || d.size == s.largest.size && d.name < s.largest.name {
+			s.largest = d
+		}
+		if s.docs == 1 || d.size < s.smallest.size || d.size == s.smallest.size && d.name < s.smallest.name {
+			s.smallest = d
+		}
+		if s.docs == 1 || d.modTime.After(s.recent.modTime) {
+			s.recent = d
+		}
+	}
+	if s.graded > 0 {
+		s.grade = gradeSum / float64(s.graded)
+	}
+	return s
+}
+
+// toggleStats opens the statistics screen, refreshing the cached figures in
+// the background, or closes it.
+func (b *Book) toggleStats() tea.Cmd {
+	b.showStats = !b.showStats
+	if !b.showStats {
+		return nil
+	}
+	b.statsLoading = true
+	b.writing = bookWriting(b.ctx.state, b.rootDir)
+	b.reading = bookReading(b.ctx.state, b.rootDir)
+	return tea.Batch(b.spinner.Tick, b.loadStats())
+}
+
+// statsLabelStyle styles the labels of the statistics screen.
+var statsLabelStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("240")).
+	Width(16)
+
+// statsView renders the statistics screen.
+func (b Book) statsView() string {
+	if b.statsCache == nil {
+		return "  " + b.spinner.View() + " Counting…"
+	}
+	s := summarizeStats(b.statsCache)
+	row := func(label, value string) string {
+		return "  " + statsLabelStyle.Render(label) + value
+	}
+	rows := []string{
+		row("Documents", fmt.Sprintf("%d", s.docs)),
+		row("Words", fmt.Sprintf("%d", s.words)),
+	}
+	if s.graded > 0 {
+		rows = append(rows, row("Average grade", fmt.Sprintf("%.1f", s.grade)))
+	}
+	if s.docs > 0 {
+		now := time.Now()
+		rows = append(rows,
+			row("Largest", fmt.Sprintf("%s (%s)", s.largest.name, formatSize(s.largest.size))),
+			row("Smallest", fmt.Sprintf("%s (%s)", s.smallest.name, formatSize(s.smallest.size))),
+			row("Last edited", fmt.Sprintf("%s (%s)", s.recent.name, relativeTime(s.recent.modTime, now))),
+		)
+	}
+	if w := b.writing; w.sessions > 0 {
+		rows = append(rows, row("Written", fmt.Sprintf("%d words in %d sessions (%s typing)", w.added, w.sessions, w.typing.Round(time.Minute))))
+	}
+	if r := b.reading; r.active > 0 {
+		rows = append(rows,
+			row("Read", formatDuration(r.active)),
+			row("Most read", fmt.Sprintf("%s (%s)", r.mostRead, formatDuration(r.mostTime))),
+		)
+	}
+	if b.statsLoading {
+		rows = append(rows, "", "  "+b.spinner.View()+" Updating…")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// formatDuration formats d for display to the minute, e.g. "2h 5m", or
+// "under a minute".
+func formatDuration(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	switch {
+	case m == 0:
+		return "under a minute"
+	case m < 60:
+		return fmt.Sprintf("%dm", m)
+	case m%60 == 0:
+		return fmt.Sprintf("%dh", m/60)
+	}
+	return fmt.Sprintf("%dh %dm", m/60, m%60)
+}
+
+// formatSize formats n bytes for display, e.g. "512 B" or "12.3 KB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
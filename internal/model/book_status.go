@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"charm.land/bubbles/v2/list"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// pipelineStatuses are the front matter statuses of a manuscript, in the
+// order documents move through them.
+var pipelineStatuses = []string{"draft", "review", "published"}
+
+// fileStatus returns the lowercased front matter "status" of the file at
+// path, or "" when it has none.
+func fileStatus(path string) string {
+	m, err := frontmatter.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(m.Get("status")))
+}
+
+// statusRank orders statuses along the pipeline, then any other status,
+// then documents without one.
+func statusRank(status string) int {
+	for i, s := range pipelineStatuses {
+		if status == s {
+			return i
+		}
+	}
+	if status == "" {
+		return len(pipelineStatuses) + 1
+	}
+	return len(pipelineStatuses)
+}
+
+// statusTitle is the section heading for documents with status.
+func statusTitle(status string) string {
+	if status == "" {
+		return "No status"
+	}
+	r, size := utf8.DecodeRuneInString(status)
+	return string(unicode.ToUpper(r)) + status[size:]
+}
+
+// groupByStatus inserts a section header before each run of documents
+// sharing a status. items must already be sorted by status; folders, which
+// come first, are left without a header. Pinned documents are left out as
+// they are listed in their own section.
+func (b Book) groupByStatus(items []list.Item) []list.Item {
+	out := make([]list.Item, 0, len(items))
+	current := "\x00"
+	for _, item := range items {
+		if f, ok := item.(fileItem); ok {
+			if b.ctx.state.Pinned(f.path) {
+				continue
+			}
+			if status := fileStatus(f.path); status != current {
+				current = status
+				out = append(out, headerItem{title: statusTitle(status)})
+			}
+		}
+		out = append(out, item)
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// tagItem is a front matter tag in the tag picker.
+type tagItem struct {
+	tag   string
+	count int
+}
+
+func (t tagItem) Title() string { return "#" + t.tag }
+func (t tagItem) Description() string {
+	return fmt.Sprintf("%d %s", t.count, pluralize(t.count, "document", "documents"))
+}
+func (t tagItem) FilterValue() string { return t.tag }
+
+// fileTags returns the front matter tags of the file at path, without any
+// leading "#".
+func fileTags(path string) []string {
+	m, err := frontmatter.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	tags := m.List("tags")
+	for i, tag := range tags {
+		tags[i] = strings.TrimPrefix(tag, "#")
+	}
+	return tags
+}
+
+// collectTags counts how many documents under root carry each tag, most used
+// first.
+func (s scanner) collectTags(root string) []list.Item {
+	files, _ := s.scanTree(root)
+	counts := make(map[string]int)
+	for _, item := range files {
+		for _, tag := range fileTags(itemPath(item)) {
+			counts[tag]++
+		}
+	}
+	items := make([]list.Item, 0, len(counts))
+	for tag, n := range counts {
+		items = append(items, tagItem{tag: tag, count: n})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i].(tagItem), items[j].(tagItem)
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.tag < b.tag
+	})
+	return items
+}
+
+// scanTag lists every document under root tagged with tag, named by its path
+// relative to root.
+func (s scanner) scanTag(root, tag string) ([]list.Item, error) {
+	files, err := s.scanTree(root)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(item list.Item) bool {
+		return !slices.Contains(fileTags(itemPath(item)), tag)
+	}), nil
+}
+
+// startTags opens the tag picker.
+func (b *Book) startTags() tea.Cmd {
+	tags := b.ctx.scanner.collectTags(b.rootDir)
+	if len(tags) == 0 {
+		return b.flashStatus("No tags")
+	}
+	b.openPicker(pickerTag, tags)
+	return nil
+}
+
+// filterByTag lists only documents tagged with tag; "" restores the folder
+// listing.
+func (b *Book) filterByTag(tag string) tea.Cmd {
+	b.tag = tag
+	return b.changeDir(b.dir)
+}
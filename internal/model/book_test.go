@@ -1,10 +1,23 @@
 package model
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"charm.land/bubbles/v2/list"
+	tea "charm.land/bubbletea/v2"
+	"github.com/atotto/clipboard"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
 )
 
 func TestCommonParentDir(t *testing.T) {
@@ -96,7 +109,7 @@ func TestBookViewContainsBookName(t *testing.T) {
 		"readme.md": "# Hello",
 	})
 	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
-	book := NewBook(ctx, dir)
+	book := loadBook(ctx, dir)
 	view := book.View()
 
 	bookName := dirToBookName(dir)
@@ -111,7 +124,7 @@ func TestBookViewContainsFileNames(t *testing.T) {
 		"chapter-two.md": "# Chapter Two",
 	})
 	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
-	book := NewBook(ctx, dir)
+	book := loadBook(ctx, dir)
 	view := book.View()
 
 	if !strings.Contains(view, "chapter-one.md") {
@@ -138,13 +151,63 @@ func TestNewBookFromFilesPreFiltered(t *testing.T) {
 	}
 }
 
+func TestBookFromFilesRefresh(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"b.md":       "# B",
+		"a.md":       "# A",
+		"c.md":       "# C",
+		"notes/d.md": "# D",
+	})
+	files := []string{filepath.Join(dir, "b.md"), filepath.Join(dir, "a.md")}
+	var m tea.Model = NewFromFiles(files, 80)
+	paths := func() []string {
+		// The documents listed, after any Pinned and Recently opened.
+		var out []string
+		for _, item := range m.(Model).book.list.Items() {
+			if h, ok := item.(headerItem); ok && h.title == "Documents" {
+				out = nil
+			} else if p := itemPath(item); p != "" {
+				out = append(out, filepath.Base(p))
+			}
+		}
+		return out
+	}
+	want := []string{"b.md", "a.md"}
+	if got := paths(); !slices.Equal(got, want) {
+		t.Fatalf("before: %v, want %v", got, want)
+	}
+	m, _ = m.Update(OpenChapterMsg{FilePath: files[0]})
+	m, _ = m.Update(BackToBookMsg{})
+	if got := paths(); !slices.Equal(got, want) {
+		t.Errorf("after going back: %v, want %v", got, want)
+	}
+	press := func(r rune) {
+		m, _ = m.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		um := m.(Model)
+		um.book = awaitScan(um.book, um.book.dir)
+		m = um
+	}
+	press('r')
+	if got := paths(); !slices.Equal(got, want) {
+		t.Errorf("after r: %v, want %v", got, want)
+	}
+	press('s')
+	if got := paths(); len(got) != 2 || !slices.Contains(got, "a.md") || !slices.Contains(got, "b.md") {
+		t.Errorf("after s: %v, want only the two files", got)
+	}
+	press('T')
+	if got := paths(); len(got) != 2 {
+		t.Errorf("after T: %v, want the two files, modified today", got)
+	}
+}
+
 func TestNewBookSkipsHiddenFiles(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{
 		".hidden.md": "# Hidden",
 		"visible.md": "# Visible",
 	})
 	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
-	book := NewBook(ctx, dir)
+	book := loadBook(ctx, dir)
 	view := book.View()
 
 	if strings.Contains(view, ".hidden.md") {
@@ -154,3 +217,1276 @@ func TestNewBookSkipsHiddenFiles(t *testing.T) {
 		t.Error("Book.View() should show visible files")
 	}
 }
+
+func TestSortItems(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	alpha := filepath.Join(dir, "alpha.md")
+	beta := filepath.Join(dir, "beta.md")
+	os.WriteFile(alpha, []byte("---\ntitle: \"Zebra\"\ndate: 2024-01-01T00:00:00Z\n---\n"), 0644)
+	os.WriteFile(beta, []byte("---\ntitle: \"Aardvark\"\ndate: 2025-01-01T00:00:00Z\n---\n"), 0644)
+
+	items := func() []list.Item {
+		return []list.Item{
+			fileItem{name: "beta.md", path: beta, modTime: now.Add(-time.Hour), size: 10},
+			dirItem{name: "notes", path: filepath.Join(dir, "notes")},
+			fileItem{name: "alpha.md", path: alpha, modTime: now, size: 5},
+		}
+	}
+	names := func(items []list.Item) []string {
+		var out []string
+		for _, it := range items {
+			out = append(out, it.FilterValue())
+		}
+		return out
+	}
+
+	tests := []struct {
+		mode sortMode
+		want []string
+	}{
+		{sortByName, []string{"notes", "alpha.md", "beta.md"}},
+		{sortByModified, []string{"notes", "alpha.md", "beta.md"}},
+		{sortByCreated, []string{"notes", "beta.md", "alpha.md"}},
+		{sortBySize, []string{"notes", "beta.md", "alpha.md"}},
+		{sortByTitle, []string{"notes", "beta.md", "alpha.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			got := items()
+			sortItems(got, tt.mode)
+			if strings.Join(names(got), ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sortItems(%s) = %v, want %v", tt.mode, names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestBookCycleSortRemembersPerDirectory(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "# A",
+		"sub/b.md": "# B",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: 's', Text: "s"})
+	if book.currentSort() != sortByModified {
+		t.Fatalf("after s: sort = %s, want modified", book.currentSort())
+	}
+	book.changeDir(filepath.Join(dir, "sub"))
+	book = awaitScan(book, filepath.Join(dir, "sub"))
+	if book.currentSort() != sortByName {
+		t.Errorf("subdirectory sort = %s, want name", book.currentSort())
+	}
+	book.changeDir(dir)
+	book = awaitScan(book, dir)
+	if book.currentSort() != sortByModified {
+		t.Errorf("root sort after returning = %s, want modified", book.currentSort())
+	}
+}
+
+func TestBookCountsFoldersInBackground(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":            "# A",
+		"docs/b.md":       "# B",
+		"docs/c.md":       "# C",
+		"empty/notes.txt": "not markdown",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	dirs := map[string]dirItem{}
+	for _, item := range book.list.Items() {
+		if d, ok := item.(dirItem); ok {
+			dirs[d.name] = d
+		}
+	}
+	if len(dirs) != 2 || dirs["docs"].mdCount != countPending || dirs["empty"].mdCount != countPending {
+		t.Fatalf("folders before counting = %+v, want docs and empty pending", dirs)
+	}
+	if got := dirs["docs"].Description(); got != "counting…" {
+		t.Errorf("pending Description() = %q, want %q", got, "counting…")
+	}
+
+	for _, d := range dirs {
+		book, _ = book.Update(dirCountMsg{path: d.path, count: scanner{}.countMarkdownFiles(d.path)})
+	}
+	var names []string
+	for _, item := range book.list.Items() {
+		if d, ok := item.(dirItem); ok {
+			names = append(names, d.name)
+			if d.mdCount != 2 {
+				t.Errorf("%s count = %d, want 2", d.name, d.mdCount)
+			}
+		}
+	}
+	if strings.Join(names, ",") != "docs" {
+		t.Errorf("folders after counting = %v, want [docs]", names)
+	}
+	if _, ok := book.list.SelectedItem().(headerItem); ok {
+		t.Error("selection landed on a section header")
+	}
+
+	// Known counts are reused when the folder is listed again.
+	book.changeDir(dir)
+	book = awaitScan(book, dir)
+	for _, item := range book.list.Items() {
+		if d, ok := item.(dirItem); ok && d.mdCount != 2 {
+			t.Errorf("%s count after rescan = %d, want cached 2", d.name, d.mdCount)
+		}
+	}
+}
+
+// loadBook creates a Book for dir and delivers its initial scan, as the
+// program loop would after Init.
+func loadBook(ctx *ViewContext, dir string) Book {
+	b := NewBook(ctx, dir)
+	return awaitScan(b, b.dir)
+}
+
+// awaitRefresh runs cmd as the program loop would and delivers the
+// directory scans it produces. Commands still running after a moment, such
+// as status timers, are abandoned.
+func awaitRefresh(b Book, cmd tea.Cmd) Book {
+	for _, msg := range settle(cmd) {
+		if msg, ok := msg.(dirScannedMsg); ok {
+			b, _ = b.Update(msg)
+		}
+	}
+	return b
+}
+
+// settle runs cmd and the commands of any batch it returns, collecting the
+// messages that arrive within a moment.
+func settle(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		batch, ok := msg.(tea.BatchMsg)
+		if !ok {
+			return []tea.Msg{msg}
+		}
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, settle(c)...)
+		}
+		return msgs
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+// awaitScan delivers the result of the background scan of dir started by
+// changeDir, as the program loop would.
+func awaitScan(b Book, dir string) Book {
+	b, _ = b.Update(b.scanCmd(dir)())
+	return b
+}
+
+func TestBookCountsFoldersWithFewWorkers(t *testing.T) {
+	files := map[string]string{}
+	for i := range 10 {
+		files[fmt.Sprintf("dir%d/a.md", i)] = "# A"
+	}
+	dir := tempDirWithFiles(t, files)
+	book := loadBook(&ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}, dir)
+
+	batch, ok := book.countDirs(book.list.Items())().(tea.BatchMsg)
+	if !ok || len(batch) != countWorkers {
+		t.Fatalf("countDirs started %d workers, want %d", len(batch), countWorkers)
+	}
+	pending, counted := []tea.Cmd(batch), 0
+	for len(pending) > 0 {
+		got := pending[0]()
+		msg, ok := got.(dirCountMsg)
+		if !ok {
+			t.Fatalf("worker sent %T, want dirCountMsg", got)
+		}
+		pending = pending[1:]
+		counted++
+		var next tea.Cmd
+		book, next = book.Update(msg)
+		if next != nil {
+			pending = append(pending, next)
+		}
+	}
+	if counted != 10 {
+		t.Errorf("counted %d folders, want 10", counted)
+	}
+	for _, item := range book.list.Items() {
+		if d, ok := item.(dirItem); ok && d.mdCount != 1 {
+			t.Errorf("%s count = %d, want 1", d.name, d.mdCount)
+		}
+	}
+}
+
+func TestBookChangeDirScansInBackground(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "# A",
+		"sub/b.md": "# B",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	sub := filepath.Join(dir, "sub")
+
+	book, cmd := book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd == nil || !book.loading {
+		t.Fatal("entering a folder should start a background scan")
+	}
+	if !strings.Contains(book.View(), "Loading…") {
+		t.Error("view should show the loading state")
+	}
+	stale := book.scanCmd(filepath.Join(dir, "gone"))
+
+	book = awaitScan(book, sub)
+	if book.loading || book.dir != sub {
+		t.Fatalf("after scan: loading = %v, dir = %q", book.loading, book.dir)
+	}
+	if got := itemPath(book.list.SelectedItem()); got != filepath.Join(sub, "b.md") {
+		t.Errorf("selected = %q, want b.md", got)
+	}
+
+	// A failed scan keeps the current listing and reports the error.
+	book.changeDir(filepath.Join(dir, "missing"))
+	book = awaitScan(book, filepath.Join(dir, "missing"))
+	if book.dir != sub || len(book.list.Items()) == 0 {
+		t.Errorf("failed scan replaced the listing: dir = %q", book.dir)
+	}
+	if !strings.HasPrefix(book.statusText, "Error:") {
+		t.Errorf("statusText = %q, want an error", book.statusText)
+	}
+
+	// Results of superseded scans are ignored.
+	book, _ = book.Update(stale())
+	if book.dir != sub {
+		t.Errorf("stale scan changed dir to %q", book.dir)
+	}
+}
+
+func TestBookStartAndRefreshScanInBackground(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# A", "b.md": "# B"})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := NewBook(ctx, dir)
+	if !book.loading || len(book.list.Items()) != 0 || book.Init() == nil {
+		t.Fatal("a new Book should list its folder in the background")
+	}
+	book = awaitScan(book, dir)
+	book.selectPath(filepath.Join(dir, "b.md"))
+
+	if err := os.WriteFile(filepath.Join(dir, "c.md"), []byte("# C"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := book.refresh()
+	if book.loading || len(book.list.Items()) != 2 {
+		t.Fatal("refresh should keep the current listing until the scan completes")
+	}
+	book = awaitRefresh(book, cmd)
+	if n := len(book.list.Items()); n != 3 {
+		t.Errorf("items after refresh = %d, want 3", n)
+	}
+	if got := itemPath(book.list.SelectedItem()); got != filepath.Join(dir, "b.md") {
+		t.Errorf("selected = %q, want b.md", got)
+	}
+}
+
+func TestScanTree(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"top.md":                "# Top",
+		"docs/api/auth.md":      "# Auth",
+		"docs/notes.txt":        "not markdown",
+		".git/hidden.md":        "# Hidden",
+		"node_modules/dep/x.md": "# Dep",
+	})
+	items, err := scanner{}.scanTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, it := range items {
+		names = append(names, it.(fileItem).name)
+	}
+	got := strings.Join(names, ",")
+	if got != "docs/api/auth.md,top.md" {
+		t.Errorf("scanTree = %q, want %q", got, "docs/api/auth.md,top.md")
+	}
+}
+
+func TestBookRenameFile(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# A",
+		"b.md": "# B",
+		"c.md": "# C",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	t.Run("collision", func(t *testing.T) {
+		book.renamePath = filepath.Join(dir, "a.md")
+		book.renameFile("b")
+		if book.statusText != "File exists" {
+			t.Errorf("statusText = %q, want %q", book.statusText, "File exists")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "a.md")); err != nil {
+			t.Errorf("a.md should be untouched: %v", err)
+		}
+	})
+
+	t.Run("rename keeps selection", func(t *testing.T) {
+		book.renamePath = filepath.Join(dir, "a.md")
+		cmd := book.renameFile("z")
+		book = awaitRefresh(book, cmd)
+		want := filepath.Join(dir, "z.md")
+		if _, err := os.Stat(want); err != nil {
+			t.Fatalf("z.md not created: %v", err)
+		}
+		if got := itemPath(book.list.SelectedItem()); got != want {
+			t.Errorf("selected = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("escape rejected", func(t *testing.T) {
+		book.renamePath = filepath.Join(dir, "b.md")
+		book.renameFile("../outside")
+		if book.statusText != "Invalid filename" {
+			t.Errorf("statusText = %q, want %q", book.statusText, "Invalid filename")
+		}
+	})
+}
+
+func TestBookDuplicateFile(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":      "# A\n\nTemplate body",
+		"a copy.md": "# Earlier copy",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book.selectPath(filepath.Join(dir, "a.md"))
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'D', Text: "D"})
+	if !book.naming || book.input.Value() != "a copy 2.md" {
+		t.Fatalf("D: naming = %v, suggested %q, want %q", book.naming, book.input.Value(), "a copy 2.md")
+	}
+	book, cmd := book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	book = awaitRefresh(book, cmd)
+	want := filepath.Join(dir, "a copy 2.md")
+	data, err := os.ReadFile(want)
+	if err != nil || string(data) != "# A\n\nTemplate body" {
+		t.Fatalf("copy = %q, %v", data, err)
+	}
+	if got := itemPath(book.list.SelectedItem()); got != want {
+		t.Errorf("selected = %q, want %q", got, want)
+	}
+
+	book.copyPath = filepath.Join(dir, "a.md")
+	book.duplicateFile("a copy")
+	if book.statusText != "File exists" {
+		t.Errorf("statusText = %q, want %q", book.statusText, "File exists")
+	}
+}
+
+func TestBookMoveFile(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"note.md":           "# Note",
+		"archive/old.md":    "# Old",
+		"archive/note.md":   "# Clash",
+		"drafts/.keep":      "",
+		".hidden/secret.md": "# Secret",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	dirs := scanner{}.scanDirTree(dir, dir)
+	var names []string
+	for _, d := range dirs {
+		names = append(names, d.(dirItem).name)
+	}
+	if got := strings.Join(names, ","); got != "archive,drafts" {
+		t.Errorf("scanDirTree = %q, want %q", got, "archive,drafts")
+	}
+
+	book.movePath = filepath.Join(dir, "note.md")
+	book.moveFile(filepath.Join(dir, "archive"))
+	if book.statusText != "File exists" {
+		t.Errorf("move onto existing file: statusText = %q", book.statusText)
+	}
+
+	book.movePath = filepath.Join(dir, "note.md")
+	book.moveFile(filepath.Join(dir, "drafts"))
+	if _, err := os.Stat(filepath.Join(dir, "drafts", "note.md")); err != nil {
+		t.Errorf("note.md not moved: %v", err)
+	}
+	if book.movePath != "" {
+		t.Error("movePath should be cleared after moving")
+	}
+}
+
+func TestBookPinnedSection(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "# A",
+		"b.md":     "# B",
+		"sub/c.md": "# C",
+	})
+	st, _ := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	st.TogglePin(filepath.Join(dir, "sub", "c.md"))
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true, state: st}
+	book := loadBook(ctx, dir)
+
+	items := book.list.Items()
+	if h, ok := items[0].(headerItem); !ok || h.title != "Pinned" {
+		t.Fatalf("first item = %#v, want Pinned header", items[0])
+	}
+	if f, ok := items[1].(fileItem); !ok || f.name != "sub/c.md" {
+		t.Errorf("second item = %#v, want pinned sub/c.md", items[1])
+	}
+	if _, ok := book.list.SelectedItem().(headerItem); ok {
+		t.Error("cursor should skip the section header")
+	}
+
+	// Pin a.md from the listing; it moves into the Pinned section once.
+	book.selectPath(filepath.Join(dir, "a.md"))
+	book.togglePin()
+	count := 0
+	for _, item := range book.list.Items() {
+		if itemPath(item) == filepath.Join(dir, "a.md") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("a.md listed %d times, want 1", count)
+	}
+	if !st.Pinned(filepath.Join(dir, "a.md")) {
+		t.Error("a.md should be pinned in state")
+	}
+}
+
+func TestBookRenameKeepsPin(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# A", "sub/.keep.md": ""})
+	st, _ := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true, state: st}
+	book := loadBook(ctx, dir)
+	book.selectPath(filepath.Join(dir, "a.md"))
+	book.togglePin()
+
+	book.renamePath = filepath.Join(dir, "a.md")
+	book.renameFile("b.md")
+	if !st.Pinned(filepath.Join(dir, "b.md")) || st.Pinned(filepath.Join(dir, "a.md")) {
+		t.Errorf("pins after rename = %v", st.Pins)
+	}
+	book.movePath = filepath.Join(dir, "b.md")
+	book.moveFile(filepath.Join(dir, "sub"))
+	if !st.Pinned(filepath.Join(dir, "sub", "b.md")) {
+		t.Errorf("pins after move = %v", st.Pins)
+	}
+}
+
+func TestBookPreviewFollowsSelection(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# Alpha heading",
+		"b.md": "# Beta heading",
+	})
+	ctx := &ViewContext{width: 120, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyTab})
+	if !book.preview {
+		t.Fatal("tab should enable the preview pane")
+	}
+	if !strings.Contains(book.View(), "Alpha heading") {
+		t.Error("preview should show the highlighted file")
+	}
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	if !strings.Contains(book.View(), "Beta heading") {
+		t.Error("preview should follow the selection")
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	out := strings.Join([]string{
+		"# branch.oid abc123",
+		"# branch.head main",
+		"1 .M N... 100644 100644 100644 aaa bbb docs/edited.md",
+		"1 A. N... 000000 100644 100644 000 ccc new file.md",
+		"1 MM N... 100644 100644 100644 aaa bbb both.md",
+		"2 R. N... 100644 100644 100644 aaa bbb R100 renamed.md",
+		"old.md",
+		"? notes/untracked.md",
+		"",
+	}, "\x00")
+	info := parseGitStatus([]byte(out))
+	if info.branch != "main" {
+		t.Errorf("branch = %q, want main", info.branch)
+	}
+	want := map[string]string{
+		"docs/edited.md":     "M",
+		"new file.md":        "S",
+		"both.md":            "SM",
+		"renamed.md":         "S",
+		"notes/untracked.md": "?",
+	}
+	for path, mark := range want {
+		if got := info.marks[path]; got != mark {
+			t.Errorf("marks[%q] = %q, want %q", path, got, mark)
+		}
+	}
+	if _, ok := info.marks["old.md"]; ok {
+		t.Error("rename source should not be marked")
+	}
+}
+
+func TestReadGitStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := tempDirWithFiles(t, map[string]string{
+		"book/clean.md": "# Clean",
+		"book/new.md":   "# New",
+	})
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=ink", "-c", "user.email=ink@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "trunk")
+	git("add", "book/clean.md")
+	git("commit", "-q", "-m", "init")
+
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, filepath.Join(dir, "book"))
+	msg := book.loadGitStatus()().(gitStatusMsg)
+	if msg.info == nil {
+		t.Fatal("expected git info inside a repository")
+	}
+	book, _ = book.Update(msg)
+	if book.git.branch != "trunk" {
+		t.Errorf("branch = %q, want trunk", book.git.branch)
+	}
+	if got := book.gitMarkFor(filepath.Join(dir, "book", "new.md")); got != "?" {
+		t.Errorf("new.md mark = %q, want ?", got)
+	}
+	if got := book.gitMarkFor(filepath.Join(dir, "book", "clean.md")); got != "" {
+		t.Errorf("clean.md mark = %q, want none", got)
+	}
+	if !strings.Contains(book.View(), "new.md [?]") {
+		t.Error("View should show the untracked marker")
+	}
+}
+
+func TestBookTagFilter(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":       "---\ntags: [go, tui]\n---\n# A",
+		"notes/b.md": "---\ntags:\n  - go\n---\n# B",
+		"c.md":       "# C (untagged)",
+		"notes/d.md": "---\ntags: [\"#writing\"]\n---\n",
+	})
+	tags := scanner{}.collectTags(dir)
+	var got []string
+	for _, item := range tags {
+		ti := item.(tagItem)
+		got = append(got, fmt.Sprintf("%s:%d", ti.tag, ti.count))
+	}
+	if strings.Join(got, ",") != "go:2,tui:1,writing:1" {
+		t.Errorf("collectTags = %v", got)
+	}
+
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book, _ = book.Update(tea.KeyPressMsg{Code: 't', Text: "t"})
+	if book.picking != pickerTag {
+		t.Fatal("t should open the tag picker")
+	}
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if book.tag != "go" {
+		t.Fatalf("tag = %q, want go", book.tag)
+	}
+	book = awaitScan(book, book.dir)
+	var names []string
+	for _, item := range book.list.Items() {
+		names = append(names, item.FilterValue())
+	}
+	if strings.Join(names, ",") != "a.md,notes/b.md" {
+		t.Errorf("tag-filtered list = %v", names)
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if book.tag != "" {
+		t.Error("esc should clear the tag filter")
+	}
+}
+
+func TestLoadOrder(t *testing.T) {
+	t.Run("ink-order", func(t *testing.T) {
+		dir := tempDirWithFiles(t, map[string]string{
+			".ink-order": "# manuscript\nintro.md\n\npart2/ch1.md\n",
+			"SUMMARY.md": "- [Ignored](other.md)",
+		})
+		order, file := loadOrder(dir)
+		if file != ".ink-order" {
+			t.Errorf("order file = %q, want .ink-order", file)
+		}
+		want := []string{filepath.Join(dir, "intro.md"), filepath.Join(dir, "part2", "ch1.md")}
+		if strings.Join(order, ",") != strings.Join(want, ",") {
+			t.Errorf("order = %v, want %v", order, want)
+		}
+	})
+
+	t.Run("summary links", func(t *testing.T) {
+		dir := tempDirWithFiles(t, map[string]string{
+			"SUMMARY.md": "# Summary\n\n[Preface](preface.md)\n\n- [One](part%201/one.md#top)\n  - [Web](https://example.com/x.md)\n",
+		})
+		order, _ := loadOrder(dir)
+		want := []string{filepath.Join(dir, "preface.md"), filepath.Join(dir, "part 1", "one.md")}
+		if strings.Join(order, ",") != strings.Join(want, ",") {
+			t.Errorf("order = %v, want %v", order, want)
+		}
+	})
+
+	t.Run("index lists only", func(t *testing.T) {
+		dir := tempDirWithFiles(t, map[string]string{
+			"index.md": "See [the intro](intro.md).\n\n1. [Two](two.md)\n2. [One](one.md)\n",
+		})
+		order, _ := loadOrder(dir)
+		want := []string{filepath.Join(dir, "two.md"), filepath.Join(dir, "one.md")}
+		if strings.Join(order, ",") != strings.Join(want, ",") {
+			t.Errorf("order = %v, want %v", order, want)
+		}
+	})
+}
+
+func TestBookManualOrder(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		".ink-order":  "zeta.md\npart/ch.md\nalpha.md\n",
+		"alpha.md":    "# A",
+		"zeta.md":     "# Z",
+		"beta.md":     "# B",
+		"part/ch.md":  "# Ch",
+		"part/end.md": "# End",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true, bookDir: dir}
+	book := loadBook(ctx, dir)
+	var names []string
+	for _, item := range book.list.Items() {
+		names = append(names, item.FilterValue())
+	}
+	if got := strings.Join(names, ","); got != "zeta.md,part,alpha.md,beta.md" {
+		t.Errorf("ordered list = %q", got)
+	}
+
+	order := scanner{}.readingOrder(dir)
+	ch := NewChapter(ctx, filepath.Join(dir, "part", "ch.md"))
+	msg := ch.openSibling(1)().(OpenChapterMsg)
+	if msg.FilePath != filepath.Join(dir, "alpha.md") {
+		t.Errorf("next chapter = %q, want alpha.md (order %v)", msg.FilePath, order)
+	}
+	last := NewChapter(ctx, order[len(order)-1])
+	last.openSibling(1)
+	if last.statusText != "Last chapter" {
+		t.Errorf("next at end: statusText = %q", last.statusText)
+	}
+}
+
+func TestBookStatistics(t *testing.T) {
+	long := strings.Repeat("The cat sat on the mat. ", 10)
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":       "one two three",
+		"docs/b.md":  long,
+		"docs/c.txt": "not markdown",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+
+	book, cmd := book.Update(tea.KeyPressMsg{Code: 'S', Text: "S"})
+	if !book.showStats || cmd == nil {
+		t.Fatal("S should open the statistics screen and start computing")
+	}
+	book, _ = book.Update(book.loadStats()())
+	s := summarizeStats(book.statsCache)
+	if s.docs != 2 || s.words != 3+60 || s.graded != 1 {
+		t.Errorf("stats = %+v, want 2 docs, 63 words, 1 graded", s)
+	}
+	if s.largest.name != "docs/b.md" || s.smallest.name != "a.md" {
+		t.Errorf("largest = %q, smallest = %q", s.largest.name, s.smallest.name)
+	}
+	view := book.View()
+	for _, want := range []string{"Statistics", "Documents", "63", "docs/b.md"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+
+	// Unchanged documents are served from the cache.
+	path := filepath.Join(dir, "a.md")
+	cached := book.statsCache[path]
+	cached.words = 1000
+	book.statsCache[path] = cached
+	book, _ = book.Update(book.loadStats()())
+	if got := book.statsCache[path].words; got != 1000 {
+		t.Errorf("a.md words = %d, want cached 1000", got)
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if book.showStats {
+		t.Error("esc should close the statistics screen")
+	}
+}
+
+func TestBookMetrics(t *testing.T) {
+	varied := "Stop. The old cat, tired after a long night of hunting mice in the barn, sat on the mat by the fire. " +
+		"It slept. Outside, rain fell on the fields and the quiet roads that wound between the hills."
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":      varied,
+		"b.md":      varied,
+		"docs/c.md": strings.Repeat("The cat sat on the mat. ", 10),
+	})
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 100, isBook: true}
+	book := loadBook(ctx, dir)
+
+	book, cmd := book.Update(tea.KeyPressMsg{Code: 'A', Text: "A"})
+	if book.metrics == nil || cmd == nil {
+		t.Fatal("A should open the metrics screen and start the analysis")
+	}
+	book, _ = book.Update(book.loadMetrics()())
+	bm := book.metrics
+	if bm.loading || bm.docs != 3 || len(bm.axes) == 0 {
+		t.Fatalf("metrics = %+v, want 3 documents analyzed", bm)
+	}
+	rhythm := bm.axes[0]
+	if rhythm.name != metrics.Rhythm || rhythm.outlier.name != "docs/c.md" || rhythm.low != 0 {
+		t.Errorf("rhythm = %+v, want docs/c.md as the outlier", rhythm)
+	}
+	if rhythm.low > rhythm.mean || rhythm.mean > rhythm.high {
+		t.Errorf("rhythm mean %.2f outside %.2f–%.2f", rhythm.mean, rhythm.low, rhythm.high)
+	}
+	view := book.View()
+	for _, want := range []string{"Metrics", "Average of 3 documents", "rhythm", "docs/c.md (0.00)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+
+	_, cmd = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter should open the outlier's Metrics")
+	}
+	open, ok := cmd().(OpenMetricsMsg)
+	if !ok || open.FilePath != filepath.Join(dir, "docs", "c.md") || !strings.HasPrefix(open.Content, "The cat") {
+		t.Errorf("enter = %+v, want OpenMetricsMsg for docs/c.md", open)
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if book.metrics != nil {
+		t.Error("esc should close the metrics screen")
+	}
+}
+
+func TestBookReadingTime(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# A", "b.md": "# B"})
+	st, _ := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true, state: st}
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	read := func(name string, touches ...time.Duration) {
+		ch := NewChapter(ctx, filepath.Join(dir, name))
+		ch.reading = newReadingSession(t0)
+		for _, d := range touches {
+			ch.reading.touch(t0.Add(d))
+		}
+		ch.logReading(t0.Add(touches[len(touches)-1]))
+	}
+	// The ten minutes without a key in between are idle, not reading.
+	read("a.md", 30*time.Second, 10*time.Minute, 11*time.Minute)
+	read("b.md", 2*time.Minute, 4*time.Minute)
+	read("b.md", time.Hour)
+
+	r := bookReading(st, dir)
+	if r.active != 5*time.Minute+30*time.Second || r.mostRead != "b.md" || r.mostTime != 4*time.Minute {
+		t.Errorf("reading = %+v, want 5m30s in all, b.md most with 4m", r)
+	}
+	book := loadBook(ctx, dir)
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'S', Text: "S"})
+	book, _ = book.Update(book.loadStats()())
+	view := book.View()
+	for _, want := range []string{"6m", "b.md (4m)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("statistics missing %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		20 * time.Second:              "under a minute",
+		45 * time.Minute:              "45m",
+		2 * time.Hour:                 "2h",
+		125*time.Minute + time.Second: "2h 5m",
+	} {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:             "512 B",
+		1536:            "1.5 KB",
+		3 * 1024 * 1024: "3.0 MB",
+	} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestScannerFollowSymlinks(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"real/a.md":     "# A",
+		"vault/note.md": "# Note",
+	})
+	vault := filepath.Join(dir, "vault")
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(vault, "linked")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	// A link back to the vault itself must not loop forever.
+	if err := os.Symlink(vault, filepath.Join(vault, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	treeNames := func(s scanner) []string {
+		items, err := s.scanTree(vault)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range items {
+			names = append(names, item.FilterValue())
+		}
+		return names
+	}
+
+	if got := treeNames(scanner{}); strings.Join(got, ",") != "note.md" {
+		t.Errorf("scanTree without following = %v, want [note.md]", got)
+	}
+	follow := scanner{followSymlinks: true}
+	if got := treeNames(follow); strings.Join(got, ",") != "linked/a.md,note.md" {
+		t.Errorf("scanTree following = %v, want [linked/a.md note.md]", got)
+	}
+	if n := follow.countMarkdownFiles(vault); n != 2 {
+		t.Errorf("countMarkdownFiles following = %d, want 2", n)
+	}
+
+	items, err := follow.scanDir(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, item := range items {
+		if d, ok := item.(dirItem); ok {
+			dirs = append(dirs, d.name)
+		}
+	}
+	if strings.Join(dirs, ",") != "linked,loop" {
+		t.Errorf("scanDir folders = %v, want [linked loop]", dirs)
+	}
+	items, _ = scanner{}.scanDir(vault)
+	for _, item := range items {
+		if _, ok := item.(dirItem); ok {
+			t.Errorf("scanDir without following listed folder %s", item.FilterValue())
+		}
+	}
+}
+
+func TestScannerHonorsIgnoreFiles(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		".gitignore":            "dist/\n*.gen.md\n",
+		".inkignore":            "drafts/\n!vendor/\n",
+		"a.md":                  "# A",
+		"api.gen.md":            "# Generated",
+		"dist/out.md":           "# Built",
+		"drafts/wip.md":         "# WIP",
+		"vendor/lib/README.md":  "# Vendored",
+		"node_modules/pkg/x.md": "# Dep",
+		"notes/.gitignore":      "scratch.md\n",
+		"notes/scratch.md":      "# Scratch",
+		"notes/kept.md":         "# Kept",
+	})
+	items, err := scanner{}.scanTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.FilterValue())
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "a.md,notes/kept.md,vendor/lib/README.md" {
+		t.Errorf("scanTree = %v", names)
+	}
+
+	items, _ = scanner{}.scanDir(dir)
+	names = nil
+	for _, item := range items {
+		names = append(names, item.FilterValue())
+	}
+	if got := strings.Join(names, ","); got != "notes,vendor,a.md" {
+		t.Errorf("scanDir = %v", names)
+	}
+}
+
+func TestBookCopyItemPath(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"docs/a.md": "# A"})
+	var copied []string
+	writeClipboard = func(text string) error {
+		copied = append(copied, text)
+		return nil
+	}
+	t.Cleanup(func() { writeClipboard = clipboard.WriteAll })
+
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book.changeDir(filepath.Join(dir, "docs"))
+	book = awaitScan(book, filepath.Join(dir, "docs"))
+
+	y := tea.KeyPressMsg{Code: 'y', Text: "y"}
+	book, _ = book.Update(y)
+	book, _ = book.Update(y)
+	want := []string{filepath.Join(dir, "docs", "a.md"), "docs/a.md"}
+	if strings.Join(copied, "|") != strings.Join(want, "|") {
+		t.Errorf("copied = %q, want %q", copied, want)
+	}
+	if book.statusText != "Copied relative path" {
+		t.Errorf("statusText = %q", book.statusText)
+	}
+}
+
+func TestBookGroupByStatus(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "---\nstatus: Published\n---\n",
+		"b.md":     "---\nstatus: draft\n---\n",
+		"c.md":     "# No front matter",
+		"d.md":     "---\nstatus: review\n---\n",
+		"e.md":     "---\nstatus: draft\n---\n",
+		"f.md":     "---\nstatus: on hold\n---\n",
+		"sub/g.md": "# G",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book.sortModes[dir] = sortByStatus
+	cmd := book.refresh()
+	book = awaitRefresh(book, cmd)
+
+	var got []string
+	for _, item := range book.list.Items() {
+		if h, ok := item.(headerItem); ok {
+			got = append(got, "["+h.title+"]")
+		} else {
+			got = append(got, item.FilterValue())
+		}
+	}
+	want := "sub,[Draft],b.md,e.md,[Review],d.md,[Published],a.md,[On hold],f.md,[No status],c.md"
+	if strings.Join(got, ",") != want {
+		t.Errorf("grouped list = %v\nwant %s", got, want)
+	}
+	if _, ok := book.list.SelectedItem().(headerItem); ok {
+		t.Error("selection landed on a section header")
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		from string
+		n    int
+		want string
+	}{
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2024-03-15", -1, "2024-02-15"},
+		{"2024-12-10", 1, "2025-01-10"},
+	}
+	for _, tt := range tests {
+		from, _ := time.Parse(time.DateOnly, tt.from)
+		if got := addMonths(from, tt.n).Format(time.DateOnly); got != tt.want {
+			t.Errorf("addMonths(%s, %d) = %s, want %s", tt.from, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBookJournal(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"2024-05-01.md":      "# May Day",
+		"2024/2024-05-14.md": "# Nested",
+		"notes.md":           "# Not a date",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book.openJournal(time.Date(2024, 5, 14, 9, 0, 0, 0, time.Local))
+	if len(book.journal.entries) != 2 {
+		t.Fatalf("entries = %v, want the two dated notes", book.journal.entries)
+	}
+	view := book.View()
+	if !strings.Contains(view, "May 2024") || !strings.Contains(view, "2 entries this month") {
+		t.Errorf("calendar view missing month or entry count:\n%s", view)
+	}
+
+	// Enter opens an existing note.
+	book, cmd := book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if msg, ok := cmd().(OpenChapterMsg); !ok || msg.FilePath != filepath.Join(dir, "2024", "2024-05-14.md") {
+		t.Errorf("enter on 14th = %#v", cmd())
+	}
+	if book.journal != nil {
+		t.Error("opening a note should close the calendar")
+	}
+
+	// Moving a week ahead and pressing enter creates the missing note.
+	book.openJournal(time.Date(2024, 5, 14, 0, 0, 0, 0, time.Local))
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyDown})
+	book, cmd = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	created := filepath.Join(dir, "2024-05-21.md")
+	if msg, ok := cmd().(OpenChapterMsg); !ok || msg.FilePath != created {
+		t.Errorf("enter on 21st = %#v", cmd())
+	}
+	if data, err := os.ReadFile(created); err != nil || !strings.Contains(string(data), `title: "2024-05-21"`) {
+		t.Errorf("created note = %q, %v", data, err)
+	}
+}
+
+func TestBookDateFilter(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"today.md":     "# Today",
+		"sub/week.md":  "# Week",
+		"sub/month.md": "# Month",
+		"old.md":       "# Old",
+	})
+	// Wednesday 2024-05-15, noon.
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.Local)
+	for name, mod := range map[string]time.Time{
+		"today.md":     now.Add(-time.Hour),
+		"sub/week.md":  time.Date(2024, 5, 13, 8, 0, 0, 0, time.Local),
+		"sub/month.md": time.Date(2024, 5, 2, 8, 0, 0, 0, time.Local),
+		"old.md":       time.Date(2024, 4, 30, 8, 0, 0, 0, time.Local),
+	} {
+		os.Chtimes(filepath.Join(dir, name), mod, mod)
+	}
+	items, _ := scanner{}.scanTree(dir)
+	for _, tt := range []struct {
+		filter dateFilter
+		want   string
+	}{
+		{dateToday, "today.md"},
+		{dateThisWeek, "sub/week.md,today.md"},
+		{dateThisMonth, "sub/month.md,sub/week.md,today.md"},
+	} {
+		got := filterByDate(slices.Clone(items), tt.filter, now)
+		sortItems(got, sortByName)
+		var names []string
+		for _, item := range got {
+			names = append(names, item.FilterValue())
+		}
+		if strings.Join(names, ",") != tt.want {
+			t.Errorf("%s: %v, want %s", tt.filter, names, tt.want)
+		}
+	}
+
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'T', Text: "T"})
+	if book.modified != dateToday {
+		t.Fatalf("T: filter = %s, want today", book.modified)
+	}
+	book = awaitScan(book, dir)
+	if !strings.Contains(book.statusBarView(), "today") {
+		t.Error("status bar should show the date filter")
+	}
+	book, _ = book.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if book.modified != dateAny {
+		t.Error("esc should clear the date filter")
+	}
+}
+
+func TestBookDeleteAndUndo(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# A",
+		"b.md": "# B",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true}
+	book := loadBook(ctx, dir)
+	path := filepath.Join(dir, "a.md")
+	book.selectPath(path)
+
+	book, cmd := book.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	book = awaitRefresh(book, cmd)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("a.md should be gone: %v", err)
+	}
+	trashed, _ := filepath.Glob(filepath.Join(dir, ".ink", "trash", "*-a.md"))
+	if len(trashed) != 1 {
+		t.Fatalf("trash = %v, want one a.md", trashed)
+	}
+	if book.docCount() != 1 {
+		t.Errorf("docCount after delete = %d, want 1", book.docCount())
+	}
+
+	book, cmd = book.Update(tea.KeyPressMsg{Code: 'u', Text: "u"})
+	book = awaitRefresh(book, cmd)
+	if data, err := os.ReadFile(path); err != nil || string(data) != "# A" {
+		t.Fatalf("undo did not restore a.md: %q, %v", data, err)
+	}
+	if got := itemPath(book.list.SelectedItem()); got != path {
+		t.Errorf("selected = %q, want restored a.md", got)
+	}
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'u', Text: "u"})
+	if book.statusText != "Nothing to undo" {
+		t.Errorf("statusText = %q, want %q", book.statusText, "Nothing to undo")
+	}
+}
+
+func TestBookExport(t *testing.T) {
+	// A stand-in pandoc that writes its arguments to the output file.
+	bin := t.TempDir()
+	script := "#!/bin/sh\nout=\nprev=\nfor a; do [ \"$prev\" = -o ] && out=$a; prev=$a; done\necho \"$@\" > \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "pandoc"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	dir := tempDirWithFiles(t, map[string]string{
+		".ink-order": "b.md\na.md\n",
+		"a.md":       "# A",
+		"b.md":       "# B",
+		"c.md":       "# C",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80, isBook: true,
+		pandocArgs: map[string][]string{"": {"--toc"}, "pdf": {"--pdf-engine=xelatex"}}}
+	book := loadBook(ctx, dir)
+	book.selectPath(filepath.Join(dir, "c.md"))
+	done := func(cmd tea.Cmd) tea.Msg {
+		for _, c := range cmd().(tea.BatchMsg) {
+			if msg, ok := c().(exportDoneMsg); ok {
+				return msg
+			}
+		}
+		return nil
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'E', Text: "E"})
+	if book.picking != pickerExport || len(book.picker.Items()) != 2*len(exportFormats)+2 {
+		t.Fatalf("E should list every format for the document and the book, EPUB and website, got %d", len(book.picker.Items()))
+	}
+	book, cmd := book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if book.exporting != "PDF" || !strings.Contains(book.statusBarView(), "Exporting PDF") {
+		t.Fatal("the status bar should show the export running")
+	}
+	book, _ = book.Update(done(cmd))
+	if book.exporting != "" || book.statusText != "Exported c.pdf" {
+		t.Fatalf("status = %q", book.statusText)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "c.pdf"))
+	if want := "--toc --pdf-engine=xelatex -o " + filepath.Join(dir, "c.pdf") + " " + filepath.Join(dir, "c.md"); strings.TrimSpace(string(got)) != want {
+		t.Errorf("pandoc ran with %q, want %q", got, want)
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'E', Text: "E"})
+	book.picker.Select(len(exportFormats) + 1)
+	book, cmd = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	book, _ = book.Update(done(cmd))
+	out := filepath.Join(dir, filepath.Base(dir)+".docx")
+	got, _ = os.ReadFile(out)
+	files := strings.Join([]string{filepath.Join(dir, "b.md"), filepath.Join(dir, "a.md"), filepath.Join(dir, "c.md")}, " ")
+	if want := "--toc -o " + out + " " + files; strings.TrimSpace(string(got)) != want {
+		t.Errorf("pandoc ran with %q, want %q", got, want)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'E', Text: "E"})
+	if len(book.picker.Items()) != 2 || book.picker.Items()[0].(exportItem).format != epubFormat {
+		t.Fatalf("without pandoc only EPUB and website should be offered, got %d items", len(book.picker.Items()))
+	}
+	book, cmd = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	book, _ = book.Update(done(cmd))
+	epub := filepath.Base(dir) + ".epub"
+	if book.statusText != "Exported "+epub {
+		t.Fatalf("status = %q", book.statusText)
+	}
+	if info, err := os.Stat(filepath.Join(dir, epub)); err != nil || info.Size() == 0 {
+		t.Errorf("the EPUB should be written: %v", err)
+	}
+
+	book, _ = book.Update(tea.KeyPressMsg{Code: 'E', Text: "E"})
+	book.picker.Select(1)
+	book, cmd = book.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if !strings.Contains(book.statusBarView(), "Exporting website") {
+		t.Error("the status bar should show the website export running")
+	}
+	book, _ = book.Update(done(cmd))
+	if book.statusText != "Exported _site" {
+		t.Fatalf("status = %q", book.statusText)
+	}
+	for _, name := range []string{"index.html", "a.html", "b.html", "c.html", "style.css"} {
+		if _, err := os.Stat(filepath.Join(dir, "_site", name)); err != nil {
+			t.Errorf("the website should have %s: %v", name, err)
+		}
+	}
+}
+
+func TestEPUBMeta(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"SUMMARY.md": "---\ntitle: The Book\n---\n- [A](a.md)\n- [B](b.md)\n",
+		"a.md":       "---\ntitle: Chapter A\nauthor: Ann\nlang: fr\n---\n# A",
+		"b.md":       "# B",
+		"extra.md":   "# Extra",
+	})
+	paths := scanner{}.exportOrder(dir)
+	var names []string
+	for _, p := range paths {
+		names = append(names, filepath.Base(p))
+	}
+	if got := strings.Join(names, ","); got != "a.md,b.md,extra.md" {
+		t.Errorf("exportOrder = %s, want the order file left out", got)
+	}
+	var docs []export.Document
+	for _, p := range paths {
+		doc, _ := export.Load(p)
+		docs = append(docs, doc)
+	}
+	meta := bookMeta(dir, "SUMMARY.md", docs)
+	if meta.Title != "The Book" || meta.Author != "Ann" || meta.Language != "fr" {
+		t.Errorf("meta = %+v, want the order file's title and the first document's author and lang", meta)
+	}
+	if meta := bookMeta(dir, "", docs); meta.Title != filepath.Base(dir) {
+		t.Errorf("without an order file the title should be the folder name, got %q", meta.Title)
+	}
+
+	out := filepath.Join(t.TempDir(), "book.epub")
+	if err := ExportEPUB(dir, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportEPUB(t.TempDir(), out); err == nil {
+		t.Error("exporting a folder without documents should fail")
+	}
+}
+
+func TestLinkIndex(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":        "---\ntitle: A\n---\nSee [[Zettel B]] and [c](notes/c.md#part).\n\n```\n[[c]]\n```\n![image](b.md) [[missing]] [web](https://x.org/b.md)\n",
+		"Zettel B.md": "Back to [[a|the start]], and [ref][1].\n\n[1]: notes/c.md\n",
+		"notes/c.md":  "Up to [a](../a.md), [[notes/c]] and [[Zettel B#Intro]].\n",
+	})
+	a, b, c := filepath.Join(dir, "a.md"), filepath.Join(dir, "Zettel B.md"), filepath.Join(dir, "notes", "c.md")
+	var got []string
+	for _, l := range (scanner{}).linkIndex(dir) {
+		rel := func(p string) string { r, _ := filepath.Rel(dir, p); return filepath.ToSlash(r) }
+		got = append(got, fmt.Sprintf("%s:%d -> %s", rel(l.from), l.line, rel(l.to)))
+	}
+	want := []string{"Zettel B.md:0 -> a.md", "Zettel B.md:2 -> notes/c.md", "a.md:3 -> Zettel B.md", "a.md:3 -> notes/c.md", "notes/c.md:0 -> Zettel B.md", "notes/c.md:0 -> a.md"}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("linkIndex = %q, want %q", got, want)
+	}
+
+	entries, in := documentLinks((scanner{}).linkIndex(dir), a)
+	if in != 2 || len(entries) != 4 || entries[2].path != b || entries[3].path != c {
+		t.Errorf("documentLinks(a.md) = %+v, %d in", entries, in)
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// trashDir is where deleted documents are moved, relative to the book root.
+const trashDir = ".ink/trash"
+
+// trashed records a deleted document so it can be restored.
+type trashed struct {
+	from string // original path
+	to   string // path inside the trash
+}
+
+// trashPath returns where path is moved when deleted at t. The timestamp
+// prefix keeps repeated deletions of the same name apart.
+func trashPath(root, path string, t time.Time) string {
+	return filepath.Join(root, trashDir, t.Format("20060102-150405.000")+"-"+filepath.Base(path))
+}
+
+// deleteFile moves the highlighted document to the trash.
+func (b *Book) deleteFile() tea.Cmd {
+	item, ok := b.list.SelectedItem().(fileItem)
+	if !ok {
+		return nil
+	}
+	to := trashPath(b.rootDir, item.path, time.Now())
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	if err := os.Rename(item.path, to); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	b.trash = append(b.trash, trashed{from: item.path, to: to})
+	b.forgetCounts(item.path)
+	return tea.Batch(b.refresh(), b.flashStatus("Deleted "+filepath.Base(item.path)+" (u to undo)"))
+}
+
+// undoDelete restores the most recently deleted document.
+func (b *Book) undoDelete() tea.Cmd {
+	if len(b.trash) == 0 {
+		return b.flashStatus("Nothing to undo")
+	}
+	last := b.trash[len(b.trash)-1]
+	if _, err := os.Stat(last.from); err == nil {
+		return b.flashStatus("File exists")
+	}
+	if err := os.MkdirAll(filepath.Dir(last.from), 0755); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	if err := os.Rename(last.to, last.from); err != nil {
+		return b.flashStatus("Error: " + err.Error())
+	}
+	b.trash = b.trash[:len(b.trash)-1]
+	b.forgetCounts(last.from)
+	return tea.Batch(b.refreshSelecting(last.from), b.flashStatus("Restored "+filepath.Base(last.from)))
+}
@@ -3,13 +3,14 @@ package model
 import (
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 
 	"github.com/inkcheck/ink/internal/render"
+	"github.com/inkcheck/ink/internal/state"
 )
 
 // clearStatusMsg clears the status bar feedback text.
@@ -17,37 +18,99 @@ type clearStatusMsg struct{}
 
 // Chapter is the markdown viewer.
 type Chapter struct {
-	viewport   viewport.Model
-	filePath   string
-	content    string // raw markdown
-	ctx        *ViewContext
-	help       HelpPane
-	statusText string
-	grade      string // cached FK grade
+	viewport    viewport.Model
+	filePath    string
+	content     string // raw markdown
+	ctx         *ViewContext
+	help        HelpPane
+	statusText  string
+	grade       string         // cached readability score
+	warning     string         // metrics thresholds the document goes beyond, if any
+	progress    float64        // furthest fraction of the document scrolled into view
+	reading     readingSession // time spent reading since the Chapter opened
+	pending     string         // first key of a two-key command, like the g of "g d" or the y of "y h"
+	diff        *chapterDiff   // git diff shown in place of the document, if open
+	diffFrom    int            // viewport offset when the diff opened
+	headings    []render.Heading
+	toc         bool // table of contents sidebar open
+	tocFrom     int  // viewport offset when the sidebar opened
+	tocCursor   int
+	links       []render.Link
+	selected    int // index of the selected link, or -1
+	footnotes   []render.Footnote
+	images      []render.Image
+	imageList   bool // image sidebar open
+	imageCursor int
+	blocks      []render.Block
+	raw         bool   // showing the markdown source instead of the rendering
+	rendered    string // viewport content before focus mode dims it
+	focus       bool   // focus mode: dim all but the middle block
+	focusBlock  int
+	speech      *speech         // reading aloud, if started
+	folded      map[string]bool // IDs of headings whose sections are folded
+	visual      *selection      // lines picked in visual mode, if any
+	marked      bool            // a sentence is highlighted until the next key
+	themes      bool            // theme sidebar open
+	themeCursor int
+	themeFrom   render.Theme // the theme when the sidebar opened
+	lint        *lintPanel   // prose checker issue sidebar, while open
+	issues      []lintIssue  // the prose checkers' last findings, counted in the status bar
+	backlinks   *linkPanel   // sidebar of the documents linking here and linked from here, while open
+	shareAsked  bool         // true when waiting for a second P to upload the document
+	converted   bool         // the document was converted to markdown from another format, so is read-only
+
+	compare         *comparePane // second document shown side by side, if any
+	compareFocus    bool         // scrolling keys move the second document
+	compareUnlocked bool         // the two documents scroll independently
 }
 
 // NewChapter creates a new Chapter viewer for the given file.
 func NewChapter(ctx *ViewContext, filePath string) Chapter {
-	help := NewHelpPane(chapterHelpEntries)
+	help := NewHelpPane(ctx.keys.help(chapterKeys, chapterHelpEntries))
 	vp := viewport.New(viewport.WithWidth(ctx.width), viewport.WithHeight(chapterViewportHeight(ctx, 0)))
 	ch := Chapter{
 		filePath: filePath,
 		ctx:      ctx,
 		viewport: vp,
 		help:     help,
+		selected: -1,
+		reading:  newReadingSession(time.Now()),
 	}
+	// refresh may find the document's progress under a name it had before.
 	ch.refresh()
+	ch.progress = ctx.state.Progress(filePath)
+	ch.viewport.SetYOffset(ctx.state.ScrollOffset(filePath))
+	ch.trackProgress()
 	return ch
 }
 
 func (c Chapter) Init() tea.Cmd {
-	return nil
+	return c.lintInBackground()
 }
 
 func (c Chapter) Update(msg tea.Msg) (Chapter, tea.Cmd) {
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		c.reading.touch(time.Now())
+	}
+	c, cmd := c.update(msg)
+	c.trackProgress()
+	return c, cmd
+}
+
+// trackProgress records the furthest point of the document brought into
+// view.
+func (c *Chapter) trackProgress() {
+	if c.content == "" {
+		return
+	}
+	c.progress = max(c.progress, c.viewport.ScrollPercent())
+}
+
+func (c Chapter) update(msg tea.Msg) (Chapter, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		c.viewport.SetWidth(c.ctx.width)
+		c.viewport.SetWidth(c.viewportWidth())
 		c.resizeViewport()
 		if c.content != "" {
 			c.renderContent()
@@ -57,12 +120,104 @@ func (c Chapter) Update(msg tea.Msg) (Chapter, tea.Cmd) {
 			c.statusText = "Editor error: " + msg.Err.Error()
 		}
 		c.refresh()
-		return c, clearStatusAfter(2*time.Second, clearStatusMsg{})
+		return c, tea.Batch(clearStatusAfter(2*time.Second, clearStatusMsg{}), c.lintInBackground())
 	case clearStatusMsg:
 		c.statusText = ""
 		return c, nil
+	case speechDoneMsg:
+		return c, c.speechDone(msg)
+	case diffBaseMsg:
+		if msg.path != c.filePath {
+			return c, nil
+		}
+		return c, c.diffLoaded(msg)
+	case lintDoneMsg:
+		if msg.path != c.filePath {
+			return c, nil
+		}
+		return c, c.lintDone(msg)
+	case pluginDoneMsg:
+		if msg.path != c.filePath {
+			return c, nil
+		}
+		return c, c.pluginDone(msg)
+	case shareDoneMsg:
+		if msg.path != c.filePath {
+			return c, nil
+		}
+		return c, c.shareDone(msg)
 	case tea.KeyMsg:
-		switch msg.String() {
+		if c.marked {
+			c.clearMark()
+		}
+		if c.toc {
+			return c.updateTOC(msg)
+		}
+		if c.imageList {
+			return c.updateImageList(msg)
+		}
+		if c.themes {
+			return c.updateThemes(msg)
+		}
+		if c.lint != nil {
+			return c.updateLint(msg)
+		}
+		if c.backlinks != nil {
+			return c.updateLinks(msg)
+		}
+		if c.diff != nil {
+			return c.updateDiff(msg)
+		}
+		if why := c.ctx.refusal(chapterKeys, msg.String()); why != "" {
+			c.statusText = why
+			return c, clearStatusAfter(2*time.Second, clearStatusMsg{})
+		}
+		if line, ok := c.ctx.plugins[msg.String()]; ok && !c.help.Visible() {
+			return c, c.startPlugin(msg.String(), line)
+		}
+		if c.visual != nil && !c.help.Visible() {
+			return c.updateSelection(msg)
+		}
+		if c.compare != nil {
+			if cmd, ok := c.updateCompare(msg); ok {
+				return c, cmd
+			}
+		}
+		if c.focus && !c.help.Visible() {
+			switch msg.String() {
+			case "j", "down":
+				c.moveFocus(1)
+				return c, nil
+			case "k", "up":
+				c.moveFocus(-1)
+				return c, nil
+			case "esc":
+				return c, c.toggleFocus()
+			}
+		}
+		if c.shareAsked && msg.String() != "P" {
+			c.shareAsked = false
+			c.statusText = ""
+		}
+		switch c.pending {
+		case "g":
+			c.pending = ""
+			if msg.String() == "d" {
+				return c, c.openDiff()
+			}
+		case "y":
+			c.pending = ""
+			return c, c.copyAs(msg.String())
+		}
+		switch c.ctx.keys.resolve(chapterKeys, msg.String()) {
+		case "g":
+			// g goes to the top at once; a d after it shows the diff.
+			c.viewport.GotoTop()
+			c.pending = "g"
+			return c, nil
+		case "G":
+			c.viewport.GotoBottom()
+			return c, nil
 		case "esc", "q", "ctrl+w", "left", "h":
 			if c.help.Visible() {
 				c.help.Hide()
@@ -75,6 +230,9 @@ func (c Chapter) Update(msg tea.Msg) (Chapter, tea.Cmd) {
 			}
 			return c, func() tea.Msg { return BackToBookMsg{} }
 		case "e":
+			if c.converted {
+				return c, c.editConverted()
+			}
 			return c, func() tea.Msg {
 				return OpenEditorMsg{
 					FilePath: c.filePath,
@@ -82,50 +240,147 @@ func (c Chapter) Update(msg tea.Msg) (Chapter, tea.Cmd) {
 				}
 			}
 		case "E":
+			line := c.topSourceLine()
+			if c.converted {
+				// The source's lines don't match the converted markdown's.
+				line = 0
+			}
 			return c, func() tea.Msg {
-				return OpenExternalEditorMsg{FilePath: c.filePath}
+				return OpenExternalEditorMsg{FilePath: c.filePath, Line: line}
 			}
-		case "y":
-			if err := clipboard.WriteAll(c.content); err != nil {
-				c.statusText = "Copy failed"
-			} else {
-				c.statusText = "Copied!"
+		case "M":
+			return c, func() tea.Msg {
+				return OpenMetricsMsg{FilePath: c.filePath, Content: c.content}
 			}
+		case "R":
+			c.statusText = c.ctx.cycleReadability()
+			c.grade = c.ctx.readabilityText(c.content)
 			return c, clearStatusAfter(2*time.Second, clearStatusMsg{})
+		case "y":
+			c.pending = "y"
+			c.statusText = copyChoices
+			return c, nil
 		case "r", "ctrl+r":
 			c.refresh()
 			return c, nil
+		case "]":
+			return c, c.openSibling(1)
+		case "[":
+			return c, c.openSibling(-1)
 		case "m":
 			toggleMouse(c.ctx)
 			return c, nil
+		case "t":
+			return c, c.openTOC()
+		case "s":
+			c.toggleRaw()
+			return c, nil
+		case "z":
+			return c, c.toggleFocus()
+		case "i":
+			return c, c.openImageList()
+		case "T":
+			return c, c.openThemes()
+		case "!":
+			return c, c.startLint()
+		case "L":
+			return c, c.openLinks()
+		case "P":
+			return c, c.startShare()
+		case "B":
+			return c, c.toggleBookmark()
+		case "'":
+			return c, c.nextBookmark()
+		case "o":
+			return c, c.toggleFold()
+		case "O":
+			return c, c.toggleFoldAll()
+		case "ctrl+o":
+			return c, c.jump(-1)
+		case "ctrl+i", "alt+i":
+			// Most terminals send ctrl+i as tab, which selects links;
+			// alt+i always arrives.
+			return c, c.jump(1)
+		case "v":
+			return c, c.startSelection()
+		case "p":
+			return c, c.toggleSpeech()
+		case "}":
+			return c, c.skipSpeech(1)
+		case "{":
+			return c, c.skipSpeech(-1)
+		case "+", "=":
+			c.ctx.widenMaxWidth()
+			return c, c.remeasure()
+		case "-":
+			c.ctx.narrowMaxWidth()
+			return c, c.remeasure()
+		case "tab":
+			return c, c.selectLink(1)
+		case "shift+tab":
+			return c, c.selectLink(-1)
+		case "enter":
+			return c, c.followLink()
+		case "backspace":
+			return c, func() tea.Msg { return LinkBackMsg{} }
 		case "?":
 			c.help.Toggle()
 			c.resizeViewport()
 			return c, nil
 		case "b", "pgup":
-			c.viewport.PageUp()
+			c.focusedViewport().PageUp()
+			c.scrolled()
 			return c, nil
 		case "f", "pgdown":
-			c.viewport.PageDown()
+			c.focusedViewport().PageDown()
+			c.scrolled()
 			return c, nil
 		case "u", "ctrl+b":
-			c.viewport.HalfPageUp()
+			c.focusedViewport().HalfPageUp()
+			c.scrolled()
 			return c, nil
 		case "d", "ctrl+f":
-			c.viewport.HalfPageDown()
+			c.focusedViewport().HalfPageDown()
+			c.scrolled()
 			return c, nil
 		}
 	}
 
 	var cmd tea.Cmd
-	c.viewport, cmd = c.viewport.Update(msg)
+	vp := c.focusedViewport()
+	*vp, cmd = vp.Update(msg)
+	c.scrolled()
 	return c, cmd
 }
 
+// scrolled keeps the comparison pane and focus mode in step after the
+// viewport moved.
+func (c *Chapter) scrolled() {
+	c.syncCompare()
+	c.refocus()
+}
+
 var chapterHelpEntries = [][]helpEntry{
-	{{"k/↑", "up"}, {"j/↓", "down"}, {"b", "page up"}, {"f", "page down"}},
-	{{"u", "½ page up"}, {"d", "½ page down"}, {"g", "go to top"}, {"G", "go to bottom"}},
-	{{"e", "edit file"}, {"E", "open in $EDITOR"}, {"y", "copy to clipboard"}, {"m", "toggle mouse"}},
+	{{"k/↑", "up"}, {"j/↓", "down"}, {"b", "page up"}, {"f", "page down"}, {"u", "½ page up"}, {"d", "½ page down"}, {"g", "go to top"}, {"G", "go to bottom"}, {"M", "metrics"}, {"R", "readability score"}, {"L", "links"}},
+	{{"]", "next chapter"}, {"[", "prev chapter"}, {"t", "contents"}, {"tab", "next link"}, {"enter", "open link"}, {"bksp", "back"}, {"^O/⌥I", "jump back/fwd"}, {"+/-", "content width"}, {"p", "read aloud"}, {"T", "theme"}, {"!", "check prose"}},
+	{{"e", "edit file"}, {"E", "open in $EDITOR"}, {"s", "source view"}, {"z", "focus mode"}, {"i", "images"}, {"o/O", "fold section/all"}, {"v", "select lines"}, {"B/'", "bookmark/next"}, {"g d", "git diff"}, {"y", "copy md/ANSI/HTML"}, {"P", "share"}, {"m", "toggle mouse"}},
+}
+
+// openSibling opens the next (step 1) or previous (step -1) document in the
+// book's reading order.
+func (c *Chapter) openSibling(step int) tea.Cmd {
+	order := c.ctx.scanner.readingOrder(c.ctx.bookDir)
+	i := slices.Index(order, c.filePath)
+	if i < 0 || i+step < 0 || i+step >= len(order) {
+		if step > 0 {
+			c.statusText = "Last chapter"
+		} else {
+			c.statusText = "First chapter"
+		}
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	next := order[i+step]
+	return func() tea.Msg { return OpenChapterMsg{FilePath: next} }
 }
 
 func chapterViewportHeight(ctx *ViewContext, helpExtraHeight int) int {
@@ -135,13 +390,70 @@ func chapterViewportHeight(ctx *ViewContext, helpExtraHeight int) int {
 // resizeViewport recomputes viewport height from current help visibility.
 func (c *Chapter) resizeViewport() {
 	c.viewport.SetHeight(chapterViewportHeight(c.ctx, c.help.HeightIfVisible()))
+	if c.compare != nil {
+		c.compare.viewport.SetHeight(c.viewport.Height())
+	}
 }
 
 // renderContent renders the current content and sets it on the viewport.
 func (c *Chapter) renderContent() {
-	rendered := render.Render([]byte(c.content), c.ctx.maxWidth)
-	centered := centerContent(rendered, c.viewport.Width(), c.ctx.maxWidth)
-	c.viewport.SetContent(centered)
+	width := min(c.ctx.maxWidth, c.viewport.Width())
+	doc := render.RenderDocument([]byte(c.content), width)
+	c.headings = doc.Headings
+	c.links = doc.Links
+	c.blocks = doc.Blocks
+	c.footnotes = doc.Footnotes
+	c.images = doc.Images
+	if c.selected >= len(c.links) {
+		c.selected = -1
+	}
+	content := doc.Content
+	if c.raw {
+		content = numberLines(c.content, width)
+		c.remapLines(func(line int) int { return sourceLine(c.blocks, line) })
+	} else {
+		content = c.applyFolds(content)
+	}
+	if c.diff != nil {
+		content = c.diff.render(width)
+	}
+	c.rendered = centerContent(content, c.viewport.Width(), width)
+	switch {
+	case c.focus:
+		c.applyFocus(false)
+	case c.visual != nil:
+		c.applySelection()
+	default:
+		c.showRendered()
+	}
+	c.renderCompare()
+}
+
+// remeasure re-renders at the current max width, keeping the reader at the
+// same relative position, and reports the new width.
+func (c *Chapter) remeasure() tea.Cmd {
+	percent := c.viewport.ScrollPercent()
+	c.renderContent()
+	c.viewport.SetYOffset(int(percent * float64(max(c.viewport.TotalLineCount()-c.viewport.Height(), 0))))
+	c.statusText = fmt.Sprintf("Width %d", c.ctx.maxWidth)
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// remapLines moves the line numbers of headings, links, footnotes and
+// images to where at places them.
+func (c *Chapter) remapLines(at func(line int) int) {
+	for i := range c.headings {
+		c.headings[i].Line = at(c.headings[i].Line)
+	}
+	for i := range c.links {
+		c.links[i].Line = at(c.links[i].Line)
+	}
+	for i := range c.footnotes {
+		c.footnotes[i].Line = at(c.footnotes[i].Line)
+	}
+	for i := range c.images {
+		c.images[i].Line = at(c.images[i].Line)
+	}
 }
 
 func (c *Chapter) refresh() {
@@ -150,17 +462,47 @@ func (c *Chapter) refresh() {
 		c.statusText = "Error reading file: " + err.Error()
 		return
 	}
-	c.content = normalizeLineEndings(string(raw))
-	c.grade = fleschKincaidGrade(c.content)
+	c.content = documentText(raw)
+	c.convert(raw)
+	c.ctx.state.Seen(c.filePath, state.ContentHash(c.content))
+	c.grade = c.ctx.readabilityText(c.content)
+	c.warning = c.ctx.thresholdWarning(c.content)
 	c.renderContent()
 }
 
 func (c Chapter) statusBarView() string {
-	left := statusBarBookName(c.ctx.bookName) + statusBarFileName(c.filePath)
+	left := statusBarBookName(c.ctx.bookName) + statusBarFileName(c.filePath) + statusBarWarning(c.warning, "M")
 	var parts []string
 	if c.statusText != "" {
 		parts = append(parts, c.statusText)
+	} else if link := c.selectedLinkStatus(); link != "" {
+		parts = append(parts, link)
 	}
+	if c.raw {
+		parts = append(parts, "source")
+	}
+	if c.converted {
+		parts = append(parts, "read-only")
+	}
+	if c.focus {
+		parts = append(parts, "focus")
+	}
+	if c.visual != nil {
+		parts = append(parts, c.selectionStatus())
+	}
+	if s := c.speechStatus(); s != "" {
+		parts = append(parts, s)
+	}
+	if c.compare != nil {
+		parts = append(parts, c.compareStatus())
+	}
+	if c.diff != nil {
+		parts = append(parts, c.diff.label)
+	}
+	if len(c.issues) > 0 {
+		parts = append(parts, issueCount(len(c.issues)))
+	}
+	parts = append(parts, fmt.Sprintf("L %d/%d", c.topSourceLine(), c.sourceLines()))
 	parts = append(parts, fmt.Sprintf("%d%%", int(c.viewport.ScrollPercent()*100)), fmt.Sprintf("%d words", countWords(c.content)))
 	if c.grade != "" {
 		parts = append(parts, c.grade)
@@ -170,5 +512,24 @@ func (c Chapter) statusBarView() string {
 
 func (c Chapter) View() string {
 	content := c.viewport.View()
+	if c.toc {
+		tocW, contentW := tocWidths(c.ctx)
+		content = splitPanes(c.tocView(tocW, c.viewport.Height()), content, tocW, contentW)
+	} else if c.imageList {
+		listW, contentW := tocWidths(c.ctx)
+		content = splitPanes(c.imageListView(listW, c.viewport.Height()), content, listW, contentW)
+	} else if c.themes {
+		listW, contentW := tocWidths(c.ctx)
+		content = splitPanes(c.themeView(listW, c.viewport.Height()), content, listW, contentW)
+	} else if c.lint != nil {
+		listW, contentW := tocWidths(c.ctx)
+		content = splitPanes(c.lintView(listW, c.viewport.Height()), content, listW, contentW)
+	} else if c.backlinks != nil {
+		listW, contentW := tocWidths(c.ctx)
+		content = splitPanes(c.linksView(listW, c.viewport.Height()), content, listW, contentW)
+	} else if c.compare != nil {
+		leftW, rightW := compareWidths(c.ctx)
+		content = splitPanes(content, c.compare.viewport.View(), leftW, rightW)
+	}
 	return layoutView(logo, content, c.statusBarView(), c.help.View(c.ctx.width))
 }
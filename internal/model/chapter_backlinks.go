@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// linkPanel lists the documents linking to the one shown, and those it links
+// to.
+type linkPanel struct {
+	entries []linkEntry
+	in      int // entries[:in] link here; the rest are linked from here
+	cursor  int
+}
+
+// linkEntry is a document in the link panel, with the link that connects it
+// to the one shown.
+type linkEntry struct {
+	path string // the other document
+	link docLink
+}
+
+// documentLinks picks out of links the documents linking to path, then
+// those path links to, each once, in the order of their first link.
+func documentLinks(links []docLink, path string) (entries []linkEntry, in int) {
+	seen := make(map[string]bool)
+	add := func(other string, l docLink) {
+		if !seen[other] {
+			seen[other] = true
+			entries = append(entries, linkEntry{path: other, link: l})
+		}
+	}
+	for _, l := range links {
+		if l.to == path {
+			add(l.from, l)
+		}
+	}
+	in = len(entries)
+	clear(seen)
+	for _, l := range links {
+		if l.from == path {
+			add(l.to, l)
+		}
+	}
+	return entries, in
+}
+
+// openLinks opens the sidebar of the documents linking to this one and
+// linked from it.
+func (c *Chapter) openLinks() tea.Cmd {
+	if c.compare != nil {
+		c.statusText = "Close the comparison first"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	path := filepath.Clean(c.filePath)
+	entries, in := documentLinks(c.ctx.scanner.linkIndex(c.ctx.bookDir), path)
+	if len(entries) == 0 {
+		c.statusText = "No links to or from this document"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.toc, c.imageList, c.themes = false, false, false
+	c.backlinks = &linkPanel{entries: entries, in: in}
+	c.resizeContent()
+	c.showLinkEntry()
+	return nil
+}
+
+// updateLinks handles keys while the link sidebar is open. Moving onto a
+// document this one links to scrolls to the link; enter opens the selected
+// document.
+func (c Chapter) updateLinks(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	p := c.backlinks
+	switch msg.String() {
+	case "j", "down":
+		p.cursor = min(p.cursor+1, len(p.entries)-1)
+	case "k", "up":
+		p.cursor = max(p.cursor-1, 0)
+	case "g", "home":
+		p.cursor = 0
+	case "G", "end":
+		p.cursor = len(p.entries) - 1
+	case "enter":
+		path := p.entries[p.cursor].path
+		c.backlinks = nil
+		c.resizeContent()
+		return c, func() tea.Msg { return FollowLinkMsg{FilePath: path} }
+	case "L", "esc", "q":
+		c.backlinks = nil
+		c.resizeContent()
+		return c, nil
+	default:
+		return c, nil
+	}
+	c.showLinkEntry()
+	return c, nil
+}
+
+// showLinkEntry scrolls the document to the selected link when it is one of
+// this document's own.
+func (c *Chapter) showLinkEntry() {
+	p := c.backlinks
+	if p.cursor < p.in {
+		return
+	}
+	row := p.entries[p.cursor].link.line
+	if c.raw {
+		c.showLine(row)
+		return
+	}
+	c.showLine(renderedLine(c.blocks, row))
+}
+
+// linksView draws the link sidebar: ← for documents linking here, with the
+// line that does, and → for documents linked from here.
+func (c Chapter) linksView(width, height int) string {
+	p := c.backlinks
+	entries := make([]string, len(p.entries))
+	for i, e := range p.entries {
+		name, err := filepath.Rel(c.ctx.bookDir, e.path)
+		if err != nil {
+			name = filepath.Base(e.path)
+		}
+		name = filepath.ToSlash(name)
+		if i < p.in {
+			entries[i] = "← " + name + ": " + e.link.text
+		} else {
+			entries[i] = "→ " + name
+		}
+	}
+	title := fmt.Sprintf("Links (%d in, %d out)", p.in, len(entries)-p.in)
+	return sidebarView(title, entries, p.cursor, width, height)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// toggleBookmark bookmarks the line at the top of the viewport, or removes
+// the bookmark there, and persists the change.
+func (c *Chapter) toggleBookmark() tea.Cmd {
+	if c.ctx.state == nil {
+		return nil
+	}
+	c.statusText = "Bookmark removed"
+	if c.ctx.state.ToggleBookmark(c.filePath, c.viewport.YOffset()) {
+		c.statusText = "Bookmarked"
+		c.ctx.state.Seen(c.filePath, state.ContentHash(c.content))
+	}
+	if err := c.ctx.state.Save(); err != nil {
+		c.statusText = "Error: " + err.Error()
+	}
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// nextBookmark scrolls to the first bookmark below the top of the viewport,
+// wrapping around to the first one in the document.
+func (c *Chapter) nextBookmark() tea.Cmd {
+	marks := c.ctx.state.Bookmarks(c.filePath)
+	if len(marks) == 0 {
+		c.statusText = "No bookmarks"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	from := c.viewport.YOffset()
+	to := marks[0]
+	for _, line := range marks {
+		if line > from {
+			to = line
+			break
+		}
+	}
+	c.recordJump(from)
+	c.viewport.SetYOffset(to)
+	c.scrolled()
+	return nil
+}
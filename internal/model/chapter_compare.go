@@ -0,0 +1,143 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"charm.land/bubbles/v2/viewport"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// comparePane is the second document of a side-by-side comparison.
+type comparePane struct {
+	filePath string
+	content  string // raw markdown
+	viewport viewport.Model
+}
+
+// compareFile marks the selected file for comparison, or opens the
+// comparison with the file marked before.
+func (b *Book) compareFile() tea.Cmd {
+	item, ok := b.list.SelectedItem().(fileItem)
+	if !ok {
+		return nil
+	}
+	if b.comparePath == "" || b.comparePath == item.path {
+		b.comparePath = item.path
+		return b.flashStatus("Compare " + item.name + " with… (C on another file)")
+	}
+	left := b.comparePath
+	b.comparePath = ""
+	return func() tea.Msg { return CompareMsg{Left: left, Right: item.path} }
+}
+
+// compareWidths splits the terminal width between the two documents.
+func compareWidths(ctx *ViewContext) (leftW, rightW int) {
+	leftW = max((ctx.width-splitDividerWidth)/2, 1)
+	rightW = max(ctx.width-leftW-splitDividerWidth, 1)
+	return leftW, rightW
+}
+
+// openCompare shows the document at path beside the chapter, with scrolling
+// locked between the two.
+func (c *Chapter) openCompare(path string) tea.Cmd {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		c.statusText = "Error reading file: " + err.Error()
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.toc = false
+	c.compare = &comparePane{
+		filePath: path,
+		content:  documentText(raw),
+		viewport: viewport.New(),
+	}
+	c.compareFocus = false
+	c.compareUnlocked = false
+	c.resizeContent()
+	c.resizeViewport()
+	return nil
+}
+
+// closeCompare returns to a single document.
+func (c *Chapter) closeCompare() {
+	c.compare = nil
+	c.resizeContent()
+}
+
+// renderCompare renders the second document to fill its pane.
+func (c *Chapter) renderCompare() {
+	if c.compare == nil {
+		return
+	}
+	_, w := compareWidths(c.ctx)
+	width := min(c.ctx.maxWidth, w)
+	c.compare.viewport.SetWidth(w)
+	c.compare.viewport.SetHeight(c.viewport.Height())
+	rendered := render.Render([]byte(c.compare.content), width)
+	c.compare.viewport.SetContent(centerContent(rendered, w, width))
+}
+
+// focusedViewport is the viewport that scrolling keys move: the right pane
+// when it has focus in a comparison, the chapter's own otherwise.
+func (c *Chapter) focusedViewport() *viewport.Model {
+	if c.compare != nil && c.compareFocus {
+		return &c.compare.viewport
+	}
+	return &c.viewport
+}
+
+// syncCompare brings the unfocused pane to the focused pane's line while
+// scrolling is locked.
+func (c *Chapter) syncCompare() {
+	if c.compare == nil || c.compareUnlocked {
+		return
+	}
+	if c.compareFocus {
+		c.viewport.SetYOffset(c.compare.viewport.YOffset())
+	} else {
+		c.compare.viewport.SetYOffset(c.viewport.YOffset())
+	}
+}
+
+// updateCompare handles the keys specific to a comparison, reporting whether
+// msg was one of them.
+func (c *Chapter) updateCompare(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "w":
+		c.compareFocus = !c.compareFocus
+		return nil, true
+	case "L":
+		c.compareUnlocked = !c.compareUnlocked
+		if c.compareUnlocked {
+			c.statusText = "Scrolling unlocked"
+		} else {
+			c.syncCompare()
+			c.statusText = "Scrolling locked"
+		}
+		return clearStatusAfter(2*time.Second, clearStatusMsg{}), true
+	case "esc", "q":
+		if c.help.Visible() {
+			return nil, false
+		}
+		c.closeCompare()
+		return nil, true
+	}
+	return nil, false
+}
+
+// compareStatus names the compared document in the status bar, marking the
+// focused pane.
+func (c Chapter) compareStatus() string {
+	name := filepath.Base(c.compare.filePath)
+	if c.compareFocus {
+		name = "▸ " + name
+	}
+	if c.compareUnlocked {
+		return "↔ " + name
+	}
+	return "⇔ " + name
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// copyChoices names what the key after y copies.
+const copyChoices = "Copy: y markdown · a rendered (ANSI) · h HTML"
+
+// copyAs copies the document to the clipboard in the format key picks from
+// copyChoices: the markdown source, the rendering as the terminal shows it,
+// with its colors, or an HTML fragment. Any other key cancels.
+func (c *Chapter) copyAs(key string) tea.Cmd {
+	var text string
+	switch key {
+	case "y":
+		text = c.content
+	case "a":
+		text = render.Render([]byte(c.content), min(c.ctx.maxWidth, c.viewport.Width()))
+	case "h":
+		html, err := export.Fragment(c.filePath, []byte(c.content))
+		if err != nil {
+			c.statusText = "Copy failed: " + err.Error()
+			return clearStatusAfter(2*time.Second, clearStatusMsg{})
+		}
+		text = html
+	default:
+		c.statusText = ""
+		return nil
+	}
+	if err := writeClipboard(text); err != nil {
+		c.statusText = "Copy failed"
+	} else {
+		c.statusText = "Copied!"
+	}
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
@@ -0,0 +1,217 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+)
+
+// diffOp is what happened to a paragraph between two versions.
+type diffOp int
+
+const (
+	diffSame diffOp = iota
+	diffAdded
+	diffRemoved
+)
+
+// diffBlock is a paragraph of a diff and what happened to it.
+type diffBlock struct {
+	op   diffOp
+	text string
+}
+
+// chapterDiff is the diff shown in place of the document by "g d".
+type chapterDiff struct {
+	label  string // what the document is compared with, for the status bar
+	blocks []diffBlock
+}
+
+// diffBaseMsg delivers the version of path to compare the document with.
+type diffBaseMsg struct {
+	path  string
+	base  string // "" when the document is new
+	label string
+	err   error
+}
+
+// loadDiffBase finds in git the version of path to compare the document
+// with: the committed one when there are uncommitted changes, and otherwise
+// the one before the last commit that changed it.
+func loadDiffBase(path string) tea.Cmd {
+	return func() tea.Msg {
+		dir, name := filepath.Dir(path), "./"+filepath.Base(path)
+		git := func(args ...string) (string, error) {
+			out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+			return string(out), err
+		}
+		if _, err := git("rev-parse", "--is-inside-work-tree"); err != nil {
+			return diffBaseMsg{path: path, err: errors.New("not in a git repository")}
+		}
+		committed, err := git("show", "HEAD:"+name)
+		if err != nil {
+			return diffBaseMsg{path: path, label: "diff: not committed"}
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return diffBaseMsg{path: path, err: err}
+		}
+		if documentText([]byte(committed)) != documentText(raw) {
+			return diffBaseMsg{path: path, base: committed, label: "diff: uncommitted"}
+		}
+		hash, err := git("log", "-1", "--format=%h", "--", name)
+		if err != nil {
+			return diffBaseMsg{path: path, err: err}
+		}
+		hash = strings.TrimSpace(hash)
+		// A document added by its last commit has no version before it.
+		before, _ := git("show", hash+"^:"+name)
+		return diffBaseMsg{path: path, base: before, label: "diff: " + hash}
+	}
+}
+
+// openDiff starts loading the version to compare the document with.
+func (c *Chapter) openDiff() tea.Cmd {
+	c.statusText = "Loading diff…"
+	return loadDiffBase(c.filePath)
+}
+
+// diffLoaded shows the diff against the version loadDiffBase found.
+func (c *Chapter) diffLoaded(msg diffBaseMsg) tea.Cmd {
+	c.statusText = ""
+	if msg.err != nil {
+		c.statusText = "No diff: " + msg.err.Error()
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.diff = &chapterDiff{
+		label:  msg.label,
+		blocks: diffParagraphs(splitParagraphs(documentText([]byte(msg.base))), splitParagraphs(c.content)),
+	}
+	c.diffFrom = c.viewport.YOffset()
+	c.renderContent()
+	c.viewport.GotoTop()
+	return nil
+}
+
+// closeDiff returns to the document where it was left.
+func (c *Chapter) closeDiff() {
+	c.diff = nil
+	c.renderContent()
+	c.viewport.SetYOffset(c.diffFrom)
+}
+
+// updateDiff handles keys while the diff is shown: esc or q closes it, and
+// the rest scroll.
+func (c Chapter) updateDiff(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		c.closeDiff()
+		return c, nil
+	}
+	var cmd tea.Cmd
+	c.viewport, cmd = c.viewport.Update(msg)
+	return c, cmd
+}
+
+// splitParagraphs splits markdown text into its blank-line separated
+// blocks, keeping fenced code blocks whole.
+func splitParagraphs(text string) []string {
+	var blocks []string
+	var cur []string
+	fenced := false
+	flush := func() {
+		if len(cur) > 0 {
+			blocks = append(blocks, strings.Join(cur, "\n"))
+			cur = nil
+		}
+	}
+	for line := range strings.SplitSeq(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if trimmed == "" && !fenced {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return blocks
+}
+
+// diffParagraphs lines up the paragraphs of two versions by their longest
+// common subsequence, marking the rest as removed from old or added in new.
+func diffParagraphs(old, new []string) []diffBlock {
+	// lcs[i][j] is the length of the longest common subsequence of old[i:]
+	// and new[j:].
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var blocks []diffBlock
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			blocks = append(blocks, diffBlock{diffSame, new[j]})
+			i++
+			j++
+		case i < len(old) && (j == len(new) || lcs[i+1][j] >= lcs[i][j+1]):
+			// Removals come first, so a rewritten paragraph reads old then new.
+			blocks = append(blocks, diffBlock{diffRemoved, old[i]})
+			i++
+		default:
+			blocks = append(blocks, diffBlock{diffAdded, new[j]})
+			j++
+		}
+	}
+	return blocks
+}
+
+// render renders the diff's paragraphs at width, with a gutter marking
+// added paragraphs in green and removed ones in red.
+func (d chapterDiff) render(width int) string {
+	if len(d.blocks) == 0 {
+		return "No changes"
+	}
+	var out []string
+	for _, b := range d.blocks {
+		gutter := "  "
+		switch b.op {
+		case diffAdded:
+			gutter = diffAddedStyle.Render(asciiOr("▌", "+") + " ")
+		case diffRemoved:
+			gutter = diffRemovedStyle.Render(asciiOr("▌", "-") + " ")
+		}
+		rendered := strings.Trim(render.Render([]byte(b.text), max(width-2, 1)), "\n")
+		lines := strings.Split(rendered, "\n")
+		for i, line := range lines {
+			lines[i] = gutter + line
+		}
+		out = append(out, strings.Join(lines, "\n"))
+	}
+	return strings.Join(out, "\n\n")
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// focusDimStyle greys out the text around the focused paragraph.
+var focusDimStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("238"))
+
+// blockStart is the first viewport line of block i, in the rendered or the
+// source view.
+func (c Chapter) blockStart(i int) int {
+	if c.raw {
+		return c.blocks[i].Source
+	}
+	return c.blocks[i].Line
+}
+
+// blockRange returns the viewport lines [start, end) of block i.
+func (c Chapter) blockRange(i int) (start, end int) {
+	start = c.blockStart(i)
+	end = c.viewport.TotalLineCount()
+	if i+1 < len(c.blocks) {
+		end = c.blockStart(i + 1)
+	}
+	return start, end
+}
+
+// centerBlock returns the block at the vertical center of the viewport.
+func (c Chapter) centerBlock() int {
+	center := c.viewport.YOffset() + c.viewport.Height()/2
+	cur := 0
+	for i := range c.blocks {
+		if c.blockStart(i) > center {
+			break
+		}
+		cur = i
+	}
+	return cur
+}
+
+// toggleFocus turns focus mode on or off. In focus mode every block but the
+// one in the middle of the viewport is dimmed.
+func (c *Chapter) toggleFocus() tea.Cmd {
+	if !c.focus && len(c.blocks) == 0 {
+		c.statusText = "Nothing to focus"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.focus = !c.focus
+	if c.focus {
+		c.focusBlock = c.centerBlock()
+		c.applyFocus(true)
+	} else {
+		c.showRendered()
+	}
+	return nil
+}
+
+// refocus moves the focus to the block now in the middle of the viewport
+// after scrolling by other means than j/k.
+func (c *Chapter) refocus() {
+	if !c.focus {
+		return
+	}
+	if b := c.centerBlock(); b != c.focusBlock {
+		c.focusBlock = b
+		c.applyFocus(false)
+	}
+}
+
+// moveFocus advances the focus by step blocks and centers the new block.
+func (c *Chapter) moveFocus(step int) {
+	c.focusBlock = min(max(c.focusBlock+step, 0), len(c.blocks)-1)
+	c.applyFocus(true)
+}
+
+// applyFocus dims all but the focused block, scrolling it to the middle of
+// the viewport when center is set.
+func (c *Chapter) applyFocus(center bool) {
+	if len(c.blocks) == 0 {
+		c.showRendered()
+		return
+	}
+	c.focusBlock = min(c.focusBlock, len(c.blocks)-1)
+	start, end := c.blockRange(c.focusBlock)
+	lines := strings.Split(c.rendered, "\n")
+	for i, line := range lines {
+		if i < start || i >= end {
+			lines[i] = focusDimStyle.Render(ansi.Strip(line))
+		}
+	}
+	c.viewport.SetContent(strings.Join(lines, "\n"))
+	if center {
+		c.viewport.SetYOffset(max((start+end)/2-c.viewport.Height()/2, 0))
+	}
+}
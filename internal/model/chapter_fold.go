@@ -0,0 +1,164 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// foldStyle styles the line standing in for a folded section.
+var foldStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+// foldRange returns the rendered lines [start, end) hidden when heading i
+// is folded: everything after the heading up to the next heading of the same
+// or a higher level, less the blank lines before that heading.
+func (c Chapter) foldRange(i int, lines []string) (start, end int) {
+	h := c.headings[i]
+	start, end = len(lines), len(lines)
+	for j, b := range c.blocks {
+		if b.Line == h.Line && j+1 < len(c.blocks) {
+			start = c.blocks[j+1].Line
+			break
+		}
+	}
+	for _, next := range c.headings[i+1:] {
+		if next.Level <= h.Level {
+			end = next.Line
+			break
+		}
+	}
+	for end > start && strings.TrimSpace(ansi.Strip(lines[end-1])) == "" {
+		end--
+	}
+	return start, end
+}
+
+// applyFolds replaces the body of each folded section of the rendered
+// content with a single line, and moves the line numbers of headings, links
+// and the rest onto the shortened content.
+func (c *Chapter) applyFolds(content string) string {
+	if len(c.folded) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	type span struct{ start, end int }
+	var folds []span
+	next := 0
+	for i, h := range c.headings {
+		if !c.folded[h.ID] || h.Line < next {
+			continue
+		}
+		start, end := c.foldRange(i, lines)
+		if start < end {
+			folds = append(folds, span{start, end})
+			next = end
+		}
+	}
+	if len(folds) == 0 {
+		return content
+	}
+
+	remap := make([]int, len(lines))
+	out := make([]string, 0, len(lines))
+	for i, f := 0, 0; i < len(lines); i++ {
+		if f < len(folds) && i == folds[f].start {
+			n := folds[f].end - folds[f].start
+			for j := i; j < folds[f].end; j++ {
+				remap[j] = len(out)
+			}
+			out = append(out, foldStyle.Render(fmt.Sprintf("  ▸ %d %s folded", n, pluralize(n, "line", "lines"))))
+			i = folds[f].end - 1
+			f++
+			continue
+		}
+		remap[i] = len(out)
+		out = append(out, lines[i])
+	}
+
+	at := func(line int) int { return remap[min(max(line, 0), len(remap)-1)] }
+	c.remapLines(at)
+	for i := range c.blocks {
+		c.blocks[i].Line = at(c.blocks[i].Line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// toggleFold folds or unfolds the section of the heading at the top of the
+// viewport.
+func (c *Chapter) toggleFold() tea.Cmd {
+	if msg := c.foldUnavailable(); msg != "" {
+		c.statusText = msg
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	id := c.headings[c.currentHeading()].ID
+	if c.folded == nil {
+		c.folded = make(map[string]bool)
+	}
+	if c.folded[id] {
+		delete(c.folded, id)
+	} else {
+		c.folded[id] = true
+	}
+	c.refold(id)
+	return nil
+}
+
+// toggleFoldAll folds every section, or unfolds them all if any is folded.
+// A lone top-level heading, usually the document title, stays open so its
+// outline shows.
+func (c *Chapter) toggleFoldAll() tea.Cmd {
+	if msg := c.foldUnavailable(); msg != "" {
+		c.statusText = msg
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	id := c.headings[c.currentHeading()].ID
+	if len(c.folded) > 0 {
+		c.folded = nil
+	} else {
+		top, tops := 6, 0
+		for _, h := range c.headings {
+			if h.Level < top {
+				top, tops = h.Level, 0
+			}
+			if h.Level == top {
+				tops++
+			}
+		}
+		c.folded = make(map[string]bool, len(c.headings))
+		for _, h := range c.headings {
+			if h.Level != top || tops > 1 {
+				c.folded[h.ID] = true
+			}
+		}
+	}
+	c.refold(id)
+	return nil
+}
+
+// foldUnavailable explains why folding can't be used right now, or returns
+// "".
+func (c Chapter) foldUnavailable() string {
+	switch {
+	case c.raw:
+		return "Not in source view"
+	case len(c.headings) == 0:
+		return "No headings"
+	}
+	return ""
+}
+
+// refold re-renders with the current folds and brings the heading with the
+// given ID to the top.
+func (c *Chapter) refold(id string) {
+	c.renderContent()
+	for _, h := range c.headings {
+		if h.ID == id {
+			c.viewport.SetYOffset(h.Line)
+			return
+		}
+	}
+}
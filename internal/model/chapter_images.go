@@ -0,0 +1,116 @@
+package model
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// imageViewerEnv names the environment variable holding the command that
+// opens images, e.g. "feh -F". The platform opener is used when it is unset.
+const imageViewerEnv = "INK_IMAGE_VIEWER"
+
+// viewImage opens the image file at path in the configured viewer.
+func viewImage(path string) error {
+	parts := strings.Fields(os.Getenv(imageViewerEnv))
+	if len(parts) == 0 {
+		return openBrowser(path)
+	}
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// openImageList opens the sidebar listing the document's images.
+func (c *Chapter) openImageList() tea.Cmd {
+	if len(c.images) == 0 {
+		c.statusText = "No images"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	if c.compare != nil {
+		c.statusText = "Close the comparison first"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.imageList = true
+	c.imageCursor = 0
+	c.resizeContent()
+	c.showLine(c.images[0].Line)
+	return nil
+}
+
+// updateImageList handles keys while the image sidebar is open. Moving the
+// cursor scrolls the document to the image; enter opens it.
+func (c Chapter) updateImageList(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down":
+		c.imageCursor = min(c.imageCursor+1, len(c.images)-1)
+	case "k", "up":
+		c.imageCursor = max(c.imageCursor-1, 0)
+	case "g", "home":
+		c.imageCursor = 0
+	case "G", "end":
+		c.imageCursor = len(c.images) - 1
+	case "enter":
+		return c, c.openImage()
+	case "i", "esc", "q":
+		c.imageList = false
+		c.resizeContent()
+		return c, nil
+	default:
+		return c, nil
+	}
+	c.showLine(c.images[c.imageCursor].Line)
+	return c, nil
+}
+
+// openImage opens the selected image: local files in the image viewer,
+// remote ones in the browser.
+func (c *Chapter) openImage() tea.Cmd {
+	dest := c.images[c.imageCursor].Dest
+	var err error
+	if isWebLink(dest) {
+		err = openBrowser(dest)
+	} else {
+		p := dest
+		if u, perr := url.Parse(dest); perr == nil && u.Scheme == "" {
+			p = u.Path
+		}
+		p = filepath.FromSlash(p)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(c.filePath), p)
+		}
+		if _, serr := os.Stat(p); serr != nil {
+			c.statusText = "Not found: " + filepath.Base(p)
+			return clearStatusAfter(2*time.Second, clearStatusMsg{})
+		}
+		err = viewImage(p)
+	}
+	if err != nil {
+		c.statusText = "Open failed: " + err.Error()
+	} else {
+		c.statusText = "Opened " + path.Base(dest)
+	}
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// imageListView renders the image sidebar, naming each image by its alt
+// text or file name.
+func (c Chapter) imageListView(width, height int) string {
+	entries := make([]string, len(c.images))
+	for i, img := range c.images {
+		entries[i] = img.Alt
+		if entries[i] == "" {
+			entries[i] = path.Base(img.Dest)
+		}
+	}
+	return sidebarView("Images", entries, c.imageCursor, width, height)
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// maxJumps caps how many positions the jump list remembers.
+const maxJumps = 100
+
+// jumpPos is a place in a document: its path and the viewport line offset.
+type jumpPos struct {
+	path   string
+	offset int
+}
+
+// jumpList remembers where jumps started so ctrl+o and ctrl+i can walk
+// back and forth through them, like vim's jumplist.
+type jumpList struct {
+	entries []jumpPos
+	at      int // index of the current entry; len(entries) when past the end
+}
+
+// record notes a jump away from p, dropping any positions ahead of the
+// current one.
+func (j *jumpList) record(p jumpPos) {
+	j.entries = append(j.entries[:j.at], p)
+	if len(j.entries) > maxJumps {
+		j.entries = j.entries[len(j.entries)-maxJumps:]
+	}
+	j.at = len(j.entries)
+}
+
+// back returns the position before the current one, remembering cur so
+// forward can return to it.
+func (j *jumpList) back(cur jumpPos) (jumpPos, bool) {
+	if j.at == 0 {
+		return jumpPos{}, false
+	}
+	if j.at == len(j.entries) {
+		j.entries = append(j.entries, cur)
+	} else {
+		j.entries[j.at] = cur
+	}
+	j.at--
+	return j.entries[j.at], true
+}
+
+// forward returns the position after the current one.
+func (j *jumpList) forward(cur jumpPos) (jumpPos, bool) {
+	if j.at+1 >= len(j.entries) {
+		return jumpPos{}, false
+	}
+	j.entries[j.at] = cur
+	j.at++
+	return j.entries[j.at], true
+}
+
+// recordJump notes that the chapter is about to jump away from offset.
+func (c *Chapter) recordJump(offset int) {
+	c.ctx.jumps.record(jumpPos{path: c.filePath, offset: offset})
+}
+
+// jump walks the jump list back (step -1) or forward (step 1), scrolling
+// within this chapter or asking to open another.
+func (c *Chapter) jump(step int) tea.Cmd {
+	cur := jumpPos{path: c.filePath, offset: c.viewport.YOffset()}
+	var pos jumpPos
+	var ok bool
+	if step < 0 {
+		pos, ok = c.ctx.jumps.back(cur)
+	} else {
+		pos, ok = c.ctx.jumps.forward(cur)
+	}
+	if !ok {
+		if step < 0 {
+			c.statusText = "No older position"
+		} else {
+			c.statusText = "No newer position"
+		}
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	if pos.path == c.filePath {
+		c.viewport.SetYOffset(pos.offset)
+		return nil
+	}
+	return func() tea.Msg { return JumpMsg{FilePath: pos.path, Offset: pos.offset} }
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// linkStyle highlights the text of the selected link. Reverse video shows
+// without color too.
+var linkStyle = lipgloss.NewStyle().Reverse(true)
+
+// selectLink moves the link selection by step (1 for tab, -1 for shift+tab),
+// wrapping around, and scrolls the selected link into view.
+func (c *Chapter) selectLink(step int) tea.Cmd {
+	if len(c.links) == 0 {
+		c.statusText = "No links"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	switch {
+	case c.selected < 0 && step < 0:
+		c.selected = len(c.links) - 1
+	case c.selected < 0:
+		c.selected = 0
+	default:
+		c.selected = (c.selected + step + len(c.links)) % len(c.links)
+	}
+	c.highlightLink()
+	c.showLine(c.links[c.selected].Line)
+	return nil
+}
+
+// highlightLink shows the selected link highlighted, unless focus mode, a
+// visual selection or a marked sentence is restyling the content.
+func (c *Chapter) highlightLink() {
+	if c.focus || c.visual != nil || c.marked {
+		return
+	}
+	c.showRendered()
+}
+
+// showRendered sets the rendered content on the viewport with the text of
+// the selected link highlighted. A link whose text wraps onto the next line
+// has its whole first line highlighted.
+func (c *Chapter) showRendered() {
+	lines := strings.Split(c.rendered, "\n")
+	if c.selected < 0 || c.selected >= len(c.links) || c.links[c.selected].Line >= len(lines) {
+		c.viewport.SetContent(c.rendered)
+		return
+	}
+	link := c.links[c.selected]
+	line := lines[link.Line]
+	plain := ansi.Strip(line)
+	i := strings.Index(plain, link.Text)
+	if link.Text == "" || i < 0 {
+		lines[link.Line] = linkStyle.Render(plain)
+	} else {
+		left := ansi.StringWidth(plain[:i])
+		right := left + ansi.StringWidth(link.Text)
+		lines[link.Line] = ansi.Truncate(line, left, "") + ansi.ResetStyle +
+			linkStyle.Render(link.Text) + ansi.TruncateLeft(line, right, "")
+	}
+	c.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// showLine scrolls line into view if it is off screen.
+func (c *Chapter) showLine(line int) {
+	if top := c.viewport.YOffset(); line < top || line >= top+c.viewport.Height() {
+		c.viewport.SetYOffset(max(line-c.viewport.Height()/3, 0))
+	}
+}
+
+// followLink opens the selected link: markdown files next to (or below) the
+// current one open in ink, anchors scroll to their heading in this document,
+// and web links open in the system browser.
+func (c *Chapter) followLink() tea.Cmd {
+	if c.selected < 0 || c.selected >= len(c.links) {
+		return nil
+	}
+	dest := c.links[c.selected].Dest
+	if anchor, ok := strings.CutPrefix(dest, "#"); ok {
+		return c.jumpToAnchor(anchor)
+	}
+	if isWebLink(dest) {
+		if c.ctx.readOnly {
+			// The browser would open on the host.
+			c.statusText = "Read-only: " + dest
+			return clearStatusAfter(2*time.Second, clearStatusMsg{})
+		}
+		if err := openBrowser(dest); err != nil {
+			c.statusText = "Open failed: " + err.Error()
+		} else {
+			c.statusText = "Opened in browser"
+		}
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	path, anchor, ok := c.linkTarget(dest)
+	if !ok {
+		c.statusText = "Not a markdown link"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	if _, err := os.Stat(path); err != nil {
+		c.statusText = "Not found: " + filepath.Base(path)
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	return func() tea.Msg { return FollowLinkMsg{FilePath: path, Anchor: anchor} }
+}
+
+// isWebLink reports whether dest is an http(s) or mailto URL.
+func isWebLink(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
+
+// linkTarget resolves a link destination to a markdown file path relative
+// to the chapter's directory and the heading anchor it names, if any.
+func (c Chapter) linkTarget(dest string) (path, anchor string, ok bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || !IsMarkdownFile(u.Path) {
+		return "", "", false
+	}
+	path = filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(c.filePath), path)
+	}
+	return path, u.Fragment, true
+}
+
+// jumpToAnchor scrolls to the heading or footnote whose anchor is id.
+func (c *Chapter) jumpToAnchor(id string) tea.Cmd {
+	if unescaped, err := url.PathUnescape(id); err == nil {
+		id = unescaped
+	}
+	id = strings.ToLower(id)
+	from := c.viewport.YOffset()
+	if c.jumpToFootnote(id) {
+		c.recordJump(from)
+		return nil
+	}
+	for _, h := range c.headings {
+		if h.ID == id {
+			c.recordJump(from)
+			c.viewport.SetYOffset(h.Line)
+			return nil
+		}
+	}
+	c.statusText = "No heading #" + id
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// jumpToFootnote follows a footnote reference ("fn:N") to its footnote, or
+// a footnote's backlink ("fnref:N") to its first reference, selecting the
+// link that leads the other way. It reports whether id was either.
+func (c *Chapter) jumpToFootnote(id string) bool {
+	var n int
+	var line int
+	var back string
+	if _, err := fmt.Sscanf(id, "fn:%d", &n); err == nil {
+		f, ok := c.footnote(n)
+		if !ok {
+			return false
+		}
+		line, back = f.Line, fmt.Sprintf("#fnref:%d", n)
+	} else if _, err := fmt.Sscanf(id, "fnref:%d", &n); err == nil {
+		back = fmt.Sprintf("#fn:%d", n)
+		i := slices.IndexFunc(c.links, func(l render.Link) bool { return l.Dest == back })
+		if i < 0 {
+			return false
+		}
+		line = c.links[i].Line
+	} else {
+		return false
+	}
+	c.selected = slices.IndexFunc(c.links, func(l render.Link) bool { return l.Dest == back })
+	c.highlightLink()
+	c.showLine(line)
+	return true
+}
+
+// footnote returns footnote number n.
+func (c Chapter) footnote(n int) (render.Footnote, bool) {
+	for _, f := range c.footnotes {
+		if f.Index == n {
+			return f, true
+		}
+	}
+	return render.Footnote{}, false
+}
+
+// selectedLinkStatus describes the selected link for the status bar; a
+// footnote reference shows the footnote itself.
+func (c Chapter) selectedLinkStatus() string {
+	if c.selected < 0 || c.selected >= len(c.links) {
+		return ""
+	}
+	dest := c.links[c.selected].Dest
+	var n int
+	if _, err := fmt.Sscanf(dest, "#fn:%d", &n); err == nil {
+		if f, ok := c.footnote(n); ok {
+			return fmt.Sprintf("[%d] %s", n, f.Text)
+		}
+	}
+	return "→ " + strings.TrimSpace(dest)
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// lintInBackground runs the configured prose checkers on the document to
+// count their issues in the status bar; nil when there are none.
+func (c Chapter) lintInBackground() tea.Cmd {
+	if len(c.ctx.lintCommands) == 0 {
+		return nil
+	}
+	return runLint(c.ctx.lintCommands, c.filePath, c.content, false)
+}
+
+// startLint runs the configured prose checkers on the document and lists
+// what they find in a sidebar.
+func (c *Chapter) startLint() tea.Cmd {
+	if len(c.ctx.lintCommands) == 0 {
+		c.statusText = "No checker: set lint in the config's [editor] section"
+		return clearStatusAfter(3*time.Second, clearStatusMsg{})
+	}
+	if c.compare != nil {
+		c.statusText = "Close the comparison first"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.statusText = "Checking…"
+	return runLint(c.ctx.lintCommands, c.filePath, c.content, true)
+}
+
+// lintDone opens the issue sidebar on the first issue, or reports that
+// there are none. A run in the background only updates the count in the
+// status bar.
+func (c *Chapter) lintDone(msg lintDoneMsg) tea.Cmd {
+	if msg.err == nil || len(msg.issues) > 0 {
+		c.issues = msg.issues
+	}
+	if !msg.show {
+		return nil
+	}
+	c.statusText = ""
+	switch {
+	case len(msg.issues) > 0 && c.compare == nil:
+		c.toc, c.imageList, c.themes, c.backlinks = false, false, false, nil
+		c.lint = &lintPanel{issues: msg.issues}
+		c.resizeContent()
+		c.showIssue()
+	case msg.err == nil:
+		c.statusText = "No issues found"
+	}
+	if msg.err != nil {
+		c.statusText = lintFailure(msg)
+	}
+	if c.statusText == "" {
+		return nil
+	}
+	return clearStatusAfter(3*time.Second, clearStatusMsg{})
+}
+
+// updateLint handles keys while the issue sidebar is open. Moving the
+// cursor scrolls the document to the issue; enter or esc close the sidebar
+// there.
+func (c Chapter) updateLint(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	p := c.lint
+	switch msg.String() {
+	case "j", "down", "tab":
+		p.move(1)
+	case "k", "up", "shift+tab":
+		p.move(-1)
+	case "g", "home":
+		p.cursor = 0
+	case "G", "end":
+		p.cursor = len(p.issues) - 1
+	case "enter", "esc", "q", "!":
+		c.lint = nil
+		c.resizeContent()
+		return c, nil
+	default:
+		return c, nil
+	}
+	c.showIssue()
+	return c, nil
+}
+
+// showIssue scrolls the document to the block of the selected issue.
+func (c *Chapter) showIssue() {
+	row := c.lint.issues[c.lint.cursor].row
+	if c.raw {
+		c.showLine(row)
+		return
+	}
+	c.showLine(renderedLine(c.blocks, row))
+}
+
+// lintView draws the issue sidebar.
+func (c Chapter) lintView(width, height int) string {
+	entries := make([]string, len(c.lint.issues))
+	for i, issue := range c.lint.issues {
+		entries[i] = issue.label()
+	}
+	return sidebarView(fmt.Sprintf("Issues (%d)", len(entries)), entries, c.lint.cursor, width, height)
+}
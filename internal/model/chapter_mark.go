@@ -0,0 +1,93 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// markStyle highlights a sentence the Metrics view pointed at.
+var markStyle = lipgloss.NewStyle().Background(lipgloss.Color("58"))
+
+// markSentence scrolls to the sentence text, which starts on source line
+// src, and highlights the lines it is rendered on until the next key. When
+// the sentence can't be found in the rendering, the start of its block is
+// marked instead.
+func (c *Chapter) markSentence(src int, text string) {
+	lines := strings.Split(c.rendered, "\n")
+	start := renderedLine(c.blocks, src)
+	if c.raw {
+		start = src
+	}
+	if start >= len(lines) {
+		return
+	}
+	end := len(lines)
+	for _, b := range c.blocks {
+		if b.Line > start && !c.raw {
+			end = b.Line
+			break
+		}
+	}
+	lo, hi, ok := findText(lines[start:end], text)
+	if !ok {
+		// The opening words are enough to tell which sentence it is.
+		lo, hi, ok = findText(lines[start:end], strings.Join(firstWords(text, 3), " "))
+	}
+	if !ok {
+		lo, hi = 0, 0
+	}
+	lo, hi = start+lo, start+hi
+	for i := lo; i <= hi; i++ {
+		lines[i] = markStyle.Render(ansi.Strip(lines[i]))
+	}
+	c.viewport.SetContent(strings.Join(lines, "\n"))
+	c.marked = true
+	c.showLine(lo)
+}
+
+// clearMark drops the highlight markSentence put on the content.
+func (c *Chapter) clearMark() {
+	c.marked = false
+	c.showRendered()
+}
+
+// findText finds text in lines, ignoring everything but letters and digits
+// so that wrapping, markup and quote bars don't get in the way, and returns
+// the first and last line it spans.
+func findText(lines []string, text string) (lo, hi int, ok bool) {
+	var hay []rune
+	var lineOf []int
+	for i, line := range lines {
+		for _, r := range ansi.Strip(line) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				hay = append(hay, unicode.ToLower(r))
+				lineOf = append(lineOf, i)
+			}
+		}
+	}
+	var needle []rune
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			needle = append(needle, unicode.ToLower(r))
+		}
+	}
+	if len(needle) == 0 {
+		return 0, 0, false
+	}
+	at := strings.Index(string(hay), string(needle))
+	if at < 0 {
+		return 0, 0, false
+	}
+	// Index counts bytes; count runes to find the line.
+	at = len([]rune(string(hay)[:at]))
+	return lineOf[at], lineOf[at+len(needle)-1], true
+}
+
+// firstWords returns up to n of the words of text.
+func firstWords(text string, n int) []string {
+	words := strings.Fields(text)
+	return words[:min(n, len(words))]
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// rawGutterStyle dims the line numbers of the raw source view.
+var rawGutterStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+// numberLines lays out markdown source one line per row with line numbers,
+// cutting rows at width so source lines and viewport lines stay aligned.
+func numberLines(content string, width int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	digits := len(strconv.Itoa(len(lines)))
+	textW := max(width-digits-3, 1)
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		lines[i] = rawGutterStyle.Render(fmt.Sprintf("%*d │ ", digits, i+1)) + ansi.Truncate(line, textW, "…")
+	}
+	return strings.Join(lines, "\n") + strings.Repeat("\n", render.BottomMargin)
+}
+
+// sourceLine maps a rendered line to the source line of the block it falls
+// in.
+func sourceLine(blocks []render.Block, line int) int {
+	src := 0
+	for _, b := range blocks {
+		if b.Line > line {
+			break
+		}
+		src = b.Source
+	}
+	return src
+}
+
+// topSourceLine returns the 1-based source line shown at the top of the
+// viewport. In the rendering it counts on from the start of the block at the
+// top, without passing the block after it.
+func (c Chapter) topSourceLine() int {
+	top := c.viewport.YOffset()
+	if c.raw {
+		return top + 1
+	}
+	src := 0
+	for i, b := range c.blocks {
+		if b.Line > top {
+			break
+		}
+		src = b.Source + (top - b.Line)
+		if i+1 < len(c.blocks) {
+			src = min(src, c.blocks[i+1].Source-1)
+		}
+	}
+	return min(src, c.sourceLines()-1) + 1
+}
+
+// sourceLines counts the lines of the markdown source.
+func (c Chapter) sourceLines() int {
+	return strings.Count(strings.TrimRight(c.content, "\n"), "\n") + 1
+}
+
+// renderedLine maps a source line to the rendered line of the block it
+// falls in.
+func renderedLine(blocks []render.Block, src int) int {
+	line := 0
+	for _, b := range blocks {
+		if b.Source > src {
+			break
+		}
+		line = b.Line
+	}
+	return line
+}
+
+// toggleRaw swaps between the rendered document and its numbered markdown
+// source, keeping the same block at the top of the viewport.
+func (c *Chapter) toggleRaw() {
+	top := c.viewport.YOffset()
+	c.raw = !c.raw
+	c.renderContent()
+	if c.raw {
+		c.viewport.SetYOffset(sourceLine(c.blocks, top))
+	} else {
+		c.viewport.SetYOffset(renderedLine(c.blocks, top))
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// readingGap is the longest pause between keys or scrolling still counted
+// as reading. Longer pauses count as idle time.
+const readingGap = 2 * time.Minute
+
+// readingSession tracks the time spent reading during one stay in the
+// Chapter.
+type readingSession struct {
+	start      time.Time
+	lastActive time.Time
+	active     time.Duration
+}
+
+// newReadingSession starts a session at t.
+func newReadingSession(t time.Time) readingSession {
+	return readingSession{start: t, lastActive: t}
+}
+
+// touch records that the reader did something at t.
+func (s *readingSession) touch(t time.Time) {
+	if gap := t.Sub(s.lastActive); gap > 0 && gap <= readingGap {
+		s.active += gap
+	}
+	s.lastActive = t
+}
+
+// logReading ends the reading session at t, appending it to the reading log
+// so the statistics screen can total the time spent in a book, and starts
+// a new one. Sessions without active time aren't logged.
+func (c *Chapter) logReading(t time.Time) {
+	s := c.reading
+	s.touch(t)
+	c.reading = newReadingSession(t)
+	if s.active == 0 {
+		return
+	}
+	// Like the session log, this is a record for the statistics screen;
+	// failing to write it shouldn't get in the way of reading.
+	_ = c.ctx.state.LogRead(state.Read{Path: c.filePath, Start: s.start, End: t, Active: s.active})
+}
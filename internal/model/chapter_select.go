@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// selectStyle highlights the lines of a visual selection.
+var selectStyle = lipgloss.NewStyle().Background(lipgloss.Color("238"))
+
+// selection is a range of viewport lines picked in visual mode, from where
+// it started to where the cursor is now.
+type selection struct {
+	anchor, cursor int
+}
+
+// lines returns the first and last selected lines.
+func (s selection) lines() (lo, hi int) {
+	return min(s.anchor, s.cursor), max(s.anchor, s.cursor)
+}
+
+// startSelection enters visual mode on the first block at the top of the
+// viewport.
+func (c *Chapter) startSelection() tea.Cmd {
+	if c.focus {
+		c.statusText = "Not in focus mode"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	line := c.viewport.YOffset()
+	if len(c.blocks) > 0 {
+		line = max(line, c.blockStart(c.topBlock()))
+	}
+	line = min(line, max(c.viewport.TotalLineCount()-1, 0))
+	c.visual = &selection{anchor: line, cursor: line}
+	c.applySelection()
+	return nil
+}
+
+// updateSelection handles keys in visual mode: j/k extend the selection by a
+// line, y or enter copy it, esc or v leave.
+func (c Chapter) updateSelection(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	last := max(c.viewport.TotalLineCount()-1, 0)
+	switch msg.String() {
+	case "j", "down":
+		c.visual.cursor = min(c.visual.cursor+1, last)
+	case "k", "up":
+		c.visual.cursor = max(c.visual.cursor-1, 0)
+	case "g", "home":
+		c.visual.cursor = 0
+	case "G", "end":
+		c.visual.cursor = last
+	case "y", "enter":
+		return c, c.copySelection()
+	case "esc", "q", "v":
+		c.endSelection()
+		return c, nil
+	default:
+		return c, nil
+	}
+	if top := c.viewport.YOffset(); c.visual.cursor < top {
+		c.viewport.SetYOffset(c.visual.cursor)
+	} else if bottom := top + c.viewport.Height(); c.visual.cursor >= bottom {
+		c.viewport.SetYOffset(c.visual.cursor - c.viewport.Height() + 1)
+	}
+	c.applySelection()
+	return c, nil
+}
+
+// applySelection shows the content with the selected lines highlighted.
+func (c *Chapter) applySelection() {
+	lines := strings.Split(c.rendered, "\n")
+	lo, hi := c.visual.lines()
+	for i := lo; i <= hi && i < len(lines); i++ {
+		lines[i] = selectStyle.Render(ansi.Strip(lines[i]))
+	}
+	c.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// endSelection leaves visual mode.
+func (c *Chapter) endSelection() {
+	c.visual = nil
+	c.showRendered()
+}
+
+// selectionSource returns the markdown source lines [start, end) behind the
+// selected viewport lines. In the rendering it takes whole blocks, from the
+// one the selection starts in to the one it ends in.
+func (c Chapter) selectionSource() (start, end int) {
+	lo, hi := c.visual.lines()
+	total := c.sourceLines()
+	if c.raw {
+		return min(lo, total), min(hi+1, total)
+	}
+	end = total
+	for i, b := range c.blocks {
+		if b.Line > hi {
+			end = b.Source
+			break
+		}
+		// Folded blocks share a line; start at the first of them.
+		if b.Line <= lo && (i == 0 || c.blocks[i-1].Line != b.Line) {
+			start = b.Source
+		}
+	}
+	return start, end
+}
+
+// copySelection copies the markdown source of the selection to the
+// clipboard and leaves visual mode.
+func (c *Chapter) copySelection() tea.Cmd {
+	start, end := c.selectionSource()
+	lines := strings.Split(c.content, "\n")
+	end = min(end, len(lines))
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	c.endSelection()
+	n := end - start
+	if err := writeClipboard(strings.Join(lines[start:end], "\n") + "\n"); err != nil {
+		c.statusText = "Copy failed"
+	} else {
+		c.statusText = fmt.Sprintf("Copied %d source %s", n, pluralize(n, "line", "lines"))
+	}
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// selectionStatus describes the visual selection for the status bar.
+func (c Chapter) selectionStatus() string {
+	lo, hi := c.visual.lines()
+	n := hi - lo + 1
+	return fmt.Sprintf("visual %d %s", n, pluralize(n, "line", "lines"))
+}
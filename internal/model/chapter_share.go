@@ -0,0 +1,152 @@
+package model
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// shareTimeout bounds how long an upload may take.
+const shareTimeout = 30 * time.Second
+
+// gistTokenEnv names the environment variable holding the GitHub token
+// documents are shared as gists with; $GITHUB_TOKEN is tried after it.
+const gistTokenEnv = "INK_GIST_TOKEN"
+
+// gistAPI is where gists are created.
+var gistAPI = "https://api.github.com/gists"
+
+// shareDoneMsg carries the address of a shared document, or why sharing
+// failed.
+type shareDoneMsg struct {
+	path string
+	url  string
+	err  error
+}
+
+// gistToken returns the GitHub token from the config, or else the
+// environment; "" when there is none.
+func (ctx *ViewContext) gistToken() string {
+	return cmp.Or(ctx.shareToken, os.Getenv(gistTokenEnv), os.Getenv("GITHUB_TOKEN"))
+}
+
+// shareDocument uploads content, the document at path, in the background:
+// through the share command when one is set, which gets the text on stdin
+// and prints the address, or else as a gist.
+func shareDocument(ctx *ViewContext, path, content string) tea.Cmd {
+	command, token, public := ctx.shareCommand, ctx.gistToken(), ctx.sharePublic
+	return func() tea.Msg {
+		c, cancel := context.WithTimeout(context.Background(), shareTimeout)
+		defer cancel()
+		var url string
+		var err error
+		if command != "" {
+			url, err = pasteDocument(c, command, path, content)
+		} else {
+			url, err = createGist(c, token, filepath.Base(path), content, public)
+		}
+		return shareDoneMsg{path: path, url: url, err: err}
+	}
+}
+
+// pasteDocument runs the share command on content and returns the address
+// it printed last.
+func pasteDocument(ctx context.Context, command, path, content string) (string, error) {
+	cmd := commandLine(ctx, command, path, filepath.Dir(path))
+	if cmd == nil {
+		return "", errors.New("empty command")
+	}
+	cmd.Stdin = strings.NewReader(content)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.New(cmp.Or(lastLine(stderr.String()), err.Error()))
+	}
+	url := lastLine(strings.TrimSpace(string(out)))
+	if !isWebLink(url) {
+		return "", fmt.Errorf("no address in the output: %q", url)
+	}
+	return url, nil
+}
+
+// createGist uploads content as a gist holding one file, name, and returns
+// its address. Gists are secret unless public is set.
+func createGist(ctx context.Context, token, name, content string, public bool) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"public": public,
+		"files":  map[string]any{name: map[string]string{"content": content}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gistAPI, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var gist struct {
+		URL     string `json:"html_url"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil && resp.StatusCode == http.StatusCreated {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("GitHub: %s", cmp.Or(gist.Message, resp.Status))
+	}
+	return gist.URL, nil
+}
+
+// startShare asks to confirm sharing the document, then uploads it on a
+// second P.
+func (c *Chapter) startShare() tea.Cmd {
+	if c.ctx.shareCommand == "" && c.ctx.gistToken() == "" {
+		c.statusText = "Nowhere to share: set $" + gistTokenEnv + " or the config's [share] section"
+		return clearStatusAfter(3*time.Second, clearStatusMsg{})
+	}
+	if !c.shareAsked {
+		c.shareAsked = true
+		where := "a secret gist"
+		switch {
+		case c.ctx.shareCommand != "":
+			where = shellWords(c.ctx.shareCommand)[0]
+		case c.ctx.sharePublic:
+			where = "a public gist"
+		}
+		c.statusText = "Press P again to upload " + filepath.Base(c.filePath) + " to " + where
+		return nil
+	}
+	c.shareAsked = false
+	c.statusText = "Sharing…"
+	return shareDocument(c.ctx, c.filePath, c.content)
+}
+
+// shareDone copies the shared document's address to the clipboard.
+func (c *Chapter) shareDone(msg shareDoneMsg) tea.Cmd {
+	switch {
+	case msg.err != nil:
+		c.statusText = "Share failed: " + msg.err.Error()
+	case writeClipboard(msg.url) != nil:
+		c.statusText = "Shared at " + msg.url
+	default:
+		c.statusText = "Copied " + msg.url
+	}
+	return clearStatusAfter(5*time.Second, clearStatusMsg{})
+}
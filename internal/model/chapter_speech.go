@@ -0,0 +1,164 @@
+package model
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// speechEnv names the environment variable holding the text-to-speech
+// command, e.g. "espeak -s 160". The text to read is passed as its last
+// argument.
+const speechEnv = "INK_TTS"
+
+// speechCommand returns the configured text-to-speech command, defaulting
+// to say on macOS and espeak elsewhere.
+func speechCommand() []string {
+	if parts := strings.Fields(os.Getenv(speechEnv)); len(parts) > 0 {
+		return parts
+	}
+	if runtime.GOOS == "darwin" {
+		return []string{"say"}
+	}
+	return []string{"espeak"}
+}
+
+// speech is the state of reading a chapter aloud, one block at a time.
+type speech struct {
+	block  int       // block being read, or to resume from when paused
+	seq    int       // identifies the latest reading, to drop stale results
+	paused bool      // stopped by the reader, to resume at block
+	proc   *exec.Cmd // running speech command, if any
+}
+
+// speechDoneMsg reports that reading a block finished or was stopped.
+type speechDoneMsg struct {
+	seq int
+}
+
+// blockText returns the plain text shown for block i.
+func (c Chapter) blockText(i int) string {
+	start, end := c.blockRange(i)
+	lines := strings.Split(c.rendered, "\n")
+	end = min(end, len(lines))
+	if start >= end {
+		return ""
+	}
+	return strings.Join(strings.Fields(ansi.Strip(strings.Join(lines[start:end], " "))), " ")
+}
+
+// toggleSpeech starts reading aloud from the block at the top of the
+// viewport (or the focused block), or pauses and resumes reading.
+func (c *Chapter) toggleSpeech() tea.Cmd {
+	if c.speech == nil {
+		if len(c.blocks) == 0 {
+			c.statusText = "Nothing to read"
+			return clearStatusAfter(2*time.Second, clearStatusMsg{})
+		}
+		c.speech = &speech{block: c.topBlock()}
+		if c.focus {
+			c.speech.block = c.focusBlock
+		}
+		return c.speak(c.speech.block)
+	}
+	if c.speech.paused {
+		return c.speak(c.speech.block)
+	}
+	c.speech.paused = true
+	c.speech.seq++
+	c.killSpeech()
+	return nil
+}
+
+// skipSpeech reads the block step blocks away from the current one.
+func (c *Chapter) skipSpeech(step int) tea.Cmd {
+	if c.speech == nil {
+		return nil
+	}
+	return c.speak(min(max(c.speech.block+step, 0), len(c.blocks)-1))
+}
+
+// topBlock returns the first block starting at or below the top of the
+// viewport.
+func (c Chapter) topBlock() int {
+	top := c.viewport.YOffset()
+	for i := range c.blocks {
+		if c.blockStart(i) >= top {
+			return i
+		}
+	}
+	return max(len(c.blocks)-1, 0)
+}
+
+// speak reads block i aloud, stopping whatever was being read, and keeps it
+// in view.
+func (c *Chapter) speak(i int) tea.Cmd {
+	c.killSpeech()
+	s := c.speech
+	s.block = i
+	s.seq++
+	s.paused = false
+	c.showLine(c.blockStart(i))
+	if c.focus {
+		c.focusBlock = i
+		c.applyFocus(true)
+	}
+	parts := speechCommand()
+	proc := exec.Command(parts[0], append(parts[1:], c.blockText(i))...)
+	if err := proc.Start(); err != nil {
+		c.speech = nil
+		c.statusText = "Speech failed: " + err.Error()
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	s.proc = proc
+	seq := s.seq
+	return func() tea.Msg {
+		_ = proc.Wait()
+		return speechDoneMsg{seq: seq}
+	}
+}
+
+// speechDone moves on to the next block once one has been read, stopping at
+// the end of the chapter.
+func (c *Chapter) speechDone(msg speechDoneMsg) tea.Cmd {
+	if c.speech == nil || msg.seq != c.speech.seq || c.speech.paused {
+		return nil
+	}
+	c.speech.proc = nil
+	if c.speech.block+1 >= len(c.blocks) {
+		c.speech = nil
+		return nil
+	}
+	return c.speak(c.speech.block + 1)
+}
+
+// killSpeech stops the running speech command, if any.
+func (c *Chapter) killSpeech() {
+	if c.speech != nil && c.speech.proc != nil {
+		_ = c.speech.proc.Process.Kill()
+		c.speech.proc = nil
+	}
+}
+
+// stopSpeech stops reading aloud altogether.
+func (c *Chapter) stopSpeech() {
+	c.killSpeech()
+	c.speech = nil
+}
+
+// speechStatus describes the reading state for the status bar.
+func (c Chapter) speechStatus() string {
+	switch {
+	case c.speech == nil:
+		return ""
+	case c.speech.paused:
+		return "⏸ paused"
+	default:
+		return "▶ reading"
+	}
+}
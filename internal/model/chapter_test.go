@@ -1,11 +1,22 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/render"
+	"github.com/inkcheck/ink/internal/state"
 )
 
 func TestChapterViewLineCount(t *testing.T) {
@@ -46,3 +57,931 @@ func TestChapterViewLineCount(t *testing.T) {
 		t.Error("View() with help: missing logo")
 	}
 }
+
+func TestChapterTableOfContents(t *testing.T) {
+	body := strings.Repeat("Line of text.\n\n", 30)
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md":   "# Intro\n\n" + body + "## Setup\n\n" + body + "## Usage\n\n" + body,
+		"plain.md": "No headings here.\n",
+	})
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	press := func(r rune) {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+
+	press('t')
+	if !ch.toc {
+		t.Fatal("t should open the table of contents")
+	}
+	if lines := strings.Count(ch.View(), "\n") + 1; lines != ctx.height {
+		t.Errorf("View() with contents: got %d lines, want %d", lines, ctx.height)
+	}
+	if view := ch.View(); !strings.Contains(view, "Contents") || !strings.Contains(view, "Usage") {
+		t.Error("View() should list the headings")
+	}
+
+	press('j')
+	if got, want := ch.viewport.YOffset(), ch.headings[1].Line; got != want {
+		t.Errorf("YOffset after j = %d, want Setup at %d", got, want)
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if ch.toc {
+		t.Error("enter should close the table of contents")
+	}
+	if got, want := ch.viewport.YOffset(), ch.headings[1].Line; got != want {
+		t.Errorf("YOffset after enter = %d, want Setup at %d", got, want)
+	}
+	if ch.viewport.Width() != ctx.width {
+		t.Errorf("viewport width = %d, want %d after closing", ch.viewport.Width(), ctx.width)
+	}
+
+	plain := NewChapter(ctx, filepath.Join(dir, "plain.md"))
+	plain, _ = plain.Update(tea.KeyPressMsg{Code: 't', Text: "t"})
+	if plain.toc || plain.statusText != "No headings" {
+		t.Errorf("toc = %v, status = %q; want closed with No headings", plain.toc, plain.statusText)
+	}
+}
+
+func TestChapterHighlightsSelectedLink(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Doc\n\nSee [first](a.md) and [second](b.md).\n",
+	})
+	ch := NewChapter(&ViewContext{width: 80, height: 30, maxWidth: 80}, filepath.Join(dir, "doc.md"))
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+
+	ch, _ = ch.Update(tab)
+	if got := ch.viewport.GetContent(); !strings.Contains(got, linkStyle.Render("first")) {
+		t.Errorf("first link not highlighted:\n%q", got)
+	}
+	ch, _ = ch.Update(tab)
+	got := ch.viewport.GetContent()
+	if !strings.Contains(got, linkStyle.Render("second")) || strings.Contains(got, linkStyle.Render("first")) {
+		t.Errorf("highlight should move to the second link:\n%q", got)
+	}
+	if line := strings.Split(ansi.Strip(got), "\n")[ch.links[1].Line]; !strings.Contains(line, "See first (a.md) and second (b.md).") {
+		t.Errorf("highlighted line = %q, want its text intact", line)
+	}
+}
+
+func TestChapterAnchorLinks(t *testing.T) {
+	body := strings.Repeat("Line of text.\n\n", 30)
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Guide\n\nJump to [install](#installation) or [nowhere](#missing).\n\n" +
+			body + "## Installation\n\n" + body,
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+
+	ch, _ = ch.Update(tab)
+	ch, cmd := ch.Update(enter)
+	if cmd != nil {
+		t.Errorf("anchor link should scroll in place, got cmd %T", cmd())
+	}
+	if got, want := ch.viewport.YOffset(), ch.headings[1].Line; got != want {
+		t.Errorf("YOffset = %d, want Installation at %d", got, want)
+	}
+
+	ch, _ = ch.Update(tab)
+	ch, _ = ch.Update(enter)
+	if ch.statusText != "No heading #missing" {
+		t.Errorf("statusText = %q, want No heading #missing", ch.statusText)
+	}
+}
+
+func TestChapterTopAndBottom(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"long.md": strings.Repeat("Line of text.\n\n", 100)})
+	c := NewChapter(&ViewContext{width: 80, height: 24, maxWidth: 80}, filepath.Join(dir, "long.md"))
+	press := func(r rune) {
+		c, _ = c.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	press('G')
+	if !c.viewport.AtBottom() {
+		t.Fatal("G should go to the bottom")
+	}
+	press('g')
+	if c.viewport.YOffset() != 0 {
+		t.Errorf("g should go to the top, offset %d", c.viewport.YOffset())
+	}
+	press('G')
+	press('g')
+	press('g')
+	if c.viewport.YOffset() != 0 || c.pending != "g" {
+		t.Errorf("gg: offset %d, pending %q", c.viewport.YOffset(), c.pending)
+	}
+	press('j')
+	if c.viewport.YOffset() != 1 || c.pending != "" {
+		t.Errorf("j after g should scroll down: offset %d, pending %q", c.viewport.YOffset(), c.pending)
+	}
+}
+
+func TestChapterAdjustWidth(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": strings.Repeat("A fairly long line of prose that wraps at narrower widths. ", 40),
+	})
+	ctx := &ViewContext{width: 120, height: 30, maxWidth: 80, initialMaxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	lines := ch.viewport.TotalLineCount()
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: '+', Text: "+"})
+	if ctx.maxWidth != 90 || ch.statusText != "Width 90" {
+		t.Errorf("after +: maxWidth = %d, status = %q; want 90", ctx.maxWidth, ch.statusText)
+	}
+	if got := ch.viewport.TotalLineCount(); got >= lines {
+		t.Errorf("wider text should wrap to fewer lines: %d, was %d", got, lines)
+	}
+
+	for range 5 {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: '-', Text: "-"})
+	}
+	if ctx.maxWidth != MinWidth {
+		t.Errorf("after -: maxWidth = %d, want floor %d", ctx.maxWidth, MinWidth)
+	}
+}
+
+func TestIsWebLink(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/a": true,
+		"HTTP://example.com":    true,
+		"mailto:me@example.com": true,
+		"https:/no-host":        false,
+		"guide.md":              false,
+		"#install":              false,
+		"file:///etc/passwd":    false,
+	}
+	for dest, want := range tests {
+		if got := isWebLink(dest); got != want {
+			t.Errorf("isWebLink(%q) = %v, want %v", dest, got, want)
+		}
+	}
+}
+
+func TestChapterSourceView(t *testing.T) {
+	body := strings.Repeat("Line of text.\n\n", 30)
+	md := "---\ntitle: Doc\n---\n# Intro\n\n" + body + "## Usage\n\n" + body
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": md})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	usage := ch.headings[1].Line
+	ch.viewport.SetYOffset(usage)
+	s := tea.KeyPressMsg{Code: 's', Text: "s"}
+
+	ch, _ = ch.Update(s)
+	if !ch.raw {
+		t.Fatal("s should switch to the source view")
+	}
+	srcLines := strings.Split(md, "\n")
+	top := ch.viewport.YOffset()
+	if srcLines[top] != "## Usage" {
+		t.Errorf("source view top line %d = %q, want ## Usage", top, srcLines[top])
+	}
+	if view := ch.View(); !strings.Contains(view, "## Usage") || !strings.Contains(view, "source") {
+		t.Error("source view should show the markdown and a status marker")
+	}
+
+	ch, _ = ch.Update(s)
+	if ch.raw || ch.viewport.YOffset() != usage {
+		t.Errorf("back to rendered: raw = %v, YOffset = %d, want %d", ch.raw, ch.viewport.YOffset(), usage)
+	}
+}
+
+func TestChapterFootnotes(t *testing.T) {
+	body := strings.Repeat("Line of text.\n\n", 40)
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Essay\n\nA bold claim.[^1]\n\n" + body + "[^1]: Citation needed.\n",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+
+	ch, _ = ch.Update(tab)
+	if got := ch.selectedLinkStatus(); got != "[1] Citation needed." {
+		t.Errorf("status on reference = %q, want the footnote", got)
+	}
+	ref := ch.links[ch.selected].Line
+
+	ch, _ = ch.Update(enter)
+	note := ch.footnotes[0].Line
+	if top := ch.viewport.YOffset(); note < top || note >= top+ch.viewport.Height() {
+		t.Errorf("footnote line %d not in view at offset %d", note, top)
+	}
+	if ch.links[ch.selected].Dest != "#fnref:1" {
+		t.Errorf("selected %q after jump, want the backlink", ch.links[ch.selected].Dest)
+	}
+
+	ch, _ = ch.Update(enter)
+	if top := ch.viewport.YOffset(); ref < top || ref >= top+ch.viewport.Height() {
+		t.Errorf("reference line %d not in view at offset %d", ref, top)
+	}
+	if ch.links[ch.selected].Dest != "#fn:1" {
+		t.Errorf("selected %q after returning, want the reference", ch.links[ch.selected].Dest)
+	}
+}
+
+func TestChapterFocusMode(t *testing.T) {
+	var md strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&md, "Paragraph %d of the draft.\n\n", i)
+	}
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": md.String()})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	press := func(r rune) {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+
+	press('z')
+	if !ch.focus {
+		t.Fatal("z should turn on focus mode")
+	}
+	first := ch.focusBlock
+	for range 12 {
+		press('j')
+	}
+	if ch.focusBlock != first+12 {
+		t.Fatalf("focusBlock = %d after 12 j, want %d", ch.focusBlock, first+12)
+	}
+	start, end := ch.blockRange(ch.focusBlock)
+	if mid := ch.viewport.YOffset() + ch.viewport.Height()/2; mid < start-1 || mid > end {
+		t.Errorf("focused block [%d,%d) not centered, middle line %d", start, end, mid)
+	}
+	lines := strings.Split(ch.viewport.GetContent(), "\n")
+	want := fmt.Sprintf("Paragraph %d of", ch.focusBlock)
+	if !strings.Contains(lines[start], want) {
+		t.Errorf("focused line = %q, want %q", lines[start], want)
+	}
+	if lines[start] == focusDimStyle.Render(ansi.Strip(lines[start])) {
+		t.Error("focused block should not be dimmed")
+	}
+	if other := lines[ch.blockStart(0)]; other != focusDimStyle.Render(ansi.Strip(other)) {
+		t.Error("other blocks should be dimmed")
+	}
+
+	press('k')
+	if ch.focusBlock != first+11 {
+		t.Errorf("focusBlock = %d after k, want %d", ch.focusBlock, first+11)
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if ch.focus || ch.viewport.GetContent() != ch.rendered {
+		t.Error("esc should leave focus mode and restore the rendering")
+	}
+}
+
+func TestChapterLineIndicator(t *testing.T) {
+	var md strings.Builder
+	md.WriteString("---\ntitle: Doc\n---\n")
+	for i := range 50 {
+		fmt.Fprintf(&md, "Paragraph %d.\n\n", i)
+	}
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": md.String()})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	total := ch.sourceLines()
+	if total != 102 {
+		t.Fatalf("sourceLines = %d, want 102", total)
+	}
+	// The front matter isn't rendered, so the top shows the fourth line.
+	if !strings.Contains(ch.statusBarView(), fmt.Sprintf("L 4/%d", total)) {
+		t.Error("status bar should start at L 4")
+	}
+	ch.viewport.SetYOffset(ch.blocks[10].Line)
+	want := ch.blocks[10].Source + 1
+	if got := ch.topSourceLine(); got != want {
+		t.Errorf("topSourceLine = %d, want %d", got, want)
+	}
+	if lines := strings.Split(md.String(), "\n"); lines[want-1] != "Paragraph 10." {
+		t.Errorf("line %d is %q, want Paragraph 10.", want, lines[want-1])
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 's', Text: "s"})
+	if got := ch.topSourceLine(); got != want {
+		t.Errorf("source view topSourceLine = %d, want %d", got, want)
+	}
+}
+
+func TestChapterImageList(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md":      "# Gallery\n\n![A cat](img/cat.png)\n\n![](img/missing.png)\n",
+		"img/cat.png": "png",
+	})
+	t.Setenv(imageViewerEnv, "")
+	var opened []string
+	orig := openBrowser
+	openBrowser = func(path string) error { opened = append(opened, path); return nil }
+	t.Cleanup(func() { openBrowser = orig })
+
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'i', Text: "i"})
+	if !ch.imageList {
+		t.Fatal("i should open the image list")
+	}
+	if view := ch.View(); !strings.Contains(view, "A cat") || !strings.Contains(view, "missing.png") {
+		t.Error("image list should name images by alt text or file name")
+	}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+	ch, _ = ch.Update(enter)
+	if want := filepath.Join(dir, "img", "cat.png"); len(opened) != 1 || opened[0] != want {
+		t.Errorf("opened %v, want %s", opened, want)
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	ch, _ = ch.Update(enter)
+	if ch.statusText != "Not found: missing.png" || len(opened) != 1 {
+		t.Errorf("missing image: status %q, opened %v", ch.statusText, opened)
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if ch.imageList || ch.viewport.Width() != ctx.width {
+		t.Error("esc should close the image list")
+	}
+}
+
+func TestChapterThemes(t *testing.T) {
+	t.Cleanup(func() { render.SetTheme(render.Themes[0]) })
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": "# Title\n\n## Section\n\nText.\n"})
+	config := filepath.Join(dir, "config")
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80, configPath: config}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	before := ch.viewport.GetContent()
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'T', Text: "T"})
+	if !ch.themes || !strings.Contains(ch.View(), "solarized") {
+		t.Fatal("T should open the theme list")
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	if render.CurrentTheme().Name != "light" || ch.viewport.GetContent() == before {
+		t.Error("moving to a theme should show the document in it")
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if ch.themes || render.CurrentTheme().Name != "ink" || ch.viewport.GetContent() != before {
+		t.Error("esc should go back to the theme before")
+	}
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'T', Text: "T"})
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'G', Text: "G"})
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if ch.themes || render.CurrentTheme().Name != "solarized" || ch.statusText != "Theme: solarized" {
+		t.Errorf("enter should keep the theme; status %q", ch.statusText)
+	}
+	if data, _ := os.ReadFile(config); string(data) != "[display]\ntheme = solarized\n" {
+		t.Errorf("config file:\n%s", data)
+	}
+}
+
+func TestChapterBookmarks(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": strings.Repeat("Line of text.\n\n", 60)})
+	path := filepath.Join(dir, "doc.md")
+	st, _ := state.Open(filepath.Join(dir, "state.json"))
+	ctx := &ViewContext{width: 80, height: 20, maxWidth: 80, state: st}
+	ch := NewChapter(ctx, path)
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: '\'', Text: "'"})
+	if ch.statusText != "No bookmarks" {
+		t.Errorf("status = %q, want No bookmarks", ch.statusText)
+	}
+	for _, offset := range []int{30, 10} {
+		ch.viewport.SetYOffset(offset)
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: 'B', Text: "B"})
+		if ch.statusText != "Bookmarked" {
+			t.Errorf("status = %q, want Bookmarked", ch.statusText)
+		}
+	}
+	ch.viewport.SetYOffset(0)
+	for _, want := range []int{10, 30, 10} {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: '\'', Text: "'"})
+		if got := ch.viewport.YOffset(); got != want {
+			t.Errorf("next bookmark at %d, want %d", got, want)
+		}
+	}
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'B', Text: "B"})
+	if ch.statusText != "Bookmark removed" {
+		t.Errorf("status = %q, want Bookmark removed", ch.statusText)
+	}
+	saved, _ := state.Open(filepath.Join(dir, "state.json"))
+	if got := saved.Bookmarks(path); len(got) != 1 || got[0] != 30 {
+		t.Errorf("saved bookmarks = %v, want [30]", got)
+	}
+}
+
+func TestChapterBookmarksFollowRename(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"old.md": strings.Repeat("Line of text.\n\n", 60)})
+	st, _ := state.Open(filepath.Join(dir, "state.json"))
+	ctx := &ViewContext{width: 80, height: 20, maxWidth: 80, state: st}
+	ch := NewChapter(ctx, filepath.Join(dir, "old.md"))
+	ch.viewport.SetYOffset(20)
+	ch.toggleBookmark()
+
+	renamed := filepath.Join(dir, "new.md")
+	if err := os.Rename(filepath.Join(dir, "old.md"), renamed); err != nil {
+		t.Fatal(err)
+	}
+	ch = NewChapter(ctx, renamed)
+	if got := st.Bookmarks(renamed); len(got) != 1 || got[0] != 20 {
+		t.Errorf("bookmarks of the renamed document = %v, want [20]", got)
+	}
+	if got := ch.viewport.YOffset(); got != 0 {
+		t.Errorf("offset = %d; a bookmark shouldn't scroll the document", got)
+	}
+}
+
+func TestChapterReadAloud(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Title\n\nFirst paragraph.\n\nSecond paragraph.\n",
+	})
+	// "true" stands in for the speech command and exits at once.
+	t.Setenv(speechEnv, "true")
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+
+	ch, cmd := ch.Update(tea.KeyPressMsg{Code: 'p', Text: "p"})
+	if ch.speech == nil || ch.speech.block != 0 || cmd == nil {
+		t.Fatal("p should start reading from the first block")
+	}
+	if got := ch.blockText(1); got != "First paragraph." {
+		t.Errorf("blockText(1) = %q", got)
+	}
+	ch, cmd = ch.Update(cmd())
+	if ch.speech.block != 1 {
+		t.Errorf("after a block finishes: block = %d, want 1", ch.speech.block)
+	}
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'p', Text: "p"})
+	if !ch.speech.paused || ch.speechStatus() != "⏸ paused" {
+		t.Error("second p should pause")
+	}
+	// The interrupted block reports back, but must not advance a pause.
+	ch, _ = ch.Update(cmd())
+	if ch.speech.block != 1 {
+		t.Errorf("paused speech advanced to block %d", ch.speech.block)
+	}
+
+	ch, cmd = ch.Update(tea.KeyPressMsg{Code: '}', Text: "}"})
+	if ch.speech.paused || ch.speech.block != 2 {
+		t.Errorf("} should resume on the next block, got block %d paused %v", ch.speech.block, ch.speech.paused)
+	}
+	ch, _ = ch.Update(cmd())
+	if ch.speech != nil {
+		t.Error("reading should stop after the last block")
+	}
+}
+
+func TestChapterFolding(t *testing.T) {
+	body := strings.Repeat("Line of text.\n\n", 20)
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Book\n\n## One\n\n" + body + "### Deep\n\n" + body + "## Two\n\n" + body,
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	lines := ch.viewport.TotalLineCount()
+	ch.viewport.SetYOffset(ch.headings[1].Line)
+	press := func(r rune) {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+
+	press('o')
+	if !ch.folded["one"] {
+		t.Fatal("o should fold the section at the top")
+	}
+	view := ansi.Strip(ch.View())
+	if !strings.Contains(view, "lines folded") || !strings.Contains(view, "Two") || strings.Contains(view, "Deep") {
+		t.Errorf("folded view should hide One's body, including Deep, and show Two:\n%s", view)
+	}
+	if got := ch.viewport.TotalLineCount(); got >= lines {
+		t.Errorf("folding should shorten the content: %d lines, was %d", got, lines)
+	}
+	two := ch.headings[3]
+	if got := strings.Split(ansi.Strip(ch.viewport.GetContent()), "\n")[two.Line]; !strings.Contains(got, "Two") {
+		t.Errorf("heading Two remapped to line %d = %q", two.Line, got)
+	}
+
+	press('o')
+	if len(ch.folded) != 0 || ch.viewport.TotalLineCount() != lines {
+		t.Error("second o should unfold the section")
+	}
+
+	press('O')
+	if len(ch.folded) != len(ch.headings)-1 || ch.folded["book"] {
+		t.Errorf("O folded %v, want all but the title", ch.folded)
+	}
+	press('O')
+	if len(ch.folded) != 0 {
+		t.Error("second O should unfold everything")
+	}
+}
+
+func TestChapterCopyAs(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": "# Title\n\nSome **bold** text.\n"})
+	var copied string
+	orig := writeClipboard
+	writeClipboard = func(text string) error { copied = text; return nil }
+	t.Cleanup(func() { writeClipboard = orig })
+	ch := NewChapter(&ViewContext{width: 80, height: 24, maxWidth: 80}, filepath.Join(dir, "doc.md"))
+	copyAs := func(key rune) {
+		t.Helper()
+		copied = ""
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+		if ch.statusText != copyChoices {
+			t.Fatalf("y should offer the formats, status %q", ch.statusText)
+		}
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: key, Text: string(key)})
+	}
+
+	copyAs('y')
+	if copied != "# Title\n\nSome **bold** text.\n" {
+		t.Errorf("y y: copied %q", copied)
+	}
+	copyAs('a')
+	if !strings.Contains(copied, "\x1b[") || !strings.Contains(ansi.Strip(copied), "Some bold text.") {
+		t.Errorf("y a: copied %q", copied)
+	}
+	copyAs('h')
+	if !strings.Contains(copied, "<h1") || !strings.Contains(copied, "<strong>bold</strong>") || strings.Contains(copied, "<html") {
+		t.Errorf("y h: copied %q", copied)
+	}
+	copyAs('x')
+	if copied != "" || ch.statusText != "" || ch.pending != "" {
+		t.Errorf("y x should cancel: copied %q, status %q", copied, ch.statusText)
+	}
+}
+
+func TestChapterVisualSelection(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"doc.md": "# Title\n\nFirst **bold** paragraph\nwrapped in the source.\n\n- one\n- two\n\nLast.\n",
+	})
+	ctx := &ViewContext{width: 80, height: 30, maxWidth: 80}
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	var copied string
+	orig := writeClipboard
+	writeClipboard = func(text string) error { copied = text; return nil }
+	t.Cleanup(func() { writeClipboard = orig })
+	press := func(r rune) {
+		ch, _ = ch.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+
+	press('v')
+	if ch.visual == nil || !strings.Contains(ansi.Strip(ch.statusBarView()), "visual 1 line") {
+		t.Fatalf("v should start a one-line selection, status: %s", ansi.Strip(ch.statusBarView()))
+	}
+	press('y')
+	if copied != "# Title\n" || ch.visual != nil {
+		t.Errorf("copying the heading line: got %q, visual = %v", copied, ch.visual)
+	}
+
+	press('v')
+	lines := strings.Split(ansi.Strip(ch.viewport.GetContent()), "\n")
+	for i := ch.visual.anchor; i < len(lines) && !strings.Contains(lines[i], "two"); i++ {
+		press('j')
+	}
+	press('y')
+	if want := "# Title\n\nFirst **bold** paragraph\nwrapped in the source.\n\n- one\n- two\n"; copied != want {
+		t.Errorf("copying down to the list: got %q, want %q", copied, want)
+	}
+
+	press('s')
+	press('v')
+	press('j')
+	press('j')
+	press('y')
+	if copied != "# Title\n\nFirst **bold** paragraph\n" {
+		t.Errorf("copying in the source view: got %q", copied)
+	}
+
+	press('v')
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if ch.visual != nil || ch.viewport.GetContent() != ch.rendered {
+		t.Error("esc should leave visual mode and clear the highlight")
+	}
+}
+
+func TestReadabilityCycle(t *testing.T) {
+	text := "The committee deliberated extensively regarding the implementation of comprehensive organizational restructuring. Everyone agreed eventually."
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": text})
+	ctx := &ViewContext{width: 120, height: 24, maxWidth: 100}
+	WithReadability([]string{"flesch-kincaid", "gunning-fog", "smog"})(ctx)
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	if !strings.HasPrefix(ch.grade, "Grade ") {
+		t.Errorf("first score = %q, want the Flesch-Kincaid grade", ch.grade)
+	}
+
+	ch, _ = ch.Update(tea.KeyPressMsg{Code: 'R', Text: "R"})
+	if !strings.HasPrefix(ch.grade, "Fog ") || !strings.Contains(ch.View(), "Readability: gunning-fog") {
+		t.Errorf("after R: score %q, view:\n%s", ch.grade, ch.View())
+	}
+
+	// The editor shows the same formula, and cycles on from it.
+	e := NewEditor(ctx, filepath.Join(dir, "doc.md"), text)
+	if !strings.HasPrefix(e.grade, "Fog ") {
+		t.Errorf("editor score = %q, want the Gunning Fog index", e.grade)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModAlt})
+	if !strings.HasPrefix(e.grade, "SMOG ") {
+		t.Errorf("editor score after alt+g = %q, want SMOG", e.grade)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModAlt})
+	if !strings.HasPrefix(e.grade, "Grade ") {
+		t.Errorf("cycling should wrap to the first formula, got %q", e.grade)
+	}
+}
+
+func TestThresholdWarning(t *testing.T) {
+	filler := "It is really very nice and it is just quite good, actually."
+	dir := tempDirWithFiles(t, map[string]string{"doc.md": filler, "plain.md": "It is nice. The rest reads well."})
+	economy, _ := metrics.ParseThreshold("economy < 0.4")
+	variety, _ := metrics.ParseThreshold("variety < 0.6")
+	ctx := &ViewContext{width: 120, height: 24, maxWidth: 100, thresholds: []metrics.Threshold{economy, variety}}
+
+	ch := NewChapter(ctx, filepath.Join(dir, "doc.md"))
+	if ch.warning != "economy 0.00 < 0.4" {
+		t.Errorf("warning = %q", ch.warning)
+	}
+	if view := ansi.Strip(ch.View()); !strings.Contains(view, "⚠ economy 0.00 < 0.4 · M") {
+		t.Errorf("status bar lacks the warning chip:\n%s", view)
+	}
+	if ch := NewChapter(ctx, filepath.Join(dir, "plain.md")); ch.warning != "" {
+		t.Errorf("plain text warning = %q, want none", ch.warning)
+	}
+
+	e := NewEditor(ctx, filepath.Join(dir, "plain.md"), "It is nice. Everything is fine.")
+	if e.warning != "" {
+		t.Errorf("editor warning = %q, want none", e.warning)
+	}
+	e.textarea.SetValue(filler + " " + filler)
+	e.gradeDirty = true
+	e, _ = e.Update(editorGradeTickMsg{})
+	if e.warning != "economy 0.00 < 0.4 +1" {
+		t.Errorf("editor warning after edits = %q, want economy and one more", e.warning)
+	}
+	if view := ansi.Strip(e.View()); !strings.Contains(view, "⚠ economy 0.00 < 0.4 +1") {
+		t.Errorf("editor status bar lacks the warning chip:\n%s", view)
+	}
+}
+
+func TestChapterGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "ink")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "ink@example.com")
+	}
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# Title\n\nOld paragraph.\n\nKept paragraph.\n"})
+	for _, args := range [][]string{{"init", "-q"}, {"add", "a.md"}, {"commit", "-q", "-m", "Add a.md"}} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+	path := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(path, []byte("# Title\n\nNew paragraph.\n\nKept paragraph.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	c := NewChapter(ctx, path)
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'g', Text: "g"})
+	c, cmd := c.Update(tea.KeyPressMsg{Code: 'd', Text: "d"})
+	if cmd == nil {
+		t.Fatal("g d should load the diff")
+	}
+	c, _ = c.Update(cmd())
+	if c.diff == nil || c.diff.label != "diff: uncommitted" {
+		t.Fatalf("diff = %+v, want the uncommitted changes", c.diff)
+	}
+	want := []diffBlock{
+		{diffSame, "# Title"},
+		{diffRemoved, "Old paragraph."},
+		{diffAdded, "New paragraph."},
+		{diffSame, "Kept paragraph."},
+	}
+	if fmt.Sprint(c.diff.blocks) != fmt.Sprint(want) {
+		t.Errorf("blocks = %v, want %v", c.diff.blocks, want)
+	}
+	view := ansi.Strip(c.View())
+	if !strings.Contains(view, "▌ Old paragraph.") || !strings.Contains(view, "diff: uncommitted") {
+		t.Errorf("view should mark the changed paragraphs and name the diff:\n%s", view)
+	}
+
+	c, _ = c.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if c.diff != nil || strings.Contains(ansi.Strip(c.View()), "Old paragraph.") {
+		t.Error("esc should return to the document")
+	}
+
+	for _, args := range [][]string{{"commit", "-q", "-a", "-m", "Edit a.md"}} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+	msg := loadDiffBase(path)().(diffBaseMsg)
+	if !strings.HasPrefix(msg.label, "diff: ") || !strings.Contains(msg.base, "Old paragraph.") {
+		t.Errorf("with nothing uncommitted, the diff should be the last commit's: %+v", msg)
+	}
+}
+
+func TestChapterLint(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":    "# Title\n\nA very good day.\n\n## More\n\nText.\n",
+		"lint.sh": `echo "$1:3:3: Avoid 'very'."; echo "$1:7:1: Short."`,
+	})
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, lintCommands: []string{"sh lint.sh {file}"}}
+	c := NewChapter(ctx, filepath.Join(dir, "a.md"))
+	cmd := c.Init()
+	if cmd == nil {
+		t.Fatal("opening a document should run the checkers")
+	}
+	c, _ = c.Update(cmd())
+	if c.lint != nil || !strings.Contains(c.statusBarView(), "2 issues") {
+		t.Errorf("background run: sidebar %v, status bar %q", c.lint, c.statusBarView())
+	}
+
+	c, cmd = c.Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+	c, _ = c.Update(cmd())
+	if c.lint == nil || !strings.Contains(c.View(), "Issues (2)") || !strings.Contains(c.View(), "3:3 Avoid 'very'.") {
+		t.Fatalf("the issue sidebar should open:\n%s", c.View())
+	}
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	if c.lint.cursor != 1 || c.viewport.YOffset() == 0 && c.viewport.TotalLineCount() > c.viewport.Height() {
+		t.Errorf("moving: cursor %d, offset %d", c.lint.cursor, c.viewport.YOffset())
+	}
+	c, _ = c.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if c.lint != nil || c.viewport.Width() != ctx.width {
+		t.Errorf("esc should close the sidebar: %v, width %d", c.lint, c.viewport.Width())
+	}
+}
+
+func TestChapterLinkPanel(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# A\n\nSee [[b]].\n",
+		"b.md": "# B\n\nSee [a](a.md) and [[c]].\n",
+		"c.md": "# C\n",
+		"d.md": "# D\n",
+	})
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, bookDir: dir}
+	c := NewChapter(ctx, filepath.Join(dir, "d.md"))
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'L', Text: "L"})
+	if c.backlinks != nil || c.statusText != "No links to or from this document" {
+		t.Errorf("unlinked document: status %q", c.statusText)
+	}
+
+	c = NewChapter(ctx, filepath.Join(dir, "b.md"))
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'L', Text: "L"})
+	view := c.View()
+	for _, want := range []string{"Links (1 in, 2 out)", "← a.md: See [[b]].", "→ a.md", "→ c.md"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("sidebar should show %q:\n%s", want, view)
+		}
+	}
+	for range 2 {
+		c, _ = c.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	}
+	c, cmd := c.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if msg, ok := cmd().(FollowLinkMsg); !ok || msg.FilePath != filepath.Join(dir, "c.md") || c.backlinks != nil {
+		t.Errorf("enter should open c.md and close the sidebar: %+v", msg)
+	}
+}
+
+func TestChapterShare(t *testing.T) {
+	t.Setenv(gistTokenEnv, "")
+	t.Setenv("GITHUB_TOKEN", "")
+	dir := tempDirWithFiles(t, map[string]string{
+		"note.md":  "# Note\n\nShare me.\n",
+		"paste.sh": "cat > pasted.txt; echo uploaded; echo https://paste.example/abc",
+	})
+	var gist struct {
+		Public bool                         `json:"public"`
+		Files  map[string]map[string]string `json:"files"`
+	}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gist)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"html_url": "https://gist.example/1"}`)
+	}))
+	defer srv.Close()
+	orig := gistAPI
+	gistAPI = srv.URL
+	t.Cleanup(func() { gistAPI = orig })
+	var copied string
+	origClipboard := writeClipboard
+	writeClipboard = func(text string) error { copied = text; return nil }
+	t.Cleanup(func() { writeClipboard = origClipboard })
+
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	c := NewChapter(ctx, filepath.Join(dir, "note.md"))
+	share := func() tea.Cmd {
+		t.Helper()
+		var cmd tea.Cmd
+		c, cmd = c.Update(tea.KeyPressMsg{Code: 'P', Text: "P"})
+		return cmd
+	}
+	share()
+	if !strings.HasPrefix(c.statusText, "Nowhere to share") {
+		t.Errorf("unconfigured: status %q", c.statusText)
+	}
+
+	// Nothing is uploaded without a second P.
+	ctx.shareToken = "secret"
+	if cmd := share(); cmd != nil || c.statusText != "Press P again to upload note.md to a secret gist" {
+		t.Errorf("first P: status %q", c.statusText)
+	}
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	share()
+	cmd := share()
+	if cmd == nil {
+		t.Fatal("a second P should upload the document")
+	}
+	c, _ = c.Update(cmd())
+	if copied != "https://gist.example/1" || auth != "Bearer secret" || gist.Public || gist.Files["note.md"]["content"] != "# Note\n\nShare me.\n" {
+		t.Errorf("gist: copied %q, auth %q, sent %+v", copied, auth, gist)
+	}
+
+	ctx.shareCommand = "sh paste.sh"
+	share()
+	if c.statusText != "Press P again to upload note.md to sh" {
+		t.Errorf("first P with a command: status %q", c.statusText)
+	}
+	c, _ = c.Update(share()())
+	pasted, _ := os.ReadFile(filepath.Join(dir, "pasted.txt"))
+	if copied != "https://paste.example/abc" || string(pasted) != "# Note\n\nShare me.\n" {
+		t.Errorf("paste: copied %q, sent %q", copied, pasted)
+	}
+}
+
+func TestChapterConvertedDocument(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":      "# A\n",
+		"b.rst":     "Title\n=====\n\nSome *text*.\n",
+		"c.org":     "* Heading\n",
+		"rst.sh":    `echo "# Converted $(basename $1)"; tail -n +3`,
+		"fail.sh":   "echo 'unknown syntax' >&2; exit 1",
+		"notes.txt": "not a document",
+	})
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, bookDir: dir}
+	ctx.scanner.formats = map[string]string{".rst": "sh rst.sh {file}", ".org": "sh fail.sh"}
+	items, _ := ctx.scanner.scanTree(dir)
+	var names []string
+	for _, item := range items {
+		names = append(names, item.(fileItem).name)
+	}
+	if got := strings.Join(names, ","); got != "a.md,b.rst,c.org" {
+		t.Errorf("listed %s, want the converted formats too", got)
+	}
+
+	c := NewChapter(ctx, filepath.Join(dir, "b.rst"))
+	if c.content != "# Converted b.rst\n\nSome *text*.\n" || !c.converted {
+		t.Errorf("converted content = %q", c.content)
+	}
+	if !strings.Contains(c.statusBarView(), "read-only") {
+		t.Errorf("status bar should say read-only: %s", c.statusBarView())
+	}
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'e', Text: "e"})
+	if c.statusText != "Converted from rst: E edits the source" {
+		t.Errorf("e on a converted document should not open the editor: status %q", c.statusText)
+	}
+
+	c = NewChapter(ctx, filepath.Join(dir, "c.org"))
+	if c.content != "* Heading\n" || c.statusText != "Conversion failed: unknown syntax" {
+		t.Errorf("failed conversion: content %q, status %q", c.content, c.statusText)
+	}
+}
+
+func TestChapterPlugins(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":    "# Title\n\nSome text.\n",
+		"b.md":    "# B\n",
+		"cat.sh":  "cat",
+		"open.sh": `echo '{"open": "b.md"}'`,
+	})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, plugins: map[string]string{
+		"alt+c": "sh cat.sh",
+		"alt+o": "sh open.sh",
+	}}
+	c := NewChapter(ctx, path)
+
+	c, _ = c.Update(tea.KeyPressMsg{Code: 'v', Text: "v"})
+	c, cmd := c.Update(tea.KeyPressMsg{Code: 'c', Mod: tea.ModAlt})
+	msg := cmd().(pluginDoneMsg)
+	if c.visual != nil || !msg.in.selection || msg.in.text != "# Title" {
+		t.Errorf("selection: visual %v, input %+v", c.visual, msg.in)
+	}
+	c, _ = c.Update(msg)
+	if !strings.Contains(c.statusText, "run it in the editor") || c.content != "# Title\n\nSome text.\n" {
+		t.Errorf("replace in the reader: status %q", c.statusText)
+	}
+
+	_, cmd = c.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModAlt})
+	_, cmd = c.Update(cmd())
+	if got, ok := cmd().(FollowLinkMsg); !ok || got.FilePath != filepath.Join(dir, "b.md") {
+		t.Errorf("open action = %#v", cmd())
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	got := splitParagraphs("One\ntwo\n\n```\ncode\n\nmore\n```\n\n\nThree")
+	want := []string{"One\ntwo", "```\ncode\n\nmore\n```", "Three"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("splitParagraphs = %q, want %q", got, want)
+	}
+}
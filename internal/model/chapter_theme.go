@@ -0,0 +1,73 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/config"
+	"github.com/inkcheck/ink/internal/render"
+)
+
+// openThemes opens the sidebar listing the themes on the current one.
+func (c *Chapter) openThemes() tea.Cmd {
+	if c.compare != nil {
+		c.statusText = "Close the comparison first"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.toc, c.imageList = false, false
+	c.themeFrom = render.CurrentTheme()
+	c.themeCursor = max(slices.Index(render.ThemeNames(), c.themeFrom.Name), 0)
+	c.themes = true
+	c.resizeContent()
+	return nil
+}
+
+// updateThemes handles keys while the theme sidebar is open. Moving the
+// cursor shows the document in that theme; enter keeps it and esc goes back
+// to the one before.
+func (c Chapter) updateThemes(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	last := len(render.Themes) - 1
+	switch msg.String() {
+	case "j", "down":
+		c.themeCursor = min(c.themeCursor+1, last)
+	case "k", "up":
+		c.themeCursor = max(c.themeCursor-1, 0)
+	case "g", "home":
+		c.themeCursor = 0
+	case "G", "end":
+		c.themeCursor = last
+	case "enter":
+		c.themes = false
+		c.resizeContent()
+		return c, c.keepTheme()
+	case "T", "esc", "q":
+		c.themes = false
+		render.SetTheme(c.themeFrom)
+		c.resizeContent()
+		return c, nil
+	default:
+		return c, nil
+	}
+	render.SetTheme(render.Themes[c.themeCursor])
+	c.renderContent()
+	return c, nil
+}
+
+// keepTheme saves the theme shown to the config file, so ink starts in it.
+func (c *Chapter) keepTheme() tea.Cmd {
+	name := render.CurrentTheme().Name
+	c.statusText = "Theme: " + name
+	if c.ctx.configPath != "" {
+		if err := config.Set(c.ctx.configPath, "display", "theme", name); err != nil {
+			c.statusText = "Theme not saved: " + err.Error()
+		}
+	}
+	return clearStatusAfter(2*time.Second, clearStatusMsg{})
+}
+
+// themeView renders the theme sidebar.
+func (c Chapter) themeView(width, height int) string {
+	return sidebarView("Themes", render.ThemeNames(), c.themeCursor, width, height)
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tocMaxWidth caps the width of the table of contents sidebar.
+const tocMaxWidth = 32
+
+var (
+	// tocTitleStyle styles the sidebar heading.
+	tocTitleStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("135"))
+	// tocCursorStyle highlights the selected heading.
+	tocCursorStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+// tocWidths splits the terminal width between the sidebar and the document.
+func tocWidths(ctx *ViewContext) (tocW, contentW int) {
+	tocW = min(max(ctx.width/4, 20), tocMaxWidth)
+	contentW = max(ctx.width-tocW-splitDividerWidth, 1)
+	return tocW, contentW
+}
+
+// viewportWidth is the width left for the document next to any sidebar.
+func (c Chapter) viewportWidth() int {
+	if c.toc || c.imageList || c.themes || c.lint != nil || c.backlinks != nil {
+		_, w := tocWidths(c.ctx)
+		return w
+	}
+	if c.compare != nil {
+		w, _ := compareWidths(c.ctx)
+		return w
+	}
+	return c.ctx.width
+}
+
+// currentHeading returns the index of the last heading at or above the top
+// of the viewport.
+func (c Chapter) currentHeading() int {
+	cur := 0
+	for i, h := range c.headings {
+		if h.Line > c.viewport.YOffset() {
+			break
+		}
+		cur = i
+	}
+	return cur
+}
+
+// openTOC opens the table of contents sidebar on the current heading.
+func (c *Chapter) openTOC() tea.Cmd {
+	if len(c.headings) == 0 {
+		c.statusText = "No headings"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.imageList = false
+	if c.compare != nil {
+		c.statusText = "Close the comparison first"
+		return clearStatusAfter(2*time.Second, clearStatusMsg{})
+	}
+	c.tocCursor = c.currentHeading()
+	c.tocFrom = c.viewport.YOffset()
+	c.toc = true
+	c.resizeContent()
+	c.viewport.SetYOffset(c.headings[c.tocCursor].Line)
+	return nil
+}
+
+// closeTOC closes the sidebar, leaving the selected heading at the top of
+// the viewport. Moving to another heading counts as a jump.
+func (c *Chapter) closeTOC() {
+	c.toc = false
+	c.resizeContent()
+	if c.tocCursor < len(c.headings) {
+		c.viewport.SetYOffset(c.headings[c.tocCursor].Line)
+	}
+	if c.viewport.YOffset() != c.tocFrom {
+		c.recordJump(c.tocFrom)
+	}
+}
+
+// resizeContent re-renders the document for the current viewport width.
+func (c *Chapter) resizeContent() {
+	c.viewport.SetWidth(c.viewportWidth())
+	if c.content != "" {
+		c.renderContent()
+	}
+}
+
+// updateTOC handles keys while the sidebar is open. Moving the cursor scrolls
+// the document along; enter jumps to the heading and closes the sidebar.
+func (c Chapter) updateTOC(msg tea.KeyMsg) (Chapter, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down":
+		c.tocCursor = min(c.tocCursor+1, len(c.headings)-1)
+	case "k", "up":
+		c.tocCursor = max(c.tocCursor-1, 0)
+	case "g", "home":
+		c.tocCursor = 0
+	case "G", "end":
+		c.tocCursor = len(c.headings) - 1
+	case "enter", "t", "esc", "q":
+		c.closeTOC()
+		return c, nil
+	default:
+		return c, nil
+	}
+	c.viewport.SetYOffset(c.headings[c.tocCursor].Line)
+	return c, nil
+}
+
+// tocView renders the table of contents sidebar.
+func (c Chapter) tocView(width, height int) string {
+	top := 6
+	for _, h := range c.headings {
+		top = min(top, h.Level)
+	}
+	entries := make([]string, len(c.headings))
+	for i, h := range c.headings {
+		entries[i] = strings.Repeat("  ", h.Level-top) + h.Text
+	}
+	return sidebarView("Contents", entries, c.tocCursor, width, height)
+}
+
+// sidebarView renders a titled list for a sidebar, height lines tall and
+// width cells wide, with the cursor kept in view.
+func sidebarView(title string, entries []string, cursor, width, height int) string {
+	lines := []string{tocTitleStyle.Render(title), ""}
+	rows := max(height-len(lines), 1)
+	start := min(max(cursor-rows/2, 0), max(len(entries)-rows, 0))
+	for i := start; i < min(start+rows, len(entries)); i++ {
+		line := ansi.Truncate(entries[i], width, "…")
+		if i == cursor {
+			line = tocCursorStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:height], "\n")
+}
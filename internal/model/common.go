@@ -4,12 +4,19 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"time"
 	"unicode"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/atotto/clipboard"
 	"github.com/inkcheck/readability"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
 )
 
 // ViewState represents which view is currently active.
@@ -19,6 +26,7 @@ const (
 	BookView ViewState = iota
 	ChapterView
 	EditorView
+	MetricsView
 )
 
 // MinWidth is the minimum usable width for the application.
@@ -29,26 +37,74 @@ const widthStep = 10
 
 // ViewContext holds shared state across all views.
 type ViewContext struct {
-	width           int
-	height          int
-	maxWidth        int
-	initialMaxWidth int
-	bookName        string
-	isBook          bool // true when there is a book view to return to
-	mouseEnabled    bool // true when mouse tracking is active
+	width            int
+	height           int
+	maxWidth         int
+	initialMaxWidth  int
+	bookName         string
+	bookDir          string              // root directory of the book, for reading order
+	isBook           bool                // true when there is a book view to return to
+	mouseEnabled     bool                // true when mouse tracking is active
+	state            *state.State        // persisted state; nil disables persistence
+	scanner          scanner             // how book directories are scanned
+	jumps            jumpList            // positions to return to with ctrl+o
+	autosaveIdle     time.Duration       // editor saves after this long without typing; 0 disables
+	autosaveOnBlur   bool                // editor saves when the terminal loses focus
+	vimKeys          bool                // editor uses vim-style modal keys
+	wrapWidth        int                 // editor wraps text at this many columns; 0 follows maxWidth
+	longSentence     int                 // editor marks sentences with more words; 0 for the default
+	hardGrade        float64             // editor marks sentences above this grade; 0 for the default
+	saveHooks        []string            // commands the editor runs after ctrl+s
+	wrapOnSave       int                 // editor rewraps paragraphs to this width on ctrl+s; 0 disables
+	dateFormat       string              // strftime format of dates the editor inserts; "" for the default
+	timeFormat       string              // likewise for times
+	timestampFormat  string              // likewise for timestamps
+	lintCommands     []string            // prose checkers run on documents, listed on alt+e in the editor and ! in the reader
+	plugins          map[string]string   // command lines the editor and reader run, by key
+	rewriteCommand   string              // command the editor asks for a rewrite of a section on alt+r; "" disables
+	rewritePrompt    string              // instructions sent to it ahead of the text
+	shareCommand     string              // command the reader uploads documents with on P; "" for gists
+	shareToken       string              // GitHub token for gists; "" to take it from the environment
+	sharePublic      bool                // gists are public rather than secret
+	preview          *export.Preview     // browser page following the open document, if served
+	editorArgs       map[string]string   // arguments opening a file at a line, by external editor name
+	pandocArgs       map[string][]string // extra pandoc arguments by export format, "" for all
+	zenWidth         int                 // editor wraps text at this many columns in zen mode; 0 keeps the wrap width
+	zenPadding       int                 // blank lines above and below the editor text in zen mode
+	analyzer         metrics.Analyzer    // measures documents for the Metrics view; nil for the built-in one
+	metricsCache     *metricsCache       // analyses by document content; nil disables caching
+	metricsTarget    metrics.Target      // writing signature the Metrics view measures against; nil for none
+	metricsTolerance float64             // how far an axis may stray from the target; 0 for the default
+	metricsStrip     []string            // axes the editor's metrics strip shows; nil for the default
+	readability      []readabilityScore  // formulas the status bars cycle through; nil for all
+	scoreIndex       int                 // which of them the status bars show
+	thresholds       []metrics.Threshold // limits that raise a warning in the status bars
+	keys             keyMap              // actions rebound to other keys; nil keeps the defaults
+	configPath       string              // config file settings are saved to; "" saves none
+	startRecent      bool                // the Book starts on the recently opened documents
+	readOnly         bool                // nothing on the host is changed or run, as when served over SSH
+	session          string              // names this session among those a server runs in one process; "" for none
 }
 
 // newViewContext creates a ViewContext with maxWidth clamped to MinWidth.
-func newViewContext(maxWidth int, isBook bool) *ViewContext {
+func newViewContext(maxWidth int, isBook bool, opts ...Option) *ViewContext {
 	clamped := max(maxWidth, MinWidth)
-	return &ViewContext{
+	// An unreadable state file starts a fresh state rather than failing.
+	st, _ := state.Load()
+	ctx := &ViewContext{
 		width:           80,
 		height:          24,
 		maxWidth:        clamped,
 		initialMaxWidth: clamped,
 		isBook:          isBook,
 		mouseEnabled:    false,
+		state:           st,
+		metricsCache:    newMetricsCache(),
+	}
+	for _, opt := range opts {
+		opt(ctx)
 	}
+	return ctx
 }
 
 // widenMaxWidth increases maxWidth by widthStep, capped at terminal width.
@@ -69,14 +125,15 @@ func (c *ViewContext) resetMaxWidth() {
 // contentWidth returns the effective content width, capped at maxWidth.
 func (c *ViewContext) contentWidth() int { return min(c.width, c.maxWidth) }
 
-// fleschKincaidGrade returns a formatted grade string for the given text.
-func fleschKincaidGrade(text string) string {
+// fleschKincaidScore returns the Flesch-Kincaid grade of text, reporting
+// false when the text is too short to grade.
+func fleschKincaidScore(text string) (float64, bool) {
 	a := readability.NewAnalysis(text)
 	score, err := a.Score(readability.FleschKincaidGrade)
 	if err != nil || a.Stats().Words < 10 {
-		return ""
+		return 0, false
 	}
-	return fmt.Sprintf("Grade %d", int(score))
+	return score, true
 }
 
 // countWords counts words in s by iterating runes and counting space-to-non-space transitions.
@@ -94,6 +151,32 @@ func countWords(s string) int {
 	return count
 }
 
+// writeClipboard copies text to the system clipboard; tests replace it.
+var writeClipboard = clipboard.WriteAll
+
+// readClipboardHTML returns the HTML on the system clipboard, or "" when it
+// holds none; tests replace it.
+var readClipboardHTML = clipboardHTML
+
+// openBrowser opens url with the platform's default handler; tests replace
+// it.
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
 // toggleMouse flips mouseEnabled. In bubbletea v2 the mouse mode is applied
 // via the MouseMode field of the View returned from the root model.
 func toggleMouse(ctx *ViewContext) {
@@ -147,6 +230,26 @@ func relativeTime(t time.Time, now time.Time) string {
 	}
 }
 
+// readOnlyFile reports whether the file at path exists but can't be
+// written: it has no write permission bits, or opening it for writing fails.
+// The permission bits matter for users like root whom the system lets write
+// anyway.
+func readOnlyFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.Mode().Perm()&0222 == 0 {
+		return true
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return true
+	}
+	f.Close()
+	return false
+}
+
 // normalizeLineEndings converts \r\n and bare \r to \n.
 func normalizeLineEndings(s string) string {
 	s = strings.ReplaceAll(s, "\r\n", "\n")
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// convertTimeout bounds how long converting a document to markdown may
+// take.
+const convertTimeout = 30 * time.Second
+
+// IsDocumentFile reports whether name is a markdown file, or has one of the
+// extensions formats maps to converters, such as ".rst".
+func IsDocumentFile(name string, formats map[string]string) bool {
+	_, ok := formats[strings.ToLower(filepath.Ext(name))]
+	return ok || IsMarkdownFile(name)
+}
+
+// isDocument reports whether the book lists the file called name.
+func (s scanner) isDocument(name string) bool {
+	return IsDocumentFile(name, s.formats)
+}
+
+// converter returns the command line that turns the file at path into
+// markdown, if it is in another format.
+func (s scanner) converter(path string) (string, bool) {
+	if IsMarkdownFile(path) {
+		return "", false
+	}
+	command, ok := s.formats[strings.ToLower(filepath.Ext(path))]
+	return command, ok
+}
+
+// convertDocument runs command, like an on-save hook, on raw, the content of
+// the file at path, given on stdin, and returns the markdown it prints.
+func convertDocument(command, path string, raw []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), convertTimeout)
+	defer cancel()
+	cmd := commandLine(ctx, command, path, filepath.Dir(path))
+	if cmd == nil {
+		return "", errors.New("empty command")
+	}
+	cmd.Stdin = bytes.NewReader(raw)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.New(cmp.Or(lastLine(stderr.String()), err.Error()))
+	}
+	return normalizeLineEndings(string(out)), nil
+}
+
+// convert replaces the Chapter's content with markdown converted from raw,
+// the file's content, when the file is in another format. If conversion
+// fails the file shows as it is.
+func (c *Chapter) convert(raw []byte) {
+	command, ok := c.ctx.scanner.converter(c.filePath)
+	if !ok {
+		return
+	}
+	c.converted = true
+	md, err := convertDocument(command, c.filePath, raw)
+	if err != nil {
+		c.statusText = "Conversion failed: " + err.Error()
+		return
+	}
+	c.content = md
+}
+
+// editConverted explains that a converted document is edited at its source.
+func (c *Chapter) editConverted() tea.Cmd {
+	c.statusText = "Converted from " + strings.TrimPrefix(filepath.Ext(c.filePath), ".") + ": E edits the source"
+	return clearStatusAfter(3*time.Second, clearStatusMsg{})
+}
@@ -1,15 +1,18 @@
 package model
 
 import (
-	"fmt"
+	"errors"
 	"os"
 	"strings"
 	"time"
 
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/textarea"
+	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+
+	"github.com/inkcheck/ink/internal/metrics"
 )
 
 // editorGradeDebounce is the delay before recalculating the FK grade after edits.
@@ -21,21 +24,57 @@ type clearEditorStatusMsg struct{}
 // editorGradeTickMsg triggers a debounced FK grade recalculation.
 type editorGradeTickMsg struct{}
 
+// editorAutosaveTickMsg fires once typing has paused for the autosave delay.
+// seq identifies the edit that scheduled it, so later edits postpone saving.
+type editorAutosaveTickMsg struct {
+	seq int
+}
+
 // Editor is the distraction-free markdown editor.
 type Editor struct {
-	textarea     textarea.Model
-	filePath     string
-	ctx          *ViewContext
-	saved        bool
-	err          error
-	savedContent string   // content at last save, for unsaved-change detection
-	prevContent  string   // content at last frame, for change detection
-	grade        string   // cached FK grade
-	gradeDirty   bool     // true when grade needs recalculation
-	zenMode      bool     // true hides all chrome (Alt+Z)
-	help         HelpPane // help pane at the bottom
-	statusText   string   // temporary status bar feedback text
-	confirmClose bool     // true when waiting for second esc/ctrl+w to discard unsaved changes
+	textarea      textarea.Model
+	filePath      string
+	ctx           *ViewContext
+	saved         bool
+	err           error
+	savedContent  string            // content at last save, for unsaved-change detection
+	prevContent   string            // content at last frame, for change detection
+	grade         string            // cached readability score
+	warning       string            // metrics thresholds the text goes beyond, refreshed with the grade
+	counts        textCounts        // cached counts, refreshed with the grade
+	countMode     countMode         // which count the status bar shows (Alt+W)
+	gradeDirty    bool              // true when grade needs recalculation
+	gradePending  bool              // true while a grade tick is scheduled
+	zenMode       bool              // true hides all chrome (Alt+Z)
+	focusMode     bool              // true dims all but the cursor's paragraph (Alt+F)
+	sentenceMarks bool              // true underlines long and hard sentences (Alt+L)
+	showStrip     bool              // true shows the metrics strip under the text (Alt+A)
+	strip         metrics.Result    // analysis behind the metrics strip, refreshed with the grade
+	help          HelpPane          // help pane at the bottom
+	statusText    string            // temporary status bar feedback text
+	hookStatus    string            // outcome of the on-save hooks, shown when there's no other status
+	confirmClose  bool              // true when waiting for second esc/ctrl+w to discard unsaved changes
+	confirmSave   bool              // true when waiting for second ctrl+s to save over another ink's copy
+	locked        bool              // true when this editor holds the document's lock
+	lockedBy      string            // the other ink that has the document open, if one does
+	editSeq       int               // counts edits, to tell whether an autosave tick is stale
+	vim           *vimState         // vim emulation, if enabled
+	snippets      map[string]string // snippet bodies by abbreviation
+	matter        *matterForm       // front matter form, while open
+	lint          *lintPanel        // prose checker diagnostics, while shown
+	issues        []lintIssue       // the prose checkers' last findings, counted in the status bar
+	rewrite       *rewriteOverlay   // suggested rewrite, while awaiting accept or reject
+	width         int               // width of the textarea, gutter included
+	encoding      fileEncoding      // how the document is stored, to save it the same way
+	bom           bool              // whether the file started with a byte order mark
+	crlf          bool              // true when the document's lines end in \r\n, to save them the same way
+	readOnly      bool              // true when the document can't be written, so edits are refused
+	backup        string            // unsaved content found on opening, until restored or discarded
+	backupTime    time.Time         // when that backup was written
+	backupPending bool              // true while a backup tick is scheduled
+	session       writingSession    // statistics of this stay in the editor
+	closedAt      time.Time         // when closing was asked for; set while the session summary shows
+	commitMsg     *textinput.Model  // commit message prompt, while open
 }
 
 // NewEditor creates a new Editor for the given file content.
@@ -43,7 +82,7 @@ func NewEditor(ctx *ViewContext, filePath string, content string) Editor {
 	ta := textarea.New()
 	ta.SetValue(content)
 	ta.ShowLineNumbers = true
-	ta.SetWidth(ctx.contentWidth())
+	ta.Prompt = asciiOr(lipgloss.ThickBorder().Left, "|") + " "
 	ta.SetHeight(editorTextareaHeight(ctx, 0))
 	ta.Focus()
 
@@ -59,10 +98,15 @@ func NewEditor(ctx *ViewContext, filePath string, content string) Editor {
 	ta.KeyMap.CharacterBackward = key.NewBinding(key.WithKeys("left"))
 	ta.KeyMap.TransposeCharacterBackward = key.NewBinding(key.WithKeys(""))
 	ta.KeyMap.DeleteWordBackward = key.NewBinding(key.WithKeys("alt+backspace"))
+	ta.KeyMap.DeleteWordForward = key.NewBinding(key.WithKeys("alt+delete"))
+	ta.KeyMap.WordBackward = key.NewBinding(key.WithKeys("alt+left"))
+	ta.KeyMap.WordForward = key.NewBinding(key.WithKeys("alt+right"))
+	ta.KeyMap.CapitalizeWordForward = key.NewBinding(key.WithKeys(""))
+	ta.KeyMap.LowercaseWordForward = key.NewBinding(key.WithKeys(""))
 
 	// Custom navigation shortcuts
 	ta.KeyMap.InputBegin = key.NewBinding(key.WithKeys("alt+<", "ctrl+home", "ctrl+t"))
-	ta.KeyMap.InputEnd = key.NewBinding(key.WithKeys("alt+>", "ctrl+end", "ctrl+g"))
+	ta.KeyMap.InputEnd = key.NewBinding(key.WithKeys("alt+>", "ctrl+end"))
 
 	dim := lipgloss.Color("240")
 	styles := ta.Styles()
@@ -71,20 +115,67 @@ func NewEditor(ctx *ViewContext, filePath string, content string) Editor {
 	styles.Focused.Prompt = lipgloss.NewStyle().Foreground(dim)
 	ta.SetStyles(styles)
 
-	return Editor{
+	var vim *vimState
+	if ctx.vimKeys {
+		vim = &vimState{}
+	}
+
+	e := Editor{
+		vim:          vim,
+		snippets:     loadSnippets(ctx.bookDir),
 		textarea:     ta,
 		filePath:     filePath,
 		ctx:          ctx,
 		saved:        true,
 		savedContent: content,
 		prevContent:  content,
-		grade:        fleschKincaidGrade(content),
-		help:         NewHelpPane(editorHelpEntries),
+		help:         NewHelpPane(ctx.keys.help(editorKeys, editorHelpEntries)),
+	}
+	if raw, err := os.ReadFile(filePath); err == nil {
+		var text string
+		text, e.encoding, e.bom = decodeText(raw)
+		e.crlf = usesCRLF(text)
+	}
+	e.readOnly = readOnlyFile(filePath)
+	e.grade, e.counts = analyzeText(content, ctx.readabilityScore())
+	e.warning = ctx.thresholdWarning(content)
+	e.session = newWritingSession(countWords(content), time.Now())
+	e.setWidth()
+	e.findBackup(content)
+	return e
+}
+
+// setWidth sizes the textarea so its text wraps at the wrap width (-wrap,
+// or the content width when unset, or the zen width in zen mode) with the gutter beside it, as far as the
+// terminal allows.
+func (e *Editor) setWidth() {
+	wrap := e.ctx.maxWidth
+	if e.ctx.wrapWidth > 0 {
+		wrap = e.ctx.wrapWidth
 	}
+	if e.zenMode && e.ctx.zenWidth > 0 {
+		wrap = e.ctx.zenWidth
+	}
+	e.textarea.SetWidth(e.ctx.width)
+	gutter := e.ctx.width - e.textarea.Width()
+	e.width = min(wrap+gutter, e.ctx.width)
+	e.textarea.SetWidth(e.width)
+}
+
+// setHeight sizes the textarea to the space left by the chrome, the help
+// pane and, in zen mode, the zen padding above and below.
+func (e *Editor) setHeight() {
+	height := editorTextareaHeight(e.ctx, e.help.HeightIfVisible())
+	if e.zenMode {
+		height -= 2 * e.ctx.zenPadding
+	} else if e.showStrip {
+		height--
+	}
+	e.textarea.SetHeight(max(height, 1))
 }
 
 func (e Editor) Init() tea.Cmd {
-	return textarea.Blink
+	return tea.Batch(textarea.Blink, e.lintInBackground())
 }
 
 func (e *Editor) reload() {
@@ -96,14 +187,19 @@ func (e *Editor) reload() {
 	row := e.textarea.Line()
 	col := e.textarea.LineInfo().CharOffset
 
-	content := normalizeLineEndings(string(raw))
+	content, enc, bom := decodeText(raw)
+	e.encoding, e.bom, e.crlf = enc, bom, usesCRLF(content)
+	e.readOnly = readOnlyFile(e.filePath)
+	content = normalizeLineEndings(content)
 	e.textarea.SetValue(content)
 	e.savedContent = content
 	e.prevContent = content
 	e.saved = true
 	e.err = nil
-	e.grade = fleschKincaidGrade(content)
+	e.grade, e.counts = analyzeText(content, e.ctx.readabilityScore())
+	e.warning = e.ctx.thresholdWarning(content)
 	e.gradeDirty = false
+	e.session.words = countWords(content)
 
 	// Restore cursor position: reset to beginning first (SetValue leaves the
 	// cursor at the end and the viewport in a state where CursorUp alone
@@ -122,42 +218,188 @@ func (e *Editor) reload() {
 	e.textarea.SetCursorColumn(col)
 }
 
+// lines returns the editor content split into lines.
+func (e Editor) lines() [][]rune {
+	var lines [][]rune
+	for _, l := range strings.Split(e.textarea.Value(), "\n") {
+		lines = append(lines, []rune(l))
+	}
+	return lines
+}
+
+// cursor returns the cursor's line and its rune offset in that line.
+func (e Editor) cursor() (row, col int) {
+	return e.textarea.Line(), e.column()
+}
+
+// column returns the cursor's rune offset in its line.
+func (e Editor) column() int {
+	info := e.textarea.LineInfo()
+	return info.StartColumn + info.ColumnOffset
+}
+
+// moveTo puts the cursor at rune col of line row. The textarea moves a row
+// at a time, so it scrolls no further than it has to.
+func (e *Editor) moveTo(row, col int) {
+	row = min(max(row, 0), e.textarea.LineCount()-1)
+	for e.textarea.Line() < row {
+		e.textarea.CursorDown()
+	}
+	for e.textarea.Line() > row {
+		e.textarea.CursorUp()
+	}
+	e.textarea.SetCursorColumn(col)
+}
+
+// setLines replaces the content and puts the cursor at (row, col), keeping
+// the view scrolled where it was.
+func (e *Editor) setLines(lines [][]rune, row, col int) {
+	top := e.textarea.ScrollYOffset()
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = string(l)
+	}
+	e.textarea.SetValue(strings.Join(text, "\n"))
+	// SetValue leaves the cursor at the end; walk down from the top until
+	// the old first row is at the top again.
+	e.textarea.MoveToBegin()
+	for e.textarea.ScrollYOffset() < top {
+		line, rowOffset := e.textarea.Line(), e.textarea.LineInfo().RowOffset
+		e.textarea.CursorDown()
+		if e.textarea.Line() == line && e.textarea.LineInfo().RowOffset == rowOffset {
+			break
+		}
+	}
+	e.moveTo(row, col)
+}
+
 func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		e.textarea.SetWidth(e.ctx.contentWidth())
-		e.textarea.SetHeight(editorTextareaHeight(e.ctx, e.help.HeightIfVisible()))
+		e.setWidth()
+		e.setHeight()
 	case clearEditorStatusMsg:
 		e.statusText = ""
 		return e, nil
+	case clearHookStatusMsg:
+		e.hookStatus = ""
+		return e, nil
+	case saveHooksDoneMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e, e.hooksDone(msg)
+	case lintDoneMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e, e.lintDone(msg)
+	case commitDoneMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e, e.commitDone(msg)
+	case pluginDoneMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e.pluginDone(msg)
+	case rewriteDoneMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e, e.rewriteDone(msg)
 	case editorGradeTickMsg:
+		e.gradePending = false
 		if e.gradeDirty {
-			e.grade = fleschKincaidGrade(e.textarea.Value())
+			e.grade, e.counts = analyzeText(e.textarea.Value(), e.ctx.readabilityScore())
+			e.warning = e.ctx.thresholdWarning(e.textarea.Value())
+			e.refreshStrip()
 			e.gradeDirty = false
 		}
 		return e, nil
+	case editorBackupTickMsg:
+		e.writeBackup()
+		return e, nil
+	case editorAutosaveTickMsg:
+		if msg.seq != e.editSeq || e.saved || e.lockedBy != "" {
+			return e, nil
+		}
+		return e, e.save("Autosaved")
+	case tea.BlurMsg:
+		if !e.ctx.autosaveOnBlur || e.saved || e.lockedBy != "" {
+			return e, nil
+		}
+		return e, e.save("Autosaved")
+	case tea.PasteMsg:
+		return e.paste(msg)
+	case pasteHTMLMsg:
+		if msg.path != e.filePath {
+			return e, nil
+		}
+		return e.pastedHTML(msg)
 	case tea.KeyMsg:
+		if !e.closedAt.IsZero() {
+			return e, e.close()
+		}
+		if e.matter != nil {
+			cmd := e.updateMatter(msg)
+			return e.contentChanged(cmd)
+		}
+		if e.commitMsg != nil {
+			return e, e.updateCommit(msg)
+		}
 		k := msg.String()
+		if e.lint != nil {
+			e.updateLint(k)
+			return e, nil
+		}
+		if e.rewrite != nil {
+			return e.updateRewrite(msg)
+		}
+		if e.backup != "" {
+			if e.updateBackupPrompt(k) {
+				return e.contentChanged(nil)
+			}
+			return e, nil
+		}
+		if line, ok := e.ctx.plugins[k]; ok {
+			return e, e.startPlugin(k, line)
+		}
+		k = e.ctx.keys.resolve(editorKeys, k)
 		// Reset close confirmation on any key that isn't esc/ctrl+w
 		if k != "esc" && k != "ctrl+w" {
 			e.confirmClose = false
 		}
+		if k != "ctrl+s" {
+			e.confirmSave = false
+		}
+		if e.vim != nil {
+			if e.updateVim(msg) {
+				return e.contentChanged(nil)
+			}
+		}
 		switch k {
 		case "ctrl+s":
-			content := e.textarea.Value()
-			err := os.WriteFile(e.filePath, []byte(content), 0644)
-			if err != nil {
-				e.err = err
+			if e.lockedBy != "" && !e.confirmSave {
+				e.confirmSave = true
 				return e, nil
 			}
-			e.saved = true
-			e.err = nil
-			e.savedContent = content
-			e.statusText = "Saved"
-			return e, tea.Batch(
-				func() tea.Msg { return FileSavedMsg{} },
-				clearStatusAfter(2*time.Second, clearEditorStatusMsg{}),
-			)
+			e.confirmSave = false
+			var reflowed tea.Cmd
+			if e.ctx.wrapOnSave > 0 {
+				e.reflow()
+				e, reflowed = e.contentChanged(nil)
+			}
+			cmd := e.save("Saved")
+			if cmd != nil && len(e.ctx.saveHooks) > 0 {
+				e.hookStatus = "Running hooks…"
+				cmd = tea.Batch(cmd, runSaveHooks(e.ctx.saveHooks, e.filePath))
+			}
+			if cmd != nil {
+				cmd = tea.Batch(cmd, e.lintInBackground())
+			}
+			return e, tea.Batch(reflowed, cmd)
 		case "ctrl+f":
 			// Loop-based scroll: the textarea widget does not expose a half-page
 			// scroll method, so we move the cursor one line at a time.
@@ -174,13 +416,60 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		case "ctrl+r":
 			e.reload()
 			return e, nil
+		case "ctrl+g":
+			return e, e.startCommit()
 		case "alt+?", "alt+/":
 			e.help.Toggle()
-			e.textarea.SetHeight(editorTextareaHeight(e.ctx, e.help.HeightIfVisible()))
+			e.setHeight()
 			return e, nil
 		case "alt+m":
 			toggleMouse(e.ctx)
 			return e, nil
+		case "alt+b":
+			e.toggleWrap("**")
+			return e.contentChanged(nil)
+		case "alt+i":
+			e.toggleWrap("*")
+			return e.contentChanged(nil)
+		case "alt+c", "alt+`":
+			e.toggleWrap("`")
+			return e.contentChanged(nil)
+		case "alt+k":
+			e.insertLink()
+			return e.contentChanged(nil)
+		case "alt+h":
+			e.cycleHeading()
+			return e.contentChanged(nil)
+		case "alt+d", "alt+t", "alt+T":
+			e.insertDate(k)
+			return e.contentChanged(nil)
+		case "alt+s":
+			cmd := e.insertSnippet()
+			return e.contentChanged(cmd)
+		case "alt+e":
+			return e, e.startLint()
+		case "alt+r":
+			return e, e.startRewrite()
+		case "alt+w":
+			e.countMode = (e.countMode + 1) % countModes
+			return e, nil
+		case "alt+l":
+			e.sentenceMarks = !e.sentenceMarks
+			return e, nil
+		case "alt+o":
+			return e.openExternal()
+		case "alt+f":
+			e.focusMode = !e.focusMode
+			return e, nil
+		case "alt+a":
+			e.toggleStrip()
+			return e, nil
+		case "alt+g":
+			e.statusText = e.ctx.cycleReadability()
+			e.grade, e.counts = analyzeText(e.textarea.Value(), e.ctx.readabilityScore())
+			return e, clearStatusAfter(2*time.Second, clearEditorStatusMsg{})
+		case "ctrl+m", "alt+p":
+			return e, e.openMatter()
 		case "alt+z":
 			e.zenMode = !e.zenMode
 			if e.zenMode {
@@ -189,16 +478,18 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 				e.textarea.SetPromptFunc(editorGutterWidth, func(textarea.PromptInfo) string {
 					return strings.Repeat(" ", editorGutterWidth)
 				})
-				e.textarea.SetWidth(e.ctx.contentWidth())
+				e.setWidth()
+				e.setHeight()
 			} else {
 				e.textarea.ShowLineNumbers = true
 				e.textarea.SetPromptFunc(0, nil)
-				e.textarea.Prompt = lipgloss.ThickBorder().Left + " "
+				e.textarea.Prompt = asciiOr(lipgloss.ThickBorder().Left, "|") + " "
 				dim := lipgloss.Color("240")
 				styles := e.textarea.Styles()
 				styles.Focused.Prompt = lipgloss.NewStyle().Foreground(dim)
 				e.textarea.SetStyles(styles)
-				e.textarea.SetWidth(e.ctx.contentWidth())
+				e.setWidth()
+				e.setHeight()
 			}
 			return e, nil
 		case "esc", "ctrl+w":
@@ -206,19 +497,50 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 				e.confirmClose = true
 				return e, nil
 			}
-			return e, func() tea.Msg {
-				return CloseEditorMsg{
-					FilePath: e.filePath,
-				}
+			e.removeBackup()
+			if e.session.edited() {
+				e.closedAt = time.Now()
+				e.logSession(e.closedAt)
+				return e, nil
 			}
+			return e, e.close()
+		}
+		// Outside insert mode only cursor keys reach the textarea.
+		if e.vim != nil && e.vim.mode != vimInsert && !vimPassthrough[k] {
+			return e, nil
 		}
 	}
 
 	var cmd tea.Cmd
 	e.textarea, cmd = e.textarea.Update(msg)
+	return e.contentChanged(cmd)
+}
+
+// close leaves the editor.
+func (e Editor) close() tea.Cmd {
+	return func() tea.Msg {
+		return CloseEditorMsg{
+			FilePath: e.filePath,
+		}
+	}
+}
 
+// contentChanged follows up an update that may have edited the content:
+// it tracks unsaved changes and schedules the grade, autosave and backup.
+// In a read-only document it undoes the edit instead.
+func (e Editor) contentChanged(cmd tea.Cmd) (Editor, tea.Cmd) {
 	// Detect content changes for unsaved-state and debounced grade
 	content := e.textarea.Value()
+	if e.readOnly && content != e.prevContent {
+		row, col := e.cursor()
+		var lines [][]rune
+		for _, l := range strings.Split(e.prevContent, "\n") {
+			lines = append(lines, []rune(l))
+		}
+		e.setLines(lines, row, col)
+		e.statusText = "Read-only file: edits are off (ctrl+r after changing its permissions)"
+		return e, tea.Batch(cmd, clearStatusAfter(3*time.Second, clearEditorStatusMsg{}))
+	}
 	if content != e.prevContent {
 		if content != e.savedContent {
 			e.saved = false
@@ -227,26 +549,98 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		}
 		e.gradeDirty = true
 		e.prevContent = content
-		gradeCmd := tea.Tick(editorGradeDebounce, func(time.Time) tea.Msg {
-			return editorGradeTickMsg{}
-		})
-		return e, tea.Batch(cmd, gradeCmd)
+		e.session.edit(countWords(content), time.Now())
+		// One grade tick at a time, however fast the edits come.
+		var gradeCmd tea.Cmd
+		if !e.gradePending {
+			e.gradePending = true
+			gradeCmd = tea.Tick(editorGradeDebounce, func(time.Time) tea.Msg {
+				return editorGradeTickMsg{}
+			})
+		}
+		e.editSeq++
+		var autosaveCmd tea.Cmd
+		if e.ctx.autosaveIdle > 0 {
+			seq := e.editSeq
+			autosaveCmd = tea.Tick(e.ctx.autosaveIdle, func(time.Time) tea.Msg {
+				return editorAutosaveTickMsg{seq: seq}
+			})
+		}
+		backupCmd := e.scheduleBackup()
+		return e, tea.Batch(cmd, gradeCmd, autosaveCmd, backupCmd)
 	}
 
 	return e, cmd
 }
 
+// save writes the content to disk and reports status ("Saved" or
+// "Autosaved") in the status bar.
+func (e *Editor) save(status string) tea.Cmd {
+	if e.readOnly {
+		e.err = errors.New("read-only file, not saved")
+		return nil
+	}
+	content := e.textarea.Value()
+	text := content
+	if e.crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	data, err := encodeText(text, e.encoding, e.bom)
+	if err == nil {
+		err = os.WriteFile(e.filePath, data, 0644)
+	}
+	if err != nil {
+		e.err = err
+		return nil
+	}
+	e.saved = true
+	e.err = nil
+	e.savedContent = content
+	e.statusText = status
+	e.removeBackup()
+	return tea.Batch(
+		func() tea.Msg { return FileSavedMsg{} },
+		clearStatusAfter(2*time.Second, clearEditorStatusMsg{}),
+	)
+}
+
 func (e Editor) statusBarView() string {
-	left := statusBarBookName(e.ctx.bookName) + statusBarFileName(e.filePath)
+	if e.commitMsg != nil {
+		return e.commitView()
+	}
+	left := statusBarBookName(e.ctx.bookName) + statusBarFileName(e.filePath) + statusBarWarning(e.warning, "")
 	var parts []string
 	if e.confirmClose {
 		parts = append(parts, "Unsaved! Press again to close")
+	} else if e.confirmSave {
+		parts = append(parts, "Also open in "+e.lockedBy+"! Press again to save")
+	} else if e.backup != "" {
+		parts = append(parts, e.backupPrompt())
 	} else if e.err != nil {
 		parts = append(parts, e.err.Error())
 	} else if e.statusText != "" {
 		parts = append(parts, e.statusText)
+	} else if e.hookStatus != "" {
+		parts = append(parts, e.hookStatus)
+	} else if e.lockedBy != "" {
+		parts = append(parts, "Also open in "+e.lockedBy)
+	}
+	if e.vim != nil {
+		parts = append(parts, e.vim.status())
+	}
+	if e.encoding != encUTF8 {
+		parts = append(parts, e.encoding.String())
+	}
+	if e.crlf {
+		parts = append(parts, "CRLF")
+	}
+	if e.readOnly {
+		parts = append(parts, "read-only")
+	}
+	if len(e.issues) > 0 {
+		parts = append(parts, issueCount(len(e.issues)))
 	}
-	parts = append(parts, fmt.Sprintf("%d words", countWords(e.prevContent)))
+	parts = append(parts, countsStatus(e.countMode, countWords(e.prevContent), e.counts))
 	if e.grade != "" {
 		parts = append(parts, e.grade)
 	}
@@ -257,9 +651,9 @@ func (e Editor) statusBarView() string {
 const editorGutterWidth = 6
 
 var editorHelpEntries = [][]helpEntry{
-	{{"^F", "½ page down"}, {"^B", "½ page up"}, {"^T", "go to top"}},
-	{{"^G", "go to end"}, {"^S", "save"}, {"^R", "reload"}},
-	{{"⌥Z", "zen mode"}, {"⌥M", "toggle mouse"}, {"⌥?", "toggle help"}},
+	{{"^F", "½ page down"}, {"^B", "½ page up"}, {"^T", "go to top"}, {"⌥+", "wider"}, {"⌥B", "bold"}, {"⌥I", "italic"}, {"⌥H", "heading level"}, {"⌥W", "cycle counts"}, {"⌥R", "rewrite"}},
+	{{"^G", "commit"}, {"^S", "save"}, {"^R", "reload"}, {"⌥-", "narrower"}, {"⌥C", "code"}, {"⌥K", "link"}, {"⌥S", "snippet"}, {"⌥E", "check prose"}, {"⌥O", "open in $EDITOR"}},
+	{{"⌥Z", "zen mode"}, {"⌥F", "focus mode"}, {"⌥L", "long sentences"}, {"⌥M", "toggle mouse"}, {"⌥?", "toggle help"}, {"⌥D/⌥T", "date/time"}, {"⌥P/^M", "front matter"}, {"⌥A", "live metrics"}, {"⌥G", "readability score"}},
 }
 
 func editorTextareaHeight(ctx *ViewContext, helpExtraHeight int) int {
@@ -267,7 +661,7 @@ func editorTextareaHeight(ctx *ViewContext, helpExtraHeight int) int {
 }
 
 func (e *Editor) renderContent() {
-	e.textarea.SetWidth(e.ctx.contentWidth())
+	e.setWidth()
 }
 
 func (e Editor) View() string {
@@ -276,6 +670,26 @@ func (e Editor) View() string {
 		logoStr = logo
 		statusBar = e.statusBarView()
 	}
-	content := centerContent(e.textarea.View(), e.ctx.width, e.ctx.maxWidth)
+	body := e.highlight(e.textarea.View())
+	if e.matter != nil {
+		body = e.matterView()
+	}
+	if e.lint != nil {
+		body = e.lintView()
+	}
+	if e.rewrite != nil {
+		body = e.rewriteView()
+	}
+	if !e.closedAt.IsZero() {
+		body = e.sessionView(e.closedAt)
+	}
+	if e.showStrip && !e.zenMode {
+		body += "\n" + e.stripView()
+	}
+	if e.zenMode && e.ctx.zenPadding > 0 {
+		pad := strings.Repeat("\n", e.ctx.zenPadding)
+		body = pad + body + pad
+	}
+	content := centerContent(body, e.ctx.width, e.width)
 	return layoutView(logoStr, content, statusBar, e.help.View(e.ctx.width))
 }
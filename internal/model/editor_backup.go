@@ -0,0 +1,110 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// backupDir holds copies of unsaved editor content, relative to the book
+// root (or to the document's directory when ink opened a single file).
+const backupDir = ".ink/backup"
+
+// backupInterval is how often unsaved editor content is backed up.
+const backupInterval = 30 * time.Second
+
+// editorBackupTickMsg asks the Editor to back up unsaved content.
+type editorBackupTickMsg struct{}
+
+// backupPath returns where the backup of the document at path is kept.
+// Documents outside root are kept by name alone.
+func backupPath(root, path string) string {
+	if root == "" {
+		root = filepath.Dir(path)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	return filepath.Join(root, backupDir, rel)
+}
+
+// scheduleBackup starts the backup timer unless it is already running.
+func (e *Editor) scheduleBackup() tea.Cmd {
+	if e.backupPending {
+		return nil
+	}
+	e.backupPending = true
+	return tea.Tick(backupInterval, func(time.Time) tea.Msg {
+		return editorBackupTickMsg{}
+	})
+}
+
+// writeBackup copies unsaved content to the backup directory. Backups are
+// a safety net, so failures are ignored.
+func (e *Editor) writeBackup() {
+	e.backupPending = false
+	if e.saved {
+		return
+	}
+	path := backupPath(e.ctx.bookDir, e.filePath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, []byte(e.textarea.Value()), 0644)
+}
+
+// removeBackup deletes the document's backup and any draft kept for it,
+// once the content is saved or deliberately discarded.
+func (e *Editor) removeBackup() {
+	_ = os.Remove(backupPath(e.ctx.bookDir, e.filePath))
+	e.ctx.state.ClearDraft(e.filePath)
+}
+
+// findBackup looks for unsaved content left by an earlier session: the
+// draft kept in the state when ink quit, or a backup left by an editor that
+// didn't exit cleanly, whichever is newer. Content that matches the file is
+// stale and removed.
+func (e *Editor) findBackup(content string) {
+	if d, ok := e.ctx.state.Draft(e.filePath); ok {
+		e.backup, e.backupTime = d.Content, d.Saved
+	}
+	path := backupPath(e.ctx.bookDir, e.filePath)
+	if info, err := os.Stat(path); err == nil && info.ModTime().After(e.backupTime) {
+		if data, err := os.ReadFile(path); err == nil {
+			e.backup, e.backupTime = string(data), info.ModTime()
+		}
+	}
+	if e.backup == content {
+		e.backup = ""
+		e.removeBackup()
+	}
+}
+
+// updateBackupPrompt answers the offer to restore unsaved content: alt+y
+// restores it, alt+n discards it. The answers can't be typed and the offer
+// is modal, swallowing every other key until it is answered, so text typed
+// into the prompt neither lands in the document nor answers it. It reports
+// whether the key was an answer.
+func (e *Editor) updateBackupPrompt(k string) bool {
+	switch k {
+	case "alt+y":
+		e.textarea.SetValue(e.backup)
+		e.textarea.MoveToBegin()
+		e.statusText = "Restored unsaved changes"
+	case "alt+n":
+		e.removeBackup()
+	default:
+		return false
+	}
+	e.backup = ""
+	return true
+}
+
+// backupPrompt is the status bar offer to restore unsaved content.
+func (e Editor) backupPrompt() string {
+	return "Unsaved changes from " + e.backupTime.Format("Jan 2 15:04") + ": alt+y restore, alt+n discard"
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+)
+
+// commitDoneMsg reports how committing path went.
+type commitDoneMsg struct {
+	path   string
+	output string // last line git printed
+	err    error
+}
+
+// startCommit opens the prompt for the message to commit the document with,
+// filled in with "Edit <file>". Unsaved changes have to be saved first, so
+// what is committed is what is on screen.
+func (e *Editor) startCommit() tea.Cmd {
+	if !e.saved {
+		e.statusText = "Save before committing"
+		return clearStatusAfter(2*time.Second, clearEditorStatusMsg{})
+	}
+	in := textinput.New()
+	in.Prompt = ""
+	in.SetValue("Edit " + filepath.Base(e.filePath))
+	in.CursorEnd()
+	e.commitMsg = &in
+	return in.Focus()
+}
+
+// updateCommit routes keys to the commit message prompt: enter commits,
+// esc cancels.
+func (e *Editor) updateCommit(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		message := strings.TrimSpace(e.commitMsg.Value())
+		e.commitMsg = nil
+		if message == "" {
+			return nil
+		}
+		e.statusText = "Committing…"
+		return gitCommit(e.filePath, message)
+	case "esc":
+		e.commitMsg = nil
+		return nil
+	}
+	var cmd tea.Cmd
+	*e.commitMsg, cmd = e.commitMsg.Update(msg)
+	return cmd
+}
+
+// gitCommit stages path and commits it, alone, with message in the
+// background. Other changes already staged stay staged.
+func gitCommit(path, message string) tea.Cmd {
+	return func() tea.Msg {
+		dir := filepath.Dir(path)
+		out, err := exec.Command("git", "-C", dir, "add", "--", path).CombinedOutput()
+		if err == nil {
+			out, err = exec.Command("git", "-C", dir, "commit", "-q", "-m", message, "--", path).CombinedOutput()
+		}
+		if err == nil {
+			out, err = exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").CombinedOutput()
+		}
+		return commitDoneMsg{path: path, output: lastLine(string(out)), err: err}
+	}
+}
+
+// commitDone reports the outcome of gitCommit in the status bar.
+func (e *Editor) commitDone(msg commitDoneMsg) tea.Cmd {
+	switch {
+	case msg.err != nil && msg.output != "":
+		e.statusText = "Commit failed: " + msg.output
+	case msg.err != nil:
+		e.statusText = "Commit failed: " + msg.err.Error()
+	default:
+		e.statusText = "Committed " + msg.output
+	}
+	return clearStatusAfter(4*time.Second, clearEditorStatusMsg{})
+}
+
+// commitView draws the commit message prompt in place of the status bar.
+func (e Editor) commitView() string {
+	label := statusBarPromptStyle.Render("Commit:")
+	input := statusBarInputStyle.Render(e.commitMsg.View())
+	hint := statusBarHintStyle.Render("enter commit | esc cancel")
+	return statusBarFill(label+input, hint, e.ctx.width)
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inkcheck/readability"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// countMode selects which count the editor status bar shows (Alt+W cycles).
+type countMode int
+
+const (
+	countWordsMode countMode = iota
+	countCharsMode
+	countSentencesMode
+	countParagraphsMode
+	countSentenceLengthMode
+	countModes
+)
+
+// textCounts are the counts beyond words the editor status bar can show.
+// They come from the same readability analysis as the grade.
+type textCounts struct {
+	chars      int // characters other than spaces
+	words      int // words as the readability analysis counts them
+	sentences  int
+	paragraphs int
+}
+
+// analyzeText returns the score of text under the readability formula s,
+// formatted for the status bar, and its counts, analyzing the text once.
+func analyzeText(text string, s readabilityScore) (string, textCounts) {
+	a := readability.NewAnalysis(text)
+	stats := a.Stats()
+	counts := textCounts{
+		chars:      stats.Chars,
+		words:      stats.Words,
+		sentences:  stats.Sentences,
+		paragraphs: countParagraphs(text),
+	}
+	return s.text(a), counts
+}
+
+// countParagraphs counts the runs of non-blank lines in text, leaving out
+// any front matter.
+func countParagraphs(text string) int {
+	text = text[frontmatter.Len([]byte(text)):]
+	n := 0
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			blank = true
+		} else if blank {
+			blank = false
+			n++
+		}
+	}
+	return n
+}
+
+// countsStatus formats the count the status bar shows in mode. words is the
+// live word count; the others are refreshed with the grade.
+func countsStatus(mode countMode, words int, c textCounts) string {
+	switch mode {
+	case countCharsMode:
+		return fmt.Sprintf("%d chars", c.chars)
+	case countSentencesMode:
+		return fmt.Sprintf("%d sentences", c.sentences)
+	case countParagraphsMode:
+		return fmt.Sprintf("%d paragraphs", c.paragraphs)
+	case countSentenceLengthMode:
+		if c.sentences == 0 {
+			return "0 words/sentence"
+		}
+		return fmt.Sprintf("%.1f words/sentence", float64(c.words)/float64(c.sentences))
+	}
+	return fmt.Sprintf("%d words", words)
+}
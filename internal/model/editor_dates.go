@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Default formats of the dates and times the editor inserts, in strftime
+// notation. The config file's date-format, time-format and timestamp-format
+// replace them.
+const (
+	defaultDateFormat      = "%Y-%m-%d"
+	defaultTimeFormat      = "%H:%M"
+	defaultTimestampFormat = "%Y-%m-%dT%H:%M:%S%:z"
+)
+
+// strftimeLayouts maps strftime directives to Go time layouts.
+var strftimeLayouts = map[string]string{
+	"Y": "2006", "y": "06", "m": "01", "d": "02", "e": "_2", "j": "002",
+	"H": "15", "I": "03", "M": "04", "S": "05", "p": "PM",
+	"b": "Jan", "B": "January", "a": "Mon", "A": "Monday",
+	"Z": "MST", "z": "-0700", ":z": "-07:00",
+}
+
+// strftime formats t as described by format: %Y, %m, %d, %H, %M and the
+// other common strftime directives, with %% for a percent sign. Other
+// characters are copied as they are.
+func strftime(format string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		d := format[i+1 : i+2]
+		if d == ":" && i+2 < len(format) {
+			d = format[i+1 : i+3]
+		}
+		switch layout, ok := strftimeLayouts[d]; {
+		case d == "%":
+			b.WriteByte('%')
+		case ok:
+			b.WriteString(t.Format(layout))
+		default:
+			b.WriteString("%" + d)
+		}
+		i += len(d)
+	}
+	return b.String()
+}
+
+// dateFormats returns the configured date, time and timestamp formats.
+func (ctx *ViewContext) dateFormats() (date, clock, stamp string) {
+	date, clock, stamp = ctx.dateFormat, ctx.timeFormat, ctx.timestampFormat
+	if date == "" {
+		date = defaultDateFormat
+	}
+	if clock == "" {
+		clock = defaultTimeFormat
+	}
+	if stamp == "" {
+		stamp = defaultTimestampFormat
+	}
+	return date, clock, stamp
+}
+
+// insertDate inserts the current date, time or timestamp at the cursor.
+func (e *Editor) insertDate(k string) {
+	date, clock, stamp := e.ctx.dateFormats()
+	format := map[string]string{"alt+d": date, "alt+t": clock, "alt+T": stamp}[k]
+	e.textarea.InsertString(strftime(format, time.Now()))
+}
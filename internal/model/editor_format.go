@@ -0,0 +1,104 @@
+package model
+
+import "strings"
+
+// wordAt returns the bounds [start, end) of the word around col in line,
+// or an empty range at col when the cursor isn't on or just after a word.
+func wordAt(line []rune, col int) (start, end int) {
+	col = min(col, len(line))
+	start, end = col, col
+	for start > 0 && wordClass(line[start-1]) == 1 {
+		start--
+	}
+	for end < len(line) && wordClass(line[end]) == 1 {
+		end++
+	}
+	return start, end
+}
+
+// toggleWrap surrounds the word under the cursor with marker (e.g. "**"),
+// or removes the marker if it's already there. With no word it inserts a
+// pair of markers with the cursor between them. In vim's visual mode it
+// wraps the selected lines.
+func (e *Editor) toggleWrap(marker string) {
+	lines := e.lines()
+	row, col := e.cursor()
+	m := []rune(marker)
+	if e.vim != nil && e.vim.mode == vimVisual {
+		lo, hi := min(e.vim.anchor, row), max(e.vim.anchor, row)
+		e.vim.mode = vimNormal
+		lines[hi] = append(lines[hi], m...)
+		start := firstNonBlank(lines[lo])
+		lines[lo] = insertRunes(lines[lo], start, m)
+		e.setLines(lines, lo, start)
+		return
+	}
+
+	line := lines[row]
+	start, end := wordAt(line, col)
+	// Count the marker characters on each side, so italic (*) doesn't
+	// unwrap half of a bold (**) word, but does unwrap bold italic (***).
+	left, right := 0, 0
+	for start-left > 0 && line[start-left-1] == m[0] {
+		left++
+	}
+	for end+right < len(line) && line[end+right] == m[0] {
+		right++
+	}
+	if start < end && left == right && (left == len(m) || left == 3) {
+		line = append(line[:end:end], line[end+len(m):]...)
+		line = append(line[:start-len(m):start-len(m)], line[start:]...)
+		lines[row] = line
+		e.setLines(lines, row, col-len(m))
+		return
+	}
+	line = insertRunes(line, end, m)
+	line = insertRunes(line, start, m)
+	lines[row] = line
+	e.setLines(lines, row, col+len(m))
+}
+
+// insertLink turns the word under the cursor into the text of a markdown
+// link and puts the cursor where the destination goes, or inserts an empty
+// link with the cursor on its text.
+func (e *Editor) insertLink() {
+	lines := e.lines()
+	row, col := e.cursor()
+	start, end := wordAt(lines[row], col)
+	lines[row] = insertRunes(lines[row], end, []rune("]()"))
+	lines[row] = insertRunes(lines[row], start, []rune("["))
+	if start == end {
+		e.setLines(lines, row, start+1)
+	} else {
+		e.setLines(lines, row, end+3)
+	}
+}
+
+// cycleHeading steps the current line through heading levels: plain text,
+// #, ##, and so on to ######, then back to plain text.
+func (e *Editor) cycleHeading() {
+	lines := e.lines()
+	row, col := e.cursor()
+	line := string(lines[row])
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	text := strings.TrimLeft(line[level:], " ")
+	if level > 0 && len(text) == len(line[level:]) && text != "" {
+		// "#tag" is not a heading.
+		level, text = 0, line
+	}
+	prefix := ""
+	if level < 6 {
+		prefix = strings.Repeat("#", level+1) + " "
+	}
+	old := len([]rune(line)) - len([]rune(text))
+	lines[row] = []rune(prefix + text)
+	e.setLines(lines, row, max(col-old, 0)+len(prefix))
+}
+
+// insertRunes returns line with ins inserted at i.
+func insertRunes(line []rune, i int, ins []rune) []rune {
+	out := make([]rune, 0, len(line)+len(ins))
+	out = append(out, line[:i]...)
+	out = append(out, ins...)
+	return append(out, line[i:]...)
+}
@@ -0,0 +1,233 @@
+package model
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// matterKeys are the fields the front matter form always offers, in order.
+// Other keys in the document follow them.
+var matterKeys = []string{"title", "date", "tags", "status"}
+
+// matterHintStyle styles the key column and the hint line of the form.
+var matterHintStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+// matterField is one row of the front matter form. Lists are edited as
+// comma-separated text.
+type matterField struct {
+	key   string
+	value string
+	list  bool // value is a list
+	block bool // list is written as "- item" lines rather than [a, b]
+}
+
+// matterForm edits a document's front matter field by field. The row after
+// the last field adds a new one, typed as "key: value".
+type matterForm struct {
+	fields []matterField
+	cursor int
+	input  textinput.Model
+}
+
+// openMatter opens the front matter form on the editor's content.
+func (e *Editor) openMatter() tea.Cmd {
+	m, _ := frontmatter.Parse([]byte(e.textarea.Value()))
+	form := &matterForm{input: textinput.New()}
+	form.input.Prompt = ""
+	field := func(key string) matterField {
+		f := matterField{key: key, list: strings.EqualFold(key, "tags")}
+		for _, mf := range m.Fields {
+			if !strings.EqualFold(mf.Key, key) {
+				continue
+			}
+			f.key = mf.Key
+			f.block = len(mf.Items) > 0
+			f.list = f.list || f.block || strings.HasPrefix(mf.Value, "[") && strings.HasSuffix(mf.Value, "]")
+			f.value = mf.Value
+			if f.list {
+				f.value = strings.Join(m.List(key), ", ")
+			}
+		}
+		return f
+	}
+	for _, key := range matterKeys {
+		form.fields = append(form.fields, field(key))
+	}
+	for _, mf := range m.Fields {
+		if !isMatterKey(mf.Key) {
+			form.fields = append(form.fields, field(mf.Key))
+		}
+	}
+	form.input.SetWidth(max(e.width-form.keyWidth()-2, 10))
+	e.matter = form
+	form.load()
+	return form.input.Focus()
+}
+
+// isMatterKey reports whether key is one of the fields the form always offers.
+func isMatterKey(key string) bool {
+	for _, k := range matterKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyWidth is the width of the form's key column.
+func (f *matterForm) keyWidth() int {
+	w := len("status")
+	for _, field := range f.fields {
+		w = max(w, ansi.StringWidth(field.key))
+	}
+	return w + 1
+}
+
+// load puts the value of the selected row in the input.
+func (f *matterForm) load() {
+	f.input.Placeholder = ""
+	if f.cursor < len(f.fields) {
+		f.input.SetValue(f.fields[f.cursor].value)
+	} else {
+		f.input.SetValue("")
+		f.input.Placeholder = "key: value"
+	}
+	f.input.CursorEnd()
+}
+
+// commit stores the input in the selected row. On the last row it adds the
+// typed "key: value" as a new field.
+func (f *matterForm) commit() {
+	value := strings.TrimSpace(f.input.Value())
+	if f.cursor < len(f.fields) {
+		f.fields[f.cursor].value = value
+		return
+	}
+	key, value, ok := strings.Cut(value, ":")
+	if key = strings.TrimSpace(key); !ok || key == "" {
+		return
+	}
+	f.fields = append(f.fields, matterField{key: key, value: strings.TrimSpace(value)})
+	f.cursor++
+}
+
+// updateMatter handles a key press while the form is open.
+func (e *Editor) updateMatter(msg tea.KeyMsg) tea.Cmd {
+	f := e.matter
+	switch msg.String() {
+	case "esc":
+		e.matter = nil
+		return nil
+	case "enter":
+		f.commit()
+		e.applyMatter()
+		e.matter = nil
+		return nil
+	case "tab", "down":
+		f.commit()
+		f.cursor = (f.cursor + 1) % (len(f.fields) + 1)
+		f.load()
+		return nil
+	case "shift+tab", "up":
+		f.commit()
+		f.cursor = (f.cursor + len(f.fields)) % (len(f.fields) + 1)
+		f.load()
+		return nil
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return cmd
+}
+
+// applyMatter writes the form's fields back as the document's front matter
+// block, replacing the old one. Empty fields are left out.
+func (e *Editor) applyMatter() {
+	var m frontmatter.Matter
+	for _, f := range e.matter.fields {
+		if f.value == "" {
+			continue
+		}
+		if !f.list {
+			m.Fields = append(m.Fields, frontmatter.Field{Key: f.key, Value: f.value})
+			continue
+		}
+		var items []string
+		for _, item := range strings.Split(f.value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		if f.block {
+			m.Fields = append(m.Fields, frontmatter.Field{Key: f.key, Items: items})
+			continue
+		}
+		for i, item := range items {
+			items[i] = frontmatter.Quote(item)
+		}
+		m.Fields = append(m.Fields, frontmatter.Field{Key: f.key, Value: "[" + strings.Join(items, ", ") + "]"})
+	}
+
+	content := e.textarea.Value()
+	n := frontmatter.Len([]byte(content))
+	old, body := content[:n], content[n:]
+	block := ""
+	if len(m.Fields) > 0 {
+		block = string(frontmatter.Format(m))
+		if n == 0 {
+			body = "\n" + body
+		}
+	} else {
+		body = strings.TrimPrefix(body, "\n")
+	}
+	if block+body == content {
+		return
+	}
+
+	// The cursor keeps its place in the body, or goes to the top if it was
+	// in the old block.
+	row, col := e.cursor()
+	if oldRows := strings.Count(old, "\n"); row < oldRows {
+		row, col = 0, 0
+	} else {
+		row += strings.Count(block+body, "\n") - strings.Count(content, "\n")
+	}
+	var lines [][]rune
+	for _, l := range strings.Split(block+body, "\n") {
+		lines = append(lines, []rune(l))
+	}
+	e.setLines(lines, max(row, 0), col)
+}
+
+// matterView draws the form in place of the textarea.
+func (e Editor) matterView() string {
+	f := e.matter
+	keyW := f.keyWidth()
+	width := e.width
+	lines := []string{tocTitleStyle.Render("Front matter"), ""}
+	for i := 0; i <= len(f.fields); i++ {
+		label, value := "+", matterHintStyle.Render("add a field")
+		if i < len(f.fields) {
+			label, value = f.fields[i].key, f.fields[i].value
+		}
+		if i == f.cursor {
+			value = f.input.View()
+			label = tocCursorStyle.Render(label)
+		} else {
+			label = matterHintStyle.Render(label)
+		}
+		pad := strings.Repeat(" ", max(keyW-lipgloss.Width(label), 1))
+		lines = append(lines, ansi.Truncate(label+pad+" "+value, width, "…"))
+	}
+	lines = append(lines, "", matterHintStyle.Render("tab next field · enter apply · esc cancel · lists are comma separated"))
+	height := e.textarea.Height()
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:min(len(lines), max(height, 1))], "\n")
+}
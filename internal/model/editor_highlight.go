@@ -0,0 +1,405 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// mdClass is the role a character plays in the markdown syntax, for
+// highlighting in the editor.
+type mdClass uint8
+
+const (
+	mdPlain    mdClass = iota
+	mdMarker           // syntax punctuation: #, *, _, `, [, ](, ), >, list bullets, fences
+	mdHeading          // heading text
+	mdStrong           // text between ** or __
+	mdEmphasis         // text between * or _
+	mdCode             // code span or fenced code
+	mdLink             // link text
+	mdURL              // link destination
+)
+
+// mdStyles styles each class of markdown syntax in the editor. The colors
+// follow the rendered view's.
+var mdStyles = [...]lipgloss.Style{
+	mdPlain:    lipgloss.NewStyle(),
+	mdMarker:   lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	mdHeading:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170")),
+	mdStrong:   lipgloss.NewStyle().Bold(true),
+	mdEmphasis: lipgloss.NewStyle().Italic(true),
+	mdCode:     lipgloss.NewStyle().Foreground(lipgloss.Color("213")),
+	mdLink:     lipgloss.NewStyle().Foreground(lipgloss.Color("87")).Underline(true),
+	mdURL:      lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Underline(true),
+}
+
+// isFence reports whether line opens or closes a fenced code block.
+func isFence(line []rune) bool {
+	s := strings.TrimLeft(string(line), " ")
+	return len(string(line))-len(s) < 4 && (strings.HasPrefix(s, "```") || strings.HasPrefix(s, "~~~"))
+}
+
+// classifyMarkdown assigns a class to each rune of a source line. inFence
+// says whether the line is inside a fenced code block; the result says
+// whether the next line is.
+func classifyMarkdown(line []rune, inFence bool) ([]mdClass, bool) {
+	classes := make([]mdClass, len(line))
+	if isFence(line) {
+		fill(classes, 0, len(line), mdMarker)
+		return classes, !inFence
+	}
+	if inFence {
+		fill(classes, 0, len(line), mdCode)
+		return classes, true
+	}
+
+	i := 0
+	for i < len(line) && i < 3 && line[i] == ' ' {
+		i++
+	}
+	// Headings: up to six #s and a space.
+	j := i
+	for j < len(line) && line[j] == '#' {
+		j++
+	}
+	if j > i && j-i <= 6 && (j == len(line) || line[j] == ' ') {
+		fill(classes, i, j, mdMarker)
+		fill(classes, j, len(line), mdHeading)
+		classifyInline(line, classes, j)
+		return classes, false
+	}
+	// Block quote and list markers.
+	for {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+		if line[i] == '>' {
+			classes[i] = mdMarker
+			i++
+			continue
+		}
+		if strings.ContainsRune("-*+", line[i]) && i+1 < len(line) && line[i+1] == ' ' {
+			classes[i] = mdMarker
+			i += 2
+			continue
+		}
+		n := i
+		for n < len(line) && line[n] >= '0' && line[n] <= '9' {
+			n++
+		}
+		if n > i && n+1 < len(line) && (line[n] == '.' || line[n] == ')') && line[n+1] == ' ' {
+			fill(classes, i, n+1, mdMarker)
+			i = n + 2
+			continue
+		}
+		break
+	}
+	classifyInline(line, classes, i)
+	return classes, false
+}
+
+// classifyInline marks code spans, links and emphasis in line from start
+// on. Text keeps the class it already has (e.g. heading) unless it is
+// inside one of them.
+func classifyInline(line []rune, classes []mdClass, start int) {
+	for i := start; i < len(line); {
+		switch r := line[i]; {
+		case r == '\\' && i+1 < len(line):
+			classes[i] = mdMarker
+			i += 2
+		case r == '`':
+			n := runLength(line, i)
+			end := findRun(line, i+n, '`', n, false)
+			if end < 0 {
+				i += n
+				continue
+			}
+			fill(classes, i, i+n, mdMarker)
+			fill(classes, i+n, end, mdCode)
+			fill(classes, end, end+n, mdMarker)
+			i = end + n
+		case r == '[' || (r == '!' && i+1 < len(line) && line[i+1] == '['):
+			open := i
+			if r == '!' {
+				open++
+			}
+			close := indexRune(line, open+1, ']')
+			if close < 0 || close+1 >= len(line) || line[close+1] != '(' {
+				i = open + 1
+				continue
+			}
+			end := indexRune(line, close+2, ')')
+			if end < 0 {
+				i = open + 1
+				continue
+			}
+			fill(classes, i, open+1, mdMarker)
+			fill(classes, open+1, close, mdLink)
+			classifyInline(line[:close], classes, open+1)
+			fill(classes, close, close+2, mdMarker)
+			fill(classes, close+2, end, mdURL)
+			classes[end] = mdMarker
+			i = end + 1
+		case r == '*' || r == '_':
+			n := min(runLength(line, i), 2)
+			if i+n >= len(line) || line[i+n] == ' ' || (r == '_' && i > 0 && isWordRune(line[i-1])) {
+				i += runLength(line, i)
+				continue
+			}
+			end := findRun(line, i+n, r, n, true)
+			if end < 0 {
+				i += runLength(line, i)
+				continue
+			}
+			class := mdEmphasis
+			if n == 2 {
+				class = mdStrong
+			}
+			fill(classes, i, i+n, mdMarker)
+			for k := i + n; k < end; k++ {
+				if classes[k] == mdPlain {
+					classes[k] = class
+				}
+			}
+			classifyInline(line[:end], classes, i+n)
+			fill(classes, end, end+n, mdMarker)
+			i = end + n
+		default:
+			i++
+		}
+	}
+}
+
+// fill sets classes[from:to] to class.
+func fill(classes []mdClass, from, to int, class mdClass) {
+	for k := from; k < to && k < len(classes); k++ {
+		classes[k] = class
+	}
+}
+
+// runLength counts the runes equal to line[i] starting at i.
+func runLength(line []rune, i int) int {
+	n := 0
+	for i+n < len(line) && line[i+n] == line[i] {
+		n++
+	}
+	return n
+}
+
+// findRun returns the index of the next run of exactly n copies of r at or
+// after from, or -1. With closing set, runs after a space are skipped, as
+// they can't close emphasis.
+func findRun(line []rune, from int, r rune, n int, closing bool) int {
+	for i := from; i < len(line); {
+		if line[i] != r {
+			i++
+			continue
+		}
+		m := runLength(line, i)
+		if m == n && (!closing || line[i-1] != ' ') {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// indexRune returns the index of r in line at or after from, or -1.
+func indexRune(line []rune, from int, r rune) int {
+	for i := from; i < len(line); i++ {
+		if line[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// isWordRune reports whether r is a letter or digit, where _ doesn't start
+// emphasis.
+func isWordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// highlight colors the markdown syntax in the textarea's rendered view. The
+// textarea can't style parts of a line itself, so each visible row is
+// rebuilt from its source text; the gutter, the cursor cell and the padding
+// are kept as the textarea drew them. In focus mode everything but the
+// cursor's paragraph is dimmed instead; with sentence marks on, sentences
+// that are too long or hard are underlined.
+func (e Editor) highlight(view string) string {
+	rows := strings.Split(view, "\n")
+	width := e.textarea.Width()
+	gutter := max(e.width-width, 0)
+	top := e.textarea.ScrollYOffset()
+	cursorRow, info := e.textarea.Line(), e.textarea.LineInfo()
+	styles := e.textarea.Styles().Focused
+
+	lines := strings.Split(e.textarea.Value(), "\n")
+	paraLo, paraHi := 0, len(lines)-1
+	if e.focusMode {
+		paraLo, paraHi = paragraphAround(lines, cursorRow)
+	}
+
+	maxWords, maxGrade := e.ctx.sentenceLimits()
+	var marks [][]bool // sentence marks for the lines of the current paragraph
+	markFrom, markTo := 0, -1
+
+	display := 0
+	inFence := false
+	for l, line := range lines {
+		if display >= top+len(rows) {
+			break
+		}
+		runes := []rune(line)
+		if e.sentenceMarks && l > markTo && strings.TrimSpace(line) != "" {
+			markFrom, markTo = paragraphAround(lines, l)
+			marks = nil
+			if !inFence && !isFence(runes) {
+				marks = sentenceMarks(lines[markFrom:markTo+1], maxWords, maxGrade)
+			}
+		}
+		var lineMarks []bool
+		if l >= markFrom && l <= markTo && marks != nil {
+			lineMarks = marks[l-markFrom]
+		}
+		var classes []mdClass
+		classes, inFence = classifyMarkdown(runes, inFence)
+		off := 0
+		for r, wrapped := range textareaWrap(runes, width) {
+			if i := display - top; i >= 0 && i < len(rows) {
+				base, cursor := styles.Text, -1
+				rowClasses, rowMarks := classes[min(off, len(classes)):], lineMarks[min(off, len(lineMarks)):]
+				if l < paraLo || l > paraHi {
+					base, rowClasses, rowMarks = focusDimStyle, nil, nil
+				} else if e.vimSelected(l) {
+					base = selectStyle
+				} else if l == cursorRow {
+					base = styles.CursorLine
+					if r == info.RowOffset {
+						cursor = info.ColumnOffset
+					}
+				}
+				rows[i] = highlightRow(rows[i], gutter, width, wrapped, rowClasses, rowMarks, cursor, base)
+			}
+			display++
+			off += len(wrapped)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// paragraphAround returns the first and last line of the paragraph holding
+// line row: the run of non-blank lines around it. A blank line is a
+// paragraph of its own.
+func paragraphAround(lines []string, row int) (lo, hi int) {
+	lo, hi = row, row
+	if strings.TrimSpace(lines[row]) == "" {
+		return lo, hi
+	}
+	for lo > 0 && strings.TrimSpace(lines[lo-1]) != "" {
+		lo--
+	}
+	for hi < len(lines)-1 && strings.TrimSpace(lines[hi+1]) != "" {
+		hi++
+	}
+	return lo, hi
+}
+
+// highlightRow redraws the text of one textarea row in its markdown styles.
+// orig is the row as the textarea drew it, starting with a gutter of the
+// given width; text is its source and classes the classes from its first
+// rune on, and marks those of its runes in marked sentences. cursor is the
+// index of the rune under the cursor, or -1.
+func highlightRow(orig string, gutter, width int, text []rune, classes []mdClass, marks []bool, cursor int, base lipgloss.Style) string {
+	if ansi.StringWidth(string(text)) > width {
+		text = []rune(strings.TrimSuffix(string(text), " "))
+	}
+	var b strings.Builder
+	b.WriteString(ansi.Cut(orig, 0, gutter))
+	x := gutter
+	styled := func(from, to int) {
+		for from < to {
+			class, marked := classAt(classes, from), from < len(marks) && marks[from]
+			end := from + 1
+			for end < to && classAt(classes, end) == class && (end < len(marks) && marks[end]) == marked {
+				end++
+			}
+			style := mdStyles[class].Inherit(base)
+			if marked {
+				style = hardSentenceStyle.Inherit(style)
+			}
+			b.WriteString(style.Render(string(text[from:end])))
+			x += ansi.StringWidth(string(text[from:end]))
+			from = end
+		}
+	}
+	if cursor >= 0 && cursor < len(text) {
+		styled(0, cursor)
+		w := max(ansi.StringWidth(string(text[cursor])), 1)
+		b.WriteString(ansi.Cut(orig, x, x+w))
+		x += w
+		styled(cursor+1, len(text))
+	} else {
+		styled(0, len(text))
+	}
+	b.WriteString(ansi.Cut(orig, x, ansi.StringWidth(orig)))
+	return b.String()
+}
+
+// classAt returns the class of rune i, treating runes past the end, like the
+// space textareaWrap adds, as plain.
+func classAt(classes []mdClass, i int) mdClass {
+	if i < len(classes) {
+		return classes[i]
+	}
+	return mdPlain
+}
+
+// textareaWrap splits a line into rows exactly as the textarea's word wrap
+// does, so highlighted rows line up with the ones it draws. The last row
+// gets a trailing space for the cursor to sit on.
+func textareaWrap(runes []rune, width int) [][]rune {
+	var (
+		lines  = [][]rune{{}}
+		word   = []rune{}
+		row    int
+		spaces int
+	)
+	for _, r := range runes {
+		if unicode.IsSpace(r) {
+			spaces++
+		} else {
+			word = append(word, r)
+		}
+		if spaces > 0 {
+			if ansi.StringWidth(string(lines[row]))+ansi.StringWidth(string(word))+spaces > width {
+				row++
+				lines = append(lines, []rune{})
+			}
+			lines[row] = append(lines[row], word...)
+			lines[row] = append(lines[row], []rune(strings.Repeat(" ", spaces))...)
+			spaces = 0
+			word = nil
+		} else if ansi.StringWidth(string(word))+ansi.StringWidth(string(word[len(word)-1])) > width {
+			if len(lines[row]) > 0 {
+				row++
+				lines = append(lines, []rune{})
+			}
+			lines[row] = append(lines[row], word...)
+			word = nil
+		}
+	}
+	if ansi.StringWidth(string(lines[row]))+ansi.StringWidth(string(word))+spaces >= width {
+		lines = append(lines, append(word, []rune(strings.Repeat(" ", spaces+1))...))
+	} else {
+		lines[row] = append(lines[row], word...)
+		lines[row] = append(lines[row], []rune(strings.Repeat(" ", spaces+1))...)
+	}
+	return lines
+}
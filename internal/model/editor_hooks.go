@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// saveHookTimeout bounds how long one on-save hook may run.
+const saveHookTimeout = time.Minute
+
+// saveHooksDoneMsg reports how the on-save hooks for path went.
+type saveHooksDoneMsg struct {
+	path   string
+	ran    int
+	failed string // the hook that failed, if one did
+	output string // last line the last hook printed
+	err    error
+}
+
+// clearHookStatusMsg clears the on-save hook report from the status bar.
+type clearHookStatusMsg struct{}
+
+// runSaveHooks runs the on-save hooks for path in the background, one after
+// another, stopping at the first that fails. Each hook is a command line;
+// {file} in it is replaced by the path, which is also set as $INK_FILE.
+// Hooks run in the document's directory.
+func runSaveHooks(hooks []string, path string) tea.Cmd {
+	return func() tea.Msg {
+		msg := saveHooksDoneMsg{path: path}
+		for _, hook := range hooks {
+			ctx, cancel := context.WithTimeout(context.Background(), saveHookTimeout)
+			cmd := commandLine(ctx, hook, path, filepath.Dir(path))
+			if cmd == nil {
+				cancel()
+				continue
+			}
+			out, err := cmd.CombinedOutput()
+			cancel()
+			msg.ran++
+			msg.output = lastLine(string(out))
+			if err != nil {
+				msg.failed, msg.err = hook, err
+				break
+			}
+		}
+		return msg
+	}
+}
+
+// commandLine builds the command for a configured command line run on the
+// file at path from dir. The line is split into words by shellWords and run
+// without a shell; "sh -c '...'" runs one. {file} in a word is replaced by
+// the path, which stays in that word however many spaces it holds, and is
+// also set as $INK_FILE. It returns nil for a blank line.
+func commandLine(ctx context.Context, line, path, dir string) *exec.Cmd {
+	parts := shellWords(line)
+	if len(parts) == 0 {
+		return nil
+	}
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(p, "{file}", path)
+	}
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "INK_FILE="+path)
+	return cmd
+}
+
+// shellWords splits a command line into words as a POSIX shell does, but
+// without expanding anything: words are separated by spaces and tabs, single
+// quotes keep everything up to the next one, double quotes keep everything
+// but a backslash before ", \, $ or `, and a backslash outside quotes keeps
+// the character after it. A quote left open runs to the end of the line.
+func shellWords(line string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(r)
+			}
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// lastLine returns the last non-blank line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// hooksDone reports the outcome of the on-save hooks in the status bar. A
+// hook that rewrote the file, like a formatter, has its changes loaded,
+// unless the content was edited meanwhile.
+func (e *Editor) hooksDone(msg saveHooksDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		detail := msg.output
+		if detail == "" {
+			detail = msg.err.Error()
+		}
+		e.hookStatus = "Hook failed: " + shellWords(msg.failed)[0] + ": " + detail
+	} else {
+		e.hookStatus = "Hooks done"
+		if msg.output != "" {
+			e.hookStatus += ": " + msg.output
+		}
+		if raw, err := os.ReadFile(e.filePath); err == nil && e.saved && documentText(raw) != e.savedContent {
+			e.reload()
+			e.hookStatus = "Hooks done, file reloaded"
+		}
+	}
+	return clearStatusAfter(5*time.Second, clearHookStatusMsg{})
+}
@@ -0,0 +1,262 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// lintTimeout bounds how long a prose checker may run.
+const lintTimeout = time.Minute
+
+// lintLine matches a diagnostic in the "file:line:column: message" form
+// that vale --output=line, proselint and most linters print, or in the
+// "file:line message" form markdownlint prints when it has no column.
+var lintLine = regexp.MustCompile(`^(.*?):(\d+)(?::(\d+))?:?\s*(.*)$`)
+
+// lintIssue is one diagnostic of a prose checker, at a rune position of
+// the document.
+type lintIssue struct {
+	row, col int
+	tool     string // the checker that found it, when several are configured
+	message  string
+}
+
+// label describes the issue in an issue list.
+func (issue lintIssue) label() string {
+	label := fmt.Sprintf("%5s ", fmt.Sprintf("%d:%d", issue.row+1, issue.col+1))
+	if issue.tool != "" {
+		label += issue.tool + ": "
+	}
+	return label + issue.message
+}
+
+// lintDoneMsg carries the prose checkers' diagnostics for path.
+type lintDoneMsg struct {
+	path   string
+	show   bool // list the issues, as asked for, rather than only count them
+	issues []lintIssue
+	output string // last line the failing checker printed
+	err    error  // the first checker that failed, if one did
+}
+
+// lintPanel lists the prose checkers' diagnostics; enter jumps to one.
+type lintPanel struct {
+	issues []lintIssue
+	cursor int
+}
+
+// move moves the cursor by delta issues, wrapping around the ends.
+func (p *lintPanel) move(delta int) {
+	p.cursor = ((p.cursor+delta)%len(p.issues) + len(p.issues)) % len(p.issues)
+}
+
+// runLint runs the prose checkers on content in the background, all at
+// once. The content is written to a temporary file named like the
+// document, so the checkers see the buffer rather than what was last saved,
+// and they run in the document's directory, where they find their own
+// configuration.
+func runLint(commands []string, path, content string, show bool) tea.Cmd {
+	return func() tea.Msg {
+		msg := lintDoneMsg{path: path, show: show}
+		dir, err := os.MkdirTemp("", "ink-lint")
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		defer os.RemoveAll(dir)
+		tmp := filepath.Join(dir, filepath.Base(path))
+		if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+			msg.err = err
+			return msg
+		}
+		results := make([]lintDoneMsg, len(commands))
+		var wg sync.WaitGroup
+		for i, command := range commands {
+			tool := ""
+			if len(commands) > 1 {
+				tool = checkerName(command)
+			}
+			wg.Go(func() {
+				results[i] = runChecker(command, tool, tmp, filepath.Dir(path))
+			})
+		}
+		wg.Wait()
+		for _, r := range results {
+			msg.issues = append(msg.issues, r.issues...)
+			if r.err != nil && msg.err == nil {
+				msg.output, msg.err = r.output, r.err
+			}
+		}
+		sortIssues(msg.issues)
+		return msg
+	}
+}
+
+// runChecker runs one prose checker on the file at tmp from dir, labelling
+// its issues with tool.
+func runChecker(command, tool, tmp, dir string) lintDoneMsg {
+	var r lintDoneMsg
+	ctx, cancel := context.WithTimeout(context.Background(), lintTimeout)
+	defer cancel()
+	cmd := commandLine(ctx, command, tmp, dir)
+	if cmd == nil {
+		return r
+	}
+	out, err := cmd.CombinedOutput()
+	r.issues = parseLint(string(out))
+	for i := range r.issues {
+		r.issues[i].tool = tool
+	}
+	r.output = lastLine(string(out))
+	if tool != "" {
+		r.output = tool + ": " + r.output
+	}
+	// Checkers exit non-zero when they find issues, so the exit status only
+	// counts as a failure when nothing could be read.
+	if len(r.issues) == 0 {
+		r.err = err
+	}
+	return r
+}
+
+// checkerName names a checker by its command line's program.
+func checkerName(command string) string {
+	fields := shellWords(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// parseLint reads the diagnostics in a checker's output, in the order they
+// appear in the document. Lines in other forms are skipped.
+func parseLint(out string) []lintIssue {
+	var issues []lintIssue
+	for _, line := range strings.Split(out, "\n") {
+		m := lintLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		row, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		issues = append(issues, lintIssue{row: max(row-1, 0), col: max(col-1, 0), message: m[4]})
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// sortIssues puts issues in the order they appear in the document.
+func sortIssues(issues []lintIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].row != issues[j].row {
+			return issues[i].row < issues[j].row
+		}
+		return issues[i].col < issues[j].col
+	})
+}
+
+// startLint runs the configured checkers on the buffer and lists what they
+// find.
+func (e *Editor) startLint() tea.Cmd {
+	if len(e.ctx.lintCommands) == 0 {
+		e.statusText = "No checker: set lint in the config's [editor] section"
+		return clearStatusAfter(3*time.Second, clearEditorStatusMsg{})
+	}
+	e.statusText = "Checking…"
+	return runLint(e.ctx.lintCommands, e.filePath, e.textarea.Value(), true)
+}
+
+// lintInBackground runs the configured checkers on the buffer to count
+// their issues in the status bar; nil when there are none.
+func (e Editor) lintInBackground() tea.Cmd {
+	if len(e.ctx.lintCommands) == 0 {
+		return nil
+	}
+	return runLint(e.ctx.lintCommands, e.filePath, e.textarea.Value(), false)
+}
+
+// lintDone opens the diagnostics panel, or reports that there are none. A
+// run in the background only updates the count in the status bar.
+func (e *Editor) lintDone(msg lintDoneMsg) tea.Cmd {
+	if msg.err == nil || len(msg.issues) > 0 {
+		e.issues = msg.issues
+	}
+	if !msg.show {
+		return nil
+	}
+	e.statusText = ""
+	switch {
+	case len(msg.issues) > 0:
+		e.lint = &lintPanel{issues: msg.issues}
+	case msg.err == nil:
+		e.statusText = "No issues found"
+	}
+	if msg.err != nil {
+		e.statusText = lintFailure(msg)
+	}
+	if e.statusText == "" {
+		return nil
+	}
+	return clearStatusAfter(3*time.Second, clearEditorStatusMsg{})
+}
+
+// issueCount tells how many issues the checkers found, for the status bar.
+func issueCount(n int) string {
+	return fmt.Sprintf("%d %s", n, pluralize(n, "issue", "issues"))
+}
+
+// lintFailure describes a failed checker for the status bar.
+func lintFailure(msg lintDoneMsg) string {
+	return "Checker failed: " + cmp.Or(msg.output, msg.err.Error())
+}
+
+// updateLint handles a key press while the diagnostics panel is open.
+func (e *Editor) updateLint(k string) {
+	p := e.lint
+	switch k {
+	case "esc", "alt+e":
+		e.lint = nil
+	case "enter":
+		issue := p.issues[p.cursor]
+		e.moveTo(issue.row, issue.col)
+		e.lint = nil
+	case "down", "tab", "ctrl+n":
+		p.move(1)
+	case "up", "shift+tab", "ctrl+p":
+		p.move(-1)
+	}
+}
+
+// lintView draws the diagnostics panel in place of the textarea, scrolled
+// to keep the selected issue in view.
+func (e Editor) lintView() string {
+	p := e.lint
+	height := e.textarea.Height()
+	lines := []string{tocTitleStyle.Render(fmt.Sprintf("Issues (%d)", len(p.issues))), ""}
+	rows := max(height-len(lines)-2, 1)
+	first := min(max(p.cursor-rows/2, 0), max(len(p.issues)-rows, 0))
+	for i := first; i < min(first+rows, len(p.issues)); i++ {
+		line := ansi.Truncate(p.issues[i].label(), e.width, "…")
+		if i == p.cursor {
+			line = tocCursorStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", matterHintStyle.Render("enter jump to issue · esc close"))
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:min(len(lines), max(height, 1))], "\n")
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// lockDir is the folder, in ink's state directory, holding lock files.
+const lockDir = "locks"
+
+// lockPath returns the lock file that marks the document at path as open in
+// an Editor. It is kept in ink's state directory, out of the book, and named
+// after the document's absolute path, so every ink sees the same one however
+// it was started.
+func lockPath(path string) (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, lockDir, hex.EncodeToString(sum[:16])), nil
+}
+
+// lockOwner returns this ink's entry in a lock file: the process and host,
+// followed by the session's name when the process runs several.
+func (c *ViewContext) lockOwner() string {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%d %s", os.Getpid(), host)
+	if c.session != "" {
+		owner += " " + strings.ReplaceAll(c.session, "\n", " ")
+	}
+	return owner
+}
+
+// acquireLock marks the document at path as open by owner. If another ink
+// that is still running holds it, it returns a description of that one
+// instead. A lock left by a process that is gone is taken over.
+func acquireLock(path, owner string) (other string, err error) {
+	lock, err := lockPath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(lock), 0755); err != nil {
+		return "", err
+	}
+	for range 2 {
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(owner + "\n")
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return "", err
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+		data, err := os.ReadFile(lock)
+		if err != nil {
+			return "", err
+		}
+		holder := strings.TrimSpace(string(data))
+		if holder == owner {
+			return "", nil
+		}
+		if lockAlive(holder) {
+			return describeOwner(holder), nil
+		}
+		if err := os.Remove(lock); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", errors.New("could not lock " + filepath.Base(path))
+}
+
+// lockAlive reports whether the process named in a lock entry may still be
+// running. Processes on other hosts can't be checked and count as running.
+func lockAlive(owner string) bool {
+	pidText, rest, _ := strings.Cut(owner, " ")
+	host, _, _ := strings.Cut(rest, " ")
+	pid, err := strconv.Atoi(pidText)
+	if err != nil {
+		return false
+	}
+	if h, _ := os.Hostname(); host != h {
+		return true
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// describeOwner turns a lock entry into words for the status bar.
+func describeOwner(owner string) string {
+	pid, rest, _ := strings.Cut(owner, " ")
+	host, session, _ := strings.Cut(rest, " ")
+	desc := "ink " + pid
+	if h, _ := os.Hostname(); host != "" && host != h {
+		desc += " on " + host
+	}
+	if session != "" {
+		desc += " (" + session + ")"
+	}
+	return desc
+}
+
+// releaseLock removes the document's lock if owner holds it.
+func releaseLock(path, owner string) {
+	lock, err := lockPath(path)
+	if err != nil {
+		return
+	}
+	if data, err := os.ReadFile(lock); err == nil && strings.TrimSpace(string(data)) == owner {
+		_ = os.Remove(lock)
+	}
+}
+
+// lock claims the document for this Editor. When another ink has it open,
+// the editor warns and asks for confirmation before saving over it.
+func (e *Editor) lock() {
+	other, err := acquireLock(e.filePath, e.ctx.lockOwner())
+	if err != nil {
+		// Locking guards against a rare mistake; an unwritable state
+		// directory shouldn't keep the document from opening.
+		return
+	}
+	e.lockedBy = other
+	e.locked = other == ""
+}
+
+// unlock gives up the Editor's claim on the document.
+func (e Editor) unlock() {
+	if e.locked {
+		releaseLock(e.filePath, e.ctx.lockOwner())
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/metrics"
+)
+
+// defaultStripAxes are the axes the editor's metrics strip shows unless
+// configured otherwise.
+var defaultStripAxes = []string{metrics.Rhythm, metrics.Economy, metrics.Variety}
+
+// stripAxes returns the axes the metrics strip shows.
+func (ctx *ViewContext) stripAxes() []string {
+	if len(ctx.metricsStrip) > 0 {
+		return ctx.metricsStrip
+	}
+	return defaultStripAxes
+}
+
+// toggleStrip shows or hides the metrics strip under the text.
+func (e *Editor) toggleStrip() {
+	e.showStrip = !e.showStrip
+	e.refreshStrip()
+	e.setHeight()
+}
+
+// refreshStrip analyzes the text again for the metrics strip, if shown.
+func (e *Editor) refreshStrip() {
+	if e.showStrip {
+		e.strip = metrics.Analyze(e.textarea.Value())
+	}
+}
+
+// stripView draws the metrics strip: a sparkline cell and score per chosen
+// axis and the Flesch-Kincaid grade. The built-in analysis is used whatever
+// the Metrics view is set to, so typing never waits on a service. Axes that
+// stray from the configured target are shown in the warning color.
+func (e Editor) stripView() string {
+	var cells []string
+	for _, name := range e.ctx.stripAxes() {
+		a, ok := e.strip.Axis(name)
+		if !ok {
+			cells = append(cells, matterHintStyle.Render(name+" –"))
+			continue
+		}
+		style := metricsBarStyle
+		if e.ctx.goal(e.ctx.metricsTarget, name).off(a.Score) {
+			style = metricsOffStyle
+		}
+		spark := string(sparks[min(int(a.Score*float64(len(sparks))), len(sparks)-1)])
+		cells = append(cells, matterHintStyle.Render(name+" ")+style.Render(spark)+fmt.Sprintf(" %.2f", a.Score))
+	}
+	if e.strip.Grade != 0 {
+		cells = append(cells, matterHintStyle.Render("FK ")+fmt.Sprintf("%.1f", e.strip.Grade))
+	}
+	return ansi.Truncate(" "+strings.Join(cells, "  "), e.width, "…")
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/htmlmd"
+)
+
+// clipboardTimeout bounds how long reading the clipboard's HTML may take.
+const clipboardTimeout = time.Second
+
+// pasteHTMLMsg carries text pasted into the editor at path along with the
+// HTML the clipboard held, read in the background.
+type pasteHTMLMsg struct {
+	path string
+	text string
+	html string
+}
+
+// paste inserts text pasted from the terminal as one edit: a single
+// insertion, followed by a single content update, however long it is.
+// Windows line endings are folded first; the textarea would make each \r\n
+// two lines.
+// While a form or panel, or the offer to restore unsaved content, is open
+// the paste goes to the form's input, or nowhere.
+// Text copied from a browser is pasted as markdown, converted from the
+// HTML the clipboard holds alongside it, except in code blocks. The
+// clipboard is read in the background and the paste lands once it has been.
+func (e Editor) paste(msg tea.PasteMsg) (Editor, tea.Cmd) {
+	text := normalizeLineEndings(msg.Content)
+	if e.matter != nil {
+		var cmd tea.Cmd
+		// The form's fields are single lines.
+		e.matter.input, cmd = e.matter.input.Update(tea.PasteMsg{Content: strings.ReplaceAll(text, "\n", " ")})
+		return e, cmd
+	}
+	if e.lint != nil || e.rewrite != nil || e.backup != "" || !e.closedAt.IsZero() {
+		return e, nil
+	}
+	if e.inCodeBlock() {
+		return e.insertPaste(text, false)
+	}
+	path := e.filePath
+	return e, func() tea.Msg {
+		return pasteHTMLMsg{path: path, text: text, html: readClipboardHTML()}
+	}
+}
+
+// pastedHTML inserts a paste once the clipboard's HTML has been read, as
+// markdown when the HTML is what was pasted. A panel opened meanwhile
+// takes nothing, as it would have from the paste itself.
+func (e Editor) pastedHTML(msg pasteHTMLMsg) (Editor, tea.Cmd) {
+	if e.matter != nil || e.lint != nil || e.rewrite != nil || e.backup != "" || !e.closedAt.IsZero() {
+		return e, nil
+	}
+	if md, ok := htmlPaste(msg.text, msg.html); ok {
+		e.statusText = "Pasted HTML as markdown"
+		return e.insertPaste(md, true)
+	}
+	return e.insertPaste(msg.text, false)
+}
+
+// insertPaste inserts text at the cursor as one edit; converted clears the
+// status saying it was converted from HTML after a while.
+func (e Editor) insertPaste(text string, converted bool) (Editor, tea.Cmd) {
+	before := e.textarea.LineCount()
+	var cmd tea.Cmd
+	e.textarea, cmd = e.textarea.Update(tea.PasteMsg{Content: text})
+	// The textarea holds a limited number of lines and drops the rest of a
+	// paste that goes past it; say so rather than lose text quietly.
+	want := strings.Count(text, "\n")
+	if got := e.textarea.LineCount() - before; got < want {
+		e.statusText = fmt.Sprintf("Pasted %d of %d lines: the editor holds %d", got+1, want+1, e.textarea.LineCount())
+	}
+	e, cmd = e.contentChanged(cmd)
+	if converted {
+		cmd = tea.Batch(cmd, clearStatusAfter(3*time.Second, clearEditorStatusMsg{}))
+	}
+	return e, cmd
+}
+
+// htmlPaste returns src, the clipboard's HTML, as markdown when text, what
+// the terminal pasted, is that HTML's text; a paste from elsewhere, such as
+// the primary selection, stays as it is.
+func htmlPaste(text, src string) (string, bool) {
+	if src == "" || letters(htmlmd.Text(src)) != letters(text) {
+		return "", false
+	}
+	md := htmlmd.Convert(src)
+	return md, md != "" && md != text
+}
+
+// letters returns the letters of s, which browsers copy alike as HTML and
+// as text, whatever they do with spacing, bullets and numbering.
+func letters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// inCodeBlock reports whether the cursor is inside a fenced code block.
+func (e Editor) inCodeBlock() bool {
+	lines := strings.Split(e.textarea.Value(), "\n")
+	in := false
+	for _, line := range lines[:min(e.textarea.Line(), len(lines))] {
+		if isFence([]rune(line)) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// clipboardHTML reads the HTML flavor of the system clipboard, which
+// browsers fill in when copying, with the platform's clipboard tool.
+func clipboardHTML() string {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		cmd = exec.CommandContext(ctx, "osascript", "-e", "the clipboard as «class HTML»")
+	case runtime.GOOS == "windows":
+		return ""
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		cmd = exec.CommandContext(ctx, "wl-paste", "--no-newline", "--type", "text/html")
+	default:
+		cmd = exec.CommandContext(ctx, "xclip", "-selection", "clipboard", "-target", "text/html", "-out")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "darwin" {
+		// AppleScript prints the data as «data HTML3C6D...», in hex.
+		s := strings.TrimSpace(string(out))
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "«data HTML"), "»")
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return ""
+		}
+		out = b
+	}
+	return string(out)
+}
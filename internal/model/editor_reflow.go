@@ -0,0 +1,225 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// reflowMarkdown rewraps the prose paragraphs of text, including list items
+// and block quotes, to at most width columns. Front matter, code, tables,
+// headings, HTML and paragraphs with hard line breaks are left as they are.
+// Only whitespace changes.
+func reflowMarkdown(text string, width int) string {
+	n := frontmatter.Len([]byte(text))
+	lines := strings.Split(text[n:], "\n")
+	var out []string
+	inFence := false
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isFence([]rune(line)) {
+			inFence = !inFence
+		}
+		if inFence || isFence([]rune(line)) || !isProse(line, i > 0 && strings.TrimSpace(lines[i-1]) != "") {
+			out = append(out, line)
+			i++
+			continue
+		}
+		first, rest := blockPrefix(line)
+		para := []string{strings.TrimPrefix(line, first)}
+		j := i + 1
+		for j < len(lines) && isContinuation(lines[j], rest) {
+			para = append(para, strings.TrimPrefix(strings.TrimLeft(lines[j], " "), strings.TrimLeft(rest, " ")))
+			j++
+		}
+		if hasHardBreak(para) {
+			out = append(out, lines[i:j]...)
+		} else {
+			out = append(out, wrapWords(strings.Fields(strings.Join(para, " ")), first, rest, width)...)
+		}
+		i = j
+	}
+	return text[:n] + strings.Join(out, "\n")
+}
+
+// isProse reports whether line starts a paragraph that may be rewrapped.
+// afterText says whether the previous line is text, where an indented line
+// continues it rather than starting a code block.
+func isProse(line string, afterText bool) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || strings.HasPrefix(line, "\t") || !afterText && len(line)-len(trimmed) >= 4 {
+		return false
+	}
+	first, _ := blockPrefix(line)
+	body := strings.TrimSpace(line[len(first):])
+	return body != "" && !startsBlock(body)
+}
+
+// startsBlock reports whether text, with any quote and list markers
+// removed, is something other than a paragraph: a heading, table row, HTML,
+// rule or setext underline, fence or link reference definition.
+func startsBlock(text string) bool {
+	switch {
+	case strings.HasPrefix(text, "#"), strings.HasPrefix(text, "|"), strings.HasPrefix(text, "<"),
+		isFence([]rune(text)), strings.Trim(text, "-*_= ") == "":
+		return true
+	case strings.HasPrefix(text, "["):
+		close := strings.Index(text, "]:")
+		return close > 0
+	}
+	return false
+}
+
+// blockPrefix splits off the indentation, quote markers and list marker
+// that start line. first is that prefix as written; rest is the prefix
+// continuation lines get, with the list marker turned into spaces.
+func blockPrefix(line string) (first, rest string) {
+	i := 0
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	for i < len(line) && line[i] == '>' {
+		i++
+		if i < len(line) && line[i] == ' ' {
+			i++
+		}
+	}
+	quote := line[:i]
+	if m := listMarker(line[i:]); m > 0 {
+		return line[:i+m], quote + strings.Repeat(" ", ansi.StringWidth(line[i:i+m]))
+	}
+	return quote, quote
+}
+
+// listMarker returns the length of the list marker ("- ", "* ", "+ ",
+// "1. ", "2) ", optionally followed by a task box) that starts s, or 0.
+func listMarker(s string) int {
+	n := 0
+	if len(s) >= 2 && strings.ContainsRune("-*+", rune(s[0])) && s[1] == ' ' {
+		n = 2
+	} else {
+		for n < len(s) && n < 9 && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		if n == 0 || n+1 >= len(s) || (s[n] != '.' && s[n] != ')') || s[n+1] != ' ' {
+			return 0
+		}
+		n += 2
+	}
+	for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+		if strings.HasPrefix(s[n:], box) {
+			return n + len(box)
+		}
+	}
+	return n
+}
+
+// isContinuation reports whether line continues the paragraph whose
+// continuation prefix is rest: it is text under the same quote markers that
+// doesn't start a block or list item of its own.
+func isContinuation(line, rest string) bool {
+	quote := strings.TrimRight(rest, " ")
+	trimmed := strings.TrimLeft(line, " ")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	if q := strings.TrimSpace(quote); q != "" {
+		if !strings.HasPrefix(trimmed, q) {
+			return false
+		}
+		trimmed = strings.TrimLeft(strings.TrimPrefix(trimmed, q), " ")
+	}
+	return trimmed != "" && listMarker(trimmed) == 0 && !startsBlock(trimmed) &&
+		!strings.HasPrefix(strings.TrimLeft(trimmed, " "), ">")
+}
+
+// hasHardBreak reports whether any line of a paragraph but the last ends in
+// a hard line break (two spaces or a backslash).
+func hasHardBreak(para []string) bool {
+	for _, line := range para[:len(para)-1] {
+		if strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\") {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapWords fills lines of at most width columns with words, the first
+// starting with first and the others with rest. A word longer than a line
+// gets a line of its own. A word that would turn a line it starts into a
+// list item, quote or other block stays at the end of the line before,
+// however long that makes it.
+func wrapWords(words []string, first, rest string, width int) []string {
+	var lines []string
+	line := first
+	empty := true
+	for _, w := range words {
+		if !empty && ansi.StringWidth(line)+1+ansi.StringWidth(w) > width && canStartLine(w) {
+			lines = append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+			line, empty = rest, true
+		}
+		if !empty {
+			line += " "
+		}
+		line += w
+		empty = false
+	}
+	return append(lines, line)
+}
+
+// canStartLine reports whether a continuation line may start with word w
+// and still continue its paragraph: "-", "2." or ">" would start a list
+// item or quote instead, and "#" a heading.
+func canStartLine(w string) bool {
+	return listMarker(w+" ") == 0 && !startsBlock(w) && !strings.HasPrefix(w, ">")
+}
+
+// reflow rewraps the content at the wrap-on-save width, keeping the cursor
+// on the same character.
+func (e *Editor) reflow() {
+	content := e.textarea.Value()
+	wrapped := reflowMarkdown(content, e.ctx.wrapOnSave)
+	if wrapped == content {
+		return
+	}
+	// Only whitespace changes, so the cursor goes to the character with as
+	// many other characters before it, or to the end.
+	row, col := e.cursor()
+	before := 0
+	for l, line := range e.lines()[:row+1] {
+		if l == row {
+			line = line[:min(col, len(line))]
+		}
+		for _, r := range line {
+			if !unicode.IsSpace(r) {
+				before++
+			}
+		}
+	}
+	var lines [][]rune
+	for _, l := range strings.Split(wrapped, "\n") {
+		lines = append(lines, []rune(l))
+	}
+	row, col = nthNonSpace(lines, before)
+	e.setLines(lines, row, col)
+}
+
+// nthNonSpace returns the position of the non-space rune with n others
+// before it, or the end of the text when there are fewer.
+func nthNonSpace(lines [][]rune, n int) (row, col int) {
+	for l, line := range lines {
+		for c, r := range line {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			if n == 0 {
+				return l, c
+			}
+			n--
+		}
+	}
+	return len(lines) - 1, len(lines[len(lines)-1])
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/viewport"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+)
+
+// rewriteTimeout bounds how long the rewrite command may take; a language
+// model running locally can be slow.
+const rewriteTimeout = 5 * time.Minute
+
+// rewriteDoneMsg carries the rewrite command's suggestion for lines
+// [from, to) of the document at path.
+type rewriteDoneMsg struct {
+	path      string
+	seq       int // the editor's edit count when the rewrite was asked for
+	from, to  int
+	old, text string // the lines as sent, and the suggestion
+	output    string // last line the command printed to stderr, when it failed
+	err       error
+}
+
+// rewriteOverlay shows a suggested rewrite as a diff in place of the
+// textarea until it is accepted or rejected.
+type rewriteOverlay struct {
+	from, to int
+	text     string
+	diff     chapterDiff
+	viewport viewport.Model
+}
+
+// runRewrite runs the rewrite command on text, lines [from, to) of the
+// document at path, in the background. The command runs in the document's
+// folder with {file} and $INK_FILE set to the path, and gets the prompt,
+// when there is one, then a blank line and the text on stdin; what it
+// prints is the suggestion.
+func runRewrite(command, prompt, path, text string, from, to, seq int) tea.Cmd {
+	return func() tea.Msg {
+		msg := rewriteDoneMsg{path: path, seq: seq, from: from, to: to, old: text}
+		ctx, cancel := context.WithTimeout(context.Background(), rewriteTimeout)
+		defer cancel()
+		cmd := commandLine(ctx, command, path, filepath.Dir(path))
+		if cmd == nil {
+			return msg
+		}
+		input := text
+		if prompt != "" {
+			input = prompt + "\n\n" + text
+		}
+		cmd.Stdin = strings.NewReader(input)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			msg.output, msg.err = lastLine(stderr.String()), err
+			return msg
+		}
+		msg.text = strings.TrimRight(string(out), " \t\n")
+		return msg
+	}
+}
+
+// atxLevel returns the level of an ATX heading line, or 0 for other lines.
+func atxLevel(line string) int {
+	s := strings.TrimLeft(line, " ")
+	if len(line)-len(s) > 3 {
+		return 0
+	}
+	n := len(s) - len(strings.TrimLeft(s, "#"))
+	if n == 0 || n > 6 || len(s) > n && s[n] != ' ' && s[n] != '\t' {
+		return 0
+	}
+	return n
+}
+
+// sectionAt returns the lines [from, to) of the section line row is in:
+// those below its heading, up to the next heading of the same level or
+// higher, or before the first heading, after any front matter. Blank lines
+// at either end are left out.
+func sectionAt(text string, row int) (from, to int) {
+	lines := strings.Split(text, "\n")
+	levels := make([]int, len(lines))
+	fenced := false
+	for i, l := range lines {
+		if isFence([]rune(l)) {
+			fenced = !fenced
+			continue
+		}
+		if !fenced {
+			levels[i] = atxLevel(l)
+		}
+	}
+	head := -1
+	for i := min(row, len(lines)-1); i >= 0; i-- {
+		if levels[i] > 0 {
+			head = i
+			break
+		}
+	}
+	from, to = head+1, len(lines)
+	if head < 0 {
+		from = strings.Count(text[:frontmatter.Len([]byte(text))], "\n")
+	}
+	for i := from; i < len(lines); i++ {
+		if levels[i] > 0 && (head < 0 || levels[i] <= levels[head]) {
+			to = i
+			break
+		}
+	}
+	for from < to && strings.TrimSpace(lines[from]) == "" {
+		from++
+	}
+	for to > from && strings.TrimSpace(lines[to-1]) == "" {
+		to--
+	}
+	return from, to
+}
+
+// startRewrite sends the lines selected in vim's visual mode, or else the
+// section the cursor is in, to the rewrite command.
+func (e *Editor) startRewrite() tea.Cmd {
+	if e.ctx.rewriteCommand == "" {
+		e.statusText = "No rewrite command: set command in the config's [rewrite] section"
+		return clearStatusAfter(3*time.Second, clearEditorStatusMsg{})
+	}
+	value := e.textarea.Value()
+	row := e.textarea.Line()
+	from, to := sectionAt(value, row)
+	if e.vim != nil && e.vim.mode == vimVisual {
+		from, to = min(e.vim.anchor, row), max(e.vim.anchor, row)+1
+		e.vim.mode = vimNormal
+	}
+	if from >= to {
+		e.statusText = "Nothing to rewrite here"
+		return clearStatusAfter(2*time.Second, clearEditorStatusMsg{})
+	}
+	text := strings.Join(strings.Split(value, "\n")[from:to], "\n")
+	e.statusText = "Rewriting…"
+	return runRewrite(e.ctx.rewriteCommand, e.ctx.rewritePrompt, e.filePath, text, from, to, e.editSeq)
+}
+
+// rewriteDone shows the suggestion as a diff against the text it would
+// replace. A suggestion for text that changed meanwhile is dropped.
+func (e *Editor) rewriteDone(msg rewriteDoneMsg) tea.Cmd {
+	e.statusText = ""
+	switch {
+	case msg.err != nil:
+		e.statusText = "Rewrite failed: " + cmp.Or(msg.output, msg.err.Error())
+	case msg.seq != e.editSeq:
+		e.statusText = "The text changed while rewriting; suggestion dropped"
+	case msg.text == "" || msg.text == msg.old:
+		e.statusText = "No changes suggested"
+	default:
+		o := &rewriteOverlay{
+			from: msg.from,
+			to:   msg.to,
+			text: msg.text,
+			diff: chapterDiff{blocks: diffParagraphs(splitParagraphs(msg.old), splitParagraphs(msg.text))},
+		}
+		o.viewport = viewport.New(viewport.WithWidth(e.width), viewport.WithHeight(max(e.textarea.Height()-2, 1)))
+		o.viewport.SetContent(o.diff.render(e.width))
+		e.rewrite = o
+		return nil
+	}
+	return clearStatusAfter(3*time.Second, clearEditorStatusMsg{})
+}
+
+// updateRewrite handles a key press while a suggestion is shown: y or enter
+// puts it in the buffer, leaving the file to be saved as usual; n or esc
+// drops it; the rest scroll.
+func (e Editor) updateRewrite(msg tea.KeyMsg) (Editor, tea.Cmd) {
+	o := e.rewrite
+	switch msg.String() {
+	case "y", "enter":
+		e.rewrite = nil
+		lines := e.lines()
+		lines = append(lines[:o.from], append(splitLines(o.text), lines[o.to:]...)...)
+		e.setLines(lines, o.from, 0)
+		e.statusText = "Rewrite applied"
+		e, cmd := e.contentChanged(nil)
+		return e, tea.Batch(cmd, clearStatusAfter(2*time.Second, clearEditorStatusMsg{}))
+	case "n", "esc", "alt+r":
+		e.rewrite = nil
+		return e, nil
+	}
+	var cmd tea.Cmd
+	o.viewport, cmd = o.viewport.Update(msg)
+	return e, cmd
+}
+
+// rewriteView draws the suggestion in place of the textarea.
+func (e Editor) rewriteView() string {
+	o := e.rewrite
+	n := o.to - o.from
+	title := tocTitleStyle.Render(fmt.Sprintf("Suggested rewrite of %d %s", n, pluralize(n, "line", "lines")))
+	hint := matterHintStyle.Render("y accept · n reject · ↑/↓ scroll")
+	return strings.Join([]string{title, o.viewport.View(), hint}, "\n")
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Sentences longer or harder than these are marked when the limits aren't
+// set with -long-sentence and -hard-grade.
+const (
+	defaultLongSentence = 25
+	defaultHardGrade    = 14
+)
+
+// hardSentenceStyle underlines the sentences marked as too long or hard.
+var hardSentenceStyle = lipgloss.NewStyle().Underline(true).UnderlineColor(lipgloss.Color("208"))
+
+// sentenceLimits returns the word count and Flesch-Kincaid grade above
+// which a sentence is marked.
+func (ctx *ViewContext) sentenceLimits() (int, float64) {
+	words, grade := ctx.longSentence, ctx.hardGrade
+	if words <= 0 {
+		words = defaultLongSentence
+	}
+	if grade <= 0 {
+		grade = defaultHardGrade
+	}
+	return words, grade
+}
+
+// sentenceMarks marks the runes of a paragraph's lines that belong to a
+// sentence of more than maxWords words or above maxGrade. The paragraph is
+// read as one text, so sentences can run across lines.
+func sentenceMarks(lines []string, maxWords int, maxGrade float64) [][]bool {
+	text := []rune(strings.Join(lines, "\n"))
+	marked := make([]bool, len(text))
+	mark := func(from, to int) {
+		for from < to && (text[from] == ' ' || text[from] == '\n') {
+			from++
+		}
+		s := string(text[from:to])
+		grade, ok := fleschKincaidScore(s)
+		if countWords(s) > maxWords || ok && grade > maxGrade {
+			for i := from; i < to; i++ {
+				marked[i] = true
+			}
+		}
+	}
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if !strings.ContainsRune(".!?", text[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && strings.ContainsRune(".!?\"')]*_", text[end]) {
+			end++
+		}
+		if end < len(text) && text[end] != ' ' && text[end] != '\n' {
+			continue
+		}
+		mark(start, end)
+		start, i = end, end
+	}
+	if start < len(text) {
+		mark(start, len(text))
+	}
+
+	marks := make([][]bool, len(lines))
+	off := 0
+	for l, line := range lines {
+		n := len([]rune(line))
+		marks[l] = marked[off : off+n]
+		off += n + 1
+	}
+	return marks
+}
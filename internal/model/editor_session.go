@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// typingGap is the longest pause between edits still counted as typing.
+// Longer pauses count as idle time.
+const typingGap = 10 * time.Second
+
+// writingSession tracks what happens during one stay in the Editor.
+type writingSession struct {
+	start    time.Time
+	lastEdit time.Time // zero until the first edit
+	typing   time.Duration
+	words    int // current word count
+	added    int
+	removed  int
+}
+
+// newWritingSession starts a session on content with words words.
+func newWritingSession(words int, t time.Time) writingSession {
+	return writingSession{start: t, words: words}
+}
+
+// edit records that the content changed to words words at t.
+func (s *writingSession) edit(words int, t time.Time) {
+	if d := words - s.words; d > 0 {
+		s.added += d
+	} else {
+		s.removed -= d
+	}
+	s.words = words
+	if !s.lastEdit.IsZero() {
+		if gap := t.Sub(s.lastEdit); gap <= typingGap {
+			s.typing += gap
+		}
+	}
+	s.lastEdit = t
+}
+
+// edited reports whether the session changed the word count at all.
+func (s writingSession) edited() bool {
+	return s.added > 0 || s.removed > 0
+}
+
+// wpm is the typing speed: words added per minute spent typing.
+func (s writingSession) wpm() int {
+	if s.typing < time.Second {
+		return 0
+	}
+	return int(float64(s.added) / s.typing.Minutes())
+}
+
+// logSession ends the session at t and appends it to the session log, so
+// the statistics screen can sum up the writing done in a book.
+func (e *Editor) logSession(t time.Time) {
+	s := e.session
+	// The log is a record for the statistics screen; failing to write it
+	// shouldn't keep the editor from closing.
+	_ = e.ctx.state.LogSession(state.Session{
+		Path:    e.filePath,
+		Start:   s.start,
+		End:     t,
+		Typing:  s.typing,
+		Added:   s.added,
+		Removed: s.removed,
+	})
+}
+
+// sessionView draws the session summary shown when the editor closes.
+func (e Editor) sessionView(t time.Time) string {
+	s := e.session
+	elapsed := t.Sub(s.start).Round(time.Second)
+	row := func(label, value string) string {
+		return "  " + statsLabelStyle.Render(label) + value
+	}
+	lines := []string{
+		tocTitleStyle.Render("Session"),
+		"",
+		row("Time", elapsed.String()),
+		row("Typing", fmt.Sprintf("%s (idle %s)", s.typing.Round(time.Second), max(elapsed-s.typing.Round(time.Second), 0))),
+		row("Words added", fmt.Sprintf("%d", s.added)),
+		row("Words removed", fmt.Sprintf("%d", s.removed)),
+		row("Net", fmt.Sprintf("%+d", s.added-s.removed)),
+		row("Speed", fmt.Sprintf("%d wpm", s.wpm())),
+		"",
+		matterHintStyle.Render("Press any key to close"),
+	}
+	height := e.textarea.Height()
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:min(len(lines), max(height, 1))], "\n")
+}
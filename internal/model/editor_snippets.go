@@ -0,0 +1,104 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// snippetDir holds a book's snippets, relative to the book root. Each file
+// is one snippet, named by its file name without the extension.
+const snippetDir = ".ink/snippets"
+
+// snippetCursor marks where the cursor goes after a snippet is inserted.
+const snippetCursor = "${cursor}"
+
+// builtinSnippets are available in every book. A book's own snippet of the
+// same name replaces one of these.
+var builtinSnippets = map[string]string{
+	"front":   "---\ntitle: ${title}\ndate: ${date}\ntags: []\nstatus: draft\n---\n\n${cursor}",
+	"fence":   "```${cursor}\n\n```",
+	"meeting": "## Meeting ${date}\n\nAttendees:\n\n- ${cursor}\n\nNotes:\n\nActions:\n\n- [ ] ",
+}
+
+// loadSnippets returns the built-in snippets together with those in the
+// book's snippet directory.
+func loadSnippets(bookDir string) map[string]string {
+	snippets := make(map[string]string, len(builtinSnippets))
+	for name, body := range builtinSnippets {
+		snippets[name] = body
+	}
+	dir := filepath.Join(bookDir, snippetDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return snippets
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		snippets[name] = strings.TrimRight(normalizeLineEndings(string(data)), "\n")
+	}
+	return snippets
+}
+
+// expandSnippet fills in a snippet's ${date}, ${time} and ${title}
+// variables and returns the text with the offset, in runes, of its cursor
+// mark (or of its end, if it has none).
+func expandSnippet(body, filePath string, now time.Time) (string, int) {
+	r := strings.NewReplacer(
+		"${date}", now.Format("2006-01-02"),
+		"${time}", now.Format("15:04"),
+		"${title}", strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)),
+	)
+	before, after, found := strings.Cut(body, snippetCursor)
+	if !found {
+		text := r.Replace(body)
+		return text, len([]rune(text))
+	}
+	before = r.Replace(before)
+	return before + r.Replace(strings.ReplaceAll(after, snippetCursor, "")), len([]rune(before))
+}
+
+// insertSnippet replaces the abbreviation before the cursor with the
+// snippet of that name. Without a matching abbreviation it lists the
+// snippets in the status bar.
+func (e *Editor) insertSnippet() tea.Cmd {
+	lines := e.lines()
+	row, col := e.cursor()
+	start := col
+	for start > 0 && lines[row][start-1] != ' ' && lines[row][start-1] != '\t' {
+		start--
+	}
+	body, ok := e.snippets[string(lines[row][start:col])]
+	if !ok {
+		names := make([]string, 0, len(e.snippets))
+		for name := range e.snippets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		e.statusText = "Snippets: " + strings.Join(names, ", ")
+		return clearStatusAfter(3*time.Second, clearEditorStatusMsg{})
+	}
+
+	text, at := expandSnippet(body, e.filePath, time.Now())
+	head := string(lines[row][:start]) + string([]rune(text)[:at])
+	inserted := strings.Split(head+string([]rune(text)[at:])+string(lines[row][col:]), "\n")
+	cursorLines := strings.Split(head, "\n")
+	var added [][]rune
+	for _, l := range inserted {
+		added = append(added, []rune(l))
+	}
+	lines = append(lines[:row], append(added, lines[row+1:]...)...)
+	e.setLines(lines, row+len(cursorLines)-1, len([]rune(cursorLines[len(cursorLines)-1])))
+	return nil
+}
@@ -0,0 +1,1345 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
+)
+
+func TestClassifyMarkdown(t *testing.T) {
+	// Each want string gives a class per rune: . plain, m marker, h heading,
+	// s strong, e emphasis, c code, l link text, u link destination.
+	tests := []struct {
+		line    string
+		inFence bool
+		want    string
+	}{
+		{"## Title `x`", false, "mmhhhhhhhmcm"},
+		{"Some **bold** and _it_.", false, ".....mmssssmm.....meem."},
+		{"See [docs](a.md) now", false, "....mllllmmuuuum...."},
+		{"![alt](p.png)", false, "mmlllmmuuuuum"},
+		{"> - item `code`", false, "m.m......mccccm"},
+		{"1. snake_case_name", false, "mm................"},
+		{"```go", false, "mmmmm"},
+		{"**not code", true, "cccccccccc"},
+		{"a * b * c", false, "........."},
+	}
+	codes := map[mdClass]byte{mdPlain: '.', mdMarker: 'm', mdHeading: 'h', mdStrong: 's', mdEmphasis: 'e', mdCode: 'c', mdLink: 'l', mdURL: 'u'}
+	for _, tt := range tests {
+		classes, _ := classifyMarkdown([]rune(tt.line), tt.inFence)
+		got := make([]byte, len(classes))
+		for i, c := range classes {
+			got[i] = codes[c]
+		}
+		if string(got) != tt.want {
+			t.Errorf("classifyMarkdown(%q) = %s, want %s", tt.line, got, tt.want)
+		}
+	}
+
+	_, inFence := classifyMarkdown([]rune("```"), false)
+	if !inFence {
+		t.Error("an opening fence should start a code block")
+	}
+	_, inFence = classifyMarkdown([]rune("~~~"), true)
+	if inFence {
+		t.Error("a closing fence should end the code block")
+	}
+}
+
+func TestEditorHighlightKeepsText(t *testing.T) {
+	content := "# Heading with `code`\n\nA paragraph with **bold**, _emphasis_ and a [link](https://example.com/a/rather/long/path) that wraps over several rows of the editor.\n\n```\nfenced **code**\n```\n"
+	ctx := &ViewContext{width: 60, height: 20, maxWidth: 60}
+	e := NewEditor(ctx, "doc.md", content)
+	check := func(name string) {
+		t.Helper()
+		plain := ansi.Strip(e.textarea.View())
+		if got := ansi.Strip(e.highlight(e.textarea.View())); got != plain {
+			t.Errorf("%s: highlighting changed the text:\n%s\nwant:\n%s", name, got, plain)
+		}
+	}
+
+	check("cursor at the top")
+	if view := e.highlight(e.textarea.View()); !strings.Contains(view, mdStyles[mdHeading].Inherit(e.textarea.Styles().Focused.CursorLine).Render(" Heading with ")) {
+		t.Errorf("heading text should be styled:\n%q", view)
+	}
+	for range 3 {
+		e.textarea.CursorDown()
+	}
+	e.textarea.SetCursorColumn(40)
+	check("cursor inside a wrapped line")
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'z', Mod: tea.ModAlt})
+	check("zen mode")
+}
+
+func TestEditorVimKeys(t *testing.T) {
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, vimKeys: true}
+	e := NewEditor(ctx, "doc.md", "one two three\nfour\nfive")
+	keys := func(s string) {
+		t.Helper()
+		for _, r := range s {
+			e, _ = e.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+	}
+	want := func(value string, row, col int) {
+		t.Helper()
+		if got := e.textarea.Value(); got != value {
+			t.Errorf("content = %q, want %q", got, value)
+		}
+		if r, c := e.cursor(); r != row || c != col {
+			t.Errorf("cursor = %d:%d, want %d:%d", r, c, row, col)
+		}
+	}
+
+	if got := e.vim.status(); got != "NORMAL" {
+		t.Errorf("vim mode starts as %q, want NORMAL", got)
+	}
+	keys("x")
+	want("ne two three\nfour\nfive", 0, 0)
+	keys("wdw")
+	want("ne three\nfour\nfive", 0, 3)
+	keys("2l")
+	want("ne three\nfour\nfive", 0, 5)
+	keys("$")
+	want("ne three\nfour\nfive", 0, 7)
+
+	keys("dd")
+	want("four\nfive", 0, 0)
+	keys("p")
+	want("four\nne three\nfive", 1, 0)
+	keys("2yyggP")
+	want("ne three\nfive\nfour\nne three\nfive", 0, 0)
+	keys("Vjd")
+	want("four\nne three\nfive", 0, 0)
+	keys("G")
+	want("four\nne three\nfive", 2, 0)
+
+	keys("A!")
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	want("four\nne three\nfive!", 2, 4)
+	if e.vim.mode != vimNormal {
+		t.Error("esc should return to normal mode")
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	keys("q")
+	want("four\nne three\nfive!", 2, 4)
+
+	keys("k0cwone")
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	want("four\none three\nfive!", 1, 2)
+	if e.saved {
+		t.Error("vim edits should mark the file unsaved")
+	}
+}
+
+func TestEditorFormattingShortcuts(t *testing.T) {
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, "doc.md", "Some words here")
+	alt := func(r rune) {
+		t.Helper()
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Mod: tea.ModAlt})
+	}
+	want := func(value string, col int) {
+		t.Helper()
+		if got := e.textarea.Value(); got != value {
+			t.Errorf("content = %q, want %q", got, value)
+		}
+		if _, c := e.cursor(); c != col {
+			t.Errorf("cursor column = %d, want %d", c, col)
+		}
+	}
+
+	e.textarea.SetCursorColumn(7)
+	alt('b')
+	want("Some **words** here", 9)
+	alt('i')
+	want("Some ***words*** here", 10)
+	alt('i')
+	want("Some **words** here", 9)
+	alt('b')
+	want("Some words here", 7)
+	alt('c')
+	want("Some `words` here", 8)
+	alt('c')
+
+	alt('k')
+	want("Some [words]() here", 13)
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEnd})
+	alt('k')
+	want("Some [words]() [here]()", 22)
+
+	alt('h')
+	want("# Some [words]() [here]()", 24)
+	alt('h')
+	want("## Some [words]() [here]()", 25)
+	for range 5 {
+		alt('h')
+	}
+	want("Some [words]() [here]()", 22)
+	if e.saved {
+		t.Error("formatting should mark the file unsaved")
+	}
+}
+
+func TestEditorSnippets(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"notes.md":                "",
+		".ink/snippets/sig.md":    "-- \n${title}, ${cursor}\n",
+		".ink/snippets/fence.txt": "~~~${cursor}~~~",
+	})
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, bookDir: dir}
+	e := NewEditor(ctx, filepath.Join(dir, "notes.md"), "Thanks sig")
+	e.textarea.CursorEnd()
+	alt := func(r rune) {
+		t.Helper()
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Mod: tea.ModAlt})
+	}
+
+	alt('s')
+	if got, want := e.textarea.Value(), "Thanks -- \nnotes, "; got != want {
+		t.Errorf("after expanding sig: %q, want %q", got, want)
+	}
+	if r, c := e.cursor(); r != 1 || c != 7 {
+		t.Errorf("cursor = %d:%d, want 1:7 at the cursor mark", r, c)
+	}
+
+	e.textarea.InsertString("fence")
+	alt('s')
+	if got, want := e.textarea.Value(), "Thanks -- \nnotes, ~~~~~~"; got != want {
+		t.Errorf("a book snippet should replace the built-in one: %q, want %q", got, want)
+	}
+
+	alt('s')
+	if !strings.HasPrefix(e.statusText, "Snippets: fence, front, meeting, sig") {
+		t.Errorf("without an abbreviation: status = %q", e.statusText)
+	}
+
+	text, at := expandSnippet("date: ${date}\n${cursor}", "x.md", time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC))
+	if text != "date: 2026-03-04\n" || at != len(text) {
+		t.Errorf("expandSnippet = %q, %d", text, at)
+	}
+}
+
+func TestEditorFrontMatterForm(t *testing.T) {
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, "doc.md", "---\ntitle: Draft\ntags:\n  - a\n  - b\nowner: me\n---\n# Body\ntext")
+	for range 8 {
+		e.textarea.CursorDown()
+	}
+	press := func(msgs ...tea.KeyPressMsg) {
+		t.Helper()
+		for _, msg := range msgs {
+			e, _ = e.Update(msg)
+		}
+	}
+	typ := func(s string) {
+		t.Helper()
+		for _, r := range s {
+			press(tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+	}
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+
+	press(tea.KeyPressMsg{Code: 'p', Mod: tea.ModAlt})
+	if e.matter == nil {
+		t.Fatal("alt+p should open the front matter form")
+	}
+	var keys []string
+	for _, f := range e.matter.fields {
+		keys = append(keys, f.key)
+	}
+	if got := strings.Join(keys, " "); got != "title date tags status owner" {
+		t.Errorf("fields = %s", got)
+	}
+	if !strings.Contains(ansi.Strip(e.View()), "Front matter") {
+		t.Error("the form should replace the text")
+	}
+
+	typ(": notes")
+	press(tab, tab)
+	typ(", c d")
+	press(tab)
+	typ("draft")
+	press(tab, tab)
+	typ("rev: 2")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	want := "---\ntitle: \"Draft: notes\"\ntags:\n  - a\n  - b\n  - c d\nstatus: draft\nowner: me\nrev: 2\n---\n# Body\ntext"
+	if got := e.textarea.Value(); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if e.matter != nil || e.saved {
+		t.Error("enter should close the form and leave the file unsaved")
+	}
+	if r, _ := e.cursor(); r != 11 {
+		t.Errorf("cursor row = %d, want 11 to stay on the body's last line", r)
+	}
+
+	e = NewEditor(ctx, "doc.md", "# Body")
+	press(tea.KeyPressMsg{Code: 'p', Mod: tea.ModAlt})
+	typ("Title")
+	press(tab, tab)
+	typ("x, y")
+	press(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if e.matter != nil || e.textarea.Value() != "# Body" {
+		t.Errorf("esc should cancel the form: %q", e.textarea.Value())
+	}
+	press(tea.KeyPressMsg{Code: 'p', Mod: tea.ModAlt})
+	typ("Title")
+	press(tab, tab)
+	typ("x, y")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if got, want := e.textarea.Value(), "---\ntitle: Title\ntags: [x, y]\n---\n\n# Body"; got != want {
+		t.Errorf("new front matter = %q, want %q", got, want)
+	}
+}
+
+func TestEditorWrapWidth(t *testing.T) {
+	tests := []struct {
+		width, maxWidth, wrap int
+		want                  int
+	}{
+		{200, 80, 0, 80},
+		{200, 80, 50, 50},
+		{200, 60, 100, 100},
+		{70, 80, 0, 64},
+	}
+	for _, tt := range tests {
+		ctx := &ViewContext{width: tt.width, height: 24, maxWidth: tt.maxWidth, wrapWidth: tt.wrap}
+		e := NewEditor(ctx, "doc.md", strings.Repeat("word ", 100))
+		if got := e.textarea.Width(); got != tt.want {
+			t.Errorf("width %d, max %d, wrap %d: text width = %d, want %d", tt.width, tt.maxWidth, tt.wrap, got, tt.want)
+		}
+		for _, line := range strings.Split(e.View(), "\n") {
+			if w := ansi.StringWidth(line); w > tt.width {
+				t.Errorf("width %d: view line is %d wide", tt.width, w)
+				break
+			}
+		}
+
+		e, _ = e.Update(tea.KeyPressMsg{Code: 'z', Mod: tea.ModAlt})
+		if got := e.textarea.Width(); got != tt.want {
+			t.Errorf("width %d, max %d, wrap %d: zen text width = %d, want %d", tt.width, tt.maxWidth, tt.wrap, got, tt.want)
+		}
+	}
+}
+
+func TestEditorZenLayout(t *testing.T) {
+	ctx := &ViewContext{width: 200, height: 30, maxWidth: 100, zenWidth: 64, zenPadding: 3}
+	e := NewEditor(ctx, "doc.md", strings.Repeat("word ", 100))
+	height := e.textarea.Height()
+	if got := e.textarea.Width(); got != 100 {
+		t.Errorf("text width outside zen mode = %d, want 100", got)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'z', Mod: tea.ModAlt})
+	if got := e.textarea.Width(); got != 64 {
+		t.Errorf("zen text width = %d, want 64", got)
+	}
+	if got := e.textarea.Height(); got != height-6 {
+		t.Errorf("zen text height = %d, want %d", got, height-6)
+	}
+	lines := strings.Split(e.View(), "\n")
+	if len(lines) > ctx.height {
+		t.Errorf("zen view is %d lines, more than the terminal's %d", len(lines), ctx.height)
+	}
+	if !strings.Contains(lines[5], "word") || strings.TrimSpace(lines[4]) != "" {
+		t.Errorf("zen text should start after the padding:\n%s", e.View())
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'z', Mod: tea.ModAlt})
+	if e.textarea.Width() != 100 || e.textarea.Height() != height {
+		t.Errorf("leaving zen mode: %dx%d, want 100x%d", e.textarea.Width(), e.textarea.Height(), height)
+	}
+}
+
+func TestEditorMetricsStrip(t *testing.T) {
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80, metricsStrip: []string{metrics.Economy, metrics.Rhythm}}
+	e := NewEditor(ctx, "doc.md", "It is nice. The rest reads well enough for a first draft.")
+	height := e.textarea.Height()
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'a', Mod: tea.ModAlt})
+	if !e.showStrip || e.textarea.Height() != height-1 {
+		t.Fatalf("alt+a should show the strip in a row taken from the text (height %d, was %d)", e.textarea.Height(), height)
+	}
+	view := ansi.Strip(e.View())
+	if n := strings.Count(view, "\n") + 1; n > ctx.height {
+		t.Errorf("view is %d lines, more than the terminal's %d", n, ctx.height)
+	}
+	if !regexp.MustCompile(`economy . 1\.00  rhythm . 0\.\d\d  FK -?\d`).MatchString(view) {
+		t.Errorf("strip missing or out of order:\n%s", view)
+	}
+
+	// Edits show on the strip after the debounce.
+	for _, r := range " Really very very nice." {
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	e, _ = e.Update(editorGradeTickMsg{})
+	if a, _ := e.strip.Axis(metrics.Economy); a.Score >= 1 {
+		t.Errorf("economy after adding fillers = %.2f, want below 1", a.Score)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'a', Mod: tea.ModAlt})
+	if e.showStrip || e.textarea.Height() != height {
+		t.Error("alt+a again should hide the strip and give the row back")
+	}
+}
+
+func TestEditorFocusMode(t *testing.T) {
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, "doc.md", "First paragraph\nstill first\n\nSecond one\n\nThird")
+	e.textarea.CursorDown()
+	dimmed := func(text string) bool {
+		// Each line ends with the space the textarea keeps for the cursor.
+		return strings.Contains(e.View(), focusDimStyle.Render(text+" "))
+	}
+	if dimmed("Second one") {
+		t.Error("nothing should be dimmed before focus mode is on")
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'f', Mod: tea.ModAlt})
+	if !dimmed("Second one") || !dimmed("Third") {
+		t.Error("other paragraphs should be dimmed in focus mode")
+	}
+	if dimmed("First paragraph") || dimmed("still first") {
+		t.Error("the cursor's paragraph should not be dimmed")
+	}
+
+	for range 2 {
+		e.textarea.CursorDown()
+	}
+	if !dimmed("First paragraph") || dimmed("Second one") {
+		t.Error("the focus should follow the cursor")
+	}
+	if lo, hi := paragraphAround([]string{"a", "", "b"}, 1); lo != 1 || hi != 1 {
+		t.Errorf("a blank line is its own paragraph, got %d-%d", lo, hi)
+	}
+}
+
+func TestEditorCounts(t *testing.T) {
+	ctx := &ViewContext{width: 120, height: 24, maxWidth: 120}
+	content := "---\ntitle: T\n---\nThe cat sat on the mat. The dog ran far away.\n\nA second paragraph has words."
+	e := NewEditor(ctx, "doc.md", content)
+	want := []string{"20 words", "chars", "3 sentences", "2 paragraphs", "words/sentence", "20 words"}
+	for i, w := range want {
+		if i > 0 {
+			e, _ = e.Update(tea.KeyPressMsg{Code: 'w', Mod: tea.ModAlt})
+		}
+		if bar := ansi.Strip(e.statusBarView()); !strings.Contains(bar, w) {
+			t.Errorf("after %d presses the status bar should show %q: %q", i, w, bar)
+		}
+	}
+	if got := countsStatus(countSentenceLengthMode, 0, textCounts{words: 15, sentences: 2}); got != "7.5 words/sentence" {
+		t.Errorf("average sentence length = %q", got)
+	}
+}
+
+func TestEditorBackup(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"notes/day.md": "saved"})
+	path := filepath.Join(dir, "notes", "day.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, bookDir: dir}
+	backup := filepath.Join(dir, ".ink", "backup", "notes", "day.md")
+
+	e := NewEditor(ctx, path, "saved")
+	e.textarea.CursorEnd()
+	e, cmd := e.Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+	if cmd == nil || !e.backupPending {
+		t.Fatal("an edit should schedule a backup")
+	}
+	e, _ = e.Update(editorBackupTickMsg{})
+	if data, err := os.ReadFile(backup); err != nil || string(data) != "saved!" {
+		t.Fatalf("backup = %q, %v", data, err)
+	}
+
+	// Reopening after a crash offers the backup.
+	e = NewEditor(ctx, path, "saved")
+	if !strings.Contains(ansi.Strip(e.statusBarView()), "alt+y restore") {
+		t.Errorf("the status bar should offer the backup: %q", ansi.Strip(e.statusBarView()))
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'y', Mod: tea.ModAlt})
+	if e.textarea.Value() != "saved!" || e.saved {
+		t.Errorf("alt+y should restore the backup unsaved: %q, saved %v", e.textarea.Value(), e.saved)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Error("saving should remove the backup")
+	}
+
+	// The offer is modal: typing doesn't answer it, or reach the text.
+	if err := os.WriteFile(backup, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e = NewEditor(ctx, path, "saved!")
+	for _, r := range "Down the lane" {
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	e, _ = pasteInto(e, "pasted")
+	if e.textarea.Value() != "saved!" || e.backup != "old" {
+		t.Errorf("typing should neither edit nor answer the offer: %q, backup %q", e.textarea.Value(), e.backup)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("typing n in text shouldn't discard the backup: %v", err)
+	}
+
+	// alt+n discards it.
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'n', Mod: tea.ModAlt})
+	if e.textarea.Value() != "saved!" || e.backup != "" {
+		t.Errorf("alt+n should keep the file: %q", e.textarea.Value())
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Error("alt+n should remove the backup")
+	}
+}
+
+func TestSentenceMarks(t *testing.T) {
+	long := "This sentence keeps going on and on with more and more words until it is far too long to read."
+	marks := sentenceMarks([]string{"Short one. " + long[:40], long[40:] + " Done."}, 15, 20)
+	got := func(m []bool) string {
+		b := make([]byte, len(m))
+		for i, v := range m {
+			b[i] = '.'
+			if v {
+				b[i] = 'x'
+			}
+		}
+		return string(b)
+	}
+	if want := strings.Repeat(".", 11) + strings.Repeat("x", 40); got(marks[0]) != want {
+		t.Errorf("first line marks = %s, want %s", got(marks[0]), want)
+	}
+	if want := strings.Repeat("x", len(long)-40) + "......"; got(marks[1]) != want {
+		t.Errorf("second line marks = %s, want %s", got(marks[1]), want)
+	}
+
+	ctx := &ViewContext{width: 120, height: 24, maxWidth: 120, longSentence: 15}
+	e := NewEditor(ctx, "doc.md", "Short one.\n\n"+long)
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'l', Mod: tea.ModAlt})
+	text := mdStyles[mdPlain].Inherit(e.textarea.Styles().Focused.Text)
+	if view := e.View(); !strings.Contains(view, hardSentenceStyle.Inherit(text).Render(long)) {
+		t.Errorf("the long sentence should be underlined:\n%q", view)
+	}
+	if view := e.View(); strings.Contains(view, hardSentenceStyle.Inherit(e.textarea.Styles().Focused.CursorLine).Render("Short one.")) {
+		t.Error("the short sentence shouldn't be underlined")
+	}
+}
+
+func TestEditorSessionSummary(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	s := newWritingSession(10, t0)
+	s.edit(12, t0.Add(time.Second))
+	s.edit(15, t0.Add(4*time.Second))
+	s.edit(14, t0.Add(time.Minute))
+	s.edit(16, t0.Add(61*time.Second))
+	if s.added != 7 || s.removed != 1 || s.typing != 4*time.Second {
+		t.Errorf("session = %+v, want 7 added, 1 removed, 4s typing", s)
+	}
+	if s.wpm() != 105 {
+		t.Errorf("wpm = %d, want 105", s.wpm())
+	}
+
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "Hello"})
+	path := filepath.Join(dir, "a.md")
+	st, _ := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, state: st}
+	e := NewEditor(ctx, path, "Hello")
+	e.textarea.CursorEnd()
+	for _, r := range " big world" {
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	e, cmd := e.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if cmd != nil {
+		t.Fatal("closing after edits should show the summary first")
+	}
+	view := ansi.Strip(e.View())
+	for _, want := range []string{"Session", "Words added", "+2"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("summary missing %q:\n%s", want, view)
+		}
+	}
+	_, cmd = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if cmd == nil {
+		t.Fatal("any key should close the summary")
+	}
+	if _, ok := cmd().(CloseEditorMsg); !ok {
+		t.Error("closing the summary should close the editor")
+	}
+
+	sessions, _ := st.Sessions()
+	if len(sessions) != 1 || sessions[0].Added != 2 || sessions[0].Path != path {
+		t.Errorf("logged sessions = %+v", sessions)
+	}
+	if w := bookWriting(st, dir); w.sessions != 1 || w.added != 2 {
+		t.Errorf("book writing = %+v", w)
+	}
+	if w := bookWriting(st, t.TempDir()); w.sessions != 0 {
+		t.Errorf("sessions outside the book counted: %+v", w)
+	}
+}
+
+func TestEditorCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "ink")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "ink@example.com")
+	}
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "draft", "b.md": "other"})
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, path, "draft")
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModCtrl})
+	if e.commitMsg != nil || e.statusText != "Save before committing" {
+		t.Fatalf("ctrl+g with unsaved changes: status %q", e.statusText)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModCtrl})
+	if e.commitMsg == nil || e.commitMsg.Value() != "Edit a.md" {
+		t.Fatal("ctrl+g should prompt for a message starting as Edit a.md")
+	}
+	if !strings.Contains(e.View(), "Commit:") {
+		t.Error("the status bar should show the commit prompt")
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+	e, cmd := e.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if e.commitMsg != nil || cmd == nil {
+		t.Fatal("enter should close the prompt and commit")
+	}
+	e, _ = e.Update(cmd())
+	if !strings.HasPrefix(e.statusText, "Committed ") {
+		t.Fatalf("status = %q", e.statusText)
+	}
+	out, _ := exec.Command("git", "-C", dir, "log", "--format=%s", "--name-only").Output()
+	if got := strings.Fields(string(out)); len(got) != 3 || got[0] != "Edit" || got[1] != "a.md!" || got[2] != "a.md" {
+		t.Errorf("git log = %q, want only a.md committed as \"Edit a.md!\"", out)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModCtrl})
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if e.commitMsg != nil {
+		t.Error("esc should cancel the commit")
+	}
+}
+
+func TestEditorSaveHooks(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "draft", "pretty.md": "Formatted"})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, saveHooks: []string{"cp {file} {file}.bak", "cp pretty.md {file}"}}
+	e := NewEditor(ctx, path, "draft")
+	e.textarea.InsertString("My ")
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if e.hookStatus != "Running hooks…" {
+		t.Errorf("hook status while running = %q", e.hookStatus)
+	}
+	e, _ = e.Update(runSaveHooks(ctx.saveHooks, path)())
+	if data, _ := os.ReadFile(path + ".bak"); string(data) != "My xdraft" {
+		t.Errorf("first hook should see the saved file, got %q", data)
+	}
+	if e.textarea.Value() != "Formatted" || e.hookStatus != "Hooks done, file reloaded" {
+		t.Errorf("after a formatting hook: %q, status %q", e.textarea.Value(), e.hookStatus)
+	}
+
+	msg := runSaveHooks([]string{"ls no-such-file", "cp pretty.md {file}.never"}, path)().(saveHooksDoneMsg)
+	e, _ = e.Update(msg)
+	if msg.ran != 1 || !strings.HasPrefix(e.hookStatus, "Hook failed: ls: ") {
+		t.Errorf("failing hook: ran %d, status %q", msg.ran, e.hookStatus)
+	}
+	if _, err := os.Stat(path + ".never"); !os.IsNotExist(err) {
+		t.Error("hooks after a failure shouldn't run")
+	}
+
+	// Quoted words keep their spaces, so a shell can be run for pipes.
+	msg = runSaveHooks([]string{`sh -c 'tr a-z A-Z < "$INK_FILE" > {file}.up'`}, path)().(saveHooksDoneMsg)
+	if data, _ := os.ReadFile(path + ".up"); msg.err != nil || string(data) != "FORMATTED" {
+		t.Errorf("sh -c hook: %v, wrote %q", msg.err, data)
+	}
+}
+
+func TestShellWords(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want []string
+	}{
+		{"prettier --write {file}", []string{"prettier", "--write", "{file}"}},
+		{`sh -c "pandoc {file} | wc -w"`, []string{"sh", "-c", "pandoc {file} | wc -w"}},
+		{`echo 'it''s' "a \"b\" \n" c\ d`, []string{"echo", "its", `a "b" \n`, "c d"}},
+		{`grep "" x`, []string{"grep", "", "x"}},
+		{"  tabs\tand  spaces ", []string{"tabs", "and", "spaces"}},
+		{`open "unterminated quote`, []string{"open", "unterminated quote"}},
+		{"", nil},
+	} {
+		if got := shellWords(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shellWords(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestReflowMarkdown(t *testing.T) {
+	src := `---
+title: A very long title that must not be wrapped by the reflow at all
+---
+# A heading that is rather long and stays on one line no matter what
+
+The quick brown fox jumps over
+the lazy dog and keeps running far away.
+
+- A list item with quite a lot of words in it
+  that continues here.
+- [ ] A task
+> Quoted text that goes on for a while and then some more words.
+
+` + "```" + `
+code that is long enough to wrap but must never be touched at all
+` + "```" + `
+
+| a table | row that is far too long for the width but stays as it is |
+
+Hard break here  
+and here.`
+	want := `---
+title: A very long title that must not be wrapped by the reflow at all
+---
+# A heading that is rather long and stays on one line no matter what
+
+The quick brown fox jumps over the
+lazy dog and keeps running far away.
+
+- A list item with quite a lot of
+  words in it that continues here.
+- [ ] A task
+> Quoted text that goes on for a
+> while and then some more words.
+
+` + "```" + `
+code that is long enough to wrap but must never be touched at all
+` + "```" + `
+
+| a table | row that is far too long for the width but stays as it is |
+
+Hard break here  
+and here.`
+	if got := reflowMarkdown(src, 36); got != want {
+		t.Errorf("reflowMarkdown:\n%s\nwant:\n%s", got, want)
+	}
+	if got := reflowMarkdown(want, 36); got != want {
+		t.Errorf("reflowing again should change nothing:\n%s", got)
+	}
+}
+
+func TestReflowKeepsBlockMarkersInline(t *testing.T) {
+	for _, tt := range []struct{ src, want string }{
+		{"The ranges are wide - often larger than expected.", "The ranges are wide -\noften larger than\nexpected."},
+		{"Add all of those up + carry the one.", "Add all of those up +\ncarry the one."},
+		{"Stars get counted as * twice here.", "Stars get counted as *\ntwice here."},
+		{"We finished in place 2. Then we left.", "We finished in place 2.\nThen we left."},
+		{"Pick either option 1) or the other.", "Pick either option 1)\nor the other."},
+		{"Arrows like this one > point right.", "Arrows like this one >\npoint right."},
+		{"Tagged with a lonely # by the author.", "Tagged with a lonely #\nby the author."},
+		{"- Items in the list - with dashes - wrap.", "- Items in the list -\n  with dashes -\n  wrap."},
+	} {
+		got := reflowMarkdown(tt.src, 20)
+		if got != tt.want {
+			t.Errorf("reflowMarkdown(%q):\n%s\nwant:\n%s", tt.src, got, tt.want)
+		}
+		if again := reflowMarkdown(got, 20); again != got {
+			t.Errorf("reflowing %q again changed it:\n%s", tt.src, again)
+		}
+	}
+}
+
+func TestEditorWrapOnSave(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": ""})
+	path := filepath.Join(dir, "a.md")
+	content := "one two three four five six seven eight nine ten"
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, wrapOnSave: 20}
+	e := NewEditor(ctx, path, content)
+	e.textarea.SetCursorColumn(strings.Index(content, "seven"))
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+
+	want := "one two three four\nfive six seven eight\nnine ten"
+	if got := e.textarea.Value(); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want || !e.saved {
+		t.Errorf("saved %q, saved flag %v", data, e.saved)
+	}
+	if r, c := e.cursor(); r != 1 || c != 9 {
+		t.Errorf("cursor = %d:%d, want 1:9 still before \"seven\"", r, c)
+	}
+}
+
+func TestStrftime(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("CET", 3600))
+	for format, want := range map[string]string{
+		defaultDateFormat:       "2024-03-05",
+		defaultTimeFormat:       "14:07",
+		defaultTimestampFormat:  "2024-03-05T14:07:09+01:00",
+		"%A %e %B %y, %I:%M %p": "Tuesday  5 March 24, 02:07 PM",
+		"100%% on %d/%m (%Q)":   "100% on 05/03 (%Q)",
+		"Jan 2 %Y":              "Jan 2 2024",
+	} {
+		if got := strftime(format, ts); got != want {
+			t.Errorf("strftime(%q) = %q, want %q", format, got, want)
+		}
+	}
+
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, dateFormat: "%d.%m.%Y"}
+	e := NewEditor(ctx, "doc.md", "")
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModAlt})
+	if got, want := e.textarea.Value(), strftime("%d.%m.%Y", time.Now()); got != want {
+		t.Errorf("alt+d inserted %q, want %q", got, want)
+	}
+}
+
+func TestEditorLint(t *testing.T) {
+	script := `echo "$1:2:5: Avoid 'very'."
+echo "$1:1:1:Vale.Headings:Use sentence case."
+echo "checked 1 file"
+grep -q unsaved "$1" && echo "$1:3:1: buffer checked"
+exit 1
+`
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# Title\nA very good day.\n", "lint.sh": script})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, path, "# Title\nA very good day.\n")
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'e', Mod: tea.ModAlt})
+	if !strings.HasPrefix(e.statusText, "No checker") {
+		t.Errorf("without a checker: status %q", e.statusText)
+	}
+
+	ctx.lintCommands = []string{"sh lint.sh {file}"}
+	e.textarea.MoveToEnd()
+	e.textarea.InsertString("unsaved")
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'e', Mod: tea.ModAlt})
+	if e.statusText != "Checking…" {
+		t.Errorf("status while checking = %q", e.statusText)
+	}
+	e, _ = e.Update(runLint(ctx.lintCommands, path, e.textarea.Value(), true)())
+	if e.lint == nil || len(e.lint.issues) != 3 {
+		t.Fatalf("lint panel = %+v", e.lint)
+	}
+	if got := e.lint.issues[0]; got.row != 0 || got.col != 0 || got.message != "Vale.Headings:Use sentence case." {
+		t.Errorf("first issue = %+v", got)
+	}
+	if !strings.Contains(e.View(), "  2:5 Avoid 'very'.") {
+		t.Errorf("panel doesn't list the issues:\n%s", e.View())
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyDown})
+	e, _ = e.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if row, col := e.cursor(); e.lint != nil || row != 1 || col != 4 {
+		t.Errorf("jump: panel %v, cursor %d:%d, want 1:4", e.lint, row, col)
+	}
+
+	e, _ = e.Update(runLint([]string{"ls no-such-file"}, path, "", true)())
+	if e.lint != nil || !strings.HasPrefix(e.statusText, "Checker failed: ") {
+		t.Errorf("failing checker: status %q", e.statusText)
+	}
+}
+
+func TestLintCheckers(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":      "# Title\nA very good day.\n",
+		"vale.sh":   `echo "$1:2:3: Avoid 'very'."`,
+		"mdlint.sh": `echo "$1:1 MD041/first-line-heading First line"; exit 1`,
+	})
+	path := filepath.Join(dir, "a.md")
+	msg := runLint([]string{"sh vale.sh {file}", "sh mdlint.sh {file}", "no-such-checker"}, path, "text", false)().(lintDoneMsg)
+	want := []lintIssue{
+		{row: 0, col: 0, tool: "sh", message: "MD041/first-line-heading First line"},
+		{row: 1, col: 2, tool: "sh", message: "Avoid 'very'."},
+	}
+	if !reflect.DeepEqual(msg.issues, want) {
+		t.Errorf("issues = %+v, want %+v", msg.issues, want)
+	}
+	if msg.err == nil || !strings.HasPrefix(msg.output, "no-such-checker: ") {
+		t.Errorf("the missing checker should be reported: output %q, err %v", msg.output, msg.err)
+	}
+	if got := msg.issues[1].label(); got != "  2:3 sh: Avoid 'very'." {
+		t.Errorf("label = %q", got)
+	}
+
+	// A background run only counts the issues.
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, lintCommands: []string{"sh vale.sh {file}"}}
+	e := NewEditor(ctx, path, "# Title\nA very good day.\n")
+	e, _ = e.Update(runLint(ctx.lintCommands, path, e.textarea.Value(), false)())
+	if e.lint != nil || !strings.Contains(e.statusBarView(), "1 issue") {
+		t.Errorf("background run: panel %v, status bar %q", e.lint, e.statusBarView())
+	}
+}
+
+func TestEditorPlugins(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "# Title\nhello there\nsecond line\n",
+		"b.md":     "# B\n",
+		"upper.sh": "tr a-z A-Z",
+		"fail.sh":  "echo broken >&2; exit 3",
+		"info.sh":  `printf '{"message": "%s at %s", "open": "b.md"}' "$INK_SELECTION" "$INK_LINE"`,
+	})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, vimKeys: true, plugins: map[string]string{
+		"alt+u": "sh upper.sh",
+		"alt+o": "sh info.sh {file}",
+	}}
+	e := NewEditor(ctx, path, "# Title\nhello there\nsecond line\n")
+	run := func(k rune) pluginDoneMsg {
+		t.Helper()
+		var cmd tea.Cmd
+		e, cmd = e.Update(tea.KeyPressMsg{Code: k, Mod: tea.ModAlt})
+		if cmd == nil {
+			t.Fatal("the plugin key did nothing")
+		}
+		return cmd().(pluginDoneMsg)
+	}
+
+	// Plain output over the whole buffer is only shown.
+	msg := run('u')
+	e, _ = e.Update(msg)
+	if !strings.HasPrefix(e.textarea.Value(), "# Title") || e.statusText != "SECOND LINE" {
+		t.Errorf("whole buffer: status %q, text %q", e.statusText, e.textarea.Value())
+	}
+
+	// Over a visual selection it replaces the selected lines.
+	e.moveTo(1, 0)
+	for _, k := range []rune{'V', 'j'} {
+		e, _ = e.Update(tea.KeyPressMsg{Code: k, Text: string(k)})
+	}
+	msg = run('u')
+	if !msg.in.selection || msg.in.text != "hello there\nsecond line" {
+		t.Errorf("plugin input = %+v", msg.in)
+	}
+	e, _ = e.Update(msg)
+	if got := e.textarea.Value(); got != "# Title\nHELLO THERE\nSECOND LINE\n" || e.saved {
+		t.Errorf("after replace: saved %v, text %q", e.saved, got)
+	}
+
+	// An edit made while the plugin ran wins over the plugin's.
+	e.moveTo(1, 0)
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'V', Text: "V"})
+	msg = run('u')
+	e.textarea.InsertString("x")
+	e, _ = e.contentChanged(nil)
+	e, _ = e.Update(msg)
+	if !strings.Contains(e.statusText, "edit dropped") {
+		t.Errorf("stale edit: status %q", e.statusText)
+	}
+
+	// JSON actions: a message, and opening a document, once saved.
+	e, _ = e.Update(run('o'))
+	if e.statusText != "Save to open b.md" {
+		t.Errorf("unsaved open: status %q", e.statusText)
+	}
+	e.save("Saved")
+	var cmd tea.Cmd
+	e, cmd = e.Update(run('o'))
+	if e.statusText != "0 at 1" || cmd == nil {
+		t.Errorf("JSON actions: status %q, cmd %v", e.statusText, cmd)
+	}
+
+	e, _ = e.Update(runPlugin("alt+x", "sh fail.sh", path, pluginInput{}, e.editSeq)())
+	if e.statusText != "Plugin alt+x failed: broken" {
+		t.Errorf("failing plugin: status %q", e.statusText)
+	}
+}
+
+func TestEditorRewrite(t *testing.T) {
+	const text = "# Title\n\nintro line\n\n## One\n\nfirst part\nstill first\n\n## Two\n\nsecond part\n"
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     text,
+		"upper.sh": "sed 1,2d | tr a-z A-Z",
+		"fail.sh":  "echo no model >&2; exit 1",
+	})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, vimKeys: true}
+	e := NewEditor(ctx, path, text)
+	rewrite := func() rewriteDoneMsg {
+		t.Helper()
+		var cmd tea.Cmd
+		e, cmd = e.Update(tea.KeyPressMsg{Code: 'r', Mod: tea.ModAlt})
+		if cmd == nil {
+			t.Fatal("alt+r did nothing")
+		}
+		msg, ok := cmd().(rewriteDoneMsg)
+		if !ok {
+			t.Fatalf("alt+r: status %q", e.statusText)
+		}
+		return msg
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'r', Mod: tea.ModAlt})
+	if !strings.HasPrefix(e.statusText, "No rewrite command") {
+		t.Errorf("unconfigured: status %q", e.statusText)
+	}
+	ctx.rewriteCommand, ctx.rewritePrompt = "sh upper.sh", "Make it louder."
+
+	// The section the cursor is in goes out, and nothing changes until the
+	// suggestion is accepted.
+	e.moveTo(6, 0)
+	msg := rewrite()
+	if msg.old != "first part\nstill first" || msg.text != "FIRST PART\nSTILL FIRST" {
+		t.Errorf("section: sent %q, got %q", msg.old, msg.text)
+	}
+	e, _ = e.Update(msg)
+	if e.rewrite == nil || e.textarea.Value() != text {
+		t.Fatalf("suggestion not shown, or applied early: %q", e.textarea.Value())
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'n', Text: "n"})
+	if e.rewrite != nil || e.textarea.Value() != text || !e.saved {
+		t.Errorf("rejected: text %q", e.textarea.Value())
+	}
+	e, _ = e.Update(msg)
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	want := strings.Replace(text, "first part\nstill first", "FIRST PART\nSTILL FIRST", 1)
+	if got := e.textarea.Value(); e.rewrite != nil || got != want || e.saved {
+		t.Errorf("accepted: saved %v, text %q", e.saved, got)
+	}
+
+	// A visual selection goes out instead of the section.
+	e.moveTo(2, 0)
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'V', Text: "V"})
+	if msg = rewrite(); msg.from != 2 || msg.to != 3 || msg.old != "intro line" {
+		t.Errorf("selection: sent lines [%d, %d) %q", msg.from, msg.to, msg.old)
+	}
+
+	// A suggestion for text edited meanwhile is dropped.
+	e.textarea.InsertString("x")
+	e, _ = e.contentChanged(nil)
+	e, _ = e.Update(msg)
+	if e.rewrite != nil || !strings.Contains(e.statusText, "suggestion dropped") {
+		t.Errorf("stale suggestion: status %q", e.statusText)
+	}
+
+	e, _ = e.Update(runRewrite("sh fail.sh", "", path, "x", 0, 1, e.editSeq)())
+	if e.statusText != "Rewrite failed: no model" {
+		t.Errorf("failing command: status %q", e.statusText)
+	}
+}
+
+func TestSectionAt(t *testing.T) {
+	const text = "---\ntitle: T\n---\n\npreface\n\n# A\n\nbody a\n```\n# not a heading\n```\n\n## A.1\n\nbody a1\n\n# B\n"
+	tests := []struct {
+		row, from, to int
+	}{
+		{row: 4, from: 4, to: 5},   // before the first heading, after the front matter
+		{row: 8, from: 8, to: 16},  // a section runs over its subsections
+		{row: 10, from: 8, to: 16}, // a # in a code block is no heading
+		{row: 15, from: 15, to: 16},
+		{row: 17, from: 19, to: 19}, // an empty section
+	}
+	for _, tt := range tests {
+		if from, to := sectionAt(text, tt.row); from != tt.from || to != tt.to {
+			t.Errorf("sectionAt(%d) = [%d, %d), want [%d, %d)", tt.row, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestParsePluginOutput(t *testing.T) {
+	tests := []struct {
+		out       string
+		selection bool
+		want      pluginActions
+		replace   string
+		err       bool
+	}{
+		{out: "", want: pluginActions{}},
+		{out: "one\ntwo\n", want: pluginActions{Message: "two"}},
+		{out: "Bonjour\n", selection: true, replace: "Bonjour"},
+		{out: `{"message": "ok", "open": "b.md"}`, want: pluginActions{Message: "ok", Open: "b.md"}},
+		{out: `{"replace": ""}`, replace: ""},
+		{out: `{"message": `, err: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePluginOutput(tt.out, tt.selection)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: err = %v", tt.out, err)
+			continue
+		}
+		if got.Message != tt.want.Message || got.Open != tt.want.Open {
+			t.Errorf("%q: got %+v, want %+v", tt.out, got, tt.want)
+		}
+		wantReplace := tt.replace != "" || strings.Contains(tt.out, "replace")
+		if (got.Replace != nil) != wantReplace || got.Replace != nil && *got.Replace != tt.replace {
+			t.Errorf("%q: replace = %v, want %q", tt.out, got.Replace, tt.replace)
+		}
+	}
+}
+
+func TestEditorLock(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "draft"})
+	path := filepath.Join(dir, "a.md")
+	host, _ := os.Hostname()
+	lock, err := lockPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relative, _ := lockPath(filepath.Join(dir, ".", "a.md")); relative != lock || strings.HasPrefix(lock, dir) {
+		t.Errorf("lock file %s should be kept out of the book, one per document", lock)
+	}
+
+	// A lock left by a process that is gone is taken over.
+	os.MkdirAll(filepath.Dir(lock), 0755)
+	os.WriteFile(lock, []byte("999999999 "+host+"\n"), 0644)
+	e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, "draft")
+	e.lock()
+	if !e.locked || e.lockedBy != "" {
+		t.Fatalf("stale lock: locked %v, by %q", e.locked, e.lockedBy)
+	}
+	if data, _ := os.ReadFile(lock); strings.TrimSpace(string(data)) != e.ctx.lockOwner() {
+		t.Errorf("lock file = %q, want %q", data, e.ctx.lockOwner())
+	}
+	e.unlock()
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Error("unlock should remove the lock file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("the book should hold only its document: %v", entries)
+	}
+
+	// Another running ink has it open: saving needs confirmation.
+	other := fmt.Sprintf("%d %s", os.Getppid(), host)
+	os.WriteFile(lock, []byte(other+"\n"), 0644)
+	e = NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, "draft")
+	e.lock()
+	if e.locked || e.lockedBy != fmt.Sprintf("ink %d", os.Getppid()) {
+		t.Fatalf("live lock: locked %v, by %q", e.locked, e.lockedBy)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if data, _ := os.ReadFile(path); string(data) != "draft" || !e.confirmSave {
+		t.Errorf("first ctrl+s should ask: file %q, confirm %v", data, e.confirmSave)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if data, _ := os.ReadFile(path); string(data) != "xdraft" {
+		t.Errorf("second ctrl+s should save, file %q", data)
+	}
+	e.unlock()
+	if data, _ := os.ReadFile(lock); strings.TrimSpace(string(data)) != other {
+		t.Errorf("another ink's lock must be left alone, got %q", data)
+	}
+
+	// Sessions a server runs in one process hold locks of their own.
+	os.Remove(lock)
+	first := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80, session: "ssh ann@10.0.0.1:5000"}, path, "draft")
+	first.lock()
+	second := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80, session: "ssh bob@10.0.0.2:5000"}, path, "draft")
+	second.lock()
+	if !first.locked || second.locked || second.lockedBy != fmt.Sprintf("ink %d (ssh ann@10.0.0.1:5000)", os.Getpid()) {
+		t.Errorf("second session: locked %v, by %q", second.locked, second.lockedBy)
+	}
+	second.unlock()
+	first.unlock()
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Error("the session holding the lock should remove it")
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		raw  []byte
+		text string
+		enc  fileEncoding
+		bom  bool
+	}{
+		{[]byte("Café"), "Café", encUTF8, false},
+		{[]byte("\xEF\xBB\xBFCafé"), "Café", encUTF8BOM, true},
+		{[]byte("\xFF\xFEC\x00a\x00f\x00\xE9\x00"), "Café", encUTF16LE, true},
+		{[]byte("\xFE\xFF\x00C\x00a\x00f\x00\xE9"), "Café", encUTF16BE, true},
+		{[]byte("C\x00a\x00f\x00\xE9\x00"), "Café", encUTF16LE, false},
+		{[]byte("\x00C\x00a\x00f\x00\xE9"), "Café", encUTF16BE, false},
+		{[]byte("Caf\xE9"), "Café", encLatin1, false},
+	}
+	for _, tt := range tests {
+		text, enc, bom := decodeText(tt.raw)
+		if text != tt.text || enc != tt.enc || bom != tt.bom {
+			t.Errorf("decodeText(%q) = %q, %v, %v; want %q, %v, %v", tt.raw, text, enc, bom, tt.text, tt.enc, tt.bom)
+		}
+		// Files are saved back as found, with a BOM only if they had one.
+		if raw, err := encodeText(text, enc, bom); err != nil || !bytes.Equal(raw, tt.raw) {
+			t.Errorf("encodeText(%q, %v) = %q, %v; want %q", text, enc, raw, err, tt.raw)
+		}
+	}
+	if _, err := encodeText("Café ✓", encLatin1, false); err == nil || !strings.Contains(err.Error(), "Latin-1") {
+		t.Errorf("encoding ✓ as Latin-1: err %v", err)
+	}
+}
+
+func TestEditorKeepsEncoding(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"bom.md": "\xEF\xBB\xBF# Café\r\n", "latin.md": "Caf\xE9\n"})
+	path := filepath.Join(dir, "bom.md")
+	raw, _ := os.ReadFile(path)
+	e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, documentText(raw))
+	e.textarea.MoveToEnd()
+	e, _ = e.Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if data, _ := os.ReadFile(path); string(data) != "\xEF\xBB\xBF# Café\r\n!" {
+		t.Errorf("saved %q, want the BOM and CRLF kept", data)
+	}
+
+	path = filepath.Join(dir, "latin.md")
+	raw, _ = os.ReadFile(path)
+	e = NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, documentText(raw))
+	if !strings.Contains(e.statusBarView(), "Latin-1") {
+		t.Errorf("status bar should name the encoding: %q", e.statusBarView())
+	}
+	e.textarea.MoveToEnd()
+	e, _ = e.Update(tea.KeyPressMsg{Code: '✓', Text: "✓"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if data, _ := os.ReadFile(path); string(data) != "Caf\xE9\n" || e.err == nil {
+		t.Errorf("unencodable text: saved %q, err %v", data, e.err)
+	}
+}
+
+func TestEditorKeepsCRLF(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"win.md": "# Title\r\n\r\nFirst line\r\n", "unix.md": "# Title\n\nStray\r\nend\n"})
+	for name, want := range map[string]string{
+		"win.md":  "# Title\r\n\r\nFirst line!\r\n",
+		"unix.md": "# Title\n\nStray!\nend\n",
+	} {
+		path := filepath.Join(dir, name)
+		raw, _ := os.ReadFile(path)
+		e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, documentText(raw))
+		e.moveTo(2, 10)
+		if name == "unix.md" {
+			e.moveTo(2, 5)
+		}
+		e, _ = e.Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+		e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s: saved %q, want %q", name, data, want)
+		}
+		if got := strings.Contains(e.statusBarView(), "CRLF"); got != (name == "win.md") {
+			t.Errorf("%s: CRLF in status bar = %v", name, got)
+		}
+	}
+}
+
+// pasteInto pastes content into e and delivers the clipboard read the paste
+// starts, as the program loop would.
+func pasteInto(e Editor, content string) (Editor, tea.Cmd) {
+	e, cmd := e.Update(tea.PasteMsg{Content: content})
+	if cmd == nil {
+		return e, nil
+	}
+	msg, ok := cmd().(pasteHTMLMsg)
+	if !ok {
+		return e, cmd
+	}
+	return e.Update(msg)
+}
+
+func TestEditorPaste(t *testing.T) {
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, "doc.md", "Start ")
+	e.textarea.MoveToEnd()
+	e, cmd := e.Update(tea.PasteMsg{Content: "one\r\ntwo\r\nthree"})
+	if e.textarea.Value() != "Start " {
+		t.Error("the paste should wait for the clipboard to be read")
+	}
+	read := cmd()
+	msg, ok := read.(pasteHTMLMsg)
+	if !ok {
+		t.Fatalf("paste cmd = %T, want pasteHTMLMsg", read)
+	}
+	e, cmd = e.Update(msg)
+	if got := e.textarea.Value(); got != "Start one\ntwo\nthree" {
+		t.Errorf("after paste: %q", got)
+	}
+	if cmd == nil || !e.gradePending || e.saved {
+		t.Errorf("paste should count as an edit: cmd %v, grade pending %v, saved %v", cmd != nil, e.gradePending, e.saved)
+	}
+	if e.session.added != 3 {
+		t.Errorf("session added %d words, want 3", e.session.added)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'p', Mod: tea.ModAlt})
+	value := e.textarea.Value()
+	e, _ = e.Update(tea.PasteMsg{Content: "Pasted\ntitle"})
+	if e.textarea.Value() != value || e.matter.input.Value() != "Pasted title" {
+		t.Errorf("paste in the form: field %q, text changed %v", e.matter.input.Value(), e.textarea.Value() != value)
+	}
+}
+
+func TestEditorPasteHTML(t *testing.T) {
+	clip := `<meta charset="utf-8"><p>See <a href="https://example.com">the <b>site</b></a></p><ul><li>one</li><li>two</li></ul>`
+	orig := readClipboardHTML
+	readClipboardHTML = func() string { return clip }
+	t.Cleanup(func() { readClipboardHTML = orig })
+
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80}
+	e := NewEditor(ctx, "doc.md", "")
+	e, _ = pasteInto(e, "See the site\none\ntwo")
+	if got, want := e.textarea.Value(), "See [the **site**](https://example.com)\n\n- one\n- two"; got != want {
+		t.Errorf("HTML paste: got %q, want %q", got, want)
+	}
+	if e.statusText != "Pasted HTML as markdown" {
+		t.Errorf("status %q", e.statusText)
+	}
+
+	// Text that isn't the clipboard's, from the primary selection, say.
+	e = NewEditor(ctx, "doc.md", "")
+	e, _ = pasteInto(e, "something else")
+	if got := e.textarea.Value(); got != "something else" {
+		t.Errorf("other paste: got %q", got)
+	}
+
+	// In a code block the text is pasted as it is.
+	e = NewEditor(ctx, "doc.md", "```\n")
+	e.textarea.MoveToEnd()
+	e, _ = e.Update(tea.PasteMsg{Content: "See the site one two"})
+	if got := e.textarea.Value(); got != "```\nSee the site one two" {
+		t.Errorf("paste in code: got %q", got)
+	}
+}
+
+func TestEditorReadOnly(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "Fixed text"})
+	path := filepath.Join(dir, "a.md")
+	os.Chmod(path, 0444)
+	e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, "Fixed text")
+	if !e.readOnly || !strings.Contains(e.statusBarView(), "read-only") {
+		t.Fatalf("read-only file: readOnly %v, status bar %q", e.readOnly, e.statusBarView())
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'b', Mod: tea.ModAlt})
+	e, _ = pasteInto(e, "pasted")
+	if e.textarea.Value() != "Fixed text" || !e.saved {
+		t.Errorf("edits went through: %q, saved %v", e.textarea.Value(), e.saved)
+	}
+	if !strings.HasPrefix(e.statusText, "Read-only file") {
+		t.Errorf("status = %q", e.statusText)
+	}
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if e.err == nil {
+		t.Error("ctrl+s on a read-only file should fail")
+	}
+
+	os.Chmod(path, 0644)
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'r', Mod: tea.ModCtrl})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if e.readOnly || e.textarea.Value() == "Fixed text" {
+		t.Errorf("after chmod and ctrl+r: readOnly %v, %q", e.readOnly, e.textarea.Value())
+	}
+}
@@ -0,0 +1,485 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// vimMode is the mode of the editor's vim emulation.
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual // selects whole lines, like vim's V
+)
+
+// vimState is the state of the vim emulation layered over the textarea.
+type vimState struct {
+	mode     vimMode
+	count    int    // count typed before a command, 0 if none
+	pending  string // operator ("d", "y", "c") or prefix ("g") waiting for the next key
+	opCount  int    // count typed before the pending operator
+	register string // text last yanked or deleted
+	linewise bool   // register holds whole lines
+	anchor   int    // line where visual mode started
+}
+
+// vimPassthrough lists the keys without text that keep their textarea
+// meaning in normal and visual mode; other keys can't edit there.
+var vimPassthrough = map[string]bool{
+	"pgup": true, "pgdown": true, "ctrl+home": true, "ctrl+end": true,
+	"ctrl+t": true, "alt+<": true, "alt+>": true,
+}
+
+// status names the mode, and any count or operator typed so far, for the
+// status bar.
+func (v vimState) status() string {
+	s := [...]string{vimNormal: "NORMAL", vimInsert: "INSERT", vimVisual: "VISUAL"}[v.mode]
+	if v.count > 0 || v.pending != "" {
+		typed := v.pending
+		if v.opCount > 1 {
+			typed = fmt.Sprint(v.opCount) + typed
+		}
+		if v.count > 0 {
+			typed += fmt.Sprint(v.count)
+		}
+		s += " " + typed
+	}
+	return s
+}
+
+// updateVim handles a key in vim mode. It reports false for keys the
+// textarea or the editor's own bindings should handle.
+func (e *Editor) updateVim(msg tea.KeyMsg) bool {
+	v := e.vim
+	k := msg.String()
+	if v.mode == vimInsert {
+		if k != "esc" {
+			return false
+		}
+		v.mode = vimNormal
+		row, col := e.cursor()
+		e.moveTo(row, max(col-1, 0))
+		return true
+	}
+	if k == "esc" {
+		v.mode = vimNormal
+		v.count, v.opCount, v.pending = 0, 0, ""
+		return true
+	}
+	if msg.Key().Text == "" && !isVimArrow(k) {
+		return false
+	}
+
+	if len(k) == 1 && k[0] >= '0' && k[0] <= '9' && (k != "0" || v.count > 0) {
+		v.count = v.count*10 + int(k[0]-'0')
+		return true
+	}
+	n, counted := max(v.count, 1), v.count > 0
+	v.count = 0
+
+	switch v.pending {
+	case "g":
+		v.pending = ""
+		if k != "g" {
+			return true
+		}
+		k = "gg"
+	case "dg", "yg", "cg":
+		op, opCount := v.pending[:1], v.opCount
+		v.pending, v.opCount = "", 0
+		if k != "g" {
+			return true
+		}
+		e.vimOperator(op, "gg", max(opCount, 1), opCount > 0)
+		return true
+	case "d", "y", "c":
+		op := v.pending
+		n *= max(v.opCount, 1)
+		counted = counted || v.opCount > 0
+		v.pending, v.opCount = "", 0
+		e.vimOperator(op, k, n, counted)
+		return true
+	}
+
+	lines := e.lines()
+	row, col := e.cursor()
+	if r, c, _, _, ok := vimMotion(lines, row, col, k, n, counted); ok {
+		e.moveTo(r, min(c, max(len(lines[r])-1, 0)))
+		return true
+	}
+
+	if v.mode == vimVisual {
+		lo, hi := min(v.anchor, row), max(v.anchor, row)
+		switch k {
+		case "y", "d", "x", "c":
+			op := k
+			if op == "x" {
+				op = "d"
+			}
+			v.mode = vimNormal
+			e.vimApply(op, lo, 0, hi, 0, true)
+			return true
+		case "v", "V":
+			v.mode = vimNormal
+		}
+		return true
+	}
+
+	switch k {
+	case "g", "d", "y", "c":
+		v.pending = k
+		if counted {
+			v.opCount = n
+		}
+	case "i":
+		v.mode = vimInsert
+	case "a":
+		v.mode = vimInsert
+		e.moveTo(row, min(col+1, len(lines[row])))
+	case "I":
+		v.mode = vimInsert
+		e.moveTo(row, firstNonBlank(lines[row]))
+	case "A":
+		v.mode = vimInsert
+		e.moveTo(row, len(lines[row]))
+	case "o", "O":
+		at := row + 1
+		if k == "O" {
+			at = row
+		}
+		indent := lines[row][:firstNonBlank(lines[row])]
+		lines = append(lines[:at], append([][]rune{append([]rune(nil), indent...)}, lines[at:]...)...)
+		e.setLines(lines, at, len(indent))
+		v.mode = vimInsert
+	case "x":
+		if len(lines[row]) > 0 {
+			e.vimApply("d", row, col, row, min(col+n, len(lines[row])), false)
+		}
+	case "D", "C":
+		e.vimApply(strings.ToLower(k), row, col, row, len(lines[row]), false)
+	case "p", "P":
+		e.vimPut(k == "p", n)
+	case "v", "V":
+		v.mode = vimVisual
+		v.anchor = row
+	}
+	return true
+}
+
+// vimOperator applies operator op ("d", "y" or "c") over the motion k, or
+// over n whole lines when k repeats the operator (dd, yy, cc).
+func (e *Editor) vimOperator(op, k string, n int, counted bool) {
+	lines := e.lines()
+	row, col := e.cursor()
+	if k == op {
+		e.vimApply(op, row, 0, min(row+n-1, len(lines)-1), 0, true)
+		return
+	}
+	if k == "g" {
+		// A two-key motion; only gg is supported.
+		e.vim.pending, e.vim.opCount = op+"g", 0
+		if counted {
+			e.vim.opCount = n
+		}
+		return
+	}
+	if op == "c" && k == "w" {
+		// Like vim, cw changes to the end of the word.
+		k = "e"
+	}
+	r, c, linewise, inclusive, ok := vimMotion(lines, row, col, k, n, counted)
+	if !ok {
+		return
+	}
+	if linewise {
+		e.vimApply(op, min(row, r), 0, max(row, r), 0, true)
+		return
+	}
+	if r < row || (r == row && c < col) {
+		row, col, r, c = r, c, row, col
+	}
+	if inclusive {
+		c = min(c+1, len(lines[r]))
+	}
+	e.vimApply(op, row, col, r, c, false)
+}
+
+// vimApply yanks, deletes or changes the text from (row, col) to (r, c),
+// or the whole lines row to r when linewise is set.
+func (e *Editor) vimApply(op string, row, col, r, c int, linewise bool) {
+	lines := e.lines()
+	v := e.vim
+	if linewise {
+		var yanked []string
+		for _, l := range lines[row : r+1] {
+			yanked = append(yanked, string(l))
+		}
+		v.register, v.linewise = strings.Join(yanked, "\n"), true
+		switch op {
+		case "y":
+			e.moveTo(row, min(e.column(), max(len(lines[row])-1, 0)))
+		case "d":
+			lines = append(lines[:row], lines[r+1:]...)
+			if len(lines) == 0 {
+				lines = [][]rune{{}}
+			}
+			row = min(row, len(lines)-1)
+			e.setLines(lines, row, firstNonBlank(lines[row]))
+		case "c":
+			lines = append(lines[:row+1], lines[r+1:]...)
+			lines[row] = nil
+			e.setLines(lines, row, 0)
+			v.mode = vimInsert
+		}
+		return
+	}
+
+	var b strings.Builder
+	for i := row; i <= r; i++ {
+		from, to := 0, len(lines[i])
+		if i == row {
+			from = col
+		}
+		if i == r {
+			to = c
+		}
+		b.WriteString(string(lines[i][from:to]))
+		if i < r {
+			b.WriteByte('\n')
+		}
+	}
+	v.register, v.linewise = b.String(), false
+	if op == "y" {
+		e.moveTo(row, col)
+		return
+	}
+	joined := append(append([]rune(nil), lines[row][:col]...), lines[r][c:]...)
+	lines = append(lines[:row+1], lines[r+1:]...)
+	lines[row] = joined
+	if op == "c" {
+		v.mode = vimInsert
+		e.setLines(lines, row, col)
+	} else {
+		e.setLines(lines, row, min(col, max(len(joined)-1, 0)))
+	}
+}
+
+// vimPut pastes the register n times after the cursor (or the current line),
+// or before it when after is false.
+func (e *Editor) vimPut(after bool, n int) {
+	v := e.vim
+	if v.register == "" && !v.linewise {
+		return
+	}
+	lines := e.lines()
+	row, col := e.cursor()
+	text := strings.Repeat(v.register+"\n", n)
+	if v.linewise {
+		at := row
+		if after {
+			at++
+		}
+		var put [][]rune
+		for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			put = append(put, []rune(l))
+		}
+		lines = append(lines[:at], append(put, lines[at:]...)...)
+		e.setLines(lines, at, firstNonBlank(lines[at]))
+		return
+	}
+	text = strings.Repeat(v.register, n)
+	if after && len(lines[row]) > 0 {
+		col++
+	}
+	put := strings.Split(text, "\n")
+	tail := append([]rune(nil), lines[row][col:]...)
+	head := append(append([]rune(nil), lines[row][:col]...), []rune(put[0])...)
+	var added [][]rune
+	for _, l := range put[1:] {
+		added = append(added, []rune(l))
+	}
+	lines = append(lines[:row], append([][]rune{head}, append(added, lines[row+1:]...)...)...)
+	last := row + len(put) - 1
+	end := len(lines[last])
+	lines[last] = append(lines[last], tail...)
+	e.setLines(lines, last, max(end-1, 0))
+}
+
+// vimMotion returns where motion k, repeated n times, takes the cursor from
+// (row, col). linewise motions (j, k, G, gg) move between whole lines;
+// inclusive ones (e, $) cover the character they land on.
+func vimMotion(lines [][]rune, row, col int, k string, n int, counted bool) (r, c int, linewise, inclusive, ok bool) {
+	r, c = row, col
+	switch k {
+	case "h", "left":
+		c = max(col-n, 0)
+	case "l", "right":
+		c = min(col+n, len(lines[row]))
+	case "j", "down":
+		r = min(row+n, len(lines)-1)
+		return r, min(col, len(lines[r])), true, false, true
+	case "k", "up":
+		r = max(row-n, 0)
+		return r, min(col, len(lines[r])), true, false, true
+	case "0", "home":
+		c = 0
+	case "^":
+		c = firstNonBlank(lines[row])
+	case "$", "end":
+		r = min(row+n-1, len(lines)-1)
+		return r, max(len(lines[r])-1, 0), false, true, true
+	case "w":
+		for range n {
+			r, c = wordForward(lines, r, c)
+		}
+	case "b":
+		for range n {
+			r, c = wordBackward(lines, r, c)
+		}
+	case "e":
+		for range n {
+			r, c = wordEnd(lines, r, c)
+		}
+		return r, c, false, true, true
+	case "G", "gg":
+		r = len(lines) - 1
+		if k == "gg" {
+			r = 0
+		}
+		if counted {
+			r = min(n, len(lines)) - 1
+		}
+		return r, firstNonBlank(lines[r]), true, false, true
+	default:
+		return row, col, false, false, false
+	}
+	return r, c, false, false, true
+}
+
+// isVimArrow reports whether k is a cursor key vim mode treats as a motion.
+func isVimArrow(k string) bool {
+	switch k {
+	case "left", "right", "up", "down", "home", "end":
+		return true
+	}
+	return false
+}
+
+// wordClass groups runes the way vim's word motions do: blanks, word
+// characters and punctuation.
+func wordClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	}
+	return 2
+}
+
+// nextPos returns the position after (r, c), counting the end of each line
+// as a position of its own, and false at the end of the text.
+func nextPos(lines [][]rune, r, c int) (int, int, bool) {
+	if c < len(lines[r]) {
+		return r, c + 1, true
+	}
+	if r+1 < len(lines) {
+		return r + 1, 0, true
+	}
+	return r, c, false
+}
+
+// prevPos returns the position before (r, c), and false at the start of the
+// text.
+func prevPos(lines [][]rune, r, c int) (int, int, bool) {
+	if c > 0 {
+		return r, c - 1, true
+	}
+	if r > 0 {
+		return r - 1, len(lines[r-1]), true
+	}
+	return r, c, false
+}
+
+// classAtPos returns the word class at (r, c); line ends count as blanks.
+func classAtPos(lines [][]rune, r, c int) int {
+	if c >= len(lines[r]) {
+		return 0
+	}
+	return wordClass(lines[r][c])
+}
+
+// wordForward moves to the start of the next word, like vim's w. An empty
+// line counts as a word.
+func wordForward(lines [][]rune, r, c int) (int, int) {
+	start := classAtPos(lines, r, c)
+	ok := true
+	if start == 0 {
+		r, c, ok = nextPos(lines, r, c)
+	}
+	for ok && start != 0 && classAtPos(lines, r, c) == start {
+		r, c, ok = nextPos(lines, r, c)
+	}
+	for ok && classAtPos(lines, r, c) == 0 {
+		if c == 0 && len(lines[r]) == 0 {
+			break
+		}
+		r, c, ok = nextPos(lines, r, c)
+	}
+	return r, min(c, max(len(lines[r])-1, 0))
+}
+
+// wordBackward moves to the start of the previous word, like vim's b.
+func wordBackward(lines [][]rune, r, c int) (int, int) {
+	r, c, ok := prevPos(lines, r, c)
+	for ok && classAtPos(lines, r, c) == 0 && !(c == 0 && len(lines[r]) == 0) {
+		r, c, ok = prevPos(lines, r, c)
+	}
+	class := classAtPos(lines, r, c)
+	for c > 0 && class != 0 && wordClass(lines[r][c-1]) == class {
+		c--
+	}
+	return r, c
+}
+
+// wordEnd moves to the end of the word, or of the next one when already at
+// an end, like vim's e.
+func wordEnd(lines [][]rune, r, c int) (int, int) {
+	r0, c0 := r, c
+	r, c, ok := nextPos(lines, r, c)
+	for ok && classAtPos(lines, r, c) == 0 {
+		r, c, ok = nextPos(lines, r, c)
+	}
+	if !ok {
+		return r0, c0
+	}
+	class := classAtPos(lines, r, c)
+	for c+1 < len(lines[r]) && wordClass(lines[r][c+1]) == class {
+		c++
+	}
+	return r, c
+}
+
+// firstNonBlank returns the index of the first non-blank rune in line.
+func firstNonBlank(line []rune) int {
+	for i, r := range line {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+// vimSelected reports whether line l is in the visual mode selection.
+func (e Editor) vimSelected(l int) bool {
+	if e.vim == nil || e.vim.mode != vimVisual {
+		return false
+	}
+	row := e.textarea.Line()
+	return l >= min(e.vim.anchor, row) && l <= max(e.vim.anchor, row)
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// fileEncoding is how a document's text is stored on disk. ink works on
+// UTF-8 text and writes documents back the way they came.
+type fileEncoding int
+
+const (
+	encUTF8 fileEncoding = iota
+	encUTF8BOM
+	encUTF16LE
+	encUTF16BE
+	encLatin1
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// String names the encoding for the status bar.
+func (enc fileEncoding) String() string {
+	switch enc {
+	case encUTF8BOM:
+		return "UTF-8 BOM"
+	case encUTF16LE:
+		return "UTF-16LE"
+	case encUTF16BE:
+		return "UTF-16BE"
+	case encLatin1:
+		return "Latin-1"
+	}
+	return "UTF-8"
+}
+
+// detectEncoding guesses how raw is encoded: by its byte order mark if it
+// has one, as UTF-16 if it is mostly NUL bytes on one side, as UTF-8 if it is
+// valid UTF-8, and as Latin-1 otherwise, since every byte is valid Latin-1.
+func detectEncoding(raw []byte) fileEncoding {
+	switch {
+	case bytes.HasPrefix(raw, bomUTF8):
+		return encUTF8BOM
+	case bytes.HasPrefix(raw, bomUTF16LE):
+		return encUTF16LE
+	case bytes.HasPrefix(raw, bomUTF16BE):
+		return encUTF16BE
+	}
+	if len(raw) >= 2 && len(raw)%2 == 0 {
+		var even, odd int
+		for i := 0; i < len(raw); i += 2 {
+			if raw[i] == 0 {
+				even++
+			}
+			if raw[i+1] == 0 {
+				odd++
+			}
+		}
+		// ASCII text in UTF-16 has a NUL in every other byte.
+		if half := len(raw) / 2; odd > half/2 && even == 0 {
+			return encUTF16LE
+		} else if even > half/2 && odd == 0 {
+			return encUTF16BE
+		}
+	}
+	if utf8.Valid(raw) {
+		return encUTF8
+	}
+	return encLatin1
+}
+
+// decodeText converts raw to UTF-8 text, dropping any byte order mark, and
+// reports the encoding it found and whether raw started with a byte order
+// mark; UTF-16 is also recognized without one.
+func decodeText(raw []byte) (string, fileEncoding, bool) {
+	enc := detectEncoding(raw)
+	bom := bytes.HasPrefix(raw, bomUTF8) || bytes.HasPrefix(raw, bomUTF16LE) || bytes.HasPrefix(raw, bomUTF16BE)
+	switch enc {
+	case encUTF8BOM:
+		return string(raw[len(bomUTF8):]), enc, bom
+	case encUTF16LE, encUTF16BE:
+		order := binary.ByteOrder(binary.LittleEndian)
+		if enc == encUTF16BE {
+			order = binary.BigEndian
+		}
+		raw = bytes.TrimPrefix(raw, bomUTF16LE)
+		raw = bytes.TrimPrefix(raw, bomUTF16BE)
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = order.Uint16(raw[2*i:])
+		}
+		return string(utf16.Decode(units)), enc, bom
+	case encLatin1:
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return string(runes), enc, bom
+	}
+	return string(raw), enc, bom
+}
+
+// documentText decodes a document's raw bytes into the text ink works on:
+// UTF-8 with \n line endings.
+func documentText(raw []byte) string {
+	text, _, _ := decodeText(raw)
+	return normalizeLineEndings(text)
+}
+
+// usesCRLF reports whether most of text's lines end in \r\n, as in
+// documents written on Windows.
+func usesCRLF(text string) bool {
+	crlf := strings.Count(text, "\r\n")
+	return crlf > 0 && 2*crlf >= strings.Count(text, "\n")
+}
+
+// encodeText converts text back to enc for saving. UTF-8 with a BOM gets its
+// byte order mark back, and so does UTF-16 when bom says it had one. Text
+// that Latin-1 can't hold is an error rather than silently mangled.
+func encodeText(text string, enc fileEncoding, bom bool) ([]byte, error) {
+	switch enc {
+	case encUTF8BOM:
+		return append(bytes.Clone(bomUTF8), text...), nil
+	case encUTF16LE, encUTF16BE:
+		order, mark := binary.AppendByteOrder(binary.LittleEndian), bomUTF16LE
+		if enc == encUTF16BE {
+			order, mark = binary.BigEndian, bomUTF16BE
+		}
+		if !bom {
+			mark = nil
+		}
+		units := utf16.Encode([]rune(text))
+		out := make([]byte, len(mark), len(mark)+2*len(units))
+		copy(out, mark)
+		for _, u := range units {
+			out = order.AppendUint16(out, u)
+		}
+		return out, nil
+	case encLatin1:
+		out := make([]byte, 0, len(text))
+		for i, r := range text {
+			if r > 0xFF {
+				line := strings.Count(text[:i], "\n") + 1
+				return nil, fmt.Errorf("can't save as Latin-1: %q on line %d has no Latin-1 form", r, line)
+			}
+			out = append(out, byte(r))
+		}
+		return out, nil
+	}
+	return []byte(text), nil
+}
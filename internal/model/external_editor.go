@@ -0,0 +1,64 @@
+package model
+
+import (
+	"cmp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// editorLineArgs gives, by program name, the arguments that open a file at
+// a line in the editors that can: {file} and {line} are replaced. The
+// config's [external-editor] section adds others or overrides these.
+var editorLineArgs = map[string]string{
+	"vi": "+{line} {file}", "vim": "+{line} {file}", "nvim": "+{line} {file}", "gvim": "+{line} {file}",
+	"nano": "+{line} {file}", "micro": "+{line} {file}", "emacs": "+{line} {file}", "emacsclient": "+{line} {file}",
+	"kak": "+{line} {file}", "joe": "+{line} {file}", "mg": "+{line} {file}",
+	"hx": "{file}:{line}", "helix": "{file}:{line}", "subl": "{file}:{line}", "zed": "{file}:{line}",
+	"code": "-g {file}:{line}", "code-insiders": "-g {file}:{line}", "codium": "-g {file}:{line}", "cursor": "-g {file}:{line}",
+}
+
+// externalEditorCommand returns the command that opens path in
+// $INK_EDITOR or $EDITOR, at line, from 1, when the editor takes one; line
+// 0 opens the file at the top.
+func externalEditorCommand(ctx *ViewContext, path string, line int) *exec.Cmd {
+	parts := strings.Fields(cmp.Or(os.Getenv(editorEnv), os.Getenv("EDITOR"), "vi"))
+	name := strings.TrimSuffix(filepath.Base(parts[0]), ".exe")
+	args, ok := ctx.editorArgs[name]
+	if !ok {
+		args, ok = editorLineArgs[name]
+	}
+	if !ok || line < 1 {
+		return exec.Command(parts[0], append(parts[1:], path)...)
+	}
+	if !strings.Contains(args, "{file}") {
+		args += " {file}"
+	}
+	// Replacing within each argument keeps a path with spaces whole.
+	r := strings.NewReplacer("{file}", path, "{line}", strconv.Itoa(line))
+	for _, arg := range strings.Fields(args) {
+		parts = append(parts, r.Replace(arg))
+	}
+	return exec.Command(parts[0], parts[1:]...)
+}
+
+// openExternal hands the document to the external editor at the cursor's
+// line, leaving the Editor; unsaved changes have to be saved first.
+func (e Editor) openExternal() (Editor, tea.Cmd) {
+	if !e.saved {
+		e.statusText = "Save before opening in $EDITOR"
+		return e, clearStatusAfter(2*time.Second, clearEditorStatusMsg{})
+	}
+	if e.session.edited() {
+		e.logSession(time.Now())
+	}
+	path, line := e.filePath, e.textarea.Line()+1
+	return e, func() tea.Msg {
+		return OpenExternalEditorMsg{FilePath: path, Line: line}
+	}
+}
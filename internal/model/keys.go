@@ -0,0 +1,278 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Actions the [keys] config section can rebind. Not every view has every
+// action; each one applies in the views that do.
+const (
+	actionOpen         = "open"
+	actionBack         = "back"
+	actionHelp         = "help"
+	actionSave         = "save"
+	actionZen          = "zen"
+	actionPageUp       = "page-up"
+	actionPageDown     = "page-down"
+	actionHalfPageUp   = "half-page-up"
+	actionHalfPageDown = "half-page-down"
+)
+
+// binding is a view's default keys for an action, and how its help pane
+// labels them; "" when the help pane doesn't list the action.
+type binding struct {
+	keys  []string
+	label string
+}
+
+// viewKeys are the keys of a view: those of the actions that can be rebound,
+// and the rest, which rebound keys must stay clear of.
+type viewKeys struct {
+	name     string
+	actions  map[string]binding
+	fixed    []string
+	typeable bool     // printable keys are text, as in the editor
+	reads    []string // fixed keys a read-only session may use; the others are refused
+	shared   []string // keys that change what every session in the process shares, like the theme
+}
+
+var bookKeys = viewKeys{
+	name: "book",
+	actions: map[string]binding{
+		actionOpen:     {[]string{"enter", "right", "l"}, "enter"},
+		actionBack:     {[]string{"backspace", "left", "h"}, "backspace"},
+		actionHelp:     {[]string{"?"}, "?"},
+		actionPageUp:   {[]string{"pgup", "b", "ctrl+b"}, ""},
+		actionPageDown: {[]string{"pgdown", "f", "d", "ctrl+f"}, ""},
+	},
+	fixed: []string{"up", "down", "k", "j", "g", "G", "home", "end", "/", "n", "R", "D", "y", "m", "x", "delete", "u",
+		"p", "t", "s", "F", "T", "O", "E", "tab", "S", "A", "c", "C", "M", "r", "ctrl+r", "esc", "q", "ctrl+w", "ctrl+c"},
+	reads: []string{"up", "down", "k", "j", "g", "G", "home", "end", "/", "p", "t", "s", "F", "T", "O", "tab", "S", "A",
+		"c", "C", "M", "r", "ctrl+r", "esc", "q", "ctrl+w", "ctrl+c"},
+}
+
+var chapterKeys = viewKeys{
+	name: "chapter",
+	actions: map[string]binding{
+		actionOpen:         {[]string{"enter"}, "enter"},
+		actionBack:         {[]string{"backspace"}, "bksp"},
+		actionHelp:         {[]string{"?"}, ""},
+		actionPageUp:       {[]string{"b", "pgup"}, "b"},
+		actionPageDown:     {[]string{"f", "pgdown"}, "f"},
+		actionHalfPageUp:   {[]string{"u", "ctrl+b"}, "u"},
+		actionHalfPageDown: {[]string{"d", "ctrl+f"}, "d"},
+	},
+	fixed: []string{"up", "down", "k", "j", "g", "G", "home", "end", "esc", "q", "ctrl+w", "left", "h", "e", "E", "M", "R",
+		"y", "r", "ctrl+r", "]", "[", "m", "t", "s", "z", "i", "o", "O", "ctrl+o", "ctrl+i", "alt+i", "v", "p", "}", "{",
+		"+", "=", "-", "T", "B", "'", "!", "L", "P", "tab", "shift+tab", "ctrl+c"},
+	reads: []string{"up", "down", "k", "j", "g", "G", "home", "end", "esc", "q", "ctrl+w", "left", "h", "M", "R", "r",
+		"ctrl+r", "]", "[", "m", "t", "s", "z", "o", "O", "ctrl+o", "ctrl+i", "alt+i", "v", "}", "{", "+", "=", "-", "B",
+		"'", "L", "tab", "shift+tab", "ctrl+c"},
+	shared: []string{"T"},
+}
+
+var editorKeys = viewKeys{
+	name: "editor",
+	actions: map[string]binding{
+		actionBack:         {[]string{"esc", "ctrl+w"}, ""},
+		actionHelp:         {[]string{"alt+?", "alt+/"}, "⌥?"},
+		actionSave:         {[]string{"ctrl+s"}, "^S"},
+		actionZen:          {[]string{"alt+z"}, "⌥Z"},
+		actionHalfPageUp:   {[]string{"ctrl+b"}, "^B"},
+		actionHalfPageDown: {[]string{"ctrl+f"}, "^F"},
+	},
+	fixed: []string{"up", "down", "left", "right", "home", "end", "pgup", "pgdown", "enter", "tab", "backspace", "delete",
+		"ctrl+a", "ctrl+e", "ctrl+k", "ctrl+u", "ctrl+r", "ctrl+t", "ctrl+g", "ctrl+m", "ctrl+c", "alt+m", "alt+b",
+		"alt+i", "alt+c", "alt+`", "alt+k", "alt+h", "alt+d", "alt+t", "alt+T", "alt+s", "alt+e", "alt+r", "alt+w", "alt+l",
+		"alt+f", "alt+a", "alt+g", "alt+o", "alt+p", "alt+=", "alt++", "alt+-", "alt+0"},
+	typeable: true,
+}
+
+var metricsKeys = viewKeys{
+	name: "metrics",
+	actions: map[string]binding{
+		actionOpen: {[]string{"enter"}, "enter"},
+		actionBack: {[]string{"esc", "q", "left", "h", "backspace", "M"}, "esc"},
+		actionHelp: {[]string{"?"}, "?"},
+	},
+	fixed: []string{"up", "down", "k", "j", "r", "x", "X", "ctrl+c"},
+	reads: []string{"up", "down", "k", "j", "r", "ctrl+c"},
+}
+
+// allViewKeys lists the views whose keys can be rebound.
+var allViewKeys = []viewKeys{bookKeys, chapterKeys, editorKeys, metricsKeys}
+
+// KeyActions returns the names of the actions the [keys] config section can
+// rebind.
+func KeyActions() []string {
+	var names []string
+	for _, v := range allViewKeys {
+		for name := range v.actions {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// keyMap holds the keys actions were rebound to, by action name. A nil
+// keyMap keeps every default.
+type keyMap map[string][]string
+
+// CheckKeys reports whether keys, rebound actions as in the [keys] config
+// section, are known actions whose keys clash with no other key of a view
+// they apply in.
+func CheckKeys(keys map[string][]string) error {
+	km := keyMap(keys)
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(KeyActions(), name) {
+			return fmt.Errorf("%s: not one of %s", name, strings.Join(KeyActions(), ", "))
+		}
+		if len(keys[name]) == 0 {
+			return fmt.Errorf("%s: no keys", name)
+		}
+		for _, v := range allViewKeys {
+			if _, ok := v.actions[name]; !ok {
+				continue
+			}
+			for _, k := range keys[name] {
+				if v.typeable && utf8.RuneCountInString(k) == 1 {
+					return fmt.Errorf("%s: %q would keep it from being typed in the %s", name, k, v.name)
+				}
+				if slices.Contains(v.fixed, k) {
+					return fmt.Errorf("%s: %q already does something else in the %s", name, k, v.name)
+				}
+				for other := range v.actions {
+					if other != name && slices.Contains(km.keys(v, other), k) {
+						return fmt.Errorf("%s: %q is also bound to %s in the %s", name, k, other, v.name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pluginViewKeys lists the views that run plugins.
+var pluginViewKeys = []viewKeys{chapterKeys, editorKeys}
+
+// CheckPlugins reports whether the keys of plugins, as in the [plugins]
+// config section, clash with no key of a view that runs them, given the
+// actions rebound as in keys.
+func CheckPlugins(plugins map[string]string, keys map[string][]string) error {
+	km := keyMap(keys)
+	names := make([]string, 0, len(plugins))
+	for k := range plugins {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range pluginViewKeys {
+			if v.typeable && utf8.RuneCountInString(k) == 1 {
+				return fmt.Errorf("%q would keep it from being typed in the %s", k, v.name)
+			}
+			if slices.Contains(v.fixed, k) {
+				return fmt.Errorf("%q already does something else in the %s", k, v.name)
+			}
+			for action := range v.actions {
+				if slices.Contains(km.keys(v, action), k) {
+					return fmt.Errorf("%q is bound to %s in the %s", k, action, v.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// refusal returns why key is turned away in view v, or "" when it isn't.
+// A read-only ink refuses plugins and the view's fixed keys but those listed
+// as reads, so a key added later is refused until it's known to be safe. A
+// session sharing its process with others refuses keys that would change
+// what they share.
+func (c *ViewContext) refusal(v viewKeys, key string) string {
+	if c.session != "" && slices.Contains(v.shared, key) {
+		return "Not in a shared session"
+	}
+	if !c.readOnly {
+		return ""
+	}
+	_, plugin := c.plugins[key]
+	if plugin || slices.Contains(v.fixed, key) && !slices.Contains(v.reads, key) {
+		return "Read-only"
+	}
+	return ""
+}
+
+// keys returns the keys of action in view v.
+func (km keyMap) keys(v viewKeys, action string) []string {
+	if keys, ok := km[action]; ok {
+		return keys
+	}
+	return v.actions[action].keys
+}
+
+// resolve translates key, as pressed in view v, to the first default key of
+// the action it is bound to, so the view's key handling carries on as if the
+// default was pressed. A default key whose action was rebound to others
+// resolves to "".
+func (km keyMap) resolve(v viewKeys, key string) string {
+	for action, keys := range km {
+		if b, ok := v.actions[action]; ok && slices.Contains(keys, key) {
+			return b.keys[0]
+		}
+	}
+	for action := range km {
+		if slices.Contains(v.actions[action].keys, key) {
+			return ""
+		}
+	}
+	return key
+}
+
+// help returns the help columns of view v with the labels of rebound
+// actions replaced by their keys.
+func (km keyMap) help(v viewKeys, cols [][]helpEntry) [][]helpEntry {
+	if len(km) == 0 {
+		return cols
+	}
+	out := make([][]helpEntry, len(cols))
+	for i, col := range cols {
+		out[i] = slices.Clone(col)
+		for j, e := range out[i] {
+			for action, b := range v.actions {
+				if keys, ok := km[action]; ok && b.label != "" && e.Key == b.label {
+					out[i][j].Key = keyLabel(keys)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// keyLabel writes keys the way the help panes do: ^ for ctrl and ⌥ for alt.
+func keyLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		switch {
+		case strings.HasPrefix(k, "ctrl+"):
+			k = "^" + strings.ToUpper(strings.TrimPrefix(k, "ctrl+"))
+		case strings.HasPrefix(k, "alt+"):
+			k = "⌥" + strings.ToUpper(strings.TrimPrefix(k, "alt+"))
+		case k == "backspace":
+			k = "bksp"
+		}
+		labels[i] = k
+	}
+	return strings.Join(labels, "/")
+}
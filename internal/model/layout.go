@@ -4,6 +4,9 @@ import (
 	"strings"
 
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/render"
 )
 
 // Layout constants for chrome height calculations.
@@ -79,6 +82,40 @@ func centerContent(content string, termWidth, maxWidth int) string {
 	return lipgloss.PlaceHorizontal(termWidth, lipgloss.Center, block)
 }
 
+// asciiOr returns glyph, or ascii when ink draws with plain ASCII.
+func asciiOr(glyph, ascii string) string {
+	if render.ASCII() {
+		return ascii
+	}
+	return glyph
+}
+
+// splitDividerWidth is the width of the divider between split panes.
+const splitDividerWidth = 3
+
+// splitPanes lays out left and right side by side with a vertical divider.
+// Both panes are clipped to the height of left and to their widths.
+func splitPanes(left, right string, leftW, rightW int) string {
+	leftLines := strings.Split(left, "\n")
+	rightLines := strings.Split(right, "\n")
+	divider := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(" " + asciiOr("│", "|") + " ")
+
+	var b strings.Builder
+	for i, l := range leftLines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		l = ansi.Truncate(l, leftW, "")
+		b.WriteString(l)
+		b.WriteString(strings.Repeat(" ", max(leftW-lipgloss.Width(l), 0)))
+		b.WriteString(divider)
+		if i < len(rightLines) {
+			b.WriteString(ansi.Truncate(rightLines[i], rightW, ""))
+		}
+	}
+	return b.String()
+}
+
 // layoutView assembles the standard view layout: logo, content, status bar, and optional help pane.
 func layoutView(logoStr, content, statusBar, helpPane string) string {
 	var b strings.Builder
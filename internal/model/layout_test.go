@@ -1,6 +1,11 @@
 package model
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
 
 func TestHelpPaneToggleVisibleHide(t *testing.T) {
 	hp := NewHelpPane([][]helpEntry{
@@ -68,3 +73,45 @@ func TestNewHelpPaneEmpty(t *testing.T) {
 		t.Fatalf("expected height 0 for empty entries, got %d", hp2.height)
 	}
 }
+
+func TestSplitPanes(t *testing.T) {
+	got := splitPanes("ab\ncd\nef", "right pane is long\nx", 4, 5)
+	lines := strings.Split(ansi.Strip(got), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("splitPanes: got %d lines, want 3 (height of left pane)", len(lines))
+	}
+	want := []string{"ab   │ right", "cd   │ x", "ef   │ "}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d = %q, want %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestASCII(t *testing.T) {
+	WithASCII(true)(nil)
+	defer WithASCII(false)(nil)
+	if got := ansi.Strip(splitPanes("ab", "cd", 2, 2)); got != "ab | cd" {
+		t.Errorf("ASCII split panes = %q", got)
+	}
+	e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, "a.md", "text")
+	if view := ansi.Strip(e.View()); !strings.Contains(view, "|   1 text") || strings.Contains(view, "┃") {
+		t.Errorf("the ASCII editor should have a plain border:\n%s", view)
+	}
+}
+
+func TestHelpPanesFitDefaultWidth(t *testing.T) {
+	const width = 80
+	panes := map[string][][]helpEntry{
+		"book":    bookHelpEntries,
+		"chapter": chapterHelpEntries,
+		"editor":  editorHelpEntries,
+	}
+	for name, entries := range panes {
+		for i, line := range strings.Split(renderHelpPane(entries, width), "\n") {
+			if w := ansi.StringWidth(line); w > width {
+				t.Errorf("%s help line %d is %d wide, want <= %d", name, i, w, width)
+			}
+		}
+	}
+}
@@ -7,20 +7,46 @@ type OpenChapterMsg struct {
 	FilePath string
 }
 
+// OpenRecentMsg requests switching to the Chapter view for a recently
+// opened document, moving to the book it was opened in.
+type OpenRecentMsg struct {
+	FilePath string
+	BookDir  string
+}
+
 // OpenEditorMsg requests switching to the Editor view.
 type OpenEditorMsg struct {
 	FilePath string
 	Content  string
 }
 
+// OpenMetricsMsg requests switching to the Metrics view for a document.
+type OpenMetricsMsg struct {
+	FilePath string
+	Content  string
+}
+
+// ShowSentenceMsg requests opening a document in the Chapter view with a
+// sentence the Metrics view found highlighted.
+type ShowSentenceMsg struct {
+	FilePath string
+	Line     int // 0-based source line the sentence starts on
+	Text     string
+}
+
+// CloseMetricsMsg signals returning from the Metrics view to the document.
+type CloseMetricsMsg struct{}
+
 // CloseEditorMsg signals the editor has closed.
 type CloseEditorMsg struct {
 	FilePath string
 }
 
-// OpenExternalEditorMsg requests opening the file in $EDITOR.
+// OpenExternalEditorMsg requests opening the file in $EDITOR, at Line, from
+// 1, when it is set.
 type OpenExternalEditorMsg struct {
 	FilePath string
+	Line     int
 }
 
 // ExternalEditorClosedMsg signals the external editor has exited.
@@ -28,6 +54,29 @@ type ExternalEditorClosedMsg struct {
 	Err error
 }
 
+// FollowLinkMsg requests opening a linked document in the Chapter view,
+// remembering the current one so LinkBackMsg can return to it.
+type FollowLinkMsg struct {
+	FilePath string
+	Anchor   string // heading to scroll to, without the "#"
+}
+
+// JumpMsg requests opening a document in the Chapter view scrolled to a
+// position from the jump list.
+type JumpMsg struct {
+	FilePath string
+	Offset   int
+}
+
+// LinkBackMsg requests returning to the document a link was followed from.
+type LinkBackMsg struct{}
+
+// CompareMsg requests showing two documents side by side in the Chapter
+// view.
+type CompareMsg struct {
+	Left, Right string
+}
+
 // BackToBookMsg signals returning to the Book view.
 type BackToBookMsg struct{}
 
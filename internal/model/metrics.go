@@ -0,0 +1,505 @@
+package model
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/frontmatter"
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// metricsChromeHeight is the total chrome for the metrics view (logo + gap + status).
+const metricsChromeHeight = 3
+
+// metricsBarWidth is the width of an axis bar at a score of 1.
+const metricsBarWidth = 20
+
+// defaultTolerance is how far an axis's score may stray from its target
+// before the Metrics view marks it.
+const defaultTolerance = 0.15
+
+// metricsTrendLength is how many analyses, the latest included, an axis's
+// sparkline shows.
+const metricsTrendLength = 8
+
+// sparks are the sparkline glyphs, from lowest to highest.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+var (
+	// metricsBarStyle draws the scored part of an axis bar.
+	metricsBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("135"))
+	// metricsTrackStyle draws the rest of an axis bar.
+	metricsTrackStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("238"))
+	// metricsUpStyle and metricsDownStyle color the change since the
+	// previous analysis.
+	metricsUpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	metricsDownStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+	// metricsTargetStyle marks an axis's target on its bar.
+	metricsTargetStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("226"))
+	// metricsOffStyle draws the bar and name of an axis that strays from
+	// its target.
+	metricsOffStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+)
+
+// metricsDoneMsg carries the analysis of the document at path.
+type metricsDoneMsg struct {
+	path    string
+	result  metrics.Result
+	at      time.Time        // when the analysis ran
+	history []state.Analysis // earlier analyses, oldest first
+	err     error
+}
+
+// clearMetricsStatusMsg clears the Metrics view's status bar feedback.
+type clearMetricsStatusMsg struct{}
+
+// Metrics shows a document's writing signature: an axis per aspect of its
+// style, scored from 0 to 1, and statistics of its sentences.
+type Metrics struct {
+	ctx      *ViewContext
+	filePath string
+	content  string
+	result   metrics.Result
+	analyzed time.Time        // when the analysis ran
+	history  []state.Analysis // earlier analyses of the document, oldest first
+	loading  bool             // true until the analysis is done
+	err      error
+	help     HelpPane
+
+	target       metrics.Target // the signature the document is measured against; nil for none
+	targetSource string         // where the target came from, for the legend
+	targetErr    error          // a malformed target in the document's front matter
+
+	statusText string
+	radar      bool // true draws the axes as a radar chart instead of bars
+	cursor     int  // index of the selected axis
+}
+
+// NewMetrics creates a Metrics view for content, the document at filePath.
+// Init starts the analysis, unless the same content was analyzed before:
+// then the scores show at once and Init only loads their history.
+func NewMetrics(ctx *ViewContext, filePath, content string) Metrics {
+	m := Metrics{
+		ctx:      ctx,
+		filePath: filePath,
+		content:  content,
+		loading:  true,
+		help:     NewHelpPane(ctx.keys.help(metricsKeys, metricsHelpEntries)),
+	}
+	if r, ok := ctx.metricsCache.get(content); ok {
+		m.result, m.analyzed, m.loading = r, time.Now(), false
+	}
+	m.target, m.targetSource = ctx.metricsTarget, "config"
+	if matter, ok := frontmatter.Parse([]byte(content)); ok {
+		if items := matter.List("metrics-target"); len(items) > 0 {
+			if t, err := metrics.ParseTarget(strings.Join(items, ",")); err != nil {
+				m.targetErr = err
+			} else {
+				m.target, m.targetSource = t, "front matter"
+			}
+		}
+	}
+	return m
+}
+
+// thresholdWarning checks text against the configured thresholds and
+// describes the ones it goes beyond, or returns "" when there are none. The
+// built-in analysis is used, as for the editor's metrics strip.
+func (ctx *ViewContext) thresholdWarning(text string) string {
+	if len(ctx.thresholds) == 0 {
+		return ""
+	}
+	r := metrics.Analyze(text)
+	var warning string
+	n := 0
+	for _, t := range ctx.thresholds {
+		value, exceeded := t.Check(r)
+		if !exceeded {
+			continue
+		}
+		if n++; n == 1 {
+			format := "%s %.2f"
+			if t.Name == "grade" {
+				format = "%s %.1f"
+			}
+			warning = fmt.Sprintf(format, t.Name, value) + strings.TrimPrefix(t.String(), t.Name)
+		}
+	}
+	if n > 1 {
+		warning += fmt.Sprintf(" +%d", n-1)
+	}
+	return warning
+}
+
+// statusBarWarning renders a threshold warning as a status bar chip, with
+// hint naming the key that shows the details.
+func statusBarWarning(warning, hint string) string {
+	if warning == "" {
+		return ""
+	}
+	if hint != "" {
+		warning += " · " + hint
+	}
+	return statusBarWarningStyle.Render("⚠ " + warning)
+}
+
+// radarView draws the axes as a radar chart, with the previous analysis
+// beneath for comparison, and a legend of the scores. It returns nil unless
+// the chart is toggled on and there are enough axes to draw one.
+func (m Metrics) radarView() []string {
+	if !m.radar {
+		return nil
+	}
+	var previous map[string]float64
+	if n := len(m.history); n > 0 {
+		previous = m.history[n-1].Scores
+	}
+	lines := radarView(m.result.Axes, previous)
+	if lines == nil {
+		return nil
+	}
+	var scores []string
+	for _, a := range m.result.Axes {
+		scores = append(scores, fmt.Sprintf("%s %.2f", a.Name, a.Score))
+	}
+	lines = append(lines, "", ansi.Truncate(matterHintStyle.Render("  "+strings.Join(scores, " · ")), m.ctx.contentWidth(), "…"))
+	if previous != nil {
+		lines = append(lines, "  "+metricsBarStyle.Render("━")+matterHintStyle.Render(" this analysis  ")+
+			radarPreviousStyle.Render("━")+matterHintStyle.Render(" previous"))
+	}
+	return lines
+}
+
+// axisGoal is an axis's target score, when the document has one.
+type axisGoal struct {
+	score     float64
+	tolerance float64
+	set       bool
+}
+
+// goal returns the target of the axis called name.
+func (m Metrics) goal(name string) axisGoal {
+	return m.ctx.goal(m.target, name)
+}
+
+// goal returns target's score for the axis called name.
+func (ctx *ViewContext) goal(target metrics.Target, name string) axisGoal {
+	score, ok := target[name]
+	if !ok {
+		return axisGoal{}
+	}
+	return axisGoal{score: score, tolerance: ctx.tolerance(), set: true}
+}
+
+// tolerance returns how far an axis may stray from its target.
+func (ctx *ViewContext) tolerance() float64 {
+	if ctx.metricsTolerance > 0 {
+		return ctx.metricsTolerance
+	}
+	return defaultTolerance
+}
+
+// off reports whether score strays from the goal beyond its tolerance.
+func (g axisGoal) off(score float64) bool {
+	return g.set && math.Abs(score-g.score) > g.tolerance+1e-9
+}
+
+// metricsCacheSize is how many analyses the cache holds before starting
+// over.
+const metricsCacheSize = 256
+
+// metricsCache remembers analyses by a hash of the content analyzed, so
+// reopening an unchanged document doesn't analyze it again. It is shared
+// with the background analyses, hence the lock.
+type metricsCache struct {
+	mu      sync.Mutex
+	results map[[sha256.Size]byte]metrics.Result
+}
+
+func newMetricsCache() *metricsCache {
+	return &metricsCache{results: make(map[[sha256.Size]byte]metrics.Result)}
+}
+
+// get returns the cached analysis of content, if any.
+func (c *metricsCache) get(content string) (metrics.Result, bool) {
+	if c == nil {
+		return metrics.Result{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.results[sha256.Sum256([]byte(content))]
+	return r, ok
+}
+
+// put caches the analysis of content.
+func (c *metricsCache) put(content string, r metrics.Result) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.results) >= metricsCacheSize {
+		clear(c.results)
+	}
+	c.results[sha256.Sum256([]byte(content))] = r
+}
+
+// analyze measures content with the Metrics view's analyzer, or returns
+// the cached analysis of the same content.
+func (ctx *ViewContext) analyze(content string) (metrics.Result, error) {
+	if r, ok := ctx.metricsCache.get(content); ok {
+		return r, nil
+	}
+	r, err := ctx.metricsAnalyzer().Analyze(content)
+	if err == nil {
+		ctx.metricsCache.put(content, r)
+	}
+	return r, err
+}
+
+// metricsAnalyzer returns the analysis behind the Metrics view.
+func (ctx *ViewContext) metricsAnalyzer() metrics.Analyzer {
+	if ctx.analyzer == nil {
+		return metrics.Native{}
+	}
+	return ctx.analyzer
+}
+
+// Init analyzes the document in the background, and logs the analysis
+// unless it matches the last one.
+func (m Metrics) Init() tea.Cmd {
+	ctx, st, path, content := m.ctx, m.ctx.state, m.filePath, m.content
+	return func() tea.Msg {
+		result, err := ctx.analyze(content)
+		if err != nil {
+			return metricsDoneMsg{path: path, err: err}
+		}
+		// The history is extra; the scores stand without it.
+		history, _ := st.Analyses(path)
+		at := time.Now()
+		a := state.Analysis{Path: path, Time: at, Words: result.Words, Grade: result.Grade, Scores: map[string]float64{}}
+		for _, axis := range result.Axes {
+			a.Scores[axis.Name] = axis.Score
+		}
+		if n := len(history); n > 0 && sameAnalysis(history[n-1], a) {
+			history = history[:n-1]
+		} else if len(a.Scores) > 0 {
+			_ = st.LogAnalysis(a)
+		}
+		return metricsDoneMsg{path: path, result: result, at: at, history: history}
+	}
+}
+
+// sameAnalysis reports whether two analyses found the same scores, as when
+// a document is analyzed again unchanged.
+func sameAnalysis(a, b state.Analysis) bool {
+	return a.Words == b.Words && maps.Equal(a.Scores, b.Scores)
+}
+
+func (m Metrics) Update(msg tea.Msg) (Metrics, tea.Cmd) {
+	switch msg := msg.(type) {
+	case metricsDoneMsg:
+		if msg.path == m.filePath {
+			m.result, m.analyzed, m.history, m.err, m.loading = msg.result, msg.at, msg.history, msg.err, false
+		}
+	case clearMetricsStatusMsg:
+		m.statusText = ""
+	case tea.KeyMsg:
+		if why := m.ctx.refusal(metricsKeys, msg.String()); why != "" {
+			m.statusText = why
+			return m, clearStatusAfter(2*time.Second, clearMetricsStatusMsg{})
+		}
+		switch m.ctx.keys.resolve(metricsKeys, msg.String()) {
+		case "esc", "q", "left", "h", "backspace", "M":
+			if m.help.Visible() {
+				m.help.Hide()
+				return m, nil
+			}
+			return m, func() tea.Msg { return CloseMetricsMsg{} }
+		case "?":
+			m.help.Toggle()
+		case "r":
+			m.radar = !m.radar
+		case "up", "k":
+			m.cursor = max(m.cursor-1, 0)
+		case "down", "j":
+			m.cursor = min(m.cursor+1, max(len(m.result.Axes)-1, 0))
+		case "enter":
+			return m, m.showEvidence()
+		case "x", "X":
+			if m.loading || m.err != nil {
+				return m, nil
+			}
+			format := "json"
+			if msg.String() == "X" {
+				format = "csv"
+			}
+			path, err := m.export(format)
+			if err != nil {
+				m.statusText = "Export failed: " + err.Error()
+			} else {
+				m.statusText = "Exported to " + filepath.Base(path)
+			}
+			return m, clearStatusAfter(2*time.Second, clearMetricsStatusMsg{})
+		}
+	}
+	return m, nil
+}
+
+// showEvidence opens the document at the sentence that pulls the selected
+// axis down the most, if the analysis found one.
+func (m *Metrics) showEvidence() tea.Cmd {
+	if m.loading || m.err != nil || m.cursor >= len(m.result.Axes) {
+		return nil
+	}
+	a := m.result.Axes[m.cursor]
+	if len(a.Evidence) == 0 {
+		m.statusText = "No sentence stands out for " + a.Name
+		return clearStatusAfter(2*time.Second, clearMetricsStatusMsg{})
+	}
+	s, path := a.Evidence[0], m.filePath
+	return func() tea.Msg { return ShowSentenceMsg{FilePath: path, Line: s.Line, Text: s.Text} }
+}
+
+var metricsHelpEntries = [][]helpEntry{
+	{{"↑/↓", "select axis"}, {"enter", "worst sentence"}, {"r", "radar chart"}},
+	{{"esc", "back to document"}, {"x", "export JSON"}, {"X", "export CSV"}, {"?", "toggle help"}},
+}
+
+// axisBar draws a bar as long as score, with the goal's target marked on
+// it. A score that strays from the goal is drawn in the warning color.
+func axisBar(score float64, g axisGoal) string {
+	style := metricsBarStyle
+	if g.off(score) {
+		style = metricsOffStyle
+	}
+	filled := int(score*metricsBarWidth + 0.5)
+	cells := make([]string, metricsBarWidth)
+	for i := range cells {
+		if i < filled {
+			cells[i] = style.Render("█")
+		} else {
+			cells[i] = metricsTrackStyle.Render("░")
+		}
+	}
+	if g.set {
+		cells[min(int(g.score*metricsBarWidth), metricsBarWidth-1)] = metricsTargetStyle.Render("│")
+	}
+	return strings.Join(cells, "")
+}
+
+// axisRow draws an axis as its name, a bar as long as its score, the score,
+// its trend over earlier analyses and what was measured.
+func axisRow(a metrics.Axis, history []state.Analysis, g axisGoal, width int) string {
+	name := statsLabelStyle.Render(a.Name)
+	if g.off(a.Score) {
+		name = statsLabelStyle.Foreground(metricsOffStyle.GetForeground()).Render(a.Name)
+	}
+	row := fmt.Sprintf("  %s%s %.2f ", name, axisBar(a.Score, g), a.Score)
+	if len(history) > 0 {
+		row += axisTrend(a, history) + " "
+	}
+	return ansi.Truncate(row+" "+matterHintStyle.Render(a.Detail), width, "…")
+}
+
+// axisTrend draws a sparkline of an axis's scores over the latest analyses
+// and its change since the previous one.
+func axisTrend(a metrics.Axis, history []state.Analysis) string {
+	var scores []float64
+	for _, h := range history {
+		if score, ok := h.Scores[a.Name]; ok {
+			scores = append(scores, score)
+		}
+	}
+	if len(scores) == 0 {
+		return strings.Repeat(" ", metricsTrendLength+6)
+	}
+	scores = append(scores, a.Score)
+	scores = scores[max(len(scores)-metricsTrendLength, 0):]
+	var spark strings.Builder
+	for _, s := range scores {
+		spark.WriteRune(sparks[min(int(s*float64(len(sparks))), len(sparks)-1)])
+	}
+	line := strings.Repeat(" ", metricsTrendLength-len(scores)) + spark.String()
+	delta := a.Score - scores[len(scores)-2]
+	change := fmt.Sprintf("%+.2f", delta)
+	switch {
+	case delta >= 0.005:
+		change = metricsUpStyle.Render(change)
+	case delta <= -0.005:
+		change = metricsDownStyle.Render(change)
+	default:
+		change = matterHintStyle.Render(" 0.00")
+	}
+	return line + " " + change
+}
+
+func (m Metrics) View() string {
+	r := m.result
+	width := m.ctx.contentWidth()
+	row := func(label, value string) string {
+		return "  " + statsLabelStyle.Render(label) + value
+	}
+	lines := []string{tocTitleStyle.Render("Metrics"), ""}
+	switch {
+	case m.loading:
+		lines = append(lines, "  Analyzing…")
+	case m.err != nil:
+		lines = append(lines, "  Analysis failed: "+m.err.Error())
+	case len(r.Axes) == 0:
+		lines = append(lines, "  No prose to measure")
+	}
+	if chart := m.radarView(); chart != nil {
+		lines = append(lines, chart...)
+	} else {
+		for i, a := range r.Axes {
+			row := axisRow(a, m.history, m.goal(a.Name), width)
+			if i == m.cursor {
+				// axisRow starts with two spaces.
+				row = "▸ " + row[2:]
+			}
+			lines = append(lines, row)
+		}
+	}
+	switch {
+	case m.targetErr != nil:
+		lines = append(lines, "", "  Front matter "+m.targetErr.Error())
+	case len(r.Axes) > 0 && len(m.target) > 0:
+		legend := fmt.Sprintf(" target from %s, ±%.2f", m.targetSource, m.ctx.tolerance())
+		lines = append(lines, "", "  "+metricsTargetStyle.Render("│")+matterHintStyle.Render(legend))
+	}
+	if s := r.Sentences; s.Count > 0 {
+		lines = append(lines, "", tocTitleStyle.Render("Sentences"), "",
+			row("Words", fmt.Sprintf("%d", r.Words)),
+			row("Sentences", fmt.Sprintf("%d", s.Count)),
+			row("Average", fmt.Sprintf("%.1f words (±%.1f)", s.Mean, s.StdDev)),
+			row("Range", fmt.Sprintf("%d to %d words", s.Shortest, s.Longest)),
+		)
+		if r.Grade > 0 {
+			lines = append(lines, row("Grade", fmt.Sprintf("%.1f", r.Grade)))
+		}
+	}
+	height := contentHeight(m.ctx, metricsChromeHeight, m.help.HeightIfVisible())
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	content := centerContent(strings.Join(lines[:height], "\n"), m.ctx.width, width)
+	left := statusBarBookName(m.ctx.bookName) + statusBarFileName(m.filePath)
+	var parts []string
+	if m.statusText != "" {
+		parts = append(parts, m.statusText)
+	}
+	statusBar := renderStatusBar(m.ctx, left, parts, "? help")
+	return layoutView(logo, content, statusBar, m.help.View(m.ctx.width))
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// metricsExport is the JSON form of an exported analysis.
+type metricsExport struct {
+	Path      string                `json:"path"`
+	Time      time.Time             `json:"time"`
+	Words     int                   `json:"words"`
+	Grade     float64               `json:"grade,omitempty"`
+	Sentences metrics.SentenceStats `json:"sentences"`
+	Axes      []metrics.Axis        `json:"axes"`
+	History   []state.Analysis      `json:"history,omitempty"` // earlier analyses, oldest first
+}
+
+// exportPath returns the file an export in format ("json" or "csv") is
+// written to: beside the document, named after it.
+func exportPath(path, format string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".metrics." + format
+}
+
+// export writes the analysis and its history beside the document in format,
+// "json" or "csv", and returns the file written.
+func (m Metrics) export(format string) (string, error) {
+	var data []byte
+	var err error
+	if format == "csv" {
+		data, err = m.exportCSV()
+	} else {
+		data, err = json.MarshalIndent(metricsExport{
+			Path:      m.filePath,
+			Time:      m.analyzed,
+			Words:     m.result.Words,
+			Grade:     m.result.Grade,
+			Sentences: m.result.Sentences,
+			Axes:      m.result.Axes,
+			History:   m.history,
+		}, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return "", err
+	}
+	path := exportPath(m.filePath, format)
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// exportCSV lays the analyses out as a table: a row per analysis, oldest
+// first and the current one last, with a column per axis. The grade is
+// left empty for text too short to grade.
+func (m Metrics) exportCSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"time", "words", "grade"}
+	for _, a := range m.result.Axes {
+		header = append(header, a.Name)
+	}
+	rows := [][]string{header}
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) }
+	row := func(at time.Time, words int, grade float64, score func(name string) (float64, bool)) {
+		r := []string{at.Format(time.RFC3339), strconv.Itoa(words), ""}
+		if grade > 0 {
+			r[2] = formatFloat(grade)
+		}
+		for _, a := range m.result.Axes {
+			if s, ok := score(a.Name); ok {
+				r = append(r, formatFloat(s))
+			} else {
+				r = append(r, "")
+			}
+		}
+		rows = append(rows, r)
+	}
+	for _, h := range m.history {
+		row(h.Time, h.Words, h.Grade, func(name string) (float64, bool) {
+			s, ok := h.Scores[name]
+			return s, ok
+		})
+	}
+	row(m.analyzed, m.result.Words, m.result.Grade, func(name string) (float64, bool) {
+		a, ok := m.result.Axis(name)
+		return a.Score, ok
+	})
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"math"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/inkcheck/ink/internal/metrics"
+)
+
+// radarRadius is the radius of the radar chart in braille dots. A dot is
+// about as wide as it is tall, so the chart comes out round.
+const radarRadius = 20
+
+// radarMargin is the room left of and right of the chart for axis names.
+const radarMargin = 13
+
+// radarPreviousStyle draws the previous analysis on the radar chart.
+var radarPreviousStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+
+// brailleDots maps a dot's position within a braille cell, by column and
+// row, to its bit.
+var brailleDots = [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+// radarLayer is a set of dots drawn in one style.
+type radarLayer struct {
+	style lipgloss.Style
+	dots  map[[2]int]bool
+}
+
+// line draws a straight line of dots from (x0, y0) to (x1, y1).
+func (l radarLayer) line(x0, y0, x1, y1 float64) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		l.dots[[2]int{int(math.Round(x0 + (x1-x0)*t)), int(math.Round(y0 + (y1-y0)*t))}] = true
+	}
+}
+
+// radarPoint returns the dot at score along the spoke of axis i of n, the
+// first pointing up and the rest clockwise.
+func radarPoint(i, n int, score float64) (x, y float64) {
+	angle := 2*math.Pi*float64(i)/float64(n) - math.Pi/2
+	return radarRadius + score*radarRadius*math.Cos(angle), radarRadius + score*radarRadius*math.Sin(angle)
+}
+
+// polygon joins the points of scores along the spokes.
+func (l radarLayer) polygon(scores []float64) {
+	n := len(scores)
+	for i := range scores {
+		x0, y0 := radarPoint(i, n, scores[i])
+		x1, y1 := radarPoint((i+1)%n, n, scores[(i+1)%n])
+		l.line(x0, y0, x1, y1)
+	}
+}
+
+// radarView draws the axes as a radar chart in braille: the spokes and
+// outline in the track color, the previous analysis, if given, in gray and
+// the current one on top. Each axis is named at the end of its spoke.
+func radarView(axes []metrics.Axis, previous map[string]float64) []string {
+	n := len(axes)
+	if n < 3 {
+		return nil
+	}
+	grid := radarLayer{metricsTrackStyle, map[[2]int]bool{}}
+	full := make([]float64, n)
+	for i := range axes {
+		full[i] = 1
+		x, y := radarPoint(i, n, 1)
+		grid.line(radarRadius, radarRadius, x, y)
+	}
+	grid.polygon(full)
+
+	layers := []radarLayer{grid}
+	if previous != nil {
+		scores := make([]float64, n)
+		for i, a := range axes {
+			scores[i] = previous[a.Name]
+		}
+		prev := radarLayer{radarPreviousStyle, map[[2]int]bool{}}
+		prev.polygon(scores)
+		layers = append(layers, prev)
+	}
+	scores := make([]float64, n)
+	for i, a := range axes {
+		scores[i] = a.Score
+	}
+	current := radarLayer{metricsBarStyle, map[[2]int]bool{}}
+	current.polygon(scores)
+	layers = append(layers, current)
+
+	// A row of text above and below the chart holds the names of the axes
+	// at the top and bottom.
+	cols, rows := radarRadius+1, radarRadius/2+1
+	width := radarMargin + cols + radarMargin
+	cells := make([][]string, rows+2)
+	for r := range cells {
+		cells[r] = make([]string, width)
+		for c := range cells[r] {
+			cells[r][c] = " "
+		}
+	}
+	for r := range rows {
+		for c := range cols {
+			var bits rune
+			style := metricsTrackStyle
+			for _, l := range layers {
+				drawn := false
+				for dx := range 2 {
+					for dy := range 4 {
+						if l.dots[[2]int{2*c + dx, 4*r + dy}] {
+							bits |= brailleDots[dx][dy]
+							drawn = true
+						}
+					}
+				}
+				if drawn {
+					style = l.style
+				}
+			}
+			if bits != 0 {
+				cells[r+1][radarMargin+c] = style.Render(string(0x2800 + bits))
+			}
+		}
+	}
+
+	// Names at the top and bottom go on the rows beyond the chart, the
+	// others beside the end of their spoke.
+	for i, a := range axes {
+		x, y := radarPoint(i, n, 1)
+		col, row := radarMargin+int(x/2), int(y/4)+1
+		name := []rune(a.Name)
+		switch {
+		case math.Abs(x-radarRadius) < radarRadius*0.2:
+			col -= len(name) / 2
+			if y < radarRadius {
+				row = 0
+			} else {
+				row = len(cells) - 1
+			}
+		case x < radarRadius:
+			col -= len(name) + 1
+		default:
+			col += 3
+		}
+		col = min(max(col, 0), width-len(name))
+		for j, r := range name {
+			cells[row][col+j] = matterHintStyle.Render(string(r))
+		}
+	}
+
+	var lines []string
+	for r := range cells {
+		lines = append(lines, strings.TrimRight(strings.Join(cells[r], ""), " "))
+	}
+	// Drop the rows the names didn't need.
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i := range lines {
+		lines[i] = "  " + lines[i]
+	}
+	return lines
+}
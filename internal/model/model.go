@@ -1,28 +1,351 @@
 package model
 
 import (
-	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/inkcheck/ink/internal/export"
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/render"
+	"github.com/inkcheck/ink/internal/state"
 )
 
+// editorEnv names the environment variable holding the command E opens
+// documents in; $EDITOR is used when it is unset.
+const editorEnv = "INK_EDITOR"
+
 // Model is the root application model that routes between views.
 type Model struct {
-	ctx     *ViewContext
-	view    ViewState
-	book    Book
-	chapter Chapter
-	editor  Editor
+	ctx         *ViewContext
+	view        ViewState
+	book        Book
+	chapter     Chapter
+	editor      Editor
+	metrics     Metrics
+	metricsFrom ViewState      // the view the Metrics view returns to
+	history     []chapterVisit // documents left by following links, newest last
+}
+
+// chapterVisit is a document left by following a link, and where it was
+// scrolled to.
+type chapterVisit struct {
+	path   string
+	offset int
+}
+
+// Option configures optional behaviour of the root model.
+type Option func(*ViewContext)
+
+// WithFollowSymlinks makes the Book descend into symbolic links to
+// directories, so books assembled from linked folders list every document.
+func WithFollowSymlinks(follow bool) Option {
+	return func(ctx *ViewContext) {
+		ctx.scanner.followSymlinks = follow
+	}
+}
+
+// WithAutosave makes the Editor save on its own once typing has paused for
+// idle (zero disables), and when the terminal loses focus if onBlur is set.
+func WithAutosave(idle time.Duration, onBlur bool) Option {
+	return func(ctx *ViewContext) {
+		ctx.autosaveIdle = idle
+		ctx.autosaveOnBlur = onBlur
+	}
+}
+
+// WithVimKeys makes the Editor start in vim's normal mode, with modal
+// editing keys layered over the plain textarea.
+func WithVimKeys(vim bool) Option {
+	return func(ctx *ViewContext) {
+		ctx.vimKeys = vim
+	}
+}
+
+// WithWrapWidth makes the Editor wrap text at columns, however wide the
+// terminal or the content width. Zero wraps at the content width.
+func WithWrapWidth(columns int) Option {
+	return func(ctx *ViewContext) {
+		ctx.wrapWidth = columns
+	}
+}
+
+// WithSentenceLimits sets when the Editor marks a sentence as long (more
+// than words words) or hard (above a Flesch-Kincaid grade). Zero keeps the
+// default.
+func WithSentenceLimits(words int, grade float64) Option {
+	return func(ctx *ViewContext) {
+		ctx.longSentence = words
+		ctx.hardGrade = grade
+	}
+}
+
+// WithSaveHooks sets commands the Editor runs after each ctrl+s, such as a
+// formatter or "git add {file}". {file} stands for the saved document.
+func WithSaveHooks(hooks []string) Option {
+	return func(ctx *ViewContext) {
+		ctx.saveHooks = hooks
+	}
+}
+
+// WithWrapOnSave makes ctrl+s in the Editor rewrap prose paragraphs to at
+// most columns columns first, leaving code, tables and front matter alone.
+// Zero disables it.
+func WithWrapOnSave(columns int) Option {
+	return func(ctx *ViewContext) {
+		ctx.wrapOnSave = columns
+	}
+}
+
+// WithDateFormats sets the strftime formats of the date (alt+d), time
+// (alt+t) and timestamp (alt+T) the Editor inserts. Empty formats keep the
+// defaults.
+func WithDateFormats(date, clock, stamp string) Option {
+	return func(ctx *ViewContext) {
+		ctx.dateFormat = date
+		ctx.timeFormat = clock
+		ctx.timestampFormat = stamp
+	}
+}
+
+// WithLintCommands sets the prose checkers run on documents, such as
+// "vale --output=line {file}" and "markdownlint {file}". {file} stands for a
+// copy of the text; a checker prints "file:line:column: message" or
+// "file:line message" diagnostics. They run together in the background when
+// a document opens and when the Editor saves, for the issue count in the
+// status bar, and list their issues on alt+e in the Editor and ! in the
+// Chapter view.
+func WithLintCommands(commands []string) Option {
+	return func(ctx *ViewContext) {
+		ctx.lintCommands = commands
+	}
+}
+
+// WithPlugins binds keys to plugins: command lines the Editor and Chapter
+// views run on the document, given the selection or the whole text on
+// stdin. {file} in a command line stands for the document's path. A plugin
+// prints text to replace the selection or to show, or a JSON object of
+// actions: "replace", "message" and "open". The keys should have passed
+// CheckPlugins.
+func WithPlugins(plugins map[string]string) Option {
+	return func(ctx *ViewContext) {
+		ctx.plugins = plugins
+	}
+}
+
+// WithRewrite sets the command the Editor asks for a rewrite of the
+// selected lines, or of the section the cursor is in, on alt+r, such as
+// "ollama run llama3.2". It gets prompt, when there is one, a blank line
+// and the text on stdin, and prints its suggestion, which the Editor shows
+// as a diff to accept or reject; the file is only changed once accepted
+// and saved.
+func WithRewrite(command, prompt string) Option {
+	return func(ctx *ViewContext) {
+		ctx.rewriteCommand = command
+		ctx.rewritePrompt = prompt
+	}
+}
+
+// WithShare sets where the Chapter view uploads a document on P: command,
+// such as "curl -s -F file=@- https://0x0.st", gets the text on stdin and
+// prints the address; without one the document becomes a GitHub gist, made
+// with token or else $INK_GIST_TOKEN or $GITHUB_TOKEN, and public only if
+// public is set. The address is copied to the clipboard.
+func WithShare(command, token string, public bool) Option {
+	return func(ctx *ViewContext) {
+		ctx.shareCommand = command
+		ctx.shareToken = token
+		ctx.sharePublic = public
+	}
+}
+
+// WithEditorArgs sets, by program name such as "code", the arguments that
+// open a file at a line in external editors, with {file} and {line} in
+// them, like "-g {file}:{line}". They add to and override the built-in ones
+// for vim, emacs, helix, VS Code and others.
+func WithEditorArgs(args map[string]string) Option {
+	return func(ctx *ViewContext) {
+		ctx.editorArgs = args
+	}
+}
+
+// WithPreview has the Chapter view and the Editor keep p, a page served
+// to a browser, showing the open document as they scroll and edit it.
+func WithPreview(p *export.Preview) Option {
+	return func(ctx *ViewContext) {
+		ctx.preview = p
+	}
+}
+
+// WithFormats lists documents in other markup, by extension such as
+// ".rst", among a book's markdown, and converts them for the Chapter view
+// with the command line given for the extension. The command gets the file
+// on stdin, with {file} and $INK_FILE set to its path, and prints markdown,
+// which the Chapter view shows read-only.
+func WithFormats(formats map[string]string) Option {
+	return func(ctx *ViewContext) {
+		ctx.scanner.formats = formats
+	}
+}
+
+// WithPandocArgs sets extra arguments the Book's export menu passes to
+// pandoc, by format name ("pdf", "docx" or "odt"). Those under "" are passed
+// for every format, before the format's own.
+func WithPandocArgs(args map[string][]string) Option {
+	return func(ctx *ViewContext) {
+		ctx.pandocArgs = args
+	}
+}
+
+// WithZenLayout gives the Editor's zen mode its own measure, wrapping text
+// at width columns (0 keeps the usual wrap width), and padding blank lines
+// above and below the text.
+func WithZenLayout(width, padding int) Option {
+	return func(ctx *ViewContext) {
+		ctx.zenWidth = width
+		ctx.zenPadding = padding
+	}
+}
+
+// WithAnalyzer replaces the built-in analysis behind the Metrics view.
+func WithAnalyzer(a metrics.Analyzer) Option {
+	return func(ctx *ViewContext) {
+		ctx.analyzer = a
+	}
+}
+
+// WithMetricsTarget sets the writing signature the Metrics view measures
+// documents against, and how far an axis may stray from it; a tolerance of
+// 0 keeps the default. A document's own metrics-target front matter takes
+// precedence.
+func WithMetricsTarget(target metrics.Target, tolerance float64) Option {
+	return func(ctx *ViewContext) {
+		ctx.metricsTarget = target
+		ctx.metricsTolerance = tolerance
+	}
+}
+
+// WithTheme renders documents in the theme called name (see
+// render.ThemeNames). Unknown names keep the default theme.
+func WithTheme(name string) Option {
+	return func(*ViewContext) {
+		if t, ok := render.ThemeNamed(name); ok {
+			render.SetTheme(t)
+		}
+	}
+}
+
+// WithASCII draws documents, pane dividers and the editor's border with
+// plain ASCII instead of box drawing, bullets and checkboxes.
+func WithASCII(on bool) Option {
+	return func(*ViewContext) {
+		render.SetASCII(on)
+	}
+}
+
+// WithNoColor, for terminals told to show no color (NO_COLOR), highlights
+// selected lines in reverse video, since a background color won't show.
+func WithNoColor(on bool) Option {
+	return func(*ViewContext) {
+		if on {
+			selectStyle = lipgloss.NewStyle().Reverse(true)
+			markStyle = lipgloss.NewStyle().Reverse(true)
+		}
+	}
+}
+
+// WithConfigPath sets the config file that settings changed in ink, like
+// the theme, are saved to.
+func WithConfigPath(path string) Option {
+	return func(ctx *ViewContext) {
+		ctx.configPath = path
+	}
+}
+
+// WithRecent starts a Book on the recently opened documents of every book
+// rather than its own listing.
+func WithRecent(on bool) Option {
+	return func(ctx *ViewContext) {
+		ctx.startRecent = on
+	}
+}
+
+// WithReadOnly keeps ink from changing files or running programs on the
+// host, for serving it to others: the editor, file operations, exports and
+// plugins are refused, and what is read is remembered for the session only.
+func WithReadOnly(on bool) Option {
+	return func(ctx *ViewContext) {
+		if on {
+			ctx.readOnly = true
+			ctx.state = &state.State{}
+		}
+	}
+}
+
+// WithSession runs the Model as one of several sessions a server runs in
+// one process, like ink ssh-server's, named name: its lock files tell it
+// from the other sessions, and keys that would change what they all share,
+// like the theme, are refused.
+func WithSession(name string) Option {
+	return func(ctx *ViewContext) {
+		ctx.session = name
+	}
+}
+
+// WithKeys rebinds actions, named as in KeyActions, to other keys in every
+// view that has them. The bindings should have passed CheckKeys.
+func WithKeys(keys map[string][]string) Option {
+	return func(ctx *ViewContext) {
+		ctx.keys = keys
+	}
+}
+
+// WithMetricsStrip chooses the axes the editor's metrics strip (alt+a)
+// shows.
+func WithMetricsStrip(axes []string) Option {
+	return func(ctx *ViewContext) {
+		ctx.metricsStrip = axes
+	}
+}
+
+// WithReadability sets the readability formulas the status bars cycle
+// through, by name (see ReadabilityNames); the first is shown at start.
+// Unknown names are skipped.
+func WithReadability(names []string) Option {
+	return func(ctx *ViewContext) {
+		ctx.readability = nil
+		for _, name := range names {
+			for _, s := range readabilityScores {
+				if s.name == name {
+					ctx.readability = append(ctx.readability, s)
+				}
+			}
+		}
+	}
+}
+
+// WithThresholds sets limits on the metrics that, when a document goes
+// beyond them, raise a warning in the Chapter and Editor status bars.
+func WithThresholds(thresholds []metrics.Threshold) Option {
+	return func(ctx *ViewContext) {
+		ctx.thresholds = thresholds
+	}
 }
 
 // New creates the root model.
-func New(dir string, maxWidth int) Model {
-	ctx := newViewContext(maxWidth, true)
+func New(dir string, maxWidth int, opts ...Option) Model {
+	ctx := newViewContext(maxWidth, true, opts...)
 	book := NewBook(ctx, dir)
 	ctx.bookName = book.bookName
+	ctx.bookDir = book.rootDir
+	ctx.state.SetLastDir(book.rootDir)
+	if ctx.startRecent {
+		if items := book.allRecentItems(); len(items) > 0 {
+			book.openPicker(pickerRecent, items)
+		}
+	}
 
 	return Model{
 		ctx:  ctx,
@@ -33,13 +356,16 @@ func New(dir string, maxWidth int) Model {
 
 // NewFromFile creates a model that opens a single markdown file directly in ChapterView.
 // Pressing back/esc quits the app instead of returning to BookView.
-func NewFromFile(filePath string, maxWidth int) Model {
+func NewFromFile(filePath string, maxWidth int, opts ...Option) Model {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		absPath = filePath
 	}
-	ctx := newViewContext(maxWidth, false)
+	ctx := newViewContext(maxWidth, false, opts...)
 	ctx.bookName = filepath.Base(absPath)
+	ctx.bookDir = filepath.Dir(absPath)
+	ctx.state.SetLastDoc(absPath)
+	ctx.state.AddRecent(absPath, "", time.Now())
 	chapter := NewChapter(ctx, absPath)
 
 	return Model{
@@ -50,10 +376,11 @@ func NewFromFile(filePath string, maxWidth int) Model {
 }
 
 // NewFromFiles creates a model that shows a filtered BookView with the given file/dir paths.
-func NewFromFiles(files []string, maxWidth int) Model {
-	ctx := newViewContext(maxWidth, true)
+func NewFromFiles(files []string, maxWidth int, opts ...Option) Model {
+	ctx := newViewContext(maxWidth, true, opts...)
 	book := NewBookFromFiles(ctx, files)
 	ctx.bookName = book.bookName
+	ctx.bookDir = book.rootDir
 
 	return Model{
 		ctx:  ctx,
@@ -63,10 +390,32 @@ func NewFromFiles(files []string, maxWidth int) Model {
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	var cmds []tea.Cmd
+	if m.book.ctx != nil {
+		cmds = append(cmds, m.book.Init())
+	}
+	if m.chapter.ctx != nil {
+		cmds = append(cmds, m.chapter.Init())
+	}
+	// NewLast may have rebuilt an Editor or Metrics view.
+	if m.editor.ctx != nil {
+		cmds = append(cmds, m.editor.Init())
+	}
+	if m.view == MetricsView {
+		cmds = append(cmds, m.metrics.Init())
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if m, ok := next.(Model); ok {
+		m.syncPreview()
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		w := msg.Width
@@ -91,8 +440,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
+			m.chapter.stopSpeech()
+			m.saveProgress()
 			return m, tea.Quit
-		case "alt+=":
+		case "alt+=", "alt++":
 			m.ctx.widenMaxWidth()
 			m.refreshActiveView()
 			return m, nil
@@ -107,17 +458,57 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case OpenChapterMsg:
-		m.chapter = NewChapter(m.ctx, msg.FilePath)
-		m.view = ChapterView
-		return m, nil
+		cmd := m.openChapter(msg.FilePath)
+		return m, cmd
+
+	case OpenRecentMsg:
+		var cmd tea.Cmd
+		if m.ctx.isBook && msg.BookDir != m.ctx.bookDir {
+			m.book = NewBook(m.ctx, msg.BookDir)
+			m.ctx.bookName = m.book.bookName
+			m.ctx.bookDir = m.book.rootDir
+			m.ctx.state.SetLastDir(m.book.rootDir)
+			cmd = m.book.Init()
+		}
+		cmd = tea.Batch(cmd, m.openChapter(msg.FilePath))
+		return m, cmd
+
+	case CompareMsg:
+		cmd := m.openChapter(msg.Left)
+		return m, tea.Batch(cmd, m.chapter.openCompare(msg.Right))
+
+	case JumpMsg:
+		cmd := m.openChapter(msg.FilePath)
+		m.chapter.viewport.SetYOffset(msg.Offset)
+		return m, cmd
+
+	case FollowLinkMsg:
+		m.chapter.recordJump(m.chapter.viewport.YOffset())
+		m.history = append(m.history, chapterVisit{path: m.chapter.filePath, offset: m.chapter.viewport.YOffset()})
+		cmd := m.openChapter(msg.FilePath)
+		if msg.Anchor != "" {
+			return m, tea.Batch(cmd, m.chapter.jumpToAnchor(msg.Anchor))
+		}
+		return m, cmd
+
+	case LinkBackMsg:
+		if len(m.history) == 0 {
+			if !m.ctx.isBook {
+				return m, nil
+			}
+			return m.Update(BackToBookMsg{})
+		}
+		visit := m.history[len(m.history)-1]
+		m.history = m.history[:len(m.history)-1]
+		cmd := m.openChapter(visit.path)
+		m.chapter.viewport.SetYOffset(visit.offset)
+		return m, cmd
 
 	case OpenExternalEditorMsg:
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			editor = "vi"
+		if m.view == EditorView {
+			m.editor.unlock()
 		}
-		parts := strings.Fields(editor)
-		c := exec.Command(parts[0], append(parts[1:], msg.FilePath)...)
+		c := externalEditorCommand(m.ctx, msg.FilePath, msg.Line)
 		return m, tea.ExecProcess(c, func(err error) tea.Msg {
 			return ExternalEditorClosedMsg{Err: err}
 		})
@@ -131,25 +522,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case OpenEditorMsg:
 		m.editor = NewEditor(m.ctx, msg.FilePath, msg.Content)
+		m.editor.lock()
 		m.view = EditorView
 		return m, m.editor.Init()
 
+	case OpenMetricsMsg:
+		m.metrics = NewMetrics(m.ctx, msg.FilePath, msg.Content)
+		m.metricsFrom = m.view
+		m.view = MetricsView
+		return m, m.metrics.Init()
+
+	case CloseMetricsMsg:
+		m.view = m.metricsFrom
+		return m, nil
+
+	case ShowSentenceMsg:
+		cmd := m.openChapter(msg.FilePath)
+		m.chapter.markSentence(msg.Line, msg.Text)
+		return m, cmd
+
 	case CloseEditorMsg:
+		m.editor.unlock()
 		// Refresh chapter content after editing (also picks up width changes)
 		m.chapter.refresh()
 		m.view = ChapterView
-		return m, nil
+		return m, m.chapter.lintInBackground()
 
 	case FileSavedMsg:
 		// File saved, stay in editor
 		return m, nil
 
 	case BackToBookMsg:
+		m.chapter.stopSpeech()
+		m.history = nil
+		m.saveProgress()
 		if !m.ctx.isBook {
 			return m, tea.Quit
 		}
 		m.view = BookView
-		return m, nil
+		// Pick up the Recently opened section and any edits made meanwhile.
+		return m, m.book.refresh()
 	}
 
 	// Route to active view
@@ -161,10 +573,79 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.chapter, cmd = m.chapter.Update(msg)
 	case EditorView:
 		m.editor, cmd = m.editor.Update(msg)
+	case MetricsView:
+		m.metrics, cmd = m.metrics.Update(msg)
 	}
 	return m, cmd
 }
 
+// syncPreview has the browser preview, if there is one, show the document
+// open in the Chapter view or the Editor, at the line on screen.
+func (m Model) syncPreview() {
+	p := m.ctx.preview
+	switch {
+	case p == nil:
+	case m.view == ChapterView:
+		p.Show(m.chapter.filePath, m.chapter.content, m.chapter.topSourceLine()-1, render.CurrentTheme())
+	case m.view == EditorView:
+		p.Show(m.editor.filePath, m.editor.textarea.Value(), m.editor.textarea.Line(), render.CurrentTheme())
+	}
+}
+
+// openChapter switches to the Chapter view for path, recording it as
+// recently opened. It returns the Chapter's Init command.
+func (m *Model) openChapter(path string) tea.Cmd {
+	m.chapter.stopSpeech()
+	m.recordProgress()
+	m.ctx.state.AddRecent(path, m.ctx.recentBook(), time.Now())
+	m.ctx.state.SetLastDoc(path)
+	// Recent history is a convenience; a failed write shouldn't block reading.
+	_ = m.ctx.state.Save()
+	m.chapter = NewChapter(m.ctx, path)
+	m.view = ChapterView
+	return m.chapter.Init()
+}
+
+// recordProgress notes how far the open chapter has been read and where it
+// is scrolled to.
+func (m *Model) recordProgress() {
+	if m.chapter.ctx != nil {
+		m.chapter.logReading(time.Now())
+		m.ctx.state.SetProgress(m.chapter.filePath, m.chapter.progress)
+		m.ctx.state.SetScrollOffset(m.chapter.filePath, m.chapter.viewport.YOffset())
+	}
+	if m.view == EditorView && m.editor.ctx != nil {
+		if m.editor.saved {
+			m.ctx.state.ClearDraft(m.editor.filePath)
+		} else {
+			m.ctx.state.SetDraft(m.editor.filePath, m.editor.textarea.Value(), time.Now())
+		}
+	}
+}
+
+// SaveState records the reading progress, any unsaved editor draft and the
+// views on screen, and saves the state. It is called once the program has
+// exited, however it exited, so a draft survives a closed terminal.
+func (m Model) SaveState() {
+	m.ctx.state.SetStack(m.stack())
+	m.saveProgress()
+}
+
+// Close releases what the model holds on to once the program has exited:
+// the open Editor's lock on its document.
+func (m Model) Close() {
+	if m.view == EditorView {
+		m.editor.unlock()
+	}
+}
+
+// saveProgress records the open chapter's reading progress and saves it.
+func (m *Model) saveProgress() {
+	m.recordProgress()
+	// Like recent history, progress shouldn't get in the way of reading.
+	_ = m.ctx.state.Save()
+}
+
 func (m *Model) refreshActiveView() {
 	switch m.view {
 	case ChapterView:
@@ -181,11 +662,14 @@ func (m Model) View() tea.View {
 		content = m.chapter.View()
 	case EditorView:
 		content = m.editor.View()
+	case MetricsView:
+		content = m.metrics.View()
 	default:
 		content = m.book.View()
 	}
 	v := tea.NewView(content)
 	v.AltScreen = true
+	v.ReportFocus = m.ctx.autosaveOnBlur
 	if m.ctx.mouseEnabled {
 		v.MouseMode = tea.MouseModeCellMotion
 	}
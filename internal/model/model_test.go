@@ -1,14 +1,49 @@
 package model
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/inkcheck/ink/internal/metrics"
+	"github.com/inkcheck/ink/internal/state"
 )
 
+// TestMain keeps tests from touching the user's real state file.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ink-state")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_STATE_HOME", dir)
+	// Pastes read the clipboard's HTML; keep the real clipboard out of it.
+	readClipboardHTML = func() string { return "" }
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// loadModel creates a Model for path and delivers the initial scan of its
+// Book, as the program loop would after Init.
+func loadModel(path string, maxWidth int, opts ...Option) Model {
+	m := New(path, maxWidth, opts...)
+	if m.book.loading {
+		m.book = awaitScan(m.book, m.book.dir)
+	}
+	return m
+}
+
 func tempDirWithFiles(t *testing.T, files map[string]string) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -26,7 +61,7 @@ func tempDirWithFiles(t *testing.T, files map[string]string) string {
 
 func TestViewRoutingBookView(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{"test.md": "# Hello"})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	view := m.book.View()
 	// Book view should contain the book name (derived from directory)
 	bookName := dirToBookName(filepath.Base(dir))
@@ -49,7 +84,7 @@ func TestViewRoutingChapterView(t *testing.T) {
 
 func TestWindowSizeMsgRespectsMinWidth(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{"test.md": "# Hello"})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	updated, _ := m.Update(tea.WindowSizeMsg{Width: 20, Height: 24})
 	um := updated.(Model)
 	if um.ctx.width < MinWidth {
@@ -61,7 +96,7 @@ func TestOpenChapterMsgSwitchesToChapterView(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{
 		"chapter.md": "# Chapter\n\nText content.",
 	})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	updated, _ := m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "chapter.md")})
 	um := updated.(Model)
 	if um.view != ChapterView {
@@ -73,7 +108,7 @@ func TestBackToBookMsgReturnsToBookView(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{
 		"chapter.md": "# Chapter\n\nText here.",
 	})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	// First go to chapter
 	updated, _ := m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "chapter.md")})
 	um := updated.(Model)
@@ -104,7 +139,7 @@ func TestOpenEditorMsgSwitchesToEditorView(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{
 		"edit.md": "# Edit\n\nEditable content.",
 	})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	updated, _ := m.Update(OpenEditorMsg{
 		FilePath: filepath.Join(dir, "edit.md"),
 		Content:  "# Edit\n\nEditable content.",
@@ -119,7 +154,7 @@ func TestCloseEditorMsgReturnsToChapterView(t *testing.T) {
 	dir := tempDirWithFiles(t, map[string]string{
 		"edit.md": "# Edit\n\nContent for editing.",
 	})
-	m := New(dir, 80)
+	m := loadModel(dir, 80)
 	// Go to chapter first
 	updated, _ := m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "edit.md")})
 	um := updated.(Model)
@@ -136,3 +171,860 @@ func TestCloseEditorMsgReturnsToChapterView(t *testing.T) {
 		t.Errorf("CloseEditorMsg: view = %v, want ChapterView", um3.view)
 	}
 }
+
+func TestEditorAutosave(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"edit.md": "Draft"})
+	path := filepath.Join(dir, "edit.md")
+	ctx := &ViewContext{width: 80, height: 24, maxWidth: 80, autosaveIdle: time.Second, autosaveOnBlur: true}
+	e := NewEditor(ctx, path, "Draft")
+	typeRune := func(r rune) {
+		e, _ = e.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	onDisk := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	typeRune('x')
+	typeRune('y')
+	e, _ = e.Update(editorAutosaveTickMsg{seq: 1})
+	if e.saved || onDisk() != "Draft" {
+		t.Error("a tick from an earlier edit shouldn't save while typing continues")
+	}
+	e, _ = e.Update(editorAutosaveTickMsg{seq: e.editSeq})
+	if !e.saved || onDisk() != e.textarea.Value() || e.statusText != "Autosaved" {
+		t.Errorf("idle tick: saved = %v, status = %q, on disk %q", e.saved, e.statusText, onDisk())
+	}
+
+	typeRune('z')
+	e, _ = e.Update(tea.BlurMsg{})
+	if !e.saved || onDisk() != e.textarea.Value() {
+		t.Errorf("losing focus should save, on disk %q", onDisk())
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if e.statusText != "Saved" {
+		t.Errorf("ctrl+s status = %q, want Saved", e.statusText)
+	}
+}
+
+func TestOpenChapterMsgRecordsRecent(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# A",
+		"b.md": "# B",
+	})
+	m := loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	updated, _ := m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "b.md")})
+	updated, cmd := updated.(Model).Update(BackToBookMsg{})
+	um := updated.(Model)
+	um.book = awaitRefresh(um.book, cmd)
+
+	items := um.book.list.Items()
+	if h, ok := items[0].(headerItem); !ok || h.title != "Recently opened" {
+		t.Fatalf("first item = %#v, want Recently opened header", items[0])
+	}
+	if f, ok := items[1].(fileItem); !ok || f.path != filepath.Join(dir, "b.md") {
+		t.Errorf("recent entry = %#v, want b.md", items[1])
+	}
+	if n := um.book.docCount(); n != 2 {
+		t.Errorf("docCount = %d, want 2", n)
+	}
+}
+
+func TestRecentAcrossBooks(t *testing.T) {
+	notes := tempDirWithFiles(t, map[string]string{"idea.md": "# Idea"})
+	book := tempDirWithFiles(t, map[string]string{"a.md": "# A"})
+	m := loadModel(book, 80)
+	m.ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	m.ctx.state.AddRecent(filepath.Join(notes, "idea.md"), notes, time.Now().Add(-time.Hour))
+	m.ctx.state.AddRecent(filepath.Join(book, "gone.md"), book, time.Now())
+
+	updated, _ := m.Update(tea.KeyPressMsg{Code: 'O', Text: "O"})
+	um := updated.(Model)
+	if um.book.picking != pickerRecent || len(um.book.picker.Items()) != 1 {
+		t.Fatalf("O should list the one recent document that still exists, got %d", len(um.book.picker.Items()))
+	}
+	item := um.book.picker.Items()[0].(recentItem)
+	if want := dirToBookName(notes) + " · opened 1 hour ago"; item.Description() != want {
+		t.Errorf("Description() = %q, want %q", item.Description(), want)
+	}
+	updated, cmd := um.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	updated, _ = updated.(Model).Update(cmd())
+	um = updated.(Model)
+	if um.view != ChapterView || um.chapter.filePath != filepath.Join(notes, "idea.md") {
+		t.Fatalf("enter should open idea.md, view %v", um.view)
+	}
+	if um.ctx.bookDir != notes || um.book.rootDir != notes {
+		t.Errorf("book = %q, want %q", um.ctx.bookDir, notes)
+	}
+	if v := um.ctx.state.Recent[0]; v.Path != filepath.Join(notes, "idea.md") || v.Book != notes {
+		t.Errorf("recent = %+v", v)
+	}
+}
+
+func TestNewLast(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if _, err := NewLast(80); err == nil {
+		t.Error("NewLast with no earlier session should fail")
+	}
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md":     "# A\n\nSome text.",
+		"b.md":     "# B",
+		"sub/c.md": "# C",
+	})
+	m := loadModel(dir, 80)
+	m.book.list.SetFilterText("a")
+	updated, _ := m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "a.md")})
+	updated, _ = updated.(Model).Update(OpenMetricsMsg{FilePath: filepath.Join(dir, "a.md"), Content: "# A\n\nSome text."})
+	updated.(Model).SaveState()
+
+	last, err := NewLast(80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.view != MetricsView || last.metricsFrom != ChapterView || last.metrics.filePath != filepath.Join(dir, "a.md") {
+		t.Errorf("view %v over %v, metrics of %q", last.view, last.metricsFrom, last.metrics.filePath)
+	}
+	if last.chapter.filePath != filepath.Join(dir, "a.md") || last.book.list.FilterValue() != "a" {
+		t.Errorf("chapter %q, book filter %q", last.chapter.filePath, last.book.list.FilterValue())
+	}
+
+	sub := filepath.Join(dir, "sub")
+	last.ctx.state.SetStack(state.Stack{Book: dir, Dir: sub, View: "editor", Doc: filepath.Join(sub, "c.md")})
+	if err := last.ctx.state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	last, err = NewLast(80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer last.Close()
+	if last.view != EditorView || last.editor.filePath != filepath.Join(sub, "c.md") || last.book.dir != sub {
+		t.Errorf("view %v, editor %q, book at %q", last.view, last.editor.filePath, last.book.dir)
+	}
+}
+
+func TestChapterProgressShownInBook(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"long.md": strings.Repeat("Line of text.\n\n", 200),
+	})
+	path := filepath.Join(dir, "long.md")
+	m := loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	updated, _ := m.Update(OpenChapterMsg{FilePath: path})
+	for range 3 {
+		updated, _ = updated.(Model).Update(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	}
+	progress := updated.(Model).chapter.progress
+	if progress <= 0 || progress >= 1 {
+		t.Fatalf("progress after paging = %v, want partial", progress)
+	}
+	updated, cmd := updated.(Model).Update(BackToBookMsg{})
+	um := updated.(Model)
+	um.book = awaitRefresh(um.book, cmd)
+	if got := um.ctx.state.Progress(path); got != progress {
+		t.Errorf("saved progress = %v, want %v", got, progress)
+	}
+	want := progressBadge(progress) + " · "
+	for _, item := range um.book.list.Items() {
+		if f, ok := item.(fileItem); ok && !strings.HasPrefix(f.Description(), want) {
+			t.Errorf("Description() = %q, want prefix %q", f.Description(), want)
+		}
+	}
+	if got := progressBadge(1); got != "✓" {
+		t.Errorf("progressBadge(1) = %q, want ✓", got)
+	}
+}
+
+func TestFollowLinksWithHistory(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"index.md":      "# Index\n\n" + strings.Repeat("Line of text.\n\n", 40) + "See [the guide](docs/guide.md) and [the web](https://example.com).\n",
+		"docs/guide.md": "# Guide\n\nBack to [index](../index.md#top).\n",
+	})
+	index := filepath.Join(dir, "index.md")
+	guide := filepath.Join(dir, "docs", "guide.md")
+	var m tea.Model = loadModel(dir, 80)
+	send := func(msg tea.Msg) {
+		t.Helper()
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		if cmd == nil {
+			return
+		}
+		switch next := cmd().(type) {
+		case FollowLinkMsg, LinkBackMsg, BackToBookMsg:
+			m, _ = m.Update(next)
+		}
+	}
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+
+	send(OpenChapterMsg{FilePath: index})
+	send(tab)
+	um := m.(Model)
+	offset := um.chapter.viewport.YOffset()
+	if offset == 0 {
+		t.Error("selecting a link below the fold should scroll to it")
+	}
+	send(enter)
+	if got := m.(Model).chapter.filePath; got != guide {
+		t.Fatalf("after enter: chapter = %s, want %s", got, guide)
+	}
+
+	send(tab)
+	send(enter)
+	if got := m.(Model).chapter.filePath; got != index {
+		t.Fatalf("relative link with fragment: chapter = %s, want %s", got, index)
+	}
+
+	send(tea.KeyPressMsg{Code: tea.KeyBackspace})
+	if got := m.(Model).chapter.filePath; got != guide {
+		t.Errorf("first backspace: chapter = %s, want %s", got, guide)
+	}
+	send(tea.KeyPressMsg{Code: tea.KeyBackspace})
+	um = m.(Model)
+	if um.chapter.filePath != index || um.chapter.viewport.YOffset() != offset {
+		t.Errorf("second backspace: chapter = %s at %d, want %s at %d", um.chapter.filePath, um.chapter.viewport.YOffset(), index, offset)
+	}
+
+	var opened string
+	orig := openBrowser
+	openBrowser = func(url string) error { opened = url; return nil }
+	t.Cleanup(func() { openBrowser = orig })
+	send(tea.KeyPressMsg{Code: tea.KeyTab, Mod: tea.ModShift})
+	send(enter)
+	if um := m.(Model); um.chapter.filePath != index || um.chapter.statusText != "Opened in browser" {
+		t.Errorf("web link: chapter = %s, status = %q", um.chapter.filePath, um.chapter.statusText)
+	}
+	if opened != "https://example.com" {
+		t.Errorf("opened %q in the browser, want https://example.com", opened)
+	}
+	send(tea.KeyPressMsg{Code: tea.KeyBackspace})
+	if um := m.(Model); um.view != BookView {
+		t.Errorf("backspace with empty history: view = %v, want BookView", um.view)
+	}
+}
+
+func TestJumpList(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"index.md": "# Index\n\nSee [the end](#end).\n\n" + strings.Repeat("Line of text.\n\n", 40) + "## End\n\nRead [the guide](guide.md).\n",
+		"guide.md": "# Guide\n\nText.\n",
+	})
+	index := filepath.Join(dir, "index.md")
+	guide := filepath.Join(dir, "guide.md")
+	var m tea.Model = loadModel(dir, 80)
+	send := func(msg tea.Msg) {
+		t.Helper()
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		if cmd == nil {
+			return
+		}
+		switch next := cmd().(type) {
+		case FollowLinkMsg, JumpMsg:
+			m, _ = m.Update(next)
+		}
+	}
+	at := func() (string, int) {
+		um := m.(Model)
+		return um.chapter.filePath, um.chapter.viewport.YOffset()
+	}
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+	back := tea.KeyPressMsg{Code: 'o', Mod: tea.ModCtrl}
+	forward := tea.KeyPressMsg{Code: 'i', Mod: tea.ModCtrl}
+
+	send(OpenChapterMsg{FilePath: index})
+	send(back)
+	if got := m.(Model).chapter.statusText; got != "No older position" {
+		t.Errorf("ctrl+o with no jumps: status = %q", got)
+	}
+
+	send(tab)
+	send(enter)
+	_, end := at()
+	if end == 0 {
+		t.Fatal("following #end should scroll")
+	}
+	send(tab)
+	send(enter)
+	if path, _ := at(); path != guide {
+		t.Fatalf("after following a link: chapter = %s, want %s", path, guide)
+	}
+
+	send(back)
+	if path, offset := at(); path != index || offset != end {
+		t.Errorf("first ctrl+o: %s at %d, want %s at %d", path, offset, index, end)
+	}
+	send(back)
+	if path, offset := at(); path != index || offset != 0 {
+		t.Errorf("second ctrl+o: %s at %d, want %s at 0", path, offset, index)
+	}
+	send(forward)
+	if path, offset := at(); path != index || offset != end {
+		t.Errorf("first ctrl+i: %s at %d, want %s at %d", path, offset, index, end)
+	}
+	send(forward)
+	if path, _ := at(); path != guide {
+		t.Errorf("second ctrl+i: chapter = %s, want %s", path, guide)
+	}
+	send(forward)
+	if got := m.(Model).chapter.statusText; got != "No newer position" {
+		t.Errorf("ctrl+i at the newest position: status = %q", got)
+	}
+	// alt+i stands in for ctrl+i where the terminal sends it as tab.
+	send(back)
+	send(tea.KeyPressMsg{Code: 'i', Mod: tea.ModAlt})
+	if path, _ := at(); path != guide {
+		t.Errorf("alt+i: chapter = %s, want %s", path, guide)
+	}
+}
+
+func TestChapterScrollRestored(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{
+		"long.md": strings.Repeat("Line of text.\n\n", 200),
+	})
+	path := filepath.Join(dir, "long.md")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	m := loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(statePath)
+	updated, _ := m.Update(OpenChapterMsg{FilePath: path})
+	for range 2 {
+		updated, _ = updated.(Model).Update(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	}
+	um := updated.(Model)
+	offset := um.chapter.viewport.YOffset()
+	if offset == 0 {
+		t.Fatal("paging should scroll the chapter")
+	}
+	um.Update(tea.KeyPressMsg{Code: 'c', Mod: tea.ModCtrl})
+
+	// A fresh session restores the offset from the saved state.
+	m = loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(statePath)
+	updated, _ = m.Update(OpenChapterMsg{FilePath: path})
+	um = updated.(Model)
+	if got := um.chapter.viewport.YOffset(); got != offset {
+		t.Errorf("restored YOffset = %d, want %d", got, offset)
+	}
+}
+
+func TestEditorDraftRestored(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "Saved"})
+	path := filepath.Join(dir, "a.md")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	m := loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(statePath)
+	updated, _ := m.Update(OpenEditorMsg{FilePath: path, Content: "Saved"})
+	updated, _ = updated.(Model).Update(tea.KeyPressMsg{Code: '!', Text: "!"})
+	updated.(Model).SaveState()
+
+	// A fresh session offers the draft.
+	m = loadModel(dir, 80)
+	m.ctx.state, _ = state.Open(statePath)
+	if d, ok := m.ctx.state.Draft(path); !ok || d.Content != "!Saved" {
+		t.Fatalf("draft = %+v, %v", d, ok)
+	}
+	updated, _ = m.Update(OpenEditorMsg{FilePath: path, Content: "Saved"})
+	updated, _ = updated.(Model).Update(tea.KeyPressMsg{Code: 'y', Mod: tea.ModAlt})
+	um := updated.(Model)
+	if got := um.editor.textarea.Value(); got != "!Saved" {
+		t.Errorf("restored content = %q, want the draft", got)
+	}
+	updated, _ = um.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	updated.(Model).SaveState()
+	if st, _ := state.Open(statePath); len(st.Drafts) != 0 {
+		t.Errorf("saving should forget the draft: %+v", st.Drafts)
+	}
+}
+
+func TestCompareSideBySide(t *testing.T) {
+	long := strings.Repeat("Line of text.\n\n", 100)
+	dir := tempDirWithFiles(t, map[string]string{
+		"a.md": "# Draft A\n\n" + long,
+		"b.md": "# Draft B\n\n" + long,
+	})
+	m := loadModel(dir, 80)
+	m.ctx.width, m.ctx.height = 120, 30
+	press := func(msg tea.KeyPressMsg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+	C := tea.KeyPressMsg{Code: 'C', Text: "C"}
+
+	press(C)
+	press(tea.KeyPressMsg{Code: tea.KeyDown})
+	msg := press(C)()
+	cmp, ok := msg.(CompareMsg)
+	if !ok {
+		t.Fatalf("second C: got %T, want CompareMsg", msg)
+	}
+	if filepath.Base(cmp.Left) != "a.md" || filepath.Base(cmp.Right) != "b.md" {
+		t.Errorf("CompareMsg = %+v, want a.md and b.md", cmp)
+	}
+	updated, _ := m.Update(cmp)
+	m = updated.(Model)
+	ch := m.chapter
+	if m.view != ChapterView || ch.compare == nil {
+		t.Fatal("CompareMsg should open the comparison")
+	}
+	if view := ch.View(); !strings.Contains(view, "Draft A") || !strings.Contains(view, "Draft B") {
+		t.Error("View() should show both documents")
+	}
+
+	press(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	left, right := m.chapter.viewport.YOffset(), m.chapter.compare.viewport.YOffset()
+	if left == 0 || left != right {
+		t.Errorf("locked scrolling: offsets %d and %d, want equal and nonzero", left, right)
+	}
+
+	press(tea.KeyPressMsg{Code: 'L', Text: "L"})
+	press(tea.KeyPressMsg{Code: 'w', Text: "w"})
+	press(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	if m.chapter.viewport.YOffset() != left || m.chapter.compare.viewport.YOffset() <= right {
+		t.Errorf("unlocked right pane scroll: offsets %d and %d", m.chapter.viewport.YOffset(), m.chapter.compare.viewport.YOffset())
+	}
+
+	press(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.view != ChapterView || m.chapter.compare != nil {
+		t.Error("esc should close the comparison and stay in the chapter")
+	}
+	if w := m.chapter.viewport.Width(); w != m.ctx.width {
+		t.Errorf("viewport width = %d after closing, want %d", w, m.ctx.width)
+	}
+}
+
+func TestMetricsView(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# Title\n\nShort one. Then a much longer sentence follows it here. The end.\n"})
+	m := NewFromFile(filepath.Join(dir, "a.md"), 80)
+	m.ctx.width, m.ctx.height = 100, 30
+
+	updated, cmd := m.Update(tea.KeyPressMsg{Code: 'M', Text: "M"})
+	updated, cmd = updated.(Model).Update(cmd())
+	m = updated.(Model)
+	if m.view != MetricsView {
+		t.Fatalf("view = %v, want MetricsView", m.view)
+	}
+	if view := ansi.Strip(m.View().Content); !strings.Contains(view, "Analyzing…") {
+		t.Errorf("metrics view before the analysis:\n%s", view)
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	view := ansi.Strip(m.View().Content)
+	for _, want := range []string{"Metrics", "rhythm", "economy", "richness", "Sentences", "3", "2 to 8 words"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("metrics view lacks %q:\n%s", want, view)
+		}
+	}
+	if n := strings.Count(view, "\n") + 1; n != m.ctx.height {
+		t.Errorf("metrics view is %d lines, want %d", n, m.ctx.height)
+	}
+
+	updated, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	updated, _ = updated.(Model).Update(cmd())
+	if v := updated.(Model).view; v != ChapterView {
+		t.Errorf("esc: view = %v, want ChapterView", v)
+	}
+
+	// From the Book, the Metrics view returns to the Book.
+	m = loadModel(dir, 80)
+	updated, _ = m.Update(OpenMetricsMsg{FilePath: filepath.Join(dir, "a.md"), Content: "Some text."})
+	updated, _ = updated.(Model).Update(CloseMetricsMsg{})
+	if v := updated.(Model).view; v != BookView {
+		t.Errorf("closing metrics opened from the book: view = %v, want BookView", v)
+	}
+}
+
+// failingAnalyzer stands in for the metrics analysis and fails.
+type failingAnalyzer struct{}
+
+func (failingAnalyzer) Analyze(string) (metrics.Result, error) {
+	return metrics.Result{}, errors.New("service unavailable")
+}
+
+func TestMetricsAnalyzer(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "Some text. More text."})
+	m := NewFromFile(filepath.Join(dir, "a.md"), 80, WithAnalyzer(failingAnalyzer{}))
+	updated, cmd := m.Update(OpenMetricsMsg{FilePath: filepath.Join(dir, "a.md"), Content: "Some text."})
+	updated, _ = updated.(Model).Update(cmd())
+	if view := ansi.Strip(updated.(Model).View().Content); !strings.Contains(view, "Analysis failed: service unavailable") {
+		t.Errorf("failed analysis not shown:\n%s", view)
+	}
+}
+
+func TestMetricsTarget(t *testing.T) {
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80, metricsTarget: metrics.Target{metrics.Economy: 0.5}}
+	analyze := func(content string) Metrics {
+		m := NewMetrics(ctx, "a.md", content)
+		m, _ = m.Update(m.Init()())
+		return m
+	}
+	text := "It is nice. Frankly, the rest reads well enough."
+
+	m := analyze(text)
+	if g := m.goal(metrics.Economy); !g.set || !g.off(1) || g.off(0.6) {
+		t.Errorf("economy goal = %+v, want 0.5 ± %.2f", g, defaultTolerance)
+	}
+	if m.goal(metrics.Rhythm).set {
+		t.Error("rhythm has no target")
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "│ target from config, ±0.15") {
+		t.Errorf("view lacks the target legend:\n%s", view)
+	}
+
+	m = analyze("---\nmetrics-target: [rhythm 0.9, economy 1]\n---\n" + text)
+	if m.target[metrics.Rhythm] != 0.9 || m.goal(metrics.Economy).off(1) {
+		t.Errorf("front matter target = %v, want it to replace the config's", m.target)
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "target from front matter") {
+		t.Errorf("view lacks the front matter legend:\n%s", view)
+	}
+
+	m = analyze("---\nmetrics-target: economy lots\n---\n" + text)
+	if view := ansi.Strip(m.View()); !strings.Contains(view, `Front matter target "economy lots"`) {
+		t.Errorf("view lacks the target error:\n%s", view)
+	}
+}
+
+func TestMetricsRadar(t *testing.T) {
+	ctx := &ViewContext{width: 100, height: 40, maxWidth: 80}
+	ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	analyze := func(content string) Metrics {
+		m := NewMetrics(ctx, "a.md", content)
+		m, _ = m.Update(m.Init()())
+		return m
+	}
+	analyze("It is really very nice. It is just quite good.")
+	m := analyze("It is nice. Frankly, the rest reads well enough.")
+
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'r', Text: "r"})
+	view := ansi.Strip(m.View())
+	if !regexp.MustCompile(`[⠁-⣿]`).MatchString(view) || strings.Contains(view, "░") {
+		t.Errorf("r should swap the bars for a radar chart:\n%s", view)
+	}
+	for _, want := range []string{"rhythm", "economy", "variety ", "economy 1.00 · ", "━ previous"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("radar view lacks %q:\n%s", want, view)
+		}
+	}
+	if n := strings.Count(view, "\n") + 1; n != ctx.height {
+		t.Errorf("radar view is %d lines, want %d", n, ctx.height)
+	}
+
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'r', Text: "r"})
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "░") {
+		t.Errorf("r again should bring the bars back:\n%s", view)
+	}
+}
+
+func TestMetricsEvidence(t *testing.T) {
+	doc := "# Title\n\n" + strings.Repeat("Short one.\n\nThis line of words runs on a while longer than the last.\n\n", 15) +
+		"> Honestly, it is really very\n> good and just quite nice.\n"
+	dir := tempDirWithFiles(t, map[string]string{"a.md": doc})
+	path := filepath.Join(dir, "a.md")
+	m := NewFromFile(path, 80)
+	m.ctx.width, m.ctx.height = 100, 20
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+	update(update(OpenMetricsMsg{FilePath: path, Content: doc})())
+
+	if view := ansi.Strip(m.View().Content); !strings.Contains(view, "▸ rhythm") {
+		t.Errorf("the first axis should be selected:\n%s", view)
+	}
+	for range 3 {
+		update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if m.metrics.statusText != "No sentence stands out for readability" {
+		t.Errorf("status = %q, want no evidence for readability", m.metrics.statusText)
+	}
+
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	cmd := update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter on economy should show its worst sentence")
+	}
+	update(cmd())
+	if m.view != ChapterView || !m.chapter.marked {
+		t.Fatalf("view = %v, marked = %v; want a marked chapter", m.view, m.chapter.marked)
+	}
+	view := m.chapter.viewport.View()
+	if !strings.Contains(ansi.Strip(view), "Honestly, it is really very") {
+		t.Errorf("the chapter isn't scrolled to the sentence:\n%s", ansi.Strip(view))
+	}
+	if !strings.Contains(view, markStyle.Render(" ")[:10]) {
+		t.Error("the sentence isn't highlighted")
+	}
+
+	update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	if m.chapter.marked || m.chapter.viewport.GetContent() != m.chapter.rendered {
+		t.Error("a key should clear the highlight")
+	}
+}
+
+// countingAnalyzer stands in for the metrics analysis and counts its runs.
+type countingAnalyzer struct{ runs *int }
+
+func (c countingAnalyzer) Analyze(markdown string) (metrics.Result, error) {
+	*c.runs++
+	return metrics.Analyze(markdown), nil
+}
+
+func TestMetricsCache(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "Some text. More text."})
+	path := filepath.Join(dir, "a.md")
+	runs := 0
+	m := NewFromFile(path, 80, WithAnalyzer(countingAnalyzer{&runs}))
+	open := func(content string) Model {
+		updated, cmd := m.Update(OpenMetricsMsg{FilePath: path, Content: content})
+		updated, _ = updated.(Model).Update(cmd())
+		return updated.(Model)
+	}
+
+	open("Some text. More text.")
+	reopened, _ := m.Update(OpenMetricsMsg{FilePath: path, Content: "Some text. More text."})
+	if view := ansi.Strip(reopened.(Model).View().Content); strings.Contains(view, "Analyzing…") || !strings.Contains(view, "rhythm") {
+		t.Errorf("an unchanged document should show its scores at once:\n%s", view)
+	}
+	open("Some text. More text.")
+	if runs != 1 {
+		t.Errorf("analyzer ran %d times for unchanged content, want 1", runs)
+	}
+	open("Other text entirely.")
+	if runs != 2 {
+		t.Errorf("analyzer ran %d times after a change, want 2", runs)
+	}
+}
+
+func TestMetricsHistory(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": ""})
+	path := filepath.Join(dir, "a.md")
+	m := NewFromFile(path, 80)
+	m.ctx.width, m.ctx.height = 120, 30
+	m.ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	analyze := func(content string) string {
+		updated, cmd := m.Update(OpenMetricsMsg{FilePath: path, Content: content})
+		updated, _ = updated.(Model).Update(cmd())
+		return ansi.Strip(updated.(Model).View().Content)
+	}
+
+	if view := analyze("It is really very nice. It is just quite good."); strings.Contains(view, "▁") || strings.Contains(view, "+0.") {
+		t.Errorf("first analysis shouldn't show a trend:\n%s", view)
+	}
+	view := analyze("It is nice. Frankly, the rest reads well enough for a first draft of this.")
+	if !regexp.MustCompile(`economy .*[▁-█]{2} \+\d\.\d\d`).MatchString(view) {
+		t.Errorf("second analysis should show economy rising:\n%s", view)
+	}
+	analyze("It is nice. Frankly, the rest reads well enough for a first draft of this.")
+	if got, _ := m.ctx.state.Analyses(path); len(got) != 2 {
+		t.Errorf("an unchanged document was logged again: %d analyses", len(got))
+	}
+}
+
+func TestMetricsExport(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": ""})
+	path := filepath.Join(dir, "a.md")
+	ctx := &ViewContext{width: 100, height: 30, maxWidth: 80}
+	ctx.state, _ = state.Open(filepath.Join(t.TempDir(), "state.json"))
+	analyze := func(content string) Metrics {
+		m := NewMetrics(ctx, path, content)
+		m, _ = m.Update(m.Init()())
+		return m
+	}
+	analyze("It is really very nice. It is just quite good.")
+	m := analyze("It is nice. Frankly, the rest reads well enough.")
+
+	m, cmd := m.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if cmd == nil || !strings.Contains(m.View(), "Exported to a.metrics.json") {
+		t.Errorf("x should report the export:\n%s", m.View())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a.metrics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got metricsExport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != path || got.Words != 9 || len(got.Axes) != len(m.result.Axes) || len(got.History) != 1 {
+		t.Errorf("JSON export = %+v", got)
+	}
+
+	m.Update(tea.KeyPressMsg{Code: 'X', Text: "X"})
+	data, err = os.ReadFile(filepath.Join(dir, "a.metrics.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][3] != metrics.Rhythm || rows[2][1] != "9" || rows[2][2] != "" {
+		t.Errorf("CSV export = %q, want a header, the earlier and the current analysis", rows)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	for _, tt := range []struct {
+		keys map[string][]string
+		err  string
+	}{
+		{map[string][]string{"jump": {"ctrl+j"}}, "jump: not one of"},
+		{map[string][]string{"save": {}}, "save: no keys"},
+		{map[string][]string{"zen": {"z"}}, `zen: "z" would keep it from being typed in the editor`},
+		{map[string][]string{"back": {"q"}}, `back: "q" already does something else in the book`},
+		{map[string][]string{"help": {"alt+x"}, "zen": {"alt+x"}}, `help: "alt+x" is also bound to zen in the editor`},
+		{map[string][]string{"page-up": {"f"}, "page-down": {"b"}}, ""},
+	} {
+		err := CheckKeys(tt.keys)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("CheckKeys(%v) = %v, want %q", tt.keys, err, tt.err)
+		}
+	}
+
+	keys := map[string][]string{"back": {"ctrl+q"}, "save": {"alt+x"}, "page-up": {"f"}, "page-down": {"b"}}
+	dir := tempDirWithFiles(t, map[string]string{"a.md": strings.Repeat("Line.\n\n", 100)})
+	path := filepath.Join(dir, "a.md")
+	m := NewFromFile(path, 80, WithKeys(keys))
+	m.ctx.width, m.ctx.height = 100, 30
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	update(tea.KeyPressMsg{Code: 'b', Text: "b"})
+	if m.chapter.viewport.YOffset() == 0 {
+		t.Error("b, rebound to page-down, should page down")
+	}
+	update(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	if m.chapter.viewport.YOffset() != 0 {
+		t.Error("f, rebound to page-up, should page up")
+	}
+	if cmd := update(tea.KeyPressMsg{Code: tea.KeyBackspace}); cmd != nil {
+		t.Error("backspace should do nothing once back is rebound")
+	}
+	if cmd := update(tea.KeyPressMsg{Code: 'q', Mod: tea.ModCtrl}); cmd == nil {
+		t.Error("ctrl+q should go back")
+	} else if _, ok := cmd().(LinkBackMsg); !ok {
+		t.Error("ctrl+q should go back to the previous document")
+	}
+	update(tea.KeyPressMsg{Code: '?', Text: "?"})
+	if view := ansi.Strip(m.View().Content); !regexp.MustCompile(`\^Q\s+back`).MatchString(view) || !regexp.MustCompile(`b\s+page down`).MatchString(view) {
+		t.Errorf("help should show the rebound keys:\n%s", view)
+	}
+
+	update(update(tea.KeyPressMsg{Code: 'e', Text: "e"})())
+	update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	update(tea.KeyPressMsg{Code: 'x', Mod: tea.ModAlt})
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "x") {
+		t.Error("alt+x, rebound to save, should save")
+	}
+}
+
+func TestPluginKeys(t *testing.T) {
+	for _, tt := range []struct {
+		plugins map[string]string
+		keys    map[string][]string
+		err     string
+	}{
+		{map[string]string{"a": "cmd"}, nil, `"a" would keep it from being typed in the editor`},
+		{map[string]string{"Z": "cmd"}, nil, `"Z" would keep it from being typed in the editor`},
+		{map[string]string{"ctrl+r": "cmd"}, nil, `"ctrl+r" already does something else in the chapter`},
+		{map[string]string{"ctrl+s": "cmd"}, nil, `"ctrl+s" is bound to save in the editor`},
+		{map[string]string{"alt+x": "cmd"}, map[string][]string{"zen": {"alt+x"}}, `"alt+x" is bound to zen in the editor`},
+		{map[string]string{"alt+x": "cmd", "alt+P": "cmd"}, nil, ""},
+	} {
+		err := CheckPlugins(tt.plugins, tt.keys)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("CheckPlugins(%v, %v) = %v, want %q", tt.plugins, tt.keys, err, tt.err)
+		}
+	}
+}
+
+func TestExternalEditorCommand(t *testing.T) {
+	ctx := &ViewContext{editorArgs: map[string]string{"ed": "-l {line}"}}
+	tests := []struct {
+		editor string
+		line   int
+		want   []string
+	}{
+		{"nvim -p", 12, []string{"nvim", "-p", "+12", "/my docs/a.md"}},
+		{"/usr/bin/code --wait", 3, []string{"/usr/bin/code", "--wait", "-g", "/my docs/a.md:3"}},
+		{"hx", 7, []string{"hx", "/my docs/a.md:7"}},
+		{"ed", 5, []string{"ed", "-l", "5", "/my docs/a.md"}},
+		{"nano", 0, []string{"nano", "/my docs/a.md"}},
+		{"notepad", 9, []string{"notepad", "/my docs/a.md"}},
+	}
+	for _, tt := range tests {
+		t.Setenv(editorEnv, tt.editor)
+		if got := externalEditorCommand(ctx, "/my docs/a.md", tt.line).Args; !slices.Equal(got, tt.want) {
+			t.Errorf("%s at %d: got %q, want %q", tt.editor, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestEditorOpenExternal(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "one\ntwo\nthree\n"})
+	path := filepath.Join(dir, "a.md")
+	e := NewEditor(&ViewContext{width: 80, height: 24, maxWidth: 80}, path, "one\ntwo\nthree\n")
+	e.textarea.CursorDown()
+	e, cmd := e.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModAlt})
+	msg, ok := cmd().(OpenExternalEditorMsg)
+	if !ok || msg.FilePath != path || msg.Line != 2 {
+		t.Fatalf("alt+o: got %#v", msg)
+	}
+
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'a', Text: "a"})
+	e, _ = e.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModAlt})
+	if e.statusText != "Save before opening in $EDITOR" {
+		t.Errorf("unsaved: status %q", e.statusText)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# A\n\nSee [the site](https://example.com).\n"})
+	path := filepath.Join(dir, "a.md")
+	var m tea.Model = loadModel(dir, 80, WithReadOnly(true))
+	for _, key := range []string{"x", "n", "R"} {
+		m, _ = m.Update(tea.KeyPressMsg{Code: rune(key[0]), Text: key})
+	}
+	b := m.(Model).book
+	if _, err := os.Stat(path); err != nil || b.naming || b.statusText != "Read-only" {
+		t.Fatalf("Book keys went through: %v, naming %v, status %q", err, b.naming, b.statusText)
+	}
+
+	m, _ = m.Update(OpenChapterMsg{FilePath: path})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'e', Text: "e"})
+	if um := m.(Model); um.view != ChapterView || um.chapter.statusText != "Read-only" {
+		t.Errorf("e: view %v, status %q", um.view, um.chapter.statusText)
+	}
+	m, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyTab})
+	m, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if got := m.(Model).chapter.statusText; got != "Read-only: https://example.com" {
+		t.Errorf("web link: status %q", got)
+	}
+	if st := m.(Model).ctx.state; len(st.Recent) != 1 || st.Save() != nil {
+		t.Error("the session should remember what was read without saving it")
+	}
+}
+
+func TestSessionSharedKeys(t *testing.T) {
+	dir := tempDirWithFiles(t, map[string]string{"a.md": "# A\n"})
+	var m tea.Model = loadModel(dir, 80, WithSession("ssh ann@10.0.0.1:5000"))
+	m, _ = m.Update(OpenChapterMsg{FilePath: filepath.Join(dir, "a.md")})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'T', Text: "T"})
+	if c := m.(Model).chapter; c.statusText != "Not in a shared session" {
+		t.Errorf("T: status %q", c.statusText)
+	}
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// pluginTimeout bounds how long one plugin may run.
+const pluginTimeout = time.Minute
+
+// pluginActions are what a plugin asks for by printing a JSON object:
+//
+//	{"replace": "new text", "message": "Done", "open": "other.md"}
+//
+// Every field is optional.
+type pluginActions struct {
+	Replace *string `json:"replace"` // new text for what the plugin was given
+	Message string  `json:"message"` // shown in the status bar
+	Open    string  `json:"open"`    // a document to open, relative to the current one's folder
+}
+
+// pluginInput is what a plugin is given on stdin: the selected lines of the
+// document, or all of it.
+type pluginInput struct {
+	text      string
+	selection bool
+	from, to  int // the lines [from, to) text was taken from
+}
+
+// pluginDoneMsg carries a plugin's answer for the document at path.
+type pluginDoneMsg struct {
+	path    string
+	key     string
+	in      pluginInput
+	seq     int // the editor's edit count when the plugin started
+	actions pluginActions
+	output  string // last line the plugin printed to stderr, when it failed
+	err     error
+}
+
+// runPlugin runs the plugin bound to key, the command line line, on the
+// document at path in the background. It runs like an on-save hook, in the
+// document's folder with {file} and $INK_FILE set to the path, and gets
+// in.text on stdin, with $INK_SELECTION set to 1 when that is a selection
+// rather than the whole document and $INK_LINE to the line it starts on.
+func runPlugin(key, line, path string, in pluginInput, seq int) tea.Cmd {
+	return func() tea.Msg {
+		msg := pluginDoneMsg{path: path, key: key, in: in, seq: seq}
+		ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+		defer cancel()
+		cmd := commandLine(ctx, line, path, filepath.Dir(path))
+		if cmd == nil {
+			return msg
+		}
+		selection := "0"
+		if in.selection {
+			selection = "1"
+		}
+		cmd.Env = append(cmd.Env, "INK_SELECTION="+selection, "INK_LINE="+strconv.Itoa(in.from+1))
+		cmd.Stdin = strings.NewReader(in.text)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			msg.output, msg.err = lastLine(stderr.String()), err
+			return msg
+		}
+		msg.actions, msg.err = parsePluginOutput(string(out), in.selection)
+		return msg
+	}
+}
+
+// parsePluginOutput reads what a plugin printed. A JSON object is taken as
+// actions. Other text replaces the selection the plugin was given, or shows
+// as a message when it was given the whole document, so a plugin never
+// rewrites a document by accident.
+func parsePluginOutput(out string, selection bool) (pluginActions, error) {
+	var a pluginActions
+	trimmed := strings.TrimSpace(out)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		if err := json.Unmarshal([]byte(trimmed), &a); err != nil {
+			return a, fmt.Errorf("unreadable actions: %w", err)
+		}
+	case selection && trimmed != "":
+		text := strings.TrimSuffix(out, "\n")
+		a.Replace = &text
+	case trimmed != "":
+		a.Message = lastLine(trimmed)
+	}
+	return a, nil
+}
+
+// pluginFailure describes a plugin's error for the status bar.
+func pluginFailure(msg pluginDoneMsg) string {
+	return "Plugin " + msg.key + " failed: " + cmp.Or(msg.output, msg.err.Error())
+}
+
+// pluginTarget resolves a plugin's open action against the folder of the
+// document at path, checking that the file is there.
+func pluginTarget(path, open string) (string, error) {
+	if !filepath.IsAbs(open) {
+		open = filepath.Join(filepath.Dir(path), open)
+	}
+	if _, err := os.Stat(open); err != nil {
+		return "", err
+	}
+	return open, nil
+}
+
+// splitLines splits text into lines of runes, as the editor holds them.
+func splitLines(text string) [][]rune {
+	var lines [][]rune
+	for _, l := range strings.Split(text, "\n") {
+		lines = append(lines, []rune(l))
+	}
+	return lines
+}
+
+// startPlugin runs the plugin bound to key on the lines selected in vim's
+// visual mode, or on the whole buffer.
+func (e *Editor) startPlugin(key, line string) tea.Cmd {
+	lines := strings.Split(e.textarea.Value(), "\n")
+	in := pluginInput{text: e.textarea.Value(), to: len(lines)}
+	if e.vim != nil && e.vim.mode == vimVisual {
+		row := e.textarea.Line()
+		lo, hi := min(e.vim.anchor, row), max(e.vim.anchor, row)
+		in = pluginInput{text: strings.Join(lines[lo:hi+1], "\n"), selection: true, from: lo, to: hi + 1}
+		e.vim.mode = vimNormal
+	}
+	e.statusText = "Running " + key + "…"
+	return runPlugin(key, line, e.filePath, in, e.editSeq)
+}
+
+// pluginDone carries out a plugin's actions. An edit is dropped when the
+// buffer changed while the plugin ran, as it was made for the old text.
+func (e Editor) pluginDone(msg pluginDoneMsg) (Editor, tea.Cmd) {
+	done := clearStatusAfter(5*time.Second, clearEditorStatusMsg{})
+	if msg.err != nil {
+		e.statusText = pluginFailure(msg)
+		return e, done
+	}
+	a := msg.actions
+	e.statusText = cmp.Or(a.Message, "Plugin "+msg.key+" done")
+	var cmd tea.Cmd
+	if a.Replace != nil {
+		if msg.seq != e.editSeq {
+			e.statusText = "Plugin " + msg.key + ": the text changed while it ran; edit dropped"
+			return e, done
+		}
+		lines := e.lines()
+		lines = append(lines[:msg.in.from], append(splitLines(*a.Replace), lines[min(msg.in.to, len(lines)):]...)...)
+		row, col := e.cursor()
+		if msg.in.selection {
+			row, col = msg.in.from, 0
+		}
+		e.setLines(lines, min(row, len(lines)-1), col)
+		e, cmd = e.contentChanged(nil)
+	}
+	if a.Open != "" {
+		path, err := pluginTarget(msg.path, a.Open)
+		switch {
+		case err != nil:
+			e.statusText = "Plugin " + msg.key + ": " + err.Error()
+		case !e.saved:
+			e.statusText = "Save to open " + filepath.Base(path)
+		default:
+			return e, tea.Sequence(cmd, e.close(), func() tea.Msg { return FollowLinkMsg{FilePath: path} })
+		}
+	}
+	return e, tea.Batch(cmd, done)
+}
+
+// startPlugin runs the plugin bound to key on the source of the visual
+// selection, or on the whole document.
+func (c *Chapter) startPlugin(key, line string) tea.Cmd {
+	lines := strings.Split(c.content, "\n")
+	in := pluginInput{text: c.content, to: len(lines)}
+	if c.visual != nil {
+		start, end := c.selectionSource()
+		end = min(end, len(lines))
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		in = pluginInput{text: strings.Join(lines[start:end], "\n"), selection: true, from: start, to: end}
+		c.endSelection()
+	}
+	c.statusText = "Running " + key + "…"
+	return runPlugin(key, line, c.filePath, in, 0)
+}
+
+// pluginDone carries out a plugin's actions. The reader leaves the text
+// alone; edits are for the editor.
+func (c *Chapter) pluginDone(msg pluginDoneMsg) tea.Cmd {
+	done := clearStatusAfter(5*time.Second, clearStatusMsg{})
+	if msg.err != nil {
+		c.statusText = pluginFailure(msg)
+		return done
+	}
+	a := msg.actions
+	c.statusText = cmp.Or(a.Message, "Plugin "+msg.key+" done")
+	if a.Replace != nil {
+		c.statusText = "Plugin " + msg.key + " edits text: run it in the editor"
+	}
+	if a.Open != "" {
+		path, err := pluginTarget(msg.path, a.Open)
+		if err != nil {
+			c.statusText = "Plugin " + msg.key + ": " + err.Error()
+			return done
+		}
+		c.statusText = ""
+		return func() tea.Msg { return FollowLinkMsg{FilePath: path} }
+	}
+	return done
+}
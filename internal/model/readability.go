@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/inkcheck/readability"
+)
+
+// readabilityScore is a readability formula the Chapter and Editor status
+// bars can show.
+type readabilityScore struct {
+	name    string // name in the configuration
+	label   string // label in the status bar
+	formula readability.Formula
+}
+
+// readabilityScores are the formulas on offer, the default first.
+var readabilityScores = []readabilityScore{
+	{"flesch-kincaid", "Grade", readability.FleschKincaidGrade},
+	{"flesch-ease", "Ease", readability.FleschReadingEase},
+	{"gunning-fog", "Fog", readability.GunningFog},
+	{"smog", "SMOG", readability.SmogIndex},
+	{"coleman-liau", "CLI", readability.ColemanLiauIndex},
+}
+
+// ReadabilityNames returns the names of the readability formulas that
+// WithReadability accepts.
+func ReadabilityNames() []string {
+	names := make([]string, len(readabilityScores))
+	for i, s := range readabilityScores {
+		names[i] = s.name
+	}
+	return names
+}
+
+// text formats the score of an analysis for the status bar, or returns ""
+// when the text is too short to score.
+func (s readabilityScore) text(a *readability.Analysis) string {
+	score, err := a.Score(s.formula)
+	if err != nil || a.Stats().Words < 10 {
+		return ""
+	}
+	return fmt.Sprintf("%s %d", s.label, int(score))
+}
+
+// readabilitySuite returns the formulas the status bars cycle through.
+func (ctx *ViewContext) readabilitySuite() []readabilityScore {
+	if len(ctx.readability) > 0 {
+		return ctx.readability
+	}
+	return readabilityScores
+}
+
+// readabilityScore returns the formula the status bars show.
+func (ctx *ViewContext) readabilityScore() readabilityScore {
+	suite := ctx.readabilitySuite()
+	return suite[ctx.scoreIndex%len(suite)]
+}
+
+// cycleReadability moves the status bars on to the next formula of the
+// suite, and returns a description of it.
+func (ctx *ViewContext) cycleReadability() string {
+	ctx.scoreIndex = (ctx.scoreIndex + 1) % len(ctx.readabilitySuite())
+	return "Readability: " + ctx.readabilityScore().name
+}
+
+// readabilityText formats the score of text under the current formula.
+func (ctx *ViewContext) readabilityText(text string) string {
+	return ctx.readabilityScore().text(readability.NewAnalysis(text))
+}
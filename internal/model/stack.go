@@ -0,0 +1,110 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+
+	"github.com/inkcheck/ink/internal/state"
+)
+
+// Views as named in a saved state.Stack.
+const (
+	stackBook    = "book"
+	stackChapter = "chapter"
+	stackEditor  = "editor"
+)
+
+// stack describes the views the model is showing, for NewLast to rebuild.
+// A Book built from several arguments is remembered as its folder.
+func (m Model) stack() state.Stack {
+	s := state.Stack{View: stackBook}
+	if m.ctx.isBook {
+		s.Book = m.ctx.bookDir
+		s.Dir = m.book.dir
+		if m.book.list.FilterState() != list.Unfiltered {
+			s.Filter = m.book.list.FilterValue()
+		}
+	}
+	view := m.view
+	if view == MetricsView {
+		s.Metrics = m.metrics.filePath
+		view = m.metricsFrom
+	}
+	switch view {
+	case ChapterView:
+		s.View, s.Doc = stackChapter, m.chapter.filePath
+	case EditorView:
+		s.View, s.Doc = stackEditor, m.editor.filePath
+	}
+	return s
+}
+
+// NewLast creates a model showing the views ink was showing when it last
+// quit: the Book at the folder and filter it had, the Chapter or Editor over
+// it, and any Metrics view on top. Documents since removed are left out.
+func NewLast(maxWidth int, opts ...Option) (Model, error) {
+	st, err := state.Load()
+	if err != nil {
+		return Model{}, err
+	}
+	last := st.Stack
+	if last == nil {
+		return Model{}, errors.New("no earlier session to return to")
+	}
+	if last.Book == "" {
+		if !fileExists(last.Doc) {
+			return Model{}, errors.New("the document open last is gone: " + last.Doc)
+		}
+		m := NewFromFile(last.Doc, maxWidth, opts...)
+		m.restore(*last)
+		return m, nil
+	}
+	if info, err := os.Stat(last.Book); err != nil || !info.IsDir() {
+		return Model{}, errors.New("the book open last is gone: " + last.Book)
+	}
+	m := New(last.Book, maxWidth, opts...)
+	m.restore(*last)
+	return m, nil
+}
+
+// restore rebuilds the views of s over the model's Book or Chapter.
+func (m *Model) restore(s state.Stack) {
+	if m.ctx.isBook {
+		b := &m.book
+		if rel, err := filepath.Rel(b.rootDir, s.Dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			// The listing is scanned in the background by Init.
+			if info, err := os.Stat(s.Dir); err == nil && info.IsDir() {
+				b.dir = s.Dir
+			}
+		}
+		if s.Filter != "" {
+			b.list.SetFilterText(s.Filter)
+			b.resizeList()
+		}
+		if s.View != stackBook && fileExists(s.Doc) {
+			m.openChapter(s.Doc)
+		}
+	}
+	if s.View == stackEditor && m.view == ChapterView {
+		m.editor = NewEditor(m.ctx, m.chapter.filePath, m.chapter.content)
+		m.editor.lock()
+		m.view = EditorView
+	}
+	if s.Metrics != "" {
+		if raw, err := os.ReadFile(s.Metrics); err == nil {
+			m.metrics = NewMetrics(m.ctx, s.Metrics, documentText(raw))
+			m.metricsFrom = m.view
+			m.view = MetricsView
+		}
+	}
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -33,6 +33,12 @@ var (
 	statusBarInputStyle = lipgloss.NewStyle().
 				Background(lipgloss.Color("236")).
 				Padding(0, 1)
+
+	statusBarWarningStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("232")).
+				Background(lipgloss.Color("203")).
+				Padding(0, 1)
 )
 
 // statusBarBookName renders the book name segment for a status bar.
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// glamourPrimitive is the part of a glamour style element ink can use.
+type glamourPrimitive struct {
+	Color           string `json:"color"`
+	BackgroundColor string `json:"background_color"`
+}
+
+// glamourStyle is a glamour (glow) style file, cut down to the elements a
+// Theme has colors for.
+type glamourStyle struct {
+	Document      glamourPrimitive `json:"document"`
+	BlockQuote    glamourPrimitive `json:"block_quote"`
+	Heading       glamourPrimitive `json:"heading"`
+	H1            glamourPrimitive `json:"h1"`
+	H2            glamourPrimitive `json:"h2"`
+	H3            glamourPrimitive `json:"h3"`
+	H4            glamourPrimitive `json:"h4"`
+	Strikethrough glamourPrimitive `json:"strikethrough"`
+	HR            glamourPrimitive `json:"hr"`
+	Link          glamourPrimitive `json:"link"`
+	LinkText      glamourPrimitive `json:"link_text"`
+	Code          glamourPrimitive `json:"code"`
+	CodeBlock     glamourPrimitive `json:"code_block"`
+	Table         glamourPrimitive `json:"table"`
+}
+
+// OpenGlamourTheme reads a glamour style JSON file, as used by glow, as a
+// Theme named after the file. Elements the style leaves uncolored fall back
+// to related ones, and then to the terminal's colors.
+func OpenGlamourTheme(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	var s glamourStyle
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	heading := func(h glamourPrimitive) string { return cmp.Or(h.Color, s.Heading.Color) }
+	return Theme{
+		Name:               strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Heading1:           heading(s.H1),
+		Heading1Background: cmp.Or(s.H1.BackgroundColor, s.Heading.BackgroundColor),
+		Heading2:           heading(s.H2),
+		Heading3:           heading(s.H3),
+		Heading4:           heading(s.H4),
+		Code:               cmp.Or(s.CodeBlock.Color, s.Document.Color),
+		CodeBackground:     cmp.Or(s.CodeBlock.BackgroundColor, s.Code.BackgroundColor),
+		InlineCode:         cmp.Or(s.Code.Color, s.Document.Color),
+		Link:               cmp.Or(s.Link.Color, s.LinkText.Color),
+		Border:             cmp.Or(s.BlockQuote.Color, s.HR.Color),
+		Muted:              cmp.Or(s.Strikethrough.Color, s.HR.Color, s.BlockQuote.Color),
+		TableHeader:        cmp.Or(s.Table.Color, s.Heading.Color),
+		Text:               s.Document.Color,
+	}, nil
+}
+
+// AddTheme makes t available by name alongside the built-in themes,
+// replacing any theme of the same name.
+func AddTheme(t Theme) {
+	for i := range Themes {
+		if strings.EqualFold(Themes[i].Name, t.Name) {
+			Themes[i] = t
+			return
+		}
+	}
+	Themes = append(Themes, t)
+}
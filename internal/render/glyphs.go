@@ -0,0 +1,63 @@
+package render
+
+import "charm.land/lipgloss/v2"
+
+// ruleWidth is how many glyphs a thematic break is drawn with.
+const ruleWidth = 40
+
+// glyphSet holds the characters documents are drawn with besides their
+// text: list bullets, task checkboxes, rules and borders.
+type glyphSet struct {
+	bullet    string
+	checked   string
+	unchecked string
+	backlink  string // after a footnote, back to its reference
+	rule      string // repeated to draw thematic breaks
+	// Table borders: the horizontal and vertical lines, then the corners
+	// and joints from top left to bottom right, row by row.
+	tableH, tableV string
+	tableJoints    [9]string
+	quote          lipgloss.Border
+}
+
+var unicodeGlyphs = glyphSet{
+	bullet:      "• ",
+	checked:     "☑ ",
+	unchecked:   "☐ ",
+	backlink:    "↩",
+	rule:        "─",
+	tableH:      "─",
+	tableV:      "│",
+	tableJoints: [9]string{"┌", "┬", "┐", "├", "┼", "┤", "└", "┴", "┘"},
+	quote:       lipgloss.ThickBorder(),
+}
+
+var asciiGlyphs = glyphSet{
+	bullet:      "* ",
+	checked:     "[x] ",
+	unchecked:   "[ ] ",
+	backlink:    "^",
+	rule:        "-",
+	tableH:      "-",
+	tableV:      "|",
+	tableJoints: [9]string{"+", "+", "+", "+", "+", "+", "+", "+", "+"},
+	quote:       lipgloss.Border{Left: "|"},
+}
+
+// glyphs is the set documents are drawn with.
+var glyphs = unicodeGlyphs
+
+// SetASCII draws documents with plain ASCII in place of box drawing,
+// bullets and checkboxes, for dumb terminals and captures to plain text.
+func SetASCII(on bool) {
+	glyphs = unicodeGlyphs
+	if on {
+		glyphs = asciiGlyphs
+	}
+	BlockquoteStyle = BlockquoteStyle.BorderStyle(glyphs.quote)
+}
+
+// ASCII reports whether documents are drawn with plain ASCII.
+func ASCII() bool {
+	return glyphs.bullet == asciiGlyphs.bullet
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"html"
 	"strings"
+	"unicode"
 
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
@@ -15,9 +17,9 @@ import (
 )
 
 // mdParser is a reusable Goldmark parser instance with GFM support
-// (Table, Strikethrough, Linkify, TaskList).
+// (Table, Strikethrough, Linkify, TaskList) and footnotes.
 var mdParser = goldmark.New(
-	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
 )
 
 // stripFrontMatter removes YAML front matter (--- delimited) from the start of source.
@@ -39,22 +41,272 @@ func stripFrontMatter(source []byte) []byte {
 // BottomMargin is the number of blank lines appended after rendered content.
 const BottomMargin = 4
 
+// Heading is a top-level heading of a rendered document.
+type Heading struct {
+	Level int    // 1 for "#", 2 for "##", ...
+	Text  string // plain heading text
+	ID    string // anchor ("#id") linking to the heading, as on GitHub
+	Line  int    // line of the rendered output the heading starts on
+}
+
+// Link is a link in a rendered document, in reading order.
+type Link struct {
+	Dest string // destination as written in the source
+	Text string // plain link text
+	Line int    // line of the rendered output the link appears on
+}
+
+// Image is an image referenced by a rendered document, in reading order.
+type Image struct {
+	Dest string // image path or URL as written in the source
+	Alt  string // plain alt text
+	Line int    // line of the rendered output the image placeholder is on
+}
+
+// Footnote is a footnote of a rendered document. Its references appear in
+// Document.Links with the destination "#fn:N", and its way back to the first
+// reference with "#fnref:N", N being Index.
+type Footnote struct {
+	Index int    // number shown at references, from 1
+	Text  string // plain footnote text
+	Line  int    // line of the rendered output the footnote starts on
+}
+
+// Block maps a top-level block of a rendered document back to the markdown
+// it came from.
+type Block struct {
+	Source int // line of the source, front matter included, the block starts on
+	Line   int // line of the rendered output the block starts on
+}
+
+// Document is rendered markdown together with an outline of its headings,
+// the links, footnotes and images it contains and a map from rendered lines
+// to source lines.
+type Document struct {
+	Content   string
+	Headings  []Heading
+	Links     []Link
+	Footnotes []Footnote
+	Images    []Image
+	Blocks    []Block
+}
+
 // Render converts markdown source to lipgloss-styled terminal output.
 func Render(source []byte, maxWidth int) string {
-	source = stripFrontMatter(source)
+	return RenderDocument(source, maxWidth).Content
+}
+
+// RenderDocument renders markdown source like Render and records where in
+// the output each top-level heading starts and each link appears.
+func RenderDocument(source []byte, maxWidth int) Document {
+	stripped := stripFrontMatter(source)
+	// Source lines count from the top of the file, front matter included.
+	srcLine := bytes.Count(source, []byte("\n")) - bytes.Count(stripped, []byte("\n"))
+	source = stripped
 	reader := text.NewReader(source)
 	doc := mdParser.Parser().Parse(reader)
 
 	var buf strings.Builder
-	renderNode(&buf, doc, source, 0, maxWidth)
+	var headings []Heading
+	var links []Link
+	var blocks []Block
+	var footnotes []Footnote
+	var images []Image
+	ids := make(map[string]int)
+	line := 0
+	srcOff := 0
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		start := buf.Len()
+		renderNode(&buf, child, source, 0, maxWidth)
+		out := buf.String()[start:]
+		if off, ok := blockOffset(child); ok && off >= srcOff {
+			srcLine += bytes.Count(source[srcOff:off], []byte("\n"))
+			srcOff = off
+			src := srcLine
+			if _, fenced := child.(*ast.FencedCodeBlock); fenced {
+				src-- // the opening fence precedes the first code line
+			}
+			blocks = append(blocks, Block{Source: src, Line: line + leadingBlankLines(out)})
+		}
+		if h, ok := child.(*ast.Heading); ok {
+			text := ansi.Strip(renderInlineChildren(h, source))
+			id := Slug(text)
+			if n := ids[id]; n > 0 {
+				ids[id]++
+				id = fmt.Sprintf("%s-%d", id, n)
+			} else {
+				ids[id] = 1
+			}
+			headings = append(headings, Heading{
+				Level: h.Level,
+				Text:  text,
+				ID:    id,
+				Line:  line + leadingBlankLines(out),
+			})
+		}
+		blockLinks, blockImages := findRefs(child, source, out, line)
+		links = append(links, blockLinks...)
+		images = append(images, blockImages...)
+		if list, ok := child.(*east.FootnoteList); ok {
+			footnotes = append(footnotes, findFootnotes(list, source, out, line)...)
+			// Every footnote ends in a "↩", so place backlinks by footnote.
+			for i, l := range links {
+				var n int
+				if _, err := fmt.Sscanf(l.Dest, "#fnref:%d", &n); err == nil && n >= 1 && n <= len(footnotes) {
+					links[i].Line = footnotes[n-1].Line
+				}
+			}
+		}
+		line += strings.Count(out, "\n")
+	}
 
 	result := buf.String()
 	// Trim trailing whitespace
 	result = strings.TrimRight(result, "\n")
 	if result == "" {
-		return ""
+		return Document{}
+	}
+	return Document{
+		Content:   result + strings.Repeat("\n", BottomMargin),
+		Headings:  headings,
+		Links:     links,
+		Footnotes: footnotes,
+		Images:    images,
+		Blocks:    blocks,
+	}
+}
+
+// footnoteText renders the paragraphs of footnote f as one line of text.
+func footnoteText(f *east.Footnote, source []byte) string {
+	var parts []string
+	for c := f.FirstChild(); c != nil; c = c.NextSibling() {
+		parts = append(parts, strings.TrimSpace(renderInlineChildren(c, source)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// findFootnotes lists the footnotes of list, whose rendered output out
+// starts at line.
+func findFootnotes(list *east.FootnoteList, source []byte, out string, line int) []Footnote {
+	var notes []Footnote
+	lines := strings.Split(ansi.Strip(out), "\n")
+	at := 0
+	for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+		f, ok := c.(*east.Footnote)
+		if !ok {
+			continue
+		}
+		marker := fmt.Sprintf("[%d]", f.Index)
+		for i := at; i < len(lines); i++ {
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), marker) {
+				at = i
+				break
+			}
+		}
+		text := ansi.Strip(footnoteText(f, source))
+		text = strings.TrimSpace(strings.TrimSuffix(text, glyphs.backlink))
+		notes = append(notes, Footnote{Index: f.Index, Text: text, Line: line + at})
 	}
-	return result + strings.Repeat("\n", BottomMargin)
+	return notes
+}
+
+// blockOffset returns the byte offset of the first source line of block n,
+// looking into container blocks such as lists and quotes.
+func blockOffset(n ast.Node) (int, bool) {
+	if n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Type() != ast.TypeBlock {
+			continue
+		}
+		if off, ok := blockOffset(c); ok {
+			return off, true
+		}
+	}
+	return 0, false
+}
+
+// findRefs lists the links and images inside block, whose rendered output
+// out starts at line. Each is placed on the first line, at or after the
+// previous one's, that shows the start of its text.
+func findRefs(block ast.Node, source []byte, out string, line int) ([]Link, []Image) {
+	var links []Link
+	var images []Image
+	lines := strings.Split(ansi.Strip(out), "\n")
+	at := 0
+	locate := func(text string) int {
+		if fields := strings.Fields(text); len(fields) > 0 {
+			for i := at; i < len(lines); i++ {
+				if strings.Contains(lines[i], fields[0]) {
+					at = i
+					break
+				}
+			}
+		}
+		return line + at
+	}
+	_ = ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if img, ok := n.(*ast.Image); ok {
+			alt := ansi.Strip(renderInlineChildren(img, source))
+			images = append(images, Image{
+				Dest: string(img.Destination),
+				Alt:  alt,
+				Line: locate("[image: " + alt + "]"),
+			})
+			return ast.WalkSkipChildren, nil
+		}
+		var link Link
+		switch n := n.(type) {
+		case *ast.Link:
+			link = Link{Dest: string(n.Destination), Text: ansi.Strip(renderInlineChildren(n, source))}
+		case *ast.AutoLink:
+			url := string(n.URL(source))
+			link = Link{Dest: url, Text: url}
+		case *east.FootnoteLink:
+			link = Link{Dest: fmt.Sprintf("#fn:%d", n.Index), Text: fmt.Sprintf("[%d]", n.Index)}
+		case *east.FootnoteBacklink:
+			link = Link{Dest: fmt.Sprintf("#fnref:%d", n.Index), Text: glyphs.backlink}
+		default:
+			return ast.WalkContinue, nil
+		}
+		link.Line = locate(link.Text)
+		links = append(links, link)
+		// Keep walking: a link may wrap an image.
+		return ast.WalkContinue, nil
+	})
+	return links, images
+}
+
+// Slug turns heading text into its anchor the way GitHub does: lowercased,
+// spaces become hyphens and other punctuation is dropped.
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// leadingBlankLines counts the lines of s, such as a heading's top margin,
+// that hold nothing visible.
+func leadingBlankLines(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(ansi.Strip(line)) != "" {
+			break
+		}
+		n++
+	}
+	return n
 }
 
 func renderNode(buf *strings.Builder, node ast.Node, source []byte, depth int, maxWidth int) {
@@ -125,7 +377,7 @@ func renderNode(buf *strings.Builder, node ast.Node, source []byte, depth int, m
 		}
 		content := strings.TrimRight(textBuf.String(), "\n")
 		indent := strings.Repeat("  ", depth)
-		marker := "• "
+		marker := glyphs.bullet
 		if parent, ok := n.Parent().(*ast.List); ok && parent.IsOrdered() {
 			idx := parent.Start
 			for sib := n.Parent().FirstChild(); sib != nil; sib = sib.NextSibling() {
@@ -145,7 +397,7 @@ func renderNode(buf *strings.Builder, node ast.Node, source []byte, depth int, m
 		renderTable(buf, n, source, maxWidth)
 
 	case *ast.ThematicBreak:
-		styled := ThematicBreakStyle.Width(maxWidth).Render("────────────────────────────────────────")
+		styled := ThematicBreakStyle.Width(maxWidth).Render(strings.Repeat(glyphs.rule, ruleWidth))
 		buf.WriteString(styled)
 		buf.WriteString("\n\n")
 
@@ -153,6 +405,19 @@ func renderNode(buf *strings.Builder, node ast.Node, source []byte, depth int, m
 		content := renderInlineChildren(n, source)
 		buf.WriteString(content)
 
+	case *east.FootnoteList:
+		rule := ThematicBreakStyle.Width(maxWidth).Render(strings.Repeat(glyphs.rule, ruleWidth))
+		buf.WriteString(rule)
+		buf.WriteString("\n")
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			if f, ok := c.(*east.Footnote); ok {
+				marker := FootnoteRefStyle.Render(fmt.Sprintf("[%d]", f.Index))
+				text := FootnoteStyle.Render(footnoteText(f, source))
+				buf.WriteString(lipgloss.NewStyle().Width(maxWidth).Render(marker + " " + text))
+				buf.WriteString("\n")
+			}
+		}
+
 	default:
 		renderChildren(buf, node, source, depth, maxWidth)
 	}
@@ -231,11 +496,17 @@ func renderInline(buf *strings.Builder, node ast.Node, source []byte) {
 		content := renderInlineChildren(n, source)
 		buf.WriteString(StrikethroughStyle.Render(content))
 
+	case *east.FootnoteLink:
+		buf.WriteString(FootnoteRefStyle.Render(fmt.Sprintf("[%d]", n.Index)))
+
+	case *east.FootnoteBacklink:
+		buf.WriteString(" " + FootnoteRefStyle.Render(glyphs.backlink))
+
 	case *east.TaskCheckBox:
 		if n.IsChecked {
-			buf.WriteString("☑ ")
+			buf.WriteString(glyphs.checked)
 		} else {
-			buf.WriteString("☐ ")
+			buf.WriteString(glyphs.unchecked)
 		}
 
 	default:
@@ -245,4 +516,3 @@ func renderInline(buf *strings.Builder, node ast.Node, source []byte) {
 		}
 	}
 }
-
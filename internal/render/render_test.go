@@ -1,6 +1,10 @@
 package render
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -193,3 +197,235 @@ func TestRenderNoFrontmatterPassthrough(t *testing.T) {
 		t.Errorf("Render malformed frontmatter: unexpected empty output")
 	}
 }
+
+func TestRenderDocumentHeadings(t *testing.T) {
+	md := "---\ntitle: x\n---\n# Intro\n\nSome text.\n\n## Setup `go`\n\n- a\n- b\n\n### Deep\n"
+	doc := RenderDocument([]byte(md), 80)
+	if doc.Content != Render([]byte(md), 80) {
+		t.Error("RenderDocument content differs from Render")
+	}
+	want := []Heading{
+		{Level: 1, Text: "Intro", ID: "intro"},
+		{Level: 2, Text: "Setup go", ID: "setup-go"},
+		{Level: 3, Text: "Deep", ID: "deep"},
+	}
+	if len(doc.Headings) != len(want) {
+		t.Fatalf("Headings = %+v, want %d", doc.Headings, len(want))
+	}
+	lines := strings.Split(ansi.Strip(doc.Content), "\n")
+	for i, h := range doc.Headings {
+		if h.Level != want[i].Level || h.Text != want[i].Text || h.ID != want[i].ID {
+			t.Errorf("heading %d = %+v, want %+v", i, h, want[i])
+		}
+		if h.Line >= len(lines) || !strings.Contains(lines[h.Line], h.Text) {
+			t.Errorf("heading %q line %d does not hold it", h.Text, h.Line)
+		}
+	}
+}
+
+func TestRenderDocumentLinks(t *testing.T) {
+	md := "# Index\n\nSee [the guide](guide.md) first.\n\n- [Setup](setup.md#install)\n- <https://example.com>\n"
+	doc := RenderDocument([]byte(md), 80)
+	want := []Link{
+		{Dest: "guide.md", Text: "the guide"},
+		{Dest: "setup.md#install", Text: "Setup"},
+		{Dest: "https://example.com", Text: "https://example.com"},
+	}
+	if len(doc.Links) != len(want) {
+		t.Fatalf("Links = %+v, want %d", doc.Links, len(want))
+	}
+	lines := strings.Split(ansi.Strip(doc.Content), "\n")
+	for i, l := range doc.Links {
+		if l.Dest != want[i].Dest || l.Text != want[i].Text {
+			t.Errorf("link %d = %+v, want %+v", i, l, want[i])
+		}
+		if l.Line >= len(lines) || !strings.Contains(lines[l.Line], l.Text) {
+			t.Errorf("link %q line %d does not hold it", l.Text, l.Line)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Installation", "installation"},
+		{"Getting Started!", "getting-started"},
+		{"What's new in v2.0?", "whats-new-in-v20"},
+		{"snake_case and-dash", "snake_case-and-dash"},
+		{"Ünïcode Tïtle", "ünïcode-tïtle"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.in); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	doc := RenderDocument([]byte("# Notes\n\n## Notes\n\n## Notes\n"), 80)
+	var ids []string
+	for _, h := range doc.Headings {
+		ids = append(ids, h.ID)
+	}
+	if got := strings.Join(ids, " "); got != "notes notes-1 notes-2" {
+		t.Errorf("duplicate heading IDs = %q", got)
+	}
+}
+
+func TestRenderDocumentBlocks(t *testing.T) {
+	md := "---\ntitle: x\n---\n# Intro\n\nSome text\nover two lines.\n\n- a\n- b\n\n```go\nx := 1\n```\n\n> quoted\n"
+	doc := RenderDocument([]byte(md), 80)
+	src := strings.Split(md, "\n")
+	wantSource := []string{"# Intro", "Some text", "- a", "```go", "> quoted"}
+	if len(doc.Blocks) != len(wantSource) {
+		t.Fatalf("Blocks = %+v, want %d", doc.Blocks, len(wantSource))
+	}
+	last := -1
+	for i, b := range doc.Blocks {
+		if got := src[b.Source]; got != wantSource[i] {
+			t.Errorf("block %d starts on source line %q, want %q", i, got, wantSource[i])
+		}
+		if b.Line <= last {
+			t.Errorf("block %d rendered at line %d, not after %d", i, b.Line, last)
+		}
+		last = b.Line
+	}
+}
+
+func TestRenderDocumentFootnotes(t *testing.T) {
+	md := "# Notes\n\nA claim[^src] and another[^2].\n\n[^src]: The *source* of it.\n[^2]: Second note.\n"
+	doc := RenderDocument([]byte(md), 80)
+	plain := ansi.Strip(doc.Content)
+	if strings.Contains(plain, "[^src]") {
+		t.Error("footnote references should not render as raw text")
+	}
+	if len(doc.Footnotes) != 2 {
+		t.Fatalf("Footnotes = %+v, want 2", doc.Footnotes)
+	}
+	lines := strings.Split(plain, "\n")
+	for i, want := range []string{"The source of it.", "Second note."} {
+		f := doc.Footnotes[i]
+		if f.Index != i+1 || f.Text != want {
+			t.Errorf("footnote %d = %+v, want %d %q", i, f, i+1, want)
+		}
+		if !strings.HasPrefix(strings.TrimSpace(lines[f.Line]), fmt.Sprintf("[%d]", f.Index)) {
+			t.Errorf("footnote %d line %d = %q", f.Index, f.Line, lines[f.Line])
+		}
+	}
+	var dests []string
+	for _, l := range doc.Links {
+		dests = append(dests, l.Dest)
+	}
+	if got := strings.Join(dests, " "); got != "#fn:1 #fn:2 #fnref:1 #fnref:2" {
+		t.Fatalf("footnote links = %q", got)
+	}
+	for i, f := range doc.Footnotes {
+		if back := doc.Links[2+i]; back.Line != f.Line {
+			t.Errorf("backlink %d on line %d, want footnote line %d", f.Index, back.Line, f.Line)
+		}
+	}
+}
+
+func TestRenderDocumentImages(t *testing.T) {
+	md := "# Gallery\n\n![A cat](img/cat.png)\n\n[![Badge](https://example.com/b.svg)](https://example.com)\n"
+	doc := RenderDocument([]byte(md), 80)
+	want := []Image{{Dest: "img/cat.png", Alt: "A cat"}, {Dest: "https://example.com/b.svg", Alt: "Badge"}}
+	if len(doc.Images) != len(want) {
+		t.Fatalf("Images = %+v, want %d", doc.Images, len(want))
+	}
+	lines := strings.Split(ansi.Strip(doc.Content), "\n")
+	for i, img := range doc.Images {
+		if img.Dest != want[i].Dest || img.Alt != want[i].Alt {
+			t.Errorf("image %d = %+v, want %+v", i, img, want[i])
+		}
+		if !strings.Contains(lines[img.Line], img.Alt) {
+			t.Errorf("image %q line %d = %q", img.Alt, img.Line, lines[img.Line])
+		}
+	}
+	if len(doc.Links) != 1 || doc.Links[0].Dest != "https://example.com" {
+		t.Errorf("Links = %+v, want the badge link", doc.Links)
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme(CurrentTheme())
+	light, ok := ThemeNamed("Light")
+	if !ok || light.Name != "light" {
+		t.Fatalf("ThemeNamed(Light) = %+v, %v", light, ok)
+	}
+	if _, ok := ThemeNamed("neon"); ok {
+		t.Error("there is no neon theme")
+	}
+
+	doc := []byte("## Heading\n")
+	before := Render(doc, 40)
+	SetTheme(light)
+	after := Render(doc, 40)
+	if CurrentTheme().Name != "light" || before == after {
+		t.Error("the heading should be rendered in the light theme's colors")
+	}
+	if ansi.Strip(before) != ansi.Strip(after) {
+		t.Error("a theme should change colors only")
+	}
+}
+
+func TestOpenGlamourTheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dracula.json")
+	style := `{
+		"document": {"block_prefix": "\n", "color": "#f8f8f2"},
+		"block_quote": {"color": "#f1fa8c", "italic": true, "indent": 2},
+		"heading": {"color": "#bd93f9", "bold": true},
+		"h1": {"prefix": "# ", "background_color": "#44475a"},
+		"h2": {"color": "#ff79c6"},
+		"hr": {"color": "#6272A4", "format": "\n--------\n"},
+		"link": {"color": "#8be9fd", "underline": true},
+		"code": {"color": "#50fa7b"},
+		"code_block": {"color": "#ffb86c", "margin": 2, "chroma": {"text": {"color": "#f8f8f2"}}}
+	}`
+	os.WriteFile(path, []byte(style), 0644)
+	got, err := OpenGlamourTheme(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Theme{
+		Name: "dracula", Heading1: "#bd93f9", Heading1Background: "#44475a", Heading2: "#ff79c6",
+		Heading3: "#bd93f9", Heading4: "#bd93f9", Code: "#ffb86c", InlineCode: "#50fa7b",
+		Link: "#8be9fd", Border: "#f1fa8c", Muted: "#6272A4", TableHeader: "#bd93f9", Text: "#f8f8f2",
+	}
+	if got != want {
+		t.Errorf("OpenGlamourTheme = %+v\nwant %+v", got, want)
+	}
+
+	defer func(themes []Theme) { Themes = themes }(slices.Clone(Themes))
+	AddTheme(got)
+	if th, ok := ThemeNamed("Dracula"); !ok || th != got {
+		t.Error("an added theme should be found by name")
+	}
+
+	os.WriteFile(path, []byte("{"), 0644)
+	if _, err := OpenGlamourTheme(path); err == nil || !strings.Contains(err.Error(), "dracula.json") {
+		t.Errorf("bad JSON: %v", err)
+	}
+}
+
+func TestRenderASCII(t *testing.T) {
+	SetASCII(true)
+	defer SetASCII(false)
+	md := "- [x] done\n- [ ] todo\n- item\n\n> quoted\n\n---\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\nNote.[^1]\n\n[^1]: A note.\n"
+	got := ansi.Strip(Render([]byte(md), 60))
+	for _, want := range []string{"[x] done", "[ ] todo", "* item", "|  quoted", "-----", "+---+---+", "| 1 | 2 |", "A note. ^"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ASCII render lacks %q:\n%s", want, got)
+		}
+	}
+	for _, r := range got {
+		if r > 0x7F {
+			t.Fatalf("ASCII render has %q:\n%s", r, got)
+		}
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	tests := map[string]string{"": "", "#268bd2": "#268bd2", "196": "#ff0000", "236": "#303030", "1": "#800000"}
+	for in, want := range tests {
+		if got := HexColor(in); got != want {
+			t.Errorf("HexColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
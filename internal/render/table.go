@@ -37,11 +37,12 @@ func renderTable(buf *strings.Builder, table *east.Table, source []byte, maxWidt
 
 	var sepParts []string
 	for _, w := range colWidths {
-		sepParts = append(sepParts, strings.Repeat("─", w+2))
+		sepParts = append(sepParts, strings.Repeat(glyphs.tableH, w+2))
 	}
-	topBorder := "┌" + strings.Join(sepParts, "┬") + "┐"
-	separator := "├" + strings.Join(sepParts, "┼") + "┤"
-	bottomBorder := "└" + strings.Join(sepParts, "┴") + "┘"
+	j := glyphs.tableJoints
+	topBorder := j[0] + strings.Join(sepParts, j[1]) + j[2]
+	separator := j[3] + strings.Join(sepParts, j[4]) + j[5]
+	bottomBorder := j[6] + strings.Join(sepParts, j[7]) + j[8]
 
 	buf.WriteString(TableBorderStyle.Render(topBorder))
 	buf.WriteString("\n")
@@ -130,7 +131,7 @@ func renderTableRow(buf *strings.Builder, row []string, colWidths []int, numCols
 
 	for line := 0; line < maxLines; line++ {
 		var out strings.Builder
-		out.WriteString(TableBorderStyle.Render("│"))
+		out.WriteString(TableBorderStyle.Render(glyphs.tableV))
 		for j := 0; j < numCols; j++ {
 			content := ""
 			if line < len(cellLines[j]) {
@@ -146,7 +147,7 @@ func renderTableRow(buf *strings.Builder, row []string, colWidths []int, numCols
 			} else {
 				out.WriteString(TableCellStyle.Render(padded))
 			}
-			out.WriteString(TableBorderStyle.Render("│"))
+			out.WriteString(TableBorderStyle.Render(glyphs.tableV))
 		}
 		buf.WriteString(out.String())
 		buf.WriteString("\n")
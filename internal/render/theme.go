@@ -0,0 +1,117 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Theme is a color scheme for rendered documents. Colors are ANSI 256-color
+// numbers or #rrggbb; "" leaves the terminal's own color.
+type Theme struct {
+	Name               string
+	Heading1           string
+	Heading1Background string
+	Heading2           string
+	Heading3           string
+	Heading4           string
+	Code               string // code blocks
+	CodeBackground     string // code blocks and inline code
+	InlineCode         string
+	Link               string // links and footnote references
+	Border             string // quote bars, rules and table borders
+	Muted              string // struck-through text and footnotes
+	TableHeader        string
+	Text               string // table cells
+}
+
+// Themes are the themes documents can be rendered in: the built-in ones,
+// the default first, then any added with AddTheme.
+var Themes = []Theme{
+	{
+		Name:     "ink",
+		Heading1: "230", Heading1Background: "63", Heading2: "170", Heading3: "141", Heading4: "105",
+		Code: "252", CodeBackground: "236", InlineCode: "213",
+		Link: "87", Border: "240", Muted: "245", TableHeader: "170", Text: "252",
+	},
+	{
+		Name:     "light",
+		Heading1: "231", Heading1Background: "25", Heading2: "90", Heading3: "54", Heading4: "24",
+		Code: "235", CodeBackground: "254", InlineCode: "161",
+		Link: "25", Border: "246", Muted: "242", TableHeader: "90", Text: "235",
+	},
+	{
+		Name:     "solarized",
+		Heading1: "#fdf6e3", Heading1Background: "#268bd2", Heading2: "#d33682", Heading3: "#6c71c4", Heading4: "#2aa198",
+		Code: "#93a1a1", CodeBackground: "#073642", InlineCode: "#cb4b16",
+		Link: "#268bd2", Border: "#586e75", Muted: "#657b83", TableHeader: "#b58900", Text: "#93a1a1",
+	},
+}
+
+// current is the theme the styles were last set from.
+var current Theme
+
+// ThemeNamed returns the theme called name, ignoring case.
+func ThemeNamed(name string) (Theme, bool) {
+	for _, t := range Themes {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
+
+// ThemeNames returns the names of the themes.
+func ThemeNames() []string {
+	names := make([]string, len(Themes))
+	for i, t := range Themes {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// CurrentTheme returns the theme documents are rendered in.
+func CurrentTheme() Theme {
+	return current
+}
+
+// SetTheme colors the styles documents are rendered with from t. Documents
+// rendered before keep their colors until rendered again.
+func SetTheme(t Theme) {
+	current = t
+	H1Style = H1Style.Foreground(themeColor(t.Heading1)).Background(themeColor(t.Heading1Background))
+	H2Style = H2Style.Foreground(themeColor(t.Heading2))
+	H3Style = H3Style.Foreground(themeColor(t.Heading3))
+	H4Style = H4Style.Foreground(themeColor(t.Heading4))
+	CodeBlockStyle = CodeBlockStyle.Foreground(themeColor(t.Code)).Background(themeColor(t.CodeBackground))
+	InlineCodeStyle = InlineCodeStyle.Foreground(themeColor(t.InlineCode)).Background(themeColor(t.CodeBackground))
+	BlockquoteStyle = BlockquoteStyle.BorderForeground(themeColor(t.Border))
+	LinkStyle = LinkStyle.Foreground(themeColor(t.Link))
+	ThematicBreakStyle = ThematicBreakStyle.Foreground(themeColor(t.Border))
+	StrikethroughStyle = StrikethroughStyle.Foreground(themeColor(t.Muted))
+	TableHeaderStyle = TableHeaderStyle.Foreground(themeColor(t.TableHeader))
+	TableCellStyle = TableCellStyle.Foreground(themeColor(t.Text))
+	TableBorderStyle = TableBorderStyle.Foreground(themeColor(t.Border))
+	FootnoteRefStyle = FootnoteRefStyle.Foreground(themeColor(t.Link))
+	FootnoteStyle = FootnoteStyle.Foreground(themeColor(t.Muted))
+}
+
+// HexColor returns a theme color as #rrggbb, for styling outside the
+// terminal, or "" for "".
+func HexColor(c string) string {
+	if c == "" {
+		return ""
+	}
+	r, g, b, _ := themeColor(c).RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// themeColor turns a theme color into a lipgloss color.
+func themeColor(c string) color.Color {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
@@ -0,0 +1,649 @@
+// Package state persists ink's application state between sessions as a JSON
+// file in the user's state directory.
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// fileName is the name of the state file inside the state directory.
+const fileName = "state.json"
+
+// sessionsFileName is the name of the editing session log, kept next to the
+// state file. It holds one JSON object per line.
+const sessionsFileName = "sessions.jsonl"
+
+// analysesFileName is the name of the log of Metrics view analyses, kept
+// like the session log.
+const analysesFileName = "analyses.jsonl"
+
+// readsFileName is the name of the log of time spent reading documents,
+// kept like the session log.
+const readsFileName = "reads.jsonl"
+
+// maxRecent caps how many recently opened documents are remembered.
+const maxRecent = 50
+
+// lockWait is how long Save waits for another ink to finish saving, and
+// staleLock the age past which a lock file is taken to be left by an ink
+// that died while saving.
+const (
+	lockWait  = 2 * time.Second
+	staleLock = 10 * time.Second
+)
+
+// State is the persisted application state. A nil *State is valid and
+// behaves as an empty, read-only state.
+type State struct {
+	Pins    []string           `json:"pins,omitempty"`      // absolute paths of pinned documents
+	Recent  []Visit            `json:"recent,omitempty"`    // recently opened documents, newest first
+	Reading map[string]float64 `json:"progress,omitempty"`  // furthest fraction read (0-1), by path
+	Scroll  map[string]int     `json:"scroll,omitempty"`    // viewport line offset when last closed, by path
+	Drafts  map[string]Draft   `json:"drafts,omitempty"`    // unsaved editor content left at exit, by path
+	Marks   map[string][]int   `json:"bookmarks,omitempty"` // bookmarked line offsets, by path
+	Hashes  map[string]string  `json:"hashes,omitempty"`    // content hash of pinned and bookmarked documents, by path
+	LastDir string             `json:"last_dir,omitempty"`  // book directory last opened
+	LastDoc string             `json:"last_file,omitempty"` // document last opened
+	Stack   *Stack             `json:"stack,omitempty"`     // views shown when ink last quit
+
+	path string
+	base []byte // the file as last read or written, to tell this session's changes from others'
+}
+
+// Visit records when a document was last opened, and in which book.
+type Visit struct {
+	Path   string    `json:"path"`
+	Book   string    `json:"book,omitempty"` // root directory of the book; "" when opened on its own
+	Opened time.Time `json:"opened"`
+}
+
+// Stack is the views ink was showing when it quit, from the Book down to
+// any Metrics view, so they can be rebuilt.
+type Stack struct {
+	Book    string `json:"book,omitempty"`    // root of the book; "" when a document was opened on its own
+	Dir     string `json:"dir,omitempty"`     // folder the Book was listing
+	Filter  string `json:"filter,omitempty"`  // text the Book's list was filtered by
+	View    string `json:"view"`              // "book", "chapter" or "editor": the view under any Metrics view
+	Doc     string `json:"doc,omitempty"`     // document open in the Chapter or Editor
+	Metrics string `json:"metrics,omitempty"` // document the Metrics view was showing, if open
+}
+
+// Draft is editor content that was never saved.
+type Draft struct {
+	Content string    `json:"content"`
+	Saved   time.Time `json:"saved"`
+}
+
+// Dir returns the directory holding ink's state: $XDG_STATE_HOME/ink, or
+// ~/.local/state/ink when XDG_STATE_HOME is unset.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "ink"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "ink"), nil
+}
+
+// Load reads the state file from the default location. A missing file yields
+// an empty state.
+func Load() (*State, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(dir, fileName))
+}
+
+// Open reads the state file at path. A missing file yields an empty state that
+// will be written to path on Save. A corrupt file yields an empty state and
+// an error.
+func Open(path string) (*State, error) {
+	s := &State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &State{path: path}, err
+	}
+	s.base = data
+	return s, nil
+}
+
+// Save writes the state atomically, creating the state directory if needed.
+// Other inks may have saved since this one read the file, so Save merges:
+// what this session changed is written over the file as it is now, and the
+// rest is taken from the file. It holds a lock file while it does.
+func (s *State) Save() error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var base, disk State
+	if s.base != nil {
+		_ = json.Unmarshal(s.base, &base)
+	}
+	// A file another ink left unreadable is replaced.
+	if data, err := os.ReadFile(s.path); err == nil {
+		_ = json.Unmarshal(data, &disk)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.merge(&base, &disk)
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), fileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.base = data
+	return nil
+}
+
+// lockFile creates the lock file at path, waiting up to lockWait for
+// another ink to remove it, and returns the function that removes it.
+func lockFile(path string) (unlock func(), err error) {
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("state file is locked by another ink: " + path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// merge brings into s what changed in disk, the state file as another ink
+// left it, since base, the file as this session last read or wrote it.
+// Where both changed something, this session's change wins.
+func (s *State) merge(base, disk *State) {
+	s.Pins = mergeSet(base.Pins, s.Pins, disk.Pins)
+	s.Recent = mergeRecent(s.Recent, disk.Recent)
+	s.Reading = mergeMap(base.Reading, s.Reading, disk.Reading)
+	s.Scroll = mergeMap(base.Scroll, s.Scroll, disk.Scroll)
+	s.Drafts = mergeMap(base.Drafts, s.Drafts, disk.Drafts)
+	s.Marks = mergeMap(base.Marks, s.Marks, disk.Marks)
+	s.Hashes = mergeMap(base.Hashes, s.Hashes, disk.Hashes)
+	s.LastDir = mergeValue(base.LastDir, s.LastDir, disk.LastDir)
+	s.LastDoc = mergeValue(base.LastDoc, s.LastDoc, disk.LastDoc)
+	s.Stack = mergeValue(base.Stack, s.Stack, disk.Stack)
+}
+
+// mergeValue returns ours when this session changed it from base, and disk
+// otherwise.
+func mergeValue[V any](base, ours, disk V) V {
+	if reflect.DeepEqual(base, ours) {
+		return disk
+	}
+	return ours
+}
+
+// mergeMap merges maps key by key, like mergeValue: keys this session
+// added, changed or deleted since base are taken from ours, the others from
+// disk.
+func mergeMap[V any](base, ours, disk map[string]V) map[string]V {
+	merged := make(map[string]V, len(disk))
+	maps.Copy(merged, disk)
+	for _, m := range []map[string]V{base, ours} {
+		for k := range m {
+			b, inBase := base[k]
+			o, inOurs := ours[k]
+			switch {
+			case inBase == inOurs && reflect.DeepEqual(b, o):
+			case inOurs:
+				merged[k] = o
+			default:
+				delete(merged, k)
+			}
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// mergeSet applies the paths this session added to or removed from base to
+// disk, keeping disk's order.
+func mergeSet(base, ours, disk []string) []string {
+	merged := slices.DeleteFunc(slices.Clone(disk), func(p string) bool {
+		return slices.Contains(base, p) && !slices.Contains(ours, p)
+	})
+	for _, p := range ours {
+		if !slices.Contains(merged, p) && !slices.Contains(base, p) {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// mergeRecent combines two recent lists, newest first, keeping the latest
+// visit to each document.
+func mergeRecent(ours, disk []Visit) []Visit {
+	var merged []Visit
+	for _, v := range slices.Concat(ours, disk) {
+		i := slices.IndexFunc(merged, func(m Visit) bool { return m.Path == v.Path })
+		if i < 0 {
+			merged = append(merged, v)
+		} else if v.Opened.After(merged[i].Opened) {
+			merged[i] = v
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b Visit) int { return b.Opened.Compare(a.Opened) })
+	if len(merged) > maxRecent {
+		merged = merged[:maxRecent]
+	}
+	return merged
+}
+
+// Pinned reports whether path is pinned.
+func (s *State) Pinned(path string) bool {
+	return s != nil && slices.Contains(s.Pins, path)
+}
+
+// TogglePin pins or unpins path and reports whether it is now pinned.
+func (s *State) TogglePin(path string) bool {
+	if s == nil {
+		return false
+	}
+	if i := slices.Index(s.Pins, path); i >= 0 {
+		s.Pins = slices.Delete(s.Pins, i, i+1)
+		s.untrack(path)
+		return false
+	}
+	s.Pins = append(s.Pins, path)
+	return true
+}
+
+// AddRecent records that path was opened in the book rooted at book at t,
+// moving it to the front of the recent list.
+func (s *State) AddRecent(path, book string, t time.Time) {
+	if s == nil {
+		return
+	}
+	s.Recent = slices.DeleteFunc(s.Recent, func(v Visit) bool { return v.Path == path })
+	s.Recent = slices.Insert(s.Recent, 0, Visit{Path: path, Book: book, Opened: t})
+	if len(s.Recent) > maxRecent {
+		s.Recent = s.Recent[:maxRecent]
+	}
+}
+
+// Progress returns how far path has been read, from 0 to 1.
+func (s *State) Progress(path string) float64 {
+	if s == nil {
+		return 0
+	}
+	return s.Reading[path]
+}
+
+// SetProgress records that path has been read up to p (0-1). Documents
+// not read at all are forgotten.
+func (s *State) SetProgress(path string, p float64) {
+	if s == nil {
+		return
+	}
+	if p <= 0 {
+		delete(s.Reading, path)
+		return
+	}
+	if s.Reading == nil {
+		s.Reading = make(map[string]float64)
+	}
+	s.Reading[path] = min(p, 1)
+}
+
+// ScrollOffset returns the line the reader was scrolled to when path was
+// last closed.
+func (s *State) ScrollOffset(path string) int {
+	if s == nil {
+		return 0
+	}
+	return s.Scroll[path]
+}
+
+// SetScrollOffset records the line path is scrolled to. Documents left at
+// the top are forgotten.
+func (s *State) SetScrollOffset(path string, offset int) {
+	if s == nil {
+		return
+	}
+	if offset <= 0 {
+		delete(s.Scroll, path)
+		return
+	}
+	if s.Scroll == nil {
+		s.Scroll = make(map[string]int)
+	}
+	s.Scroll[path] = offset
+}
+
+// SetLastDir records dir as the book directory last opened.
+func (s *State) SetLastDir(dir string) {
+	if s != nil {
+		s.LastDir = dir
+	}
+}
+
+// SetLastDoc records path as the document last opened.
+func (s *State) SetLastDoc(path string) {
+	if s != nil {
+		s.LastDoc = path
+	}
+}
+
+// SetStack records the views ink is showing as it quits.
+func (s *State) SetStack(stack Stack) {
+	if s != nil {
+		s.Stack = &stack
+	}
+}
+
+// Bookmarks returns the line offsets bookmarked in path, in order.
+func (s *State) Bookmarks(path string) []int {
+	if s == nil {
+		return nil
+	}
+	return s.Marks[path]
+}
+
+// ToggleBookmark bookmarks line in path, or removes the bookmark there, and
+// reports whether it is now bookmarked.
+func (s *State) ToggleBookmark(path string, line int) bool {
+	if s == nil {
+		return false
+	}
+	marks := s.Marks[path]
+	i, found := slices.BinarySearch(marks, line)
+	if found {
+		marks = slices.Delete(marks, i, i+1)
+	} else {
+		marks = slices.Insert(marks, i, line)
+	}
+	if len(marks) == 0 {
+		delete(s.Marks, path)
+		s.untrack(path)
+		return false
+	}
+	if s.Marks == nil {
+		s.Marks = make(map[string][]int)
+	}
+	s.Marks[path] = marks
+	return !found
+}
+
+// ContentHash identifies a document by its content, so its pins and
+// bookmarks can follow it when it is renamed.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// tracked reports whether path is pinned or bookmarked.
+func (s *State) tracked(path string) bool {
+	return s.Pinned(path) || len(s.Marks[path]) > 0
+}
+
+// untrack forgets the content hash of path once it is neither pinned nor
+// bookmarked.
+func (s *State) untrack(path string) {
+	if !s.tracked(path) {
+		delete(s.Hashes, path)
+	}
+}
+
+// Seen notes that the document at path has content hash. A pinned or
+// bookmarked document has its hash updated. Any other document takes over the
+// pin and bookmarks of a document with the same hash that is gone from disk,
+// as one renamed outside ink would be, and Seen reports whether it did.
+func (s *State) Seen(path, hash string) bool {
+	if s == nil {
+		return false
+	}
+	if s.tracked(path) {
+		if s.Hashes == nil {
+			s.Hashes = make(map[string]string)
+		}
+		s.Hashes[path] = hash
+		return false
+	}
+	for old, h := range s.Hashes {
+		if h != hash || old == path {
+			continue
+		}
+		if _, err := os.Stat(old); errors.Is(err, os.ErrNotExist) {
+			s.Rename(old, path)
+			return true
+		}
+	}
+	return false
+}
+
+// Rename moves what is remembered about the document at from, its pin,
+// bookmarks, reading progress and scroll offset, to to.
+func (s *State) Rename(from, to string) {
+	if s == nil || from == to {
+		return
+	}
+	if i := slices.Index(s.Pins, from); i >= 0 && s.Pinned(to) {
+		s.Pins = slices.Delete(s.Pins, i, i+1)
+	} else if i >= 0 {
+		s.Pins[i] = to
+	}
+	moveKey(s.Marks, from, to)
+	moveKey(s.Hashes, from, to)
+	moveKey(s.Reading, from, to)
+	moveKey(s.Scroll, from, to)
+}
+
+// moveKey moves the value of from in m to to.
+func moveKey[V any](m map[string]V, from, to string) {
+	if v, ok := m[from]; ok {
+		m[to] = v
+		delete(m, from)
+	}
+}
+
+// Draft returns the unsaved editor content left for path, if any.
+func (s *State) Draft(path string) (Draft, bool) {
+	if s == nil {
+		return Draft{}, false
+	}
+	d, ok := s.Drafts[path]
+	return d, ok
+}
+
+// SetDraft records content as the unsaved editor content of path at t.
+func (s *State) SetDraft(path, content string, t time.Time) {
+	if s == nil {
+		return
+	}
+	if s.Drafts == nil {
+		s.Drafts = make(map[string]Draft)
+	}
+	s.Drafts[path] = Draft{Content: content, Saved: t}
+}
+
+// ClearDraft forgets the unsaved editor content of path.
+func (s *State) ClearDraft(path string) {
+	if s == nil {
+		return
+	}
+	delete(s.Drafts, path)
+}
+
+// Session is one editing session, as logged for the statistics screen.
+type Session struct {
+	Path    string        `json:"path"`
+	Start   time.Time     `json:"start"`
+	End     time.Time     `json:"end"`
+	Typing  time.Duration `json:"typing"` // time spent typing, as opposed to idle
+	Added   int           `json:"added"`  // words added
+	Removed int           `json:"removed"`
+}
+
+// LogSession appends sess to the session log.
+func (s *State) LogSession(sess Session) error {
+	return s.appendLog(sessionsFileName, sess)
+}
+
+// Sessions returns the logged editing sessions, oldest first. Lines that
+// don't parse are skipped.
+func (s *State) Sessions() ([]Session, error) {
+	var sessions []Session
+	err := s.readLog(sessionsFileName, func(line []byte) {
+		var sess Session
+		if json.Unmarshal(line, &sess) == nil {
+			sessions = append(sessions, sess)
+		}
+	})
+	return sessions, err
+}
+
+// Read is one stay in the viewer on a document, as logged for the
+// statistics screen.
+type Read struct {
+	Path   string        `json:"path"`
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Active time.Duration `json:"active"` // time spent reading, as opposed to idle
+}
+
+// LogRead appends r to the reading log.
+func (s *State) LogRead(r Read) error {
+	return s.appendLog(readsFileName, r)
+}
+
+// Reads returns the logged reading, oldest first. Lines that don't parse
+// are skipped.
+func (s *State) Reads() ([]Read, error) {
+	var reads []Read
+	err := s.readLog(readsFileName, func(line []byte) {
+		var r Read
+		if json.Unmarshal(line, &r) == nil {
+			reads = append(reads, r)
+		}
+	})
+	return reads, err
+}
+
+// Analysis is one analysis of a document in the Metrics view, as logged to
+// show how its scores change over revisions.
+type Analysis struct {
+	Path   string             `json:"path"`
+	Time   time.Time          `json:"time"`
+	Words  int                `json:"words"`
+	Grade  float64            `json:"grade,omitempty"`
+	Scores map[string]float64 `json:"scores"` // score by axis name
+}
+
+// LogAnalysis appends a to the analysis log.
+func (s *State) LogAnalysis(a Analysis) error {
+	return s.appendLog(analysesFileName, a)
+}
+
+// Analyses returns the logged analyses of the document at path, oldest
+// first.
+func (s *State) Analyses(path string) ([]Analysis, error) {
+	var analyses []Analysis
+	err := s.readLog(analysesFileName, func(line []byte) {
+		var a Analysis
+		if json.Unmarshal(line, &a) == nil && a.Path == path {
+			analyses = append(analyses, a)
+		}
+	})
+	return analyses, err
+}
+
+// appendLog appends v as a line of JSON to the log called name, kept next
+// to the state file.
+func (s *State) appendLog(name string, v any) error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(s.path), name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readLog calls fn with each line of the log called name. A missing log is
+// empty.
+func (s *State) readLog(name string, fn func(line []byte)) error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(s.path), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		fn(sc.Bytes())
+	}
+	return sc.Err()
+}
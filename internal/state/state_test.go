@@ -0,0 +1,344 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOpenMissingFile(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Open missing: %v", err)
+	}
+	if len(s.Pins) != 0 {
+		t.Errorf("Open missing: expected empty state, got %+v", s)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	s, _ := Open(path)
+	if !s.TogglePin("/books/a.md") {
+		t.Fatal("TogglePin: expected pinned")
+	}
+	s.TogglePin("/books/b.md")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !loaded.Pinned("/books/a.md") || !loaded.Pinned("/books/b.md") {
+		t.Errorf("round trip lost pins: %+v", loaded.Pins)
+	}
+	if loaded.TogglePin("/books/a.md") {
+		t.Error("TogglePin: expected unpinned")
+	}
+	if loaded.Pinned("/books/a.md") {
+		t.Error("Pinned after unpin")
+	}
+}
+
+func TestSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	s.TogglePin("/books/old.md")
+	s.SetProgress("/books/old.md", 0.5)
+	s.Save()
+
+	// Two inks open the same state, and each saves its own changes.
+	a, _ := Open(path)
+	b, _ := Open(path)
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	a.TogglePin("/books/a.md")
+	a.AddRecent("/books/a.md", "", t0)
+	a.SetProgress("/books/old.md", 0.9)
+	a.SetLastDoc("/books/a.md")
+	b.TogglePin("/books/old.md")
+	b.ToggleBookmark("/books/b.md", 12)
+	b.AddRecent("/books/b.md", "", t0.Add(time.Minute))
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := Open(path)
+	if !slices.Equal(loaded.Pins, []string{"/books/a.md"}) {
+		t.Errorf("pins = %q, want a's pin without the one b removed", loaded.Pins)
+	}
+	if loaded.Progress("/books/old.md") != 0.9 || !slices.Equal(loaded.Bookmarks("/books/b.md"), []int{12}) {
+		t.Errorf("progress %v, bookmarks %v: each ink's change should be kept", loaded.Reading, loaded.Marks)
+	}
+	if len(loaded.Recent) != 2 || loaded.Recent[0].Path != "/books/b.md" || loaded.LastDoc != "/books/a.md" {
+		t.Errorf("recent %+v, last doc %q", loaded.Recent, loaded.LastDoc)
+	}
+	if !b.Pinned("/books/a.md") {
+		t.Error("saving should bring in what the other ink saved")
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Error("Save should remove its lock file")
+	}
+}
+
+func TestSaveWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	s.TogglePin("/a.md")
+	os.WriteFile(path+".lock", nil, 0644)
+	done := make(chan error)
+	go func() { done <- s.Save() }()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Save shouldn't write while another ink holds the lock")
+	}
+	os.Remove(path + ".lock")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := Open(path); !loaded.Pinned("/a.md") {
+		t.Error("Save should write once the lock is released")
+	}
+}
+
+func TestOpenCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	s, err := Open(path)
+	if err == nil {
+		t.Error("Open corrupt: expected error")
+	}
+	if s == nil || len(s.Pins) != 0 {
+		t.Errorf("Open corrupt: expected empty state, got %+v", s)
+	}
+}
+
+func TestNilState(t *testing.T) {
+	var s *State
+	if s.Pinned("/a.md") || s.TogglePin("/a.md") {
+		t.Error("nil state should report nothing pinned")
+	}
+	if err := s.Save(); err != nil {
+		t.Errorf("nil Save: %v", err)
+	}
+}
+
+func TestAddRecent(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "state.json"))
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.AddRecent("/a.md", "/", t0)
+	s.AddRecent("/b.md", "", t0.Add(time.Hour))
+	s.AddRecent("/a.md", "/", t0.Add(2*time.Hour))
+	if len(s.Recent) != 2 || s.Recent[0].Path != "/a.md" || s.Recent[0].Book != "/" || s.Recent[1].Path != "/b.md" {
+		t.Fatalf("AddRecent order = %+v", s.Recent)
+	}
+	for i := range maxRecent + 5 {
+		s.AddRecent(filepath.Join("/", string(rune('a'+i%26)), "x.md"), "", t0)
+	}
+	if len(s.Recent) > maxRecent {
+		t.Errorf("Recent length = %d, want <= %d", len(s.Recent), maxRecent)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	s.SetProgress("/books/a.md", 0.37)
+	s.SetProgress("/books/b.md", 1.5)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, _ := Open(path)
+	if got := loaded.Progress("/books/a.md"); got != 0.37 {
+		t.Errorf("Progress(a) = %v, want 0.37", got)
+	}
+	if got := loaded.Progress("/books/b.md"); got != 1 {
+		t.Errorf("Progress(b) = %v, want clamped to 1", got)
+	}
+	loaded.SetProgress("/books/a.md", 0)
+	if _, ok := loaded.Reading["/books/a.md"]; ok {
+		t.Error("zero progress should be forgotten")
+	}
+	var nilState *State
+	nilState.SetProgress("/a.md", 0.5)
+	if nilState.Progress("/a.md") != 0 {
+		t.Error("nil state should report no progress")
+	}
+}
+
+func TestScrollOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	s.SetScrollOffset("/books/a.md", 120)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, _ := Open(path)
+	if got := loaded.ScrollOffset("/books/a.md"); got != 120 {
+		t.Errorf("ScrollOffset(a) = %d, want 120", got)
+	}
+	loaded.SetScrollOffset("/books/a.md", 0)
+	if _, ok := loaded.Scroll["/books/a.md"]; ok {
+		t.Error("offset at the top should be forgotten")
+	}
+	var nilState *State
+	nilState.SetScrollOffset("/a.md", 5)
+	if nilState.ScrollOffset("/a.md") != 0 {
+		t.Error("nil state should report no offset")
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	t0 := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	s.SetDraft("/books/a.md", "unsaved\ntext", t0)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, _ := Open(path)
+	d, ok := loaded.Draft("/books/a.md")
+	if !ok || d.Content != "unsaved\ntext" || !d.Saved.Equal(t0) {
+		t.Errorf("Draft(a) = %+v, %v", d, ok)
+	}
+	loaded.ClearDraft("/books/a.md")
+	if _, ok := loaded.Draft("/books/a.md"); ok {
+		t.Error("cleared draft should be gone")
+	}
+	var nilState *State
+	nilState.SetDraft("/a.md", "x", t0)
+	if _, ok := nilState.Draft("/a.md"); ok {
+		t.Error("nil state should have no drafts")
+	}
+}
+
+func TestSessionLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		sess := Session{Path: "/books/a.md", Start: t0, End: t0.Add(time.Hour), Typing: time.Duration(i+1) * time.Minute, Added: 100 * (i + 1), Removed: 3}
+		if err := s.LogSession(sess); err != nil {
+			t.Fatalf("LogSession: %v", err)
+		}
+	}
+	loaded, _ := Open(path)
+	sessions, err := loaded.Sessions()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("Sessions = %+v, %v", sessions, err)
+	}
+	if sessions[1].Added != 200 || sessions[1].Typing != 2*time.Minute || !sessions[1].End.Equal(t0.Add(time.Hour)) {
+		t.Errorf("second session = %+v", sessions[1])
+	}
+	var nilState *State
+	if err := nilState.LogSession(sessions[0]); err != nil {
+		t.Errorf("nil LogSession: %v", err)
+	}
+}
+
+func TestReadLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	if err := s.LogRead(Read{Path: "/books/a.md", Start: t0, End: t0.Add(time.Hour), Active: 20 * time.Minute}); err != nil {
+		t.Fatalf("LogRead: %v", err)
+	}
+	loaded, _ := Open(path)
+	reads, err := loaded.Reads()
+	if err != nil || len(reads) != 1 || reads[0].Active != 20*time.Minute || reads[0].Path != "/books/a.md" {
+		t.Errorf("Reads = %+v, %v", reads, err)
+	}
+}
+
+func TestAnalysisLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i, doc := range []string{"/books/a.md", "/books/b.md", "/books/a.md"} {
+		a := Analysis{Path: doc, Time: t0.Add(time.Duration(i) * time.Hour), Words: 100 * (i + 1), Scores: map[string]float64{"rhythm": float64(i+1) / 10}}
+		if err := s.LogAnalysis(a); err != nil {
+			t.Fatalf("LogAnalysis: %v", err)
+		}
+	}
+	loaded, _ := Open(path)
+	analyses, err := loaded.Analyses("/books/a.md")
+	if err != nil || len(analyses) != 2 {
+		t.Fatalf("Analyses = %+v, %v", analyses, err)
+	}
+	if a := analyses[1]; a.Words != 300 || a.Scores["rhythm"] != 0.3 || !a.Time.Equal(t0.Add(2*time.Hour)) {
+		t.Errorf("second analysis of a.md = %+v", a)
+	}
+	var nilState *State
+	if got, err := nilState.Analyses("/books/a.md"); got != nil || err != nil {
+		t.Errorf("nil Analyses = %v, %v", got, err)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, _ := Open(path)
+	for _, line := range []int{40, 10, 25} {
+		if !s.ToggleBookmark("/a.md", line) {
+			t.Errorf("ToggleBookmark(%d): expected bookmarked", line)
+		}
+	}
+	if s.ToggleBookmark("/a.md", 25) {
+		t.Error("ToggleBookmark(25) again: expected removed")
+	}
+	s.SetLastDir("/books")
+	s.SetLastDoc("/books/a.md")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := Open(path)
+	if got := loaded.Bookmarks("/a.md"); len(got) != 2 || got[0] != 10 || got[1] != 40 {
+		t.Errorf("Bookmarks = %v, want [10 40]", got)
+	}
+	if loaded.LastDir != "/books" || loaded.LastDoc != "/books/a.md" {
+		t.Errorf("last opened = %q, %q", loaded.LastDir, loaded.LastDoc)
+	}
+	loaded.ToggleBookmark("/a.md", 10)
+	loaded.ToggleBookmark("/a.md", 40)
+	if _, ok := loaded.Marks["/a.md"]; ok {
+		t.Error("a document without bookmarks should be forgotten")
+	}
+}
+
+func TestSeen(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(filepath.Join(dir, "state.json"))
+	old, renamed := filepath.Join(dir, "old.md"), filepath.Join(dir, "new.md")
+	hash := ContentHash("# Notes\n")
+	s.TogglePin(old)
+	s.ToggleBookmark(old, 12)
+	s.SetProgress(old, 0.5)
+	if s.Seen(old, hash) {
+		t.Error("Seen on the pinned path itself should only record its hash")
+	}
+	if s.Hashes[old] != hash {
+		t.Fatalf("hash of pinned document = %q, want %q", s.Hashes[old], hash)
+	}
+
+	// old.md doesn't exist on disk, as if it had been renamed to new.md.
+	if s.Seen(filepath.Join(dir, "other.md"), ContentHash("other")) {
+		t.Error("a document with other content shouldn't take over the pin")
+	}
+	if !s.Seen(renamed, hash) {
+		t.Fatal("a document with the same content should take over the pin")
+	}
+	if s.Pinned(old) || !s.Pinned(renamed) || len(s.Bookmarks(renamed)) != 1 || s.Progress(renamed) != 0.5 {
+		t.Errorf("after rename: pins %v, bookmarks %v, progress %v", s.Pins, s.Marks, s.Reading)
+	}
+
+	s.TogglePin(renamed)
+	s.ToggleBookmark(renamed, 12)
+	if _, ok := s.Hashes[renamed]; ok {
+		t.Error("an unpinned document without bookmarks should lose its hash")
+	}
+}